@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/harshpatel5940/gitvigil/internal/config"
+	"github.com/harshpatel5940/gitvigil/internal/database"
+	"github.com/harshpatel5940/gitvigil/internal/detection"
+	"github.com/harshpatel5940/gitvigil/internal/github"
+	"github.com/harshpatel5940/gitvigil/internal/models"
+	"github.com/harshpatel5940/gitvigil/internal/server"
+	"github.com/harshpatel5940/gitvigil/internal/webhook"
+	"github.com/rs/zerolog"
+)
+
+// runCommand executes a one-shot CLI subcommand against the configured database.
+func runCommand(ctx context.Context, cfg *config.Config, db *database.DB, gh *github.AppClient, logger zerolog.Logger, name string, args []string) error {
+	switch name {
+	case "replay":
+		return runReplay(ctx, cfg, db, gh, logger, args)
+	case "rules":
+		return runRules(ctx, cfg, db, gh, logger, args)
+	default:
+		return fmt.Errorf("unknown command %q (available: replay, rules)", name)
+	}
+}
+
+// runOfflineCommand runs a subcommand that needs no database, reporting
+// whether args named one.
+func runOfflineCommand(cfg *config.Config, gh *github.AppClient, logger zerolog.Logger, args []string) (bool, error) {
+	if len(args) >= 2 && args[0] == "rules" && args[1] == "validate" {
+		return true, runRulesValidate(cfg, gh, logger, args[2:])
+	}
+	return false, nil
+}
+
+// runReplay reprocesses stored webhook deliveries:
+//
+//	gitvigil replay -delivery <id>
+//	gitvigil replay -from 2024-01-01T00:00:00Z [-to 2024-01-02T00:00:00Z]
+func runReplay(ctx context.Context, cfg *config.Config, db *database.DB, gh *github.AppClient, logger zerolog.Logger, args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	deliveryID := fs.String("delivery", "", "delivery ID to replay")
+	fromStr := fs.String("from", "", "replay deliveries received at or after this time (RFC 3339)")
+	toStr := fs.String("to", "", "replay deliveries received before this time (RFC 3339, default now)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// Jobs enqueued during the replay are picked up by the running server
+	handler := webhook.NewHandler(cfg, db, gh, server.NewQueue(cfg, db, logger), logger)
+
+	if *deliveryID != "" {
+		return handler.Replay(ctx, *deliveryID)
+	}
+
+	if *fromStr == "" {
+		return fmt.Errorf("either -delivery or -from is required")
+	}
+	from, err := time.Parse(time.RFC3339, *fromStr)
+	if err != nil {
+		return fmt.Errorf("invalid -from: %w", err)
+	}
+	to := time.Now()
+	if *toStr != "" {
+		if to, err = time.Parse(time.RFC3339, *toStr); err != nil {
+			return fmt.Errorf("invalid -to: %w", err)
+		}
+	}
+
+	result, err := handler.ReplayRange(ctx, from, to)
+	if err != nil {
+		return err
+	}
+	for _, e := range result.Errors {
+		logger.Error().Str("error", e).Msg("replay failed")
+	}
+	logger.Info().Int("replayed", result.Replayed).Int("failed", result.Failed).Msg("replay finished")
+	return nil
+}
+
+// runRulesValidate checks a custom rules file without touching the
+// database:
+//
+//	gitvigil rules validate [-file rules.json]
+//
+// The file defaults to CUSTOM_RULES_FILE.
+func runRulesValidate(cfg *config.Config, gh *github.AppClient, logger zerolog.Logger, args []string) error {
+	fs := flag.NewFlagSet("rules validate", flag.ContinueOnError)
+	file := fs.String("file", cfg.CustomRulesFile, "custom rules file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *file == "" {
+		return fmt.Errorf("no rules file given (-file or CUSTOM_RULES_FILE)")
+	}
+
+	custom, err := detection.LoadCustomRules(cfg, gh, *file)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "RULE\tTRIGGER\tSEVERITY\tFIELDS")
+	for _, rule := range custom {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", rule.Name(), rule.Triggers()[0], rule.DefaultSeverity(), strings.Join(rule.Fields(), ","))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	logger.Info().Int("rules", len(custom)).Str("file", *file).Msg("custom rules are valid")
+	return nil
+}
+
+// runRules tries a custom rules file on stored data:
+//
+//	gitvigil rules dry-run [-file rules.json] [-repo <id>] [-from <time>] [-to <time>] [-limit <n>]
+//
+// The file defaults to CUSTOM_RULES_FILE. A dry run raises no alerts; it
+// prints every stored commit and push the rules would have matched.
+// Validation runs offline, see runRulesValidate.
+func runRules(ctx context.Context, cfg *config.Config, db *database.DB, gh *github.AppClient, logger zerolog.Logger, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("rules needs a subcommand (available: validate, dry-run)")
+	}
+	sub := args[0]
+
+	fs := flag.NewFlagSet("rules "+sub, flag.ContinueOnError)
+	file := fs.String("file", cfg.CustomRulesFile, "custom rules file")
+	repoID := fs.Int64("repo", 0, "only this repository ID")
+	fromStr := fs.String("from", "", "only data pushed at or after this time (RFC 3339)")
+	toStr := fs.String("to", "", "only data pushed at or before this time (RFC 3339)")
+	limit := fs.Int("limit", 0, "examine at most this many commits and pushes each")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *file == "" {
+		return fmt.Errorf("no rules file given (-file or CUSTOM_RULES_FILE)")
+	}
+
+	custom, err := detection.LoadCustomRules(cfg, gh, *file)
+	if err != nil {
+		return err
+	}
+
+	switch sub {
+	case "dry-run":
+		filter := models.RecordFilter{Limit: *limit}
+		if *repoID != 0 {
+			filter.RepositoryID = repoID
+		}
+		if *fromStr != "" {
+			from, err := time.Parse(time.RFC3339, *fromStr)
+			if err != nil {
+				return fmt.Errorf("invalid -from: %w", err)
+			}
+			filter.From = &from
+		}
+		if *toStr != "" {
+			to, err := time.Parse(time.RFC3339, *toStr)
+			if err != nil {
+				return fmt.Errorf("invalid -to: %w", err)
+			}
+			filter.To = &to
+		}
+
+		detector := detection.NewDetector(cfg, db, gh, logger)
+		result, err := detector.Rules().DryRun(ctx, custom, filter, cfg.SubmissionGraceMinutes)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "RULE\tREPOSITORY\tCOMMIT/PUSH\tPUSHED AT\tSEVERITY\tTITLE")
+		for _, m := range result.Matches {
+			target := m.CommitSHA
+			if target == "" {
+				target = fmt.Sprintf("push %d", m.PushEventID)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", m.Rule, m.Repository, target, m.At.UTC().Format(time.RFC3339), m.Severity, m.Title)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		logger.Info().
+			Int("commits", result.Commits).
+			Int("pushes", result.Pushes).
+			Int("matches", len(result.Matches)).
+			Int("disabled", result.Disabled).
+			Msg("dry run finished")
+		return nil
+	}
+	return fmt.Errorf("unknown rules subcommand %q (available: validate, dry-run)", sub)
+}
@@ -45,6 +45,28 @@ func main() {
 		cancel()
 	}()
 
+	// Create GitHub App client (optional - webhooks won't work without it)
+	var gh *github.AppClient
+	if len(cfg.PrivateKey) > 0 {
+		gh, err = github.NewAppClient(cfg.AppID, cfg.PrivateKey)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("failed to create GitHub App client")
+		}
+		logger.Info().Int64("app_id", gh.AppID()).Msg("GitHub App client created")
+	} else {
+		logger.Warn().Msg("no private key configured - GitHub App features disabled (webhooks, license checks)")
+	}
+
+	// Validating a rules file needs no database
+	if len(os.Args) > 1 {
+		if ok, err := runOfflineCommand(cfg, gh, logger, os.Args[1:]); ok {
+			if err != nil {
+				logger.Fatal().Err(err).Str("command", os.Args[1]).Msg("command failed")
+			}
+			return
+		}
+	}
+
 	// Connect to database
 	db, err := database.New(ctx, cfg.DatabaseURL)
 	if err != nil {
@@ -59,16 +81,12 @@ func main() {
 	}
 	logger.Info().Msg("migrations completed")
 
-	// Create GitHub App client (optional - webhooks won't work without it)
-	var gh *github.AppClient
-	if len(cfg.PrivateKey) > 0 {
-		gh, err = github.NewAppClient(cfg.AppID, cfg.PrivateKey)
-		if err != nil {
-			logger.Fatal().Err(err).Msg("failed to create GitHub App client")
+	// Run a CLI subcommand instead of the server if one was given
+	if len(os.Args) > 1 {
+		if err := runCommand(ctx, cfg, db, gh, logger, os.Args[1], os.Args[2:]); err != nil {
+			logger.Fatal().Err(err).Str("command", os.Args[1]).Msg("command failed")
 		}
-		logger.Info().Int64("app_id", gh.AppID()).Msg("GitHub App client created")
-	} else {
-		logger.Warn().Msg("no private key configured - GitHub App features disabled (webhooks, license checks)")
+		return
 	}
 
 	// Create and start server
@@ -0,0 +1,250 @@
+package analysis
+
+import (
+	"sort"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Winnowing parameters: fingerprints are hashes of KGramSize consecutive
+// non-whitespace characters, and one is kept from every WinnowWindow
+// consecutive hashes, so any match of at least
+// KGramSize+WinnowWindow-1 characters is guaranteed to be detected.
+const (
+	KGramSize    = 25
+	WinnowWindow = 20
+)
+
+const hashBase = 257
+
+// FileFingerprints is the winnowed fingerprint set of one source file.
+type FileFingerprints struct {
+	Path   string
+	Hashes []uint64
+}
+
+// SnapshotFingerprints is the fingerprinted source of one repository.
+type SnapshotFingerprints struct {
+	RepositoryID int64
+	Files        []FileFingerprints
+}
+
+// FileMatch is a pair of files from two repositories sharing fingerprints.
+type FileMatch struct {
+	FileA      string  `json:"file_a"`
+	FileB      string  `json:"file_b"`
+	Shared     int     `json:"shared_fingerprints"`
+	Similarity float64 `json:"similarity"`
+}
+
+// SimilarityPair compares two repositories. Similarity is the share of the
+// smaller repository's fingerprints found in the other, as a percentage, so
+// copying a whole project into a larger one still scores high; Jaccard is the
+// overlap relative to both.
+type SimilarityPair struct {
+	RepositoryA int64
+	RepositoryB int64
+	Shared      int
+	Similarity  float64
+	Jaccard     float64
+	TopFiles    []FileMatch
+}
+
+// Fingerprint computes the winnowed k-gram fingerprints of source text.
+// Whitespace is ignored and letters are lowercased, so reformatting does
+// not hide a copy. The result is sorted and free of duplicates.
+func Fingerprint(content []byte) []uint64 {
+	text := normalize(content)
+	if len(text) < KGramSize {
+		return nil
+	}
+
+	// Rolling hash of every k-gram
+	var pow uint64 = 1
+	for i := 0; i < KGramSize-1; i++ {
+		pow *= hashBase
+	}
+	hashes := make([]uint64, 0, len(text)-KGramSize+1)
+	var h uint64
+	for i, r := range text {
+		if i >= KGramSize {
+			h -= uint64(text[i-KGramSize]) * pow
+		}
+		h = h*hashBase + uint64(r)
+		if i >= KGramSize-1 {
+			hashes = append(hashes, h)
+		}
+	}
+
+	// Keep the rightmost minimum of each window
+	seen := make(map[uint64]bool)
+	var selected []uint64
+	last := -1
+	windows := max(len(hashes)-WinnowWindow+1, 1)
+	for start := 0; start < windows; start++ {
+		end := min(start+WinnowWindow, len(hashes))
+		minAt := start
+		for i := start; i < end; i++ {
+			if hashes[i] <= hashes[minAt] {
+				minAt = i
+			}
+		}
+		if minAt != last {
+			last = minAt
+			if !seen[hashes[minAt]] {
+				seen[hashes[minAt]] = true
+				selected = append(selected, hashes[minAt])
+			}
+		}
+	}
+
+	sort.Slice(selected, func(i, j int) bool { return selected[i] < selected[j] })
+	return selected
+}
+
+func normalize(content []byte) []rune {
+	text := make([]rune, 0, len(content))
+	for len(content) > 0 {
+		r, size := utf8.DecodeRune(content)
+		content = content[size:]
+		if unicode.IsSpace(r) {
+			continue
+		}
+		text = append(text, unicode.ToLower(r))
+	}
+	return text
+}
+
+// fileRef locates a file within the snapshots passed to CompareSnapshots.
+type fileRef struct {
+	snapshot, file int
+}
+
+// CompareSnapshots compares every pair of repositories and returns the pairs
+// sharing any fingerprints, most similar first, each with up to topFiles of
+// its best matching file pairs. With four or more repositories, fingerprints
+// found in more than half of them are treated as common boilerplate, such as
+// framework scaffolding, and ignored.
+func CompareSnapshots(snapshots []SnapshotFingerprints, topFiles int) []SimilarityPair {
+	// Index every fingerprint by the files holding it
+	index := make(map[uint64][]fileRef)
+	for s, snap := range snapshots {
+		for f, file := range snap.Files {
+			for _, h := range file.Hashes {
+				index[h] = append(index[h], fileRef{snapshot: s, file: f})
+			}
+		}
+	}
+
+	common := func(refs []fileRef) bool {
+		if len(snapshots) < 4 {
+			return false
+		}
+		seen := make(map[int]bool)
+		for _, ref := range refs {
+			seen[ref.snapshot] = true
+		}
+		return len(seen)*2 > len(snapshots)
+	}
+
+	// Fingerprint set sizes per repository and file, without common ones
+	repoSize := make([]int, len(snapshots))
+	fileSize := make(map[fileRef]int)
+	type pairKey struct{ a, b int }
+	type fileKey struct{ a, b fileRef }
+	shared := make(map[pairKey]int)
+	fileShared := make(map[pairKey]map[fileKey]int)
+
+	for _, refs := range index {
+		if common(refs) {
+			continue
+		}
+
+		repos := make(map[int][]fileRef)
+		for _, ref := range refs {
+			fileSize[ref]++
+			repos[ref.snapshot] = append(repos[ref.snapshot], ref)
+		}
+		for s := range repos {
+			repoSize[s]++
+		}
+		if len(repos) < 2 {
+			continue
+		}
+
+		for a, refsA := range repos {
+			for b, refsB := range repos {
+				if a >= b {
+					continue
+				}
+				key := pairKey{a, b}
+				shared[key]++
+				if fileShared[key] == nil {
+					fileShared[key] = make(map[fileKey]int)
+				}
+				for _, fa := range refsA {
+					for _, fb := range refsB {
+						fileShared[key][fileKey{fa, fb}]++
+					}
+				}
+			}
+		}
+	}
+
+	pairs := make([]SimilarityPair, 0, len(shared))
+	for key, n := range shared {
+		sizeA, sizeB := repoSize[key.a], repoSize[key.b]
+		pair := SimilarityPair{
+			RepositoryA: snapshots[key.a].RepositoryID,
+			RepositoryB: snapshots[key.b].RepositoryID,
+			Shared:      n,
+			Similarity:  percent(n, min(sizeA, sizeB)),
+			Jaccard:     percent(n, sizeA+sizeB-n),
+		}
+
+		for fk, count := range fileShared[key] {
+			pair.TopFiles = append(pair.TopFiles, FileMatch{
+				FileA:      snapshots[fk.a.snapshot].Files[fk.a.file].Path,
+				FileB:      snapshots[fk.b.snapshot].Files[fk.b.file].Path,
+				Shared:     count,
+				Similarity: percent(count, min(fileSize[fk.a], fileSize[fk.b])),
+			})
+		}
+		sort.Slice(pair.TopFiles, func(i, j int) bool {
+			if pair.TopFiles[i].Shared != pair.TopFiles[j].Shared {
+				return pair.TopFiles[i].Shared > pair.TopFiles[j].Shared
+			}
+			return pair.TopFiles[i].FileA < pair.TopFiles[j].FileA
+		})
+		if len(pair.TopFiles) > topFiles {
+			pair.TopFiles = pair.TopFiles[:topFiles]
+		}
+
+		// Keep the lower repository ID first so pairs have one canonical order
+		if pair.RepositoryA > pair.RepositoryB {
+			pair.RepositoryA, pair.RepositoryB = pair.RepositoryB, pair.RepositoryA
+			for i := range pair.TopFiles {
+				pair.TopFiles[i].FileA, pair.TopFiles[i].FileB = pair.TopFiles[i].FileB, pair.TopFiles[i].FileA
+			}
+		}
+		pairs = append(pairs, pair)
+	}
+
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].Similarity != pairs[j].Similarity {
+			return pairs[i].Similarity > pairs[j].Similarity
+		}
+		if pairs[i].RepositoryA != pairs[j].RepositoryA {
+			return pairs[i].RepositoryA < pairs[j].RepositoryA
+		}
+		return pairs[i].RepositoryB < pairs[j].RepositoryB
+	})
+	return pairs
+}
+
+func percent(n, of int) float64 {
+	if of <= 0 {
+		return 0
+	}
+	return float64(n) * 100 / float64(of)
+}
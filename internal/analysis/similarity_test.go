@@ -0,0 +1,309 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// sourceText returns n lines of distinct, code-like text.
+func sourceText(prefix string, n int) string {
+	var sb strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&sb, "func %s%d(x int) int { return x*%d + %d }\n", prefix, i, i+3, i*7)
+	}
+	return sb.String()
+}
+
+func TestFingerprintShortText(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    int
+	}{
+		{"empty", "", 0},
+		{"whitespace only", " \n\t\r\n  ", 0},
+		{"one short of a k-gram", strings.Repeat("a", KGramSize-1), 0},
+		{"short once whitespace is dropped", strings.Repeat("a ", KGramSize-1), 0},
+		{"exactly one k-gram", strings.Repeat("b", KGramSize), 1},
+		{"one k-gram across whitespace", strings.Repeat("c\n", KGramSize), 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Fingerprint([]byte(tt.content)); len(got) != tt.want {
+				t.Errorf("Fingerprint(%q) has %d fingerprints, want %d", tt.content, len(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestFingerprintNormalization(t *testing.T) {
+	original := "func Add(a, b int) int {\n\treturn a + b\n}\n\nfunc Sub(a, b int) int {\n\treturn a - b\n}\n"
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"spaces for tabs", strings.ReplaceAll(original, "\t", "    ")},
+		{"crlf line endings", strings.ReplaceAll(original, "\n", "\r\n")},
+		{"no whitespace at all", strings.Join(strings.Fields(original), "")},
+		{"reindented and spread out", strings.ReplaceAll(original, " ", "   \n ")},
+		{"upper case", strings.ToUpper(original)},
+	}
+	want := Fingerprint([]byte(original))
+	if len(want) == 0 {
+		t.Fatal("original text has no fingerprints")
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Fingerprint([]byte(tt.content)); !slices.Equal(got, want) {
+				t.Errorf("Fingerprint(%q) = %v, want %v", tt.content, got, want)
+			}
+		})
+	}
+
+	if got := Fingerprint([]byte(strings.ReplaceAll(original, "+", "*"))); slices.Equal(got, want) {
+		t.Error("changing an operator left the fingerprints unchanged")
+	}
+}
+
+func TestFingerprintSortedAndUnique(t *testing.T) {
+	// Repeated text yields repeated hashes, which are kept once
+	hashes := Fingerprint([]byte(strings.Repeat(sourceText("f", 5), 4)))
+	if len(hashes) == 0 {
+		t.Fatal("no fingerprints")
+	}
+	for i := 1; i < len(hashes); i++ {
+		if hashes[i] <= hashes[i-1] {
+			t.Fatalf("fingerprints not strictly increasing at %d: %v", i, hashes)
+		}
+	}
+}
+
+// TestFingerprintGuarantee checks the winnowing guarantee: a match at least
+// KGramSize+WinnowWindow-1 characters long shares a fingerprint wherever it
+// sits in two otherwise unrelated texts.
+func TestFingerprintGuarantee(t *testing.T) {
+	shared := "sharedblock_abcdefghijklmnopqrstuvwxyz0123456789"[:KGramSize+WinnowWindow-1]
+	for _, offset := range []int{0, 1, 7, 19, 33} {
+		a := strings.Repeat("q", offset) + sourceText("a", 3) + shared + sourceText("b", 3)
+		b := sourceText("c", 2) + shared + strings.Repeat("z", offset)
+		common := intersect(Fingerprint([]byte(a)), Fingerprint([]byte(b)))
+		if common == 0 {
+			t.Errorf("offset %d: a %d-character match shares no fingerprint", offset, len(shared))
+		}
+	}
+}
+
+func intersect(a, b []uint64) int {
+	n := 0
+	for _, h := range a {
+		if _, ok := slices.BinarySearch(b, h); ok {
+			n++
+		}
+	}
+	return n
+}
+
+func hashRange(from, to uint64) []uint64 {
+	var hashes []uint64
+	for h := from; h <= to; h++ {
+		hashes = append(hashes, h)
+	}
+	return hashes
+}
+
+func snapshot(id int64, files ...FileFingerprints) SnapshotFingerprints {
+	return SnapshotFingerprints{RepositoryID: id, Files: files}
+}
+
+func file(path string, hashes []uint64) FileFingerprints {
+	return FileFingerprints{Path: path, Hashes: hashes}
+}
+
+func approx(a, b float64) bool {
+	return math.Abs(a-b) < 0.01
+}
+
+func TestCompareSnapshotsOverlap(t *testing.T) {
+	tests := []struct {
+		name       string
+		snapshots  []SnapshotFingerprints
+		similarity float64
+		jaccard    float64
+		shared     int
+	}{
+		{
+			name: "half overlap",
+			snapshots: []SnapshotFingerprints{
+				snapshot(1, file("a.go", hashRange(1, 10))),
+				snapshot(2, file("b.go", hashRange(6, 15))),
+			},
+			similarity: 50, jaccard: 100.0 / 3, shared: 5,
+		},
+		{
+			name: "small project copied into a large one",
+			snapshots: []SnapshotFingerprints{
+				snapshot(1, file("big.go", hashRange(1, 20))),
+				snapshot(2, file("small.go", hashRange(1, 5))),
+			},
+			similarity: 100, jaccard: 25, shared: 5,
+		},
+		{
+			name: "identical",
+			snapshots: []SnapshotFingerprints{
+				snapshot(1, file("a.go", hashRange(1, 8))),
+				snapshot(2, file("a.go", hashRange(1, 8))),
+			},
+			similarity: 100, jaccard: 100, shared: 8,
+		},
+		{
+			name: "split across files",
+			snapshots: []SnapshotFingerprints{
+				snapshot(1, file("a.go", hashRange(1, 4)), file("b.go", hashRange(5, 8))),
+				snapshot(2, file("all.go", hashRange(3, 6)), file("other.go", hashRange(100, 103))),
+			},
+			similarity: 50, jaccard: 100.0 / 3, shared: 4,
+		},
+		{
+			name: "one shared fingerprint",
+			snapshots: []SnapshotFingerprints{
+				snapshot(1, file("a.go", hashRange(1, 4))),
+				snapshot(2, file("b.go", hashRange(4, 7))),
+			},
+			similarity: 25, jaccard: 100.0 / 7, shared: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pairs := CompareSnapshots(tt.snapshots, 10)
+			if len(pairs) != 1 {
+				t.Fatalf("got %d pairs, want 1", len(pairs))
+			}
+			p := pairs[0]
+			if p.Shared != tt.shared {
+				t.Errorf("Shared = %d, want %d", p.Shared, tt.shared)
+			}
+			if !approx(p.Similarity, tt.similarity) {
+				t.Errorf("Similarity = %.2f, want %.2f", p.Similarity, tt.similarity)
+			}
+			if !approx(p.Jaccard, tt.jaccard) {
+				t.Errorf("Jaccard = %.2f, want %.2f", p.Jaccard, tt.jaccard)
+			}
+		})
+	}
+}
+
+func TestCompareSnapshotsNoOverlap(t *testing.T) {
+	pairs := CompareSnapshots([]SnapshotFingerprints{
+		snapshot(1, file("a.go", hashRange(1, 10))),
+		snapshot(2, file("b.go", hashRange(11, 20))),
+		snapshot(3),
+	}, 10)
+	if len(pairs) != 0 {
+		t.Errorf("got %d pairs, want none", len(pairs))
+	}
+	if pairs := CompareSnapshots(nil, 10); len(pairs) != 0 {
+		t.Errorf("got %d pairs for no snapshots, want none", len(pairs))
+	}
+}
+
+func TestCompareSnapshotsTopFiles(t *testing.T) {
+	pairs := CompareSnapshots([]SnapshotFingerprints{
+		// The higher ID first: the pair is still reported from the lower
+		snapshot(9,
+			file("copy.go", hashRange(1, 6)),
+			file("partial.go", hashRange(21, 22)),
+			file("mine.go", hashRange(50, 60)),
+		),
+		snapshot(4,
+			file("orig.go", hashRange(1, 6)),
+			file("other.go", hashRange(21, 30)),
+		),
+	}, 1)
+	if len(pairs) != 1 {
+		t.Fatalf("got %d pairs, want 1", len(pairs))
+	}
+	p := pairs[0]
+	if p.RepositoryA != 4 || p.RepositoryB != 9 {
+		t.Errorf("pair is %d-%d, want 4-9", p.RepositoryA, p.RepositoryB)
+	}
+	if len(p.TopFiles) != 1 {
+		t.Fatalf("got %d top files, want 1", len(p.TopFiles))
+	}
+	want := FileMatch{FileA: "orig.go", FileB: "copy.go", Shared: 6, Similarity: 100}
+	if p.TopFiles[0] != want {
+		t.Errorf("top file = %+v, want %+v", p.TopFiles[0], want)
+	}
+}
+
+func TestCompareSnapshotsIgnoresCommonFingerprints(t *testing.T) {
+	boilerplate := hashRange(1000, 1009)
+	withBoilerplate := func(own []uint64) []uint64 {
+		return append(slices.Clone(boilerplate), own...)
+	}
+	snapshots := []SnapshotFingerprints{
+		snapshot(1, file("main.go", withBoilerplate(hashRange(1, 10)))),
+		snapshot(2, file("main.go", withBoilerplate(hashRange(1, 10)))),
+		snapshot(3, file("main.go", withBoilerplate(hashRange(21, 30)))),
+		snapshot(4, file("main.go", hashRange(31, 40))),
+	}
+	pairs := CompareSnapshots(snapshots, 10)
+	if len(pairs) != 1 {
+		t.Fatalf("got %d pairs, want only the copying pair: %+v", len(pairs), pairs)
+	}
+	p := pairs[0]
+	if p.RepositoryA != 1 || p.RepositoryB != 2 || p.Shared != 10 || !approx(p.Similarity, 100) {
+		t.Errorf("pair = %+v, want 1-2 sharing 10 fingerprints at 100%%", p)
+	}
+
+	// With fewer than four repositories nothing counts as boilerplate
+	pairs = CompareSnapshots(snapshots[1:3], 10)
+	if len(pairs) != 1 || pairs[0].Shared != len(boilerplate) {
+		t.Errorf("three repositories: got %+v, want one pair sharing the boilerplate", pairs)
+	}
+}
+
+func TestCompareSnapshotsOrder(t *testing.T) {
+	pairs := CompareSnapshots([]SnapshotFingerprints{
+		snapshot(1, file("a.go", hashRange(1, 10))),
+		snapshot(2, file("b.go", hashRange(1, 2))),
+		snapshot(3, file("c.go", hashRange(1, 10))),
+	}, 10)
+	if len(pairs) != 3 {
+		t.Fatalf("got %d pairs, want 3", len(pairs))
+	}
+	got := make([]string, len(pairs))
+	for i, p := range pairs {
+		got[i] = fmt.Sprintf("%d-%d", p.RepositoryA, p.RepositoryB)
+	}
+	// All three pairs are at 100%; ties go by repository IDs
+	if want := []string{"1-2", "1-3", "2-3"}; !slices.Equal(got, want) {
+		t.Errorf("pairs in order %v, want %v", got, want)
+	}
+}
+
+func TestCompareSnapshotsFromSource(t *testing.T) {
+	original := sourceText("handler", 40)
+	copied := strings.ToUpper(strings.ReplaceAll(original, "\n", "\n\n    "))
+	unrelated := sourceText("other", 40)
+
+	snapshots := []SnapshotFingerprints{
+		snapshot(1, file("server.go", Fingerprint([]byte(original)))),
+		snapshot(2, file("app.go", Fingerprint([]byte(copied)))),
+		snapshot(3, file("main.go", Fingerprint([]byte(unrelated)))),
+	}
+	pairs := CompareSnapshots(snapshots, 10)
+	if len(pairs) == 0 {
+		t.Fatal("no pairs")
+	}
+	if p := pairs[0]; p.RepositoryA != 1 || p.RepositoryB != 2 || !approx(p.Similarity, 100) {
+		t.Errorf("most similar pair = %d-%d at %.2f%%, want 1-2 at 100%%", p.RepositoryA, p.RepositoryB, p.Similarity)
+	}
+	for _, p := range pairs[1:] {
+		if p.Similarity >= 50 {
+			t.Errorf("unrelated pair %d-%d scored %.2f%%", p.RepositoryA, p.RepositoryB, p.Similarity)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/harshpatel5940/gitvigil/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+type EventResponse struct {
+	ID                 int64      `json:"id"`
+	Name               string     `json:"name"`
+	Timezone           string     `json:"timezone"`
+	OnSite             bool       `json:"on_site"`
+	StartsAt           time.Time  `json:"starts_at"`
+	EndsAt             time.Time  `json:"ends_at"`
+	SubmissionDeadline *time.Time `json:"submission_deadline,omitempty"`
+	Phase              string     `json:"phase"`
+	RepositoriesCount  int        `json:"repositories_count"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+}
+
+type EventRequest struct {
+	Name               string     `json:"name"`
+	Timezone           string     `json:"timezone"`
+	OnSite             bool       `json:"on_site"`
+	StartsAt           time.Time  `json:"starts_at"`
+	EndsAt             time.Time  `json:"ends_at"`
+	SubmissionDeadline *time.Time `json:"submission_deadline"`
+}
+
+// eventPhase describes where now falls relative to the event window.
+func eventPhase(e *models.Event, now time.Time) string {
+	switch {
+	case now.Before(e.StartsAt):
+		return "upcoming"
+	case now.After(e.EndsAt):
+		return "ended"
+	default:
+		return "running"
+	}
+}
+
+func eventToResponse(e *models.EventWithStats) EventResponse {
+	return EventResponse{
+		ID:                 e.ID,
+		Name:               e.Name,
+		Timezone:           e.Timezone,
+		OnSite:             e.OnSite,
+		StartsAt:           e.StartsAt,
+		EndsAt:             e.EndsAt,
+		SubmissionDeadline: e.SubmissionDeadline,
+		Phase:              eventPhase(&e.Event, time.Now()),
+		RepositoriesCount:  e.RepositoriesCount,
+		CreatedAt:          e.CreatedAt,
+		UpdatedAt:          e.UpdatedAt,
+	}
+}
+
+// toEvent validates the request and converts it to an event.
+func (req *EventRequest) toEvent() (*models.Event, error) {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return nil, errors.New("name is required")
+	}
+	if req.Timezone == "" {
+		req.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(req.Timezone); err != nil {
+		return nil, errors.New("unknown timezone " + strconv.Quote(req.Timezone))
+	}
+	if req.StartsAt.IsZero() || req.EndsAt.IsZero() {
+		return nil, errors.New("starts_at and ends_at are required")
+	}
+	if !req.EndsAt.After(req.StartsAt) {
+		return nil, errors.New("ends_at must be after starts_at")
+	}
+	if req.SubmissionDeadline != nil && !req.SubmissionDeadline.After(req.StartsAt) {
+		return nil, errors.New("submission_deadline must be after starts_at")
+	}
+
+	return &models.Event{
+		Name:               req.Name,
+		Timezone:           req.Timezone,
+		OnSite:             req.OnSite,
+		StartsAt:           req.StartsAt,
+		EndsAt:             req.EndsAt,
+		SubmissionDeadline: req.SubmissionDeadline,
+	}, nil
+}
+
+func (h *Handler) ListEvents(w http.ResponseWriter, r *http.Request) {
+	store := models.NewEventStore(h.db.Pool)
+	events, err := store.List(r.Context())
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to list events")
+		h.respondError(w, http.StatusInternalServerError, "failed to list events")
+		return
+	}
+
+	response := make([]EventResponse, 0, len(events))
+	for _, e := range events {
+		response = append(response, eventToResponse(e))
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"events": response,
+	})
+}
+
+func (h *Handler) GetEvent(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid event ID")
+		return
+	}
+
+	store := models.NewEventStore(h.db.Pool)
+	event, err := store.GetByID(r.Context(), id)
+	if err != nil {
+		h.logger.Error().Err(err).Int64("id", id).Msg("failed to get event")
+		h.respondError(w, http.StatusNotFound, "event not found")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, eventToResponse(event))
+}
+
+func (h *Handler) CreateEvent(w http.ResponseWriter, r *http.Request) {
+	var req EventRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	event, err := req.toEvent()
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	store := models.NewEventStore(h.db.Pool)
+	if err := store.Create(r.Context(), event); err != nil {
+		h.logger.Error().Err(err).Msg("failed to create event")
+		h.respondError(w, http.StatusInternalServerError, "failed to create event")
+		return
+	}
+
+	h.respondJSON(w, http.StatusCreated, eventToResponse(&models.EventWithStats{Event: *event}))
+}
+
+func (h *Handler) UpdateEvent(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid event ID")
+		return
+	}
+
+	var req EventRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	event, err := req.toEvent()
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	event.ID = id
+
+	store := models.NewEventStore(h.db.Pool)
+	if err := store.Update(ctx, event); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			h.respondError(w, http.StatusNotFound, "event not found")
+			return
+		}
+		h.logger.Error().Err(err).Int64("id", id).Msg("failed to update event")
+		h.respondError(w, http.StatusInternalServerError, "failed to update event")
+		return
+	}
+
+	updated, err := store.GetByID(ctx, id)
+	if err != nil {
+		h.logger.Error().Err(err).Int64("id", id).Msg("failed to get event")
+		h.respondError(w, http.StatusInternalServerError, "failed to get event")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, eventToResponse(updated))
+}
+
+func (h *Handler) DeleteEvent(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid event ID")
+		return
+	}
+
+	store := models.NewEventStore(h.db.Pool)
+	deleted, err := store.Delete(r.Context(), id)
+	if err != nil {
+		h.logger.Error().Err(err).Int64("id", id).Msg("failed to delete event")
+		h.respondError(w, http.StatusInternalServerError, "failed to delete event")
+		return
+	}
+	if !deleted {
+		h.respondError(w, http.StatusNotFound, "event not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) ListEventRepositories(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid event ID")
+		return
+	}
+
+	store := models.NewEventStore(h.db.Pool)
+	if _, err := store.GetByID(ctx, id); err != nil {
+		h.logger.Error().Err(err).Int64("id", id).Msg("failed to get event")
+		h.respondError(w, http.StatusNotFound, "event not found")
+		return
+	}
+
+	repos, err := store.ListRepositories(ctx, id)
+	if err != nil {
+		h.logger.Error().Err(err).Int64("id", id).Msg("failed to list event repositories")
+		h.respondError(w, http.StatusInternalServerError, "failed to list repositories")
+		return
+	}
+
+	response := make([]RepositoryResponse, 0, len(repos))
+	for _, repo := range repos {
+		response = append(response, repoToResponse(&models.RepositoryWithStats{Repository: *repo}))
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"repositories": response,
+	})
+}
+
+// EnrollRepository enrolls a repository in the event, moving it out of any
+// event it was enrolled in before.
+func (h *Handler) EnrollRepository(w http.ResponseWriter, r *http.Request) {
+	h.setEnrollment(w, r, true)
+}
+
+func (h *Handler) UnenrollRepository(w http.ResponseWriter, r *http.Request) {
+	h.setEnrollment(w, r, false)
+}
+
+func (h *Handler) setEnrollment(w http.ResponseWriter, r *http.Request, enroll bool) {
+	ctx := r.Context()
+
+	eventID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid event ID")
+		return
+	}
+	repoID, err := strconv.ParseInt(chi.URLParam(r, "repoID"), 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid repository ID")
+		return
+	}
+
+	repoStore := models.NewRepositoryStore(h.db.Pool)
+	repo, err := repoStore.GetByID(ctx, repoID)
+	if err != nil {
+		h.logger.Error().Err(err).Int64("id", repoID).Msg("failed to get repository")
+		h.respondError(w, http.StatusNotFound, "repository not found")
+		return
+	}
+
+	eventStore := models.NewEventStore(h.db.Pool)
+	if _, err := eventStore.GetByID(ctx, eventID); err != nil {
+		h.logger.Error().Err(err).Int64("id", eventID).Msg("failed to get event")
+		h.respondError(w, http.StatusNotFound, "event not found")
+		return
+	}
+
+	target := &eventID
+	if !enroll {
+		if repo.EventID == nil || *repo.EventID != eventID {
+			h.respondError(w, http.StatusNotFound, "repository is not enrolled in this event")
+			return
+		}
+		target = nil
+	}
+
+	if _, err := eventStore.Enroll(ctx, repoID, target); err != nil {
+		h.logger.Error().Err(err).Int64("event_id", eventID).Int64("repository_id", repoID).Msg("failed to update enrollment")
+		h.respondError(w, http.StatusInternalServerError, "failed to update enrollment")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -2,33 +2,72 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/harshpatel5940/gitvigil/internal/database"
+	"github.com/harshpatel5940/gitvigil/internal/models"
+	"github.com/harshpatel5940/gitvigil/internal/rules"
 	"github.com/rs/zerolog"
 )
 
 type Handler struct {
 	db     *database.DB
+	engine *rules.Engine
 	logger zerolog.Logger
 }
 
-func NewHandler(db *database.DB, logger zerolog.Logger) *Handler {
+func NewHandler(db *database.DB, engine *rules.Engine, logger zerolog.Logger) *Handler {
 	return &Handler{
 		db:     db,
+		engine: engine,
 		logger: logger.With().Str("component", "api").Logger(),
 	}
 }
 
-// Router returns a chi router with all API routes
-func (h *Handler) Router() chi.Router {
+// Router returns a chi router with all API routes. Organizer actions are
+// wrapped in admin, the server's admin authentication.
+func (h *Handler) Router(admin func(http.Handler) http.Handler) chi.Router {
 	r := chi.NewRouter()
 
 	// Repositories
 	r.Get("/repositories", h.ListRepositories)
 	r.Get("/repositories/{id}", h.GetRepository)
+	r.Get("/repositories/{id}/backfill", h.GetRepositoryBackfill)
+	r.Get("/repositories/{id}/streak", h.GetRepositoryStreak)
+	r.Get("/repositories/{id}/members", h.GetRepositoryMembers)
+	r.Get("/repositories/{id}/starter-template", h.GetStarterTemplate)
+	r.With(admin).Put("/repositories/{id}/starter-template", h.DeclareStarterTemplate)
+	r.With(admin).Delete("/repositories/{id}/starter-template", h.DeleteStarterTemplate)
+
+	// Events
+	r.Get("/events", h.ListEvents)
+	r.With(admin).Post("/events", h.CreateEvent)
+	r.Get("/events/{id}", h.GetEvent)
+	r.With(admin).Put("/events/{id}", h.UpdateEvent)
+	r.With(admin).Delete("/events/{id}", h.DeleteEvent)
+	r.Get("/events/{id}/repositories", h.ListEventRepositories)
+	r.Get("/events/{id}/submissions", h.ListEventSubmissions)
+	r.Get("/events/{id}/similarity", h.GetEventSimilarity)
+	r.With(admin).Put("/events/{id}/repositories/{repoID}", h.EnrollRepository)
+	r.With(admin).Delete("/events/{id}/repositories/{repoID}", h.UnenrollRepository)
+
+	// Teams
+	r.Get("/teams", h.ListTeams)
+	r.With(admin).Post("/teams", h.CreateTeam)
+	r.Get("/teams/{id}", h.GetTeam)
+	r.With(admin).Put("/teams/{id}", h.UpdateTeam)
+	r.With(admin).Delete("/teams/{id}", h.DeleteTeam)
+	r.Get("/teams/{id}/members", h.ListTeamMembers)
+	r.With(admin).Post("/teams/{id}/members", h.CreateTeamMember)
+	r.With(admin).Post("/teams/{id}/members/import", h.ImportTeamMembers)
+	r.With(admin).Put("/teams/{id}/members/{memberID}", h.UpdateTeamMember)
+	r.With(admin).Delete("/teams/{id}/members/{memberID}", h.DeleteTeamMember)
+	r.With(admin).Put("/teams/{id}/repositories/{repoID}", h.AssignTeamRepository)
+	r.With(admin).Delete("/teams/{id}/repositories/{repoID}", h.UnassignTeamRepository)
 
 	// Installations
 	r.Get("/installations", h.ListInstallations)
@@ -93,3 +132,27 @@ func (h *Handler) getPagination(r *http.Request) PaginationParams {
 		Offset:  (page - 1) * perPage,
 	}
 }
+
+// Lifecycle status filter
+
+// getStatusFilter parses the ?status= query parameter. It defaults to active
+// only; "all" disables filtering and a comma-separated list selects several.
+func (h *Handler) getStatusFilter(r *http.Request) ([]models.LifecycleStatus, error) {
+	param := r.URL.Query().Get("status")
+	if param == "" {
+		return []models.LifecycleStatus{models.LifecycleActive}, nil
+	}
+	if param == "all" {
+		return nil, nil
+	}
+
+	var statuses []models.LifecycleStatus
+	for _, part := range strings.Split(param, ",") {
+		status := models.LifecycleStatus(strings.TrimSpace(part))
+		if !status.IsValid() {
+			return nil, fmt.Errorf("invalid status %q (expected active, suspended, removed, deleted or all)", part)
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
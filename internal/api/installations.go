@@ -10,15 +10,18 @@ import (
 )
 
 type InstallationResponse struct {
-	ID             int64     `json:"id"`
-	InstallationID int64     `json:"installation_id"`
-	AccountLogin   string    `json:"account_login"`
-	AccountType    string    `json:"account_type"`
-	RepoCount      int       `json:"repo_count"`
-	AlertCount     int       `json:"alert_count"`
-	CommitCount    int       `json:"commit_count"`
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
+	ID             int64      `json:"id"`
+	InstallationID int64      `json:"installation_id"`
+	AccountLogin   string     `json:"account_login"`
+	AccountType    string     `json:"account_type"`
+	Status         string     `json:"status"`
+	SuspendedAt    *time.Time `json:"suspended_at,omitempty"`
+	DeletedAt      *time.Time `json:"deleted_at,omitempty"`
+	RepoCount      int        `json:"repo_count"`
+	AlertCount     int        `json:"alert_count"`
+	CommitCount    int        `json:"commit_count"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
 }
 
 type InstallationsListResponse struct {
@@ -32,6 +35,9 @@ func installationToResponse(i *models.InstallationWithStats) InstallationRespons
 		InstallationID: i.InstallationID,
 		AccountLogin:   i.AccountLogin,
 		AccountType:    i.AccountType,
+		Status:         string(i.Status),
+		SuspendedAt:    i.SuspendedAt,
+		DeletedAt:      i.DeletedAt,
 		RepoCount:      i.RepoCount,
 		AlertCount:     i.AlertCount,
 		CommitCount:    i.CommitCount,
@@ -43,8 +49,14 @@ func installationToResponse(i *models.InstallationWithStats) InstallationRespons
 func (h *Handler) ListInstallations(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
+	statuses, err := h.getStatusFilter(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	store := models.NewInstallationStore(h.db.Pool)
-	installations, err := store.List(ctx)
+	installations, err := store.List(ctx, statuses)
 	if err != nil {
 		h.logger.Error().Err(err).Msg("failed to list installations")
 		h.respondError(w, http.StatusInternalServerError, "failed to list installations")
@@ -94,8 +106,14 @@ func (h *Handler) ListInstallationRepositories(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	statuses, err := h.getStatusFilter(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	store := models.NewRepositoryStore(h.db.Pool)
-	repos, err := store.ListByInstallation(ctx, id)
+	repos, err := store.ListByInstallation(ctx, id, statuses)
 	if err != nil {
 		h.logger.Error().Err(err).Int64("installation_id", id).Msg("failed to list repositories")
 		h.respondError(w, http.StatusInternalServerError, "failed to list repositories")
@@ -109,6 +127,7 @@ func (h *Handler) ListInstallationRepositories(w http.ResponseWriter, r *http.Re
 		FullName       string     `json:"full_name"`
 		HasLicense     bool       `json:"has_license"`
 		StreakStatus   string     `json:"streak_status"`
+		Status         string     `json:"status"`
 		LastActivityAt *time.Time `json:"last_activity_at,omitempty"`
 	}
 
@@ -126,7 +145,8 @@ func (h *Handler) ListInstallationRepositories(w http.ResponseWriter, r *http.Re
 			GitHubID:       repo.GitHubID,
 			FullName:       repo.FullName,
 			HasLicense:     repo.HasLicense,
-			StreakStatus:   repo.StreakStatus,
+			StreakStatus:   string(repo.StreakStatus),
+			Status:         string(repo.Status),
 			LastActivityAt: repo.LastActivityAt,
 		})
 	}
@@ -10,20 +10,51 @@ import (
 )
 
 type RepositoryResponse struct {
-	ID             int64      `json:"id"`
-	GitHubID       int64      `json:"github_id"`
-	InstallationID int64      `json:"installation_id"`
-	Owner          string     `json:"owner"`
-	Name           string     `json:"name"`
-	FullName       string     `json:"full_name"`
-	HasLicense     bool       `json:"has_license"`
-	LicenseSPDXID  *string    `json:"license_spdx_id,omitempty"`
-	StreakStatus   string     `json:"streak_status"`
-	LastActivityAt *time.Time `json:"last_activity_at,omitempty"`
-	AlertsCount    int        `json:"alerts_count"`
-	CommitsCount   int        `json:"commits_count"`
-	CreatedAt      time.Time  `json:"created_at"`
-	UpdatedAt      time.Time  `json:"updated_at"`
+	ID             int64             `json:"id"`
+	GitHubID       int64             `json:"github_id"`
+	InstallationID int64             `json:"installation_id"`
+	Owner          string            `json:"owner"`
+	Name           string            `json:"name"`
+	FullName       string            `json:"full_name"`
+	HasLicense     bool              `json:"has_license"`
+	LicenseSPDXID  *string           `json:"license_spdx_id,omitempty"`
+	StreakStatus   string            `json:"streak_status"`
+	StreakDays     int               `json:"streak_days"`
+	LongestStreak  int               `json:"longest_streak_days"`
+	Status         string            `json:"status"`
+	SuspendedAt    *time.Time        `json:"suspended_at,omitempty"`
+	RemovedAt      *time.Time        `json:"removed_at,omitempty"`
+	DeletedAt      *time.Time        `json:"deleted_at,omitempty"`
+	LastActivityAt *time.Time        `json:"last_activity_at,omitempty"`
+	Backfill       *BackfillResponse `json:"backfill,omitempty"`
+	EventID        *int64            `json:"event_id,omitempty"`
+	TeamID         *int64            `json:"team_id,omitempty"`
+	AlertsCount    int               `json:"alerts_count"`
+	CommitsCount   int               `json:"commits_count"`
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+}
+
+type BackfillResponse struct {
+	Status      string     `json:"status"`
+	Commits     int        `json:"commits"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	Error       *string    `json:"error,omitempty"`
+}
+
+// backfillToResponse returns nil for repositories that were never backfilled.
+func backfillToResponse(b models.BackfillProgress) *BackfillResponse {
+	if b.Status == nil {
+		return nil
+	}
+	return &BackfillResponse{
+		Status:      string(*b.Status),
+		Commits:     b.Commits,
+		StartedAt:   b.StartedAt,
+		CompletedAt: b.CompletedAt,
+		Error:       b.Error,
+	}
 }
 
 type RepositoriesListResponse struct {
@@ -43,8 +74,17 @@ func repoToResponse(r *models.RepositoryWithStats) RepositoryResponse {
 		FullName:       r.FullName,
 		HasLicense:     r.HasLicense,
 		LicenseSPDXID:  r.LicenseSPDXID,
-		StreakStatus:   r.StreakStatus,
+		StreakStatus:   string(r.StreakStatus),
+		StreakDays:     r.CurrentStreakDays(),
+		LongestStreak:  max(r.LongestStreakDays, r.CurrentStreakDays()),
+		Status:         string(r.Status),
+		SuspendedAt:    r.SuspendedAt,
+		RemovedAt:      r.RemovedAt,
+		DeletedAt:      r.DeletedAt,
 		LastActivityAt: r.LastActivityAt,
+		Backfill:       backfillToResponse(r.Backfill),
+		EventID:        r.EventID,
+		TeamID:         r.TeamID,
 		AlertsCount:    r.AlertsCount,
 		CommitsCount:   r.CommitsCount,
 		CreatedAt:      r.CreatedAt,
@@ -56,8 +96,14 @@ func (h *Handler) ListRepositories(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	pagination := h.getPagination(r)
 
+	statuses, err := h.getStatusFilter(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	store := models.NewRepositoryStore(h.db.Pool)
-	repos, total, err := store.ListAll(ctx, pagination.PerPage, pagination.Offset)
+	repos, total, err := store.ListAll(ctx, statuses, pagination.PerPage, pagination.Offset)
 	if err != nil {
 		h.logger.Error().Err(err).Msg("failed to list repositories")
 		h.respondError(w, http.StatusInternalServerError, "failed to list repositories")
@@ -98,3 +144,98 @@ func (h *Handler) GetRepository(w http.ResponseWriter, r *http.Request) {
 
 	h.respondJSON(w, http.StatusOK, repoToResponse(repo))
 }
+
+func (h *Handler) GetRepositoryBackfill(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid repository ID")
+		return
+	}
+
+	store := models.NewRepositoryStore(h.db.Pool)
+	repo, err := store.GetByID(ctx, id)
+	if err != nil {
+		h.logger.Error().Err(err).Int64("id", id).Msg("failed to get repository")
+		h.respondError(w, http.StatusNotFound, "repository not found")
+		return
+	}
+
+	backfill := backfillToResponse(repo.Backfill)
+	if backfill == nil {
+		h.respondError(w, http.StatusNotFound, "repository has not been backfilled")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, backfill)
+}
+
+type StreakTransitionResponse struct {
+	From           string     `json:"from"`
+	To             string     `json:"to"`
+	Reason         string     `json:"reason"`
+	StreakDays     int        `json:"streak_days"`
+	LastActivityAt *time.Time `json:"last_activity_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+type StreakResponse struct {
+	Status            string                     `json:"status"`
+	StreakDays        int                        `json:"streak_days"`
+	LongestStreakDays int                        `json:"longest_streak_days"`
+	StartedAt         *time.Time                 `json:"started_at,omitempty"`
+	ChangedAt         *time.Time                 `json:"changed_at,omitempty"`
+	LastActivityAt    *time.Time                 `json:"last_activity_at,omitempty"`
+	Transitions       []StreakTransitionResponse `json:"transitions"`
+}
+
+func (h *Handler) GetRepositoryStreak(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid repository ID")
+		return
+	}
+
+	store := models.NewRepositoryStore(h.db.Pool)
+	repo, err := store.GetByID(ctx, id)
+	if err != nil {
+		h.logger.Error().Err(err).Int64("id", id).Msg("failed to get repository")
+		h.respondError(w, http.StatusNotFound, "repository not found")
+		return
+	}
+
+	streakStore := models.NewStreakStore(h.db.Pool)
+	transitions, err := streakStore.ListTransitions(ctx, id, 100)
+	if err != nil {
+		h.logger.Error().Err(err).Int64("id", id).Msg("failed to list streak transitions")
+		h.respondError(w, http.StatusInternalServerError, "failed to list streak transitions")
+		return
+	}
+
+	response := StreakResponse{
+		Status:            string(repo.StreakStatus),
+		StreakDays:        repo.CurrentStreakDays(),
+		LongestStreakDays: max(repo.LongestStreakDays, repo.CurrentStreakDays()),
+		StartedAt:         repo.StreakStartedAt,
+		ChangedAt:         repo.StreakChangedAt,
+		LastActivityAt:    repo.LastActivityAt,
+		Transitions:       make([]StreakTransitionResponse, 0, len(transitions)),
+	}
+	for _, t := range transitions {
+		response.Transitions = append(response.Transitions, StreakTransitionResponse{
+			From:           string(t.FromStatus),
+			To:             string(t.ToStatus),
+			Reason:         t.Reason,
+			StreakDays:     t.StreakDays,
+			LastActivityAt: t.LastActivityAt,
+			CreatedAt:      t.CreatedAt,
+		})
+	}
+
+	h.respondJSON(w, http.StatusOK, response)
+}
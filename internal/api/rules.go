@@ -0,0 +1,288 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/harshpatel5940/gitvigil/internal/models"
+	"github.com/harshpatel5940/gitvigil/internal/rules"
+)
+
+type RuleResponse struct {
+	Name            string           `json:"name"`
+	Description     string           `json:"description"`
+	Triggers        []rules.Trigger  `json:"triggers"`
+	AlertType       models.AlertType `json:"alert_type"`
+	DefaultSeverity *models.Severity `json:"default_severity"`
+	DefaultParams   rules.Params     `json:"default_params"`
+}
+
+type RuleSettingResponse struct {
+	ID           int64              `json:"id"`
+	Rule         string             `json:"rule"`
+	EventID      *int64             `json:"event_id"`
+	RepositoryID *int64             `json:"repository_id"`
+	Enabled      *bool              `json:"enabled,omitempty"`
+	Severity     *models.Severity   `json:"severity,omitempty"`
+	Params       map[string]float64 `json:"params,omitempty"`
+	CreatedAt    time.Time          `json:"created_at"`
+	UpdatedAt    time.Time          `json:"updated_at"`
+}
+
+type RuleSettingRequest struct {
+	EventID      *int64             `json:"event_id"`
+	RepositoryID *int64             `json:"repository_id"`
+	Enabled      *bool              `json:"enabled"`
+	Severity     *models.Severity   `json:"severity"`
+	Params       map[string]float64 `json:"params"`
+}
+
+// EffectiveRuleResponse is a rule's resolved settings for one repository.
+type EffectiveRuleResponse struct {
+	Rule     string           `json:"rule"`
+	Enabled  bool             `json:"enabled"`
+	Severity *models.Severity `json:"severity"`
+	Params   rules.Params     `json:"params"`
+}
+
+func ruleToResponse(rule rules.Rule) RuleResponse {
+	resp := RuleResponse{
+		Name:          rule.Name(),
+		Description:   rule.Description(),
+		Triggers:      rule.Triggers(),
+		AlertType:     rule.AlertType(),
+		DefaultParams: rule.DefaultParams(),
+	}
+	if severity := rule.DefaultSeverity(); severity != "" {
+		resp.DefaultSeverity = &severity
+	}
+	if resp.DefaultParams == nil {
+		resp.DefaultParams = rules.Params{}
+	}
+	return resp
+}
+
+func ruleSettingToResponse(s *models.RuleSetting) RuleSettingResponse {
+	return RuleSettingResponse{
+		ID:           s.ID,
+		Rule:         s.Rule,
+		EventID:      s.EventID,
+		RepositoryID: s.RepositoryID,
+		Enabled:      s.Enabled,
+		Severity:     s.Severity,
+		Params:       s.Params,
+		CreatedAt:    s.CreatedAt,
+		UpdatedAt:    s.UpdatedAt,
+	}
+}
+
+// ruleParam looks up the {name} URL parameter in the rule registry, writing
+// the error response when no such rule is registered.
+func (h *Handler) ruleParam(w http.ResponseWriter, r *http.Request) (rules.Rule, bool) {
+	rule := h.engine.Registry().Get(chi.URLParam(r, "name"))
+	if rule == nil {
+		h.respondError(w, http.StatusNotFound, "rule not found")
+		return nil, false
+	}
+	return rule, true
+}
+
+// ListRules lists the registered detection rules with their defaults.
+func (h *Handler) ListRules(w http.ResponseWriter, r *http.Request) {
+	registered := h.engine.Registry().List()
+	resp := make([]RuleResponse, 0, len(registered))
+	for _, rule := range registered {
+		resp = append(resp, ruleToResponse(rule))
+	}
+	h.respondJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) ListRuleSettings(w http.ResponseWriter, r *http.Request) {
+	rule, ok := h.ruleParam(w, r)
+	if !ok {
+		return
+	}
+
+	settings, err := models.NewRuleSettingStore(h.db.Pool).List(r.Context(), rule.Name())
+	if err != nil {
+		h.logger.Error().Err(err).Str("rule", rule.Name()).Msg("failed to list rule settings")
+		h.respondError(w, http.StatusInternalServerError, "failed to list rule settings")
+		return
+	}
+
+	resp := make([]RuleSettingResponse, 0, len(settings))
+	for _, s := range settings {
+		resp = append(resp, ruleSettingToResponse(s))
+	}
+	h.respondJSON(w, http.StatusOK, resp)
+}
+
+// PutRuleSetting creates or replaces a rule's setting globally, or for the
+// event or repository given in the body. Fields left out fall back to the
+// less specific setting, then to the rule's defaults. Parameters the rule
+// rejects once resolved with its other settings are a bad request.
+func (h *Handler) PutRuleSetting(w http.ResponseWriter, r *http.Request) {
+	rule, ok := h.ruleParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req RuleSettingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	setting := &models.RuleSetting{
+		Rule:         rule.Name(),
+		EventID:      req.EventID,
+		RepositoryID: req.RepositoryID,
+		Enabled:      req.Enabled,
+		Severity:     req.Severity,
+		Params:       req.Params,
+	}
+	if err := rules.Validate(rule, setting); err != nil {
+		h.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.EventID != nil {
+		if _, err := models.NewEventStore(h.db.Pool).GetByID(r.Context(), *req.EventID); err != nil {
+			h.respondError(w, http.StatusBadRequest, "event not found")
+			return
+		}
+	}
+	if req.RepositoryID != nil {
+		if _, err := models.NewRepositoryStore(h.db.Pool).GetByID(r.Context(), *req.RepositoryID); err != nil {
+			h.respondError(w, http.StatusBadRequest, "repository not found")
+			return
+		}
+	}
+	if err := h.validateResolved(r.Context(), rule, setting); err != nil {
+		var invalid *invalidParamsError
+		if errors.As(err, &invalid) {
+			h.respondError(w, http.StatusBadRequest, invalid.Error())
+			return
+		}
+		h.logger.Error().Err(err).Str("rule", rule.Name()).Msg("failed to check rule settings")
+		h.respondError(w, http.StatusInternalServerError, "failed to check rule settings")
+		return
+	}
+
+	if err := models.NewRuleSettingStore(h.db.Pool).Upsert(r.Context(), setting); err != nil {
+		h.logger.Error().Err(err).Str("rule", rule.Name()).Msg("failed to save rule setting")
+		h.respondError(w, http.StatusInternalServerError, "failed to save rule setting")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, ruleSettingToResponse(setting))
+}
+
+// invalidParamsError is a setting whose parameters the rule rejects once
+// resolved with its other settings.
+type invalidParamsError struct{ err error }
+
+func (e *invalidParamsError) Error() string { return e.err.Error() }
+
+// validateResolved checks setting against the rule's existing settings with
+// rules.ValidateResolved, looking up the events of the repositories involved.
+func (h *Handler) validateResolved(ctx context.Context, rule rules.Rule, setting *models.RuleSetting) error {
+	if _, ok := rule.(rules.ParamChecker); !ok {
+		return nil
+	}
+
+	existing, err := models.NewRuleSettingStore(h.db.Pool).List(ctx, rule.Name())
+	if err != nil {
+		return err
+	}
+
+	repoEvents := make(map[int64]*int64)
+	repoStore := models.NewRepositoryStore(h.db.Pool)
+	for _, o := range append(existing, setting) {
+		if o.RepositoryID == nil {
+			continue
+		}
+		if _, ok := repoEvents[*o.RepositoryID]; ok {
+			continue
+		}
+		repo, err := repoStore.GetByID(ctx, *o.RepositoryID)
+		if err != nil {
+			return err
+		}
+		repoEvents[repo.ID] = repo.EventID
+	}
+
+	if err := rules.ValidateResolved(rule, setting, existing, repoEvents); err != nil {
+		return &invalidParamsError{err: err}
+	}
+	return nil
+}
+
+// DeleteRuleSetting removes a rule's setting for the scope selected by the
+// event_id or repository_id query parameter, or its global setting when
+// neither is given.
+func (h *Handler) DeleteRuleSetting(w http.ResponseWriter, r *http.Request) {
+	rule, ok := h.ruleParam(w, r)
+	if !ok {
+		return
+	}
+
+	var eventID, repoID *int64
+	for param, dst := range map[string]**int64{"event_id": &eventID, "repository_id": &repoID} {
+		value := r.URL.Query().Get(param)
+		if value == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			h.respondError(w, http.StatusBadRequest, "invalid "+param)
+			return
+		}
+		*dst = &id
+	}
+
+	deleted, err := models.NewRuleSettingStore(h.db.Pool).Delete(r.Context(), rule.Name(), eventID, repoID)
+	if err != nil {
+		h.logger.Error().Err(err).Str("rule", rule.Name()).Msg("failed to delete rule setting")
+		h.respondError(w, http.StatusInternalServerError, "failed to delete rule setting")
+		return
+	}
+	if !deleted {
+		h.respondError(w, http.StatusNotFound, "rule setting not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetRepositoryRules returns the settings every rule resolves to for a
+// repository once global, event and repository settings are applied.
+func (h *Handler) GetRepositoryRules(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.repositoryIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	settings, err := h.engine.RepositorySettings(r.Context(), h.db.Pool, id)
+	if err != nil {
+		h.logger.Error().Err(err).Int64("id", id).Msg("failed to resolve rule settings")
+		h.respondError(w, http.StatusInternalServerError, "failed to resolve rule settings")
+		return
+	}
+
+	registered := h.engine.Registry().List()
+	resp := make([]EffectiveRuleResponse, 0, len(registered))
+	for _, rule := range registered {
+		s := settings.For(rule)
+		effective := EffectiveRuleResponse{Rule: rule.Name(), Enabled: s.Enabled, Params: s.Params}
+		if severity := s.SeverityOr(rule.DefaultSeverity()); severity != "" {
+			effective.Severity = &severity
+		}
+		resp = append(resp, effective)
+	}
+	h.respondJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/harshpatel5940/gitvigil/internal/models"
+)
+
+type SimilarityPairResponse struct {
+	RepositoryA        RepositoryRef        `json:"repository_a"`
+	RepositoryB        RepositoryRef        `json:"repository_b"`
+	Similarity         float64              `json:"similarity"`
+	Jaccard            float64              `json:"jaccard"`
+	SharedFingerprints int                  `json:"shared_fingerprints"`
+	TopFiles           []models.SimilarFile `json:"top_files"`
+	ComputedAt         time.Time            `json:"computed_at"`
+}
+
+type RepositoryRef struct {
+	ID       int64  `json:"id"`
+	FullName string `json:"full_name"`
+}
+
+type CodeSnapshotResponse struct {
+	RepositoryID int64     `json:"repository_id"`
+	Ref          string    `json:"ref"`
+	HeadSHA      string    `json:"head_sha"`
+	Files        int       `json:"files"`
+	Fingerprints int       `json:"fingerprints"`
+	Truncated    bool      `json:"truncated"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// GetEventSimilarity returns the pairwise code similarity of the event's
+// submissions, most similar first. ?min= drops pairs below that percentage.
+func (h *Handler) GetEventSimilarity(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid event ID")
+		return
+	}
+
+	minSimilarity := 0.0
+	if v := r.URL.Query().Get("min"); v != "" {
+		minSimilarity, err = strconv.ParseFloat(v, 64)
+		if err != nil || minSimilarity < 0 || minSimilarity > 100 {
+			h.respondError(w, http.StatusBadRequest, "min must be a percentage between 0 and 100")
+			return
+		}
+	}
+
+	if _, err := models.NewEventStore(h.db.Pool).GetByID(ctx, id); err != nil {
+		h.logger.Error().Err(err).Int64("id", id).Msg("failed to get event")
+		h.respondError(w, http.StatusNotFound, "event not found")
+		return
+	}
+
+	store := models.NewSimilarityStore(h.db.Pool)
+	snapshots, err := store.ListSnapshots(ctx, id)
+	if err != nil {
+		h.logger.Error().Err(err).Int64("id", id).Msg("failed to list code snapshots")
+		h.respondError(w, http.StatusInternalServerError, "failed to list code snapshots")
+		return
+	}
+	pairs, err := store.ListPairs(ctx, id, minSimilarity)
+	if err != nil {
+		h.logger.Error().Err(err).Int64("id", id).Msg("failed to list similarity pairs")
+		h.respondError(w, http.StatusInternalServerError, "failed to list similarity pairs")
+		return
+	}
+
+	snapshotResponse := make([]CodeSnapshotResponse, 0, len(snapshots))
+	for _, s := range snapshots {
+		snapshotResponse = append(snapshotResponse, CodeSnapshotResponse{
+			RepositoryID: s.RepositoryID,
+			Ref:          s.Ref,
+			HeadSHA:      s.HeadSHA,
+			Files:        s.Files,
+			Fingerprints: s.Fingerprints,
+			Truncated:    s.Truncated,
+			CreatedAt:    s.CreatedAt,
+		})
+	}
+
+	pairResponse := make([]SimilarityPairResponse, 0, len(pairs))
+	for _, p := range pairs {
+		pairResponse = append(pairResponse, SimilarityPairResponse{
+			RepositoryA:        RepositoryRef{ID: p.RepositoryAID, FullName: p.RepositoryA},
+			RepositoryB:        RepositoryRef{ID: p.RepositoryBID, FullName: p.RepositoryB},
+			Similarity:         p.Similarity,
+			Jaccard:            p.Jaccard,
+			SharedFingerprints: p.SharedFingerprints,
+			TopFiles:           p.TopFiles,
+			ComputedAt:         p.ComputedAt,
+		})
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"event_id":  id,
+		"snapshots": snapshotResponse,
+		"pairs":     pairResponse,
+	})
+}
@@ -12,6 +12,8 @@ type StatsResponse struct {
 	TotalAlerts      int            `json:"total_alerts"`
 	ActiveRepos      int            `json:"active_repos"`
 	AtRiskRepos      int            `json:"at_risk_repos"`
+	InactiveRepos    int            `json:"inactive_repos"`
+	RecoveredRepos   int            `json:"recovered_repos"`
 	BackdateAlerts   int            `json:"backdate_alerts"`
 	ForcePushAlerts  int            `json:"force_push_alerts"`
 	AlertsBySeverity map[string]int `json:"alerts_by_severity"`
@@ -37,6 +39,8 @@ func (h *Handler) GetStats(w http.ResponseWriter, r *http.Request) {
 		{"SELECT COUNT(*) FROM alerts", &stats.TotalAlerts},
 		{"SELECT COUNT(*) FROM repositories WHERE streak_status = 'active'", &stats.ActiveRepos},
 		{"SELECT COUNT(*) FROM repositories WHERE streak_status = 'at_risk'", &stats.AtRiskRepos},
+		{"SELECT COUNT(*) FROM repositories WHERE streak_status = 'inactive'", &stats.InactiveRepos},
+		{"SELECT COUNT(*) FROM repositories WHERE streak_status = 'recovered'", &stats.RecoveredRepos},
 		{"SELECT COUNT(*) FROM alerts WHERE alert_type LIKE 'backdate%'", &stats.BackdateAlerts},
 		{"SELECT COUNT(*) FROM alerts WHERE alert_type = 'force_push'", &stats.ForcePushAlerts},
 	}
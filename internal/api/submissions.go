@@ -0,0 +1,119 @@
+package api
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/harshpatel5940/gitvigil/internal/models"
+)
+
+type SubmissionResponse struct {
+	RepositoryID       int64      `json:"repository_id"`
+	Repository         string     `json:"repository"`
+	FrozenAt           *time.Time `json:"frozen_at"`
+	Ref                *string    `json:"ref"`
+	HeadSHA            *string    `json:"head_sha"`
+	PushedAt           *time.Time `json:"pushed_at"`
+	CutoffAt           *time.Time `json:"cutoff_at"`
+	PostDeadlinePushes int        `json:"post_deadline_pushes"`
+}
+
+// ListEventSubmissions exports the frozen branch heads of every repository
+// enrolled in the event for judging. Repositories not frozen yet, or with no
+// branches at the cutoff, appear once with empty branch fields. Pass
+// ?format=csv for a spreadsheet-friendly export.
+func (h *Handler) ListEventSubmissions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid event ID")
+		return
+	}
+
+	event, err := models.NewEventStore(h.db.Pool).GetByID(ctx, id)
+	if err != nil {
+		h.logger.Error().Err(err).Int64("id", id).Msg("failed to get event")
+		h.respondError(w, http.StatusNotFound, "event not found")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		h.respondError(w, http.StatusBadRequest, "format must be json or csv")
+		return
+	}
+
+	exports, err := models.NewSubmissionStore(h.db.Pool).ListByEvent(ctx, id)
+	if err != nil {
+		h.logger.Error().Err(err).Int64("id", id).Msg("failed to list submissions")
+		h.respondError(w, http.StatusInternalServerError, "failed to list submissions")
+		return
+	}
+
+	response := make([]SubmissionResponse, 0, len(exports))
+	for _, e := range exports {
+		response = append(response, SubmissionResponse{
+			RepositoryID:       e.RepositoryID,
+			Repository:         e.FullName,
+			FrozenAt:           e.FrozenAt,
+			Ref:                e.Ref,
+			HeadSHA:            e.HeadSHA,
+			PushedAt:           e.PushedAt,
+			CutoffAt:           e.CutoffAt,
+			PostDeadlinePushes: e.PostDeadlinePushes,
+		})
+	}
+
+	if format == "csv" {
+		h.writeSubmissionsCSV(w, id, response)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"event_id":            event.ID,
+		"submission_deadline": event.Deadline(),
+		"submissions":         response,
+	})
+}
+
+func (h *Handler) writeSubmissionsCSV(w http.ResponseWriter, eventID int64, submissions []SubmissionResponse) {
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="event-%d-submissions.csv"`, eventID))
+
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"repository", "ref", "head_sha", "pushed_at", "cutoff_at", "frozen_at", "post_deadline_pushes"})
+	for _, s := range submissions {
+		writer.Write([]string{
+			s.Repository,
+			derefString(s.Ref),
+			derefString(s.HeadSHA),
+			formatTime(s.PushedAt),
+			formatTime(s.CutoffAt),
+			formatTime(s.FrozenAt),
+			strconv.Itoa(s.PostDeadlinePushes),
+		})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		h.logger.Error().Err(err).Int64("event_id", eventID).Msg("failed to write submissions csv")
+	}
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
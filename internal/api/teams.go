@@ -0,0 +1,632 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/harshpatel5940/gitvigil/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// maxRosterBytes caps the size of an uploaded roster CSV.
+const maxRosterBytes = 1 << 20
+
+type TeamResponse struct {
+	ID                int64     `json:"id"`
+	EventID           *int64    `json:"event_id,omitempty"`
+	Name              string    `json:"name"`
+	MembersCount      int       `json:"members_count"`
+	RepositoriesCount int       `json:"repositories_count"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+type TeamDetailResponse struct {
+	TeamResponse
+	Members      []TeamMemberResponse `json:"members"`
+	Repositories []RepositoryResponse `json:"repositories"`
+}
+
+type TeamMemberResponse struct {
+	ID          int64     `json:"id"`
+	GitHubLogin string    `json:"github_login"`
+	Name        *string   `json:"name,omitempty"`
+	Emails      []string  `json:"emails"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type TeamRequest struct {
+	Name    string `json:"name"`
+	EventID *int64 `json:"event_id"`
+}
+
+type TeamMemberRequest struct {
+	GitHubLogin string   `json:"github_login"`
+	Name        *string  `json:"name"`
+	Emails      []string `json:"emails"`
+}
+
+type MemberStatsResponse struct {
+	Member        TeamMemberResponse `json:"member"`
+	Commits       int                `json:"commits"`
+	Additions     int64              `json:"additions"`
+	Deletions     int64              `json:"deletions"`
+	FirstCommitAt *time.Time         `json:"first_commit_at,omitempty"`
+	LastCommitAt  *time.Time         `json:"last_commit_at,omitempty"`
+}
+
+func teamToResponse(t *models.TeamWithStats) TeamResponse {
+	return TeamResponse{
+		ID:                t.ID,
+		EventID:           t.EventID,
+		Name:              t.Name,
+		MembersCount:      t.MembersCount,
+		RepositoriesCount: t.RepositoriesCount,
+		CreatedAt:         t.CreatedAt,
+		UpdatedAt:         t.UpdatedAt,
+	}
+}
+
+func memberToResponse(m *models.TeamMember) TeamMemberResponse {
+	emails := m.Emails
+	if emails == nil {
+		emails = []string{}
+	}
+	return TeamMemberResponse{
+		ID:          m.ID,
+		GitHubLogin: m.GitHubLogin,
+		Name:        m.Name,
+		Emails:      emails,
+		CreatedAt:   m.CreatedAt,
+		UpdatedAt:   m.UpdatedAt,
+	}
+}
+
+func (req *TeamMemberRequest) toMember(teamID int64) (*models.TeamMember, error) {
+	login := strings.TrimPrefix(strings.TrimSpace(req.GitHubLogin), "@")
+	if login == "" {
+		return nil, errors.New("github_login is required")
+	}
+	return &models.TeamMember{
+		TeamID:      teamID,
+		GitHubLogin: login,
+		Name:        req.Name,
+		Emails:      req.Emails,
+	}, nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// getTeamID parses the {id} URL parameter and checks the team exists,
+// writing the error response when it does not.
+func (h *Handler) getTeamID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid team ID")
+		return 0, false
+	}
+
+	store := models.NewTeamStore(h.db.Pool)
+	if _, err := store.GetByID(r.Context(), id); err != nil {
+		h.logger.Error().Err(err).Int64("id", id).Msg("failed to get team")
+		h.respondError(w, http.StatusNotFound, "team not found")
+		return 0, false
+	}
+	return id, true
+}
+
+// validateTeamRequest trims the name and checks the event exists.
+func (h *Handler) validateTeamRequest(r *http.Request, req *TeamRequest) error {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return errors.New("name is required")
+	}
+	if req.EventID != nil {
+		if _, err := models.NewEventStore(h.db.Pool).GetByID(r.Context(), *req.EventID); err != nil {
+			return errors.New("event not found")
+		}
+	}
+	return nil
+}
+
+func (h *Handler) ListTeams(w http.ResponseWriter, r *http.Request) {
+	var eventID *int64
+	if e := r.URL.Query().Get("event_id"); e != "" {
+		id, err := strconv.ParseInt(e, 10, 64)
+		if err != nil {
+			h.respondError(w, http.StatusBadRequest, "invalid event_id")
+			return
+		}
+		eventID = &id
+	}
+
+	store := models.NewTeamStore(h.db.Pool)
+	teams, err := store.List(r.Context(), eventID)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to list teams")
+		h.respondError(w, http.StatusInternalServerError, "failed to list teams")
+		return
+	}
+
+	response := make([]TeamResponse, 0, len(teams))
+	for _, t := range teams {
+		response = append(response, teamToResponse(t))
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"teams": response,
+	})
+}
+
+func (h *Handler) GetTeam(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid team ID")
+		return
+	}
+
+	store := models.NewTeamStore(h.db.Pool)
+	team, err := store.GetByID(ctx, id)
+	if err != nil {
+		h.logger.Error().Err(err).Int64("id", id).Msg("failed to get team")
+		h.respondError(w, http.StatusNotFound, "team not found")
+		return
+	}
+
+	members, err := store.ListMembers(ctx, id)
+	if err != nil {
+		h.logger.Error().Err(err).Int64("id", id).Msg("failed to list team members")
+		h.respondError(w, http.StatusInternalServerError, "failed to list team members")
+		return
+	}
+
+	repos, err := store.ListRepositories(ctx, id)
+	if err != nil {
+		h.logger.Error().Err(err).Int64("id", id).Msg("failed to list team repositories")
+		h.respondError(w, http.StatusInternalServerError, "failed to list team repositories")
+		return
+	}
+
+	response := TeamDetailResponse{
+		TeamResponse: teamToResponse(team),
+		Members:      make([]TeamMemberResponse, 0, len(members)),
+		Repositories: make([]RepositoryResponse, 0, len(repos)),
+	}
+	for _, m := range members {
+		response.Members = append(response.Members, memberToResponse(m))
+	}
+	for _, repo := range repos {
+		response.Repositories = append(response.Repositories, repoToResponse(&models.RepositoryWithStats{Repository: *repo}))
+	}
+
+	h.respondJSON(w, http.StatusOK, response)
+}
+
+func (h *Handler) CreateTeam(w http.ResponseWriter, r *http.Request) {
+	var req TeamRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := h.validateTeamRequest(r, &req); err != nil {
+		h.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	team := &models.Team{Name: req.Name, EventID: req.EventID}
+	store := models.NewTeamStore(h.db.Pool)
+	if err := store.Create(r.Context(), team); err != nil {
+		h.logger.Error().Err(err).Msg("failed to create team")
+		h.respondError(w, http.StatusInternalServerError, "failed to create team")
+		return
+	}
+
+	h.respondJSON(w, http.StatusCreated, teamToResponse(&models.TeamWithStats{Team: *team}))
+}
+
+func (h *Handler) UpdateTeam(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid team ID")
+		return
+	}
+
+	var req TeamRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := h.validateTeamRequest(r, &req); err != nil {
+		h.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	store := models.NewTeamStore(h.db.Pool)
+	if err := store.Update(ctx, &models.Team{ID: id, Name: req.Name, EventID: req.EventID}); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			h.respondError(w, http.StatusNotFound, "team not found")
+			return
+		}
+		h.logger.Error().Err(err).Int64("id", id).Msg("failed to update team")
+		h.respondError(w, http.StatusInternalServerError, "failed to update team")
+		return
+	}
+
+	team, err := store.GetByID(ctx, id)
+	if err != nil {
+		h.logger.Error().Err(err).Int64("id", id).Msg("failed to get team")
+		h.respondError(w, http.StatusInternalServerError, "failed to get team")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, teamToResponse(team))
+}
+
+func (h *Handler) DeleteTeam(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid team ID")
+		return
+	}
+
+	store := models.NewTeamStore(h.db.Pool)
+	deleted, err := store.Delete(r.Context(), id)
+	if err != nil {
+		h.logger.Error().Err(err).Int64("id", id).Msg("failed to delete team")
+		h.respondError(w, http.StatusInternalServerError, "failed to delete team")
+		return
+	}
+	if !deleted {
+		h.respondError(w, http.StatusNotFound, "team not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) ListTeamMembers(w http.ResponseWriter, r *http.Request) {
+	teamID, ok := h.getTeamID(w, r)
+	if !ok {
+		return
+	}
+
+	store := models.NewTeamStore(h.db.Pool)
+	members, err := store.ListMembers(r.Context(), teamID)
+	if err != nil {
+		h.logger.Error().Err(err).Int64("team_id", teamID).Msg("failed to list team members")
+		h.respondError(w, http.StatusInternalServerError, "failed to list team members")
+		return
+	}
+
+	response := make([]TeamMemberResponse, 0, len(members))
+	for _, m := range members {
+		response = append(response, memberToResponse(m))
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"members": response,
+	})
+}
+
+func (h *Handler) CreateTeamMember(w http.ResponseWriter, r *http.Request) {
+	teamID, ok := h.getTeamID(w, r)
+	if !ok {
+		return
+	}
+
+	var req TeamMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	member, err := req.toMember(teamID)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	store := models.NewTeamStore(h.db.Pool)
+	if err := store.CreateMember(r.Context(), member); err != nil {
+		if isUniqueViolation(err) {
+			h.respondError(w, http.StatusConflict, "team already has a member with this login")
+			return
+		}
+		h.logger.Error().Err(err).Int64("team_id", teamID).Msg("failed to create team member")
+		h.respondError(w, http.StatusInternalServerError, "failed to create team member")
+		return
+	}
+
+	member.Emails = models.NormalizeEmails(member.Emails)
+	h.respondJSON(w, http.StatusCreated, memberToResponse(member))
+}
+
+func (h *Handler) UpdateTeamMember(w http.ResponseWriter, r *http.Request) {
+	teamID, ok := h.getTeamID(w, r)
+	if !ok {
+		return
+	}
+	memberID, err := strconv.ParseInt(chi.URLParam(r, "memberID"), 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid member ID")
+		return
+	}
+
+	var req TeamMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	member, err := req.toMember(teamID)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	member.ID = memberID
+
+	store := models.NewTeamStore(h.db.Pool)
+	if err := store.UpdateMember(r.Context(), member); err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			h.respondError(w, http.StatusNotFound, "team member not found")
+		case isUniqueViolation(err):
+			h.respondError(w, http.StatusConflict, "team already has a member with this login")
+		default:
+			h.logger.Error().Err(err).Int64("member_id", memberID).Msg("failed to update team member")
+			h.respondError(w, http.StatusInternalServerError, "failed to update team member")
+		}
+		return
+	}
+
+	member.Emails = models.NormalizeEmails(member.Emails)
+	h.respondJSON(w, http.StatusOK, memberToResponse(member))
+}
+
+func (h *Handler) DeleteTeamMember(w http.ResponseWriter, r *http.Request) {
+	teamID, ok := h.getTeamID(w, r)
+	if !ok {
+		return
+	}
+	memberID, err := strconv.ParseInt(chi.URLParam(r, "memberID"), 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid member ID")
+		return
+	}
+
+	store := models.NewTeamStore(h.db.Pool)
+	deleted, err := store.DeleteMember(r.Context(), teamID, memberID)
+	if err != nil {
+		h.logger.Error().Err(err).Int64("member_id", memberID).Msg("failed to delete team member")
+		h.respondError(w, http.StatusInternalServerError, "failed to delete team member")
+		return
+	}
+	if !deleted {
+		h.respondError(w, http.StatusNotFound, "team member not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ImportTeamMembers imports a roster from a CSV body with a header row.
+// Recognised columns are github_login (or login), name and emails (or
+// email), with several emails separated by semicolons. Rows repeating a
+// login are merged. With ?replace=true, members not in the file are removed.
+func (h *Handler) ImportTeamMembers(w http.ResponseWriter, r *http.Request) {
+	teamID, ok := h.getTeamID(w, r)
+	if !ok {
+		return
+	}
+
+	members, err := parseRoster(http.MaxBytesReader(w, r.Body, maxRosterBytes))
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	replace := r.URL.Query().Get("replace") == "true"
+
+	store := models.NewTeamStore(h.db.Pool)
+	created, updated, err := store.ImportMembers(r.Context(), teamID, members, replace)
+	if err != nil {
+		h.logger.Error().Err(err).Int64("team_id", teamID).Msg("failed to import roster")
+		h.respondError(w, http.StatusInternalServerError, "failed to import roster")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"created":  created,
+		"updated":  updated,
+		"replaced": replace,
+	})
+}
+
+// parseRoster reads roster members from CSV.
+func parseRoster(body io.Reader) ([]*models.TeamMember, error) {
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errors.New("roster must start with a header row")
+	}
+
+	loginCol, nameCol, emailCol := -1, -1, -1
+	for i, col := range header {
+		switch strings.ToLower(strings.TrimSpace(col)) {
+		case "github_login", "login", "github":
+			loginCol = i
+		case "name":
+			nameCol = i
+		case "emails", "email":
+			emailCol = i
+		}
+	}
+	if loginCol < 0 {
+		return nil, errors.New("roster header must include a github_login column")
+	}
+
+	var members []*models.TeamMember
+	byLogin := make(map[string]*models.TeamMember)
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid roster CSV: %w", err)
+		}
+
+		field := func(col int) string {
+			if col < 0 || col >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[col])
+		}
+
+		login := strings.TrimPrefix(field(loginCol), "@")
+		if login == "" {
+			return nil, fmt.Errorf("line %d: github_login is required", line)
+		}
+
+		member, ok := byLogin[strings.ToLower(login)]
+		if !ok {
+			member = &models.TeamMember{GitHubLogin: login}
+			byLogin[strings.ToLower(login)] = member
+			members = append(members, member)
+		}
+		if name := field(nameCol); name != "" {
+			member.Name = &name
+		}
+		member.Emails = append(member.Emails, strings.Split(field(emailCol), ";")...)
+	}
+
+	if len(members) == 0 {
+		return nil, errors.New("roster has no members")
+	}
+	return members, nil
+}
+
+// AssignTeamRepository assigns a repository to the team, moving it out of
+// any team it was assigned to before.
+func (h *Handler) AssignTeamRepository(w http.ResponseWriter, r *http.Request) {
+	h.setTeamAssignment(w, r, true)
+}
+
+func (h *Handler) UnassignTeamRepository(w http.ResponseWriter, r *http.Request) {
+	h.setTeamAssignment(w, r, false)
+}
+
+func (h *Handler) setTeamAssignment(w http.ResponseWriter, r *http.Request, assign bool) {
+	ctx := r.Context()
+
+	teamID, ok := h.getTeamID(w, r)
+	if !ok {
+		return
+	}
+	repoID, err := strconv.ParseInt(chi.URLParam(r, "repoID"), 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid repository ID")
+		return
+	}
+
+	repoStore := models.NewRepositoryStore(h.db.Pool)
+	repo, err := repoStore.GetByID(ctx, repoID)
+	if err != nil {
+		h.logger.Error().Err(err).Int64("id", repoID).Msg("failed to get repository")
+		h.respondError(w, http.StatusNotFound, "repository not found")
+		return
+	}
+
+	target := &teamID
+	if !assign {
+		if repo.TeamID == nil || *repo.TeamID != teamID {
+			h.respondError(w, http.StatusNotFound, "repository is not assigned to this team")
+			return
+		}
+		target = nil
+	}
+
+	store := models.NewTeamStore(h.db.Pool)
+	if _, err := store.AssignRepository(ctx, repoID, target); err != nil {
+		h.logger.Error().Err(err).Int64("team_id", teamID).Int64("repository_id", repoID).Msg("failed to update team assignment")
+		h.respondError(w, http.StatusInternalServerError, "failed to update team assignment")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetRepositoryMembers returns per-member activity for the repository's team,
+// counted within the repository's event window.
+func (h *Handler) GetRepositoryMembers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid repository ID")
+		return
+	}
+
+	repoStore := models.NewRepositoryStore(h.db.Pool)
+	repo, err := repoStore.GetByID(ctx, id)
+	if err != nil {
+		h.logger.Error().Err(err).Int64("id", id).Msg("failed to get repository")
+		h.respondError(w, http.StatusNotFound, "repository not found")
+		return
+	}
+	if repo.TeamID == nil {
+		h.respondError(w, http.StatusNotFound, "repository is not assigned to a team")
+		return
+	}
+
+	event, err := models.NewEventStore(h.db.Pool).GetForRepository(ctx, &repo.Repository)
+	if err != nil {
+		h.logger.Error().Err(err).Int64("id", id).Msg("failed to get event")
+		h.respondError(w, http.StatusInternalServerError, "failed to get event")
+		return
+	}
+	from, to := models.EventWindow(event)
+
+	store := models.NewTeamStore(h.db.Pool)
+	stats, err := store.MemberStats(ctx, id, from, to)
+	if err != nil {
+		h.logger.Error().Err(err).Int64("id", id).Msg("failed to get member stats")
+		h.respondError(w, http.StatusInternalServerError, "failed to get member stats")
+		return
+	}
+
+	response := make([]MemberStatsResponse, 0, len(stats))
+	for _, s := range stats {
+		response = append(response, MemberStatsResponse{
+			Member:        memberToResponse(&s.Member),
+			Commits:       s.Commits,
+			Additions:     s.Additions,
+			Deletions:     s.Deletions,
+			FirstCommitAt: s.FirstCommitAt,
+			LastCommitAt:  s.LastCommitAt,
+		})
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"team_id": *repo.TeamID,
+		"members": response,
+	})
+}
@@ -0,0 +1,190 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/harshpatel5940/gitvigil/internal/models"
+)
+
+var shaPattern = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+type StarterTemplateResponse struct {
+	Source     string     `json:"source"`
+	BaseSHA    *string    `json:"base_sha"`
+	Notes      *string    `json:"notes,omitempty"`
+	Approved   bool       `json:"approved"`
+	ApprovedBy *string    `json:"approved_by,omitempty"`
+	ApprovedAt *time.Time `json:"approved_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+type StarterTemplateRequest struct {
+	Source  string  `json:"source"`
+	BaseSHA *string `json:"base_sha"`
+	Notes   *string `json:"notes"`
+}
+
+type ApproveTemplateRequest struct {
+	ApprovedBy string `json:"approved_by"`
+}
+
+func templateToResponse(t *models.StarterTemplate) StarterTemplateResponse {
+	return StarterTemplateResponse{
+		Source:     t.Source,
+		BaseSHA:    t.BaseSHA,
+		Notes:      t.Notes,
+		Approved:   t.Approved(),
+		ApprovedBy: t.ApprovedBy,
+		ApprovedAt: t.ApprovedAt,
+		CreatedAt:  t.CreatedAt,
+		UpdatedAt:  t.UpdatedAt,
+	}
+}
+
+// repositoryIDParam parses the {id} URL parameter and checks the repository
+// exists, writing the error response when it does not.
+func (h *Handler) repositoryIDParam(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid repository ID")
+		return 0, false
+	}
+	if _, err := models.NewRepositoryStore(h.db.Pool).GetByID(r.Context(), id); err != nil {
+		h.logger.Error().Err(err).Int64("id", id).Msg("failed to get repository")
+		h.respondError(w, http.StatusNotFound, "repository not found")
+		return 0, false
+	}
+	return id, true
+}
+
+func (h *Handler) GetStarterTemplate(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.repositoryIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	template, err := models.NewStarterTemplateStore(h.db.Pool).Get(r.Context(), id)
+	if err != nil {
+		h.logger.Error().Err(err).Int64("id", id).Msg("failed to get starter template")
+		h.respondError(w, http.StatusInternalServerError, "failed to get starter template")
+		return
+	}
+	if template == nil {
+		h.respondError(w, http.StatusNotFound, "no starter template declared")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, templateToResponse(template))
+}
+
+// DeclareStarterTemplate records the starter code a team built on. Commits
+// authored up to base_sha, or all pre-event commits when it is omitted, stop
+// counting as pre-event code once an organizer approves the declaration.
+// Redeclaring withdraws any earlier approval. Like the approval, it is an
+// organizer action behind admin auth.
+func (h *Handler) DeclareStarterTemplate(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.repositoryIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req StarterTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	req.Source = strings.TrimSpace(req.Source)
+	if req.Source == "" {
+		h.respondError(w, http.StatusBadRequest, "source is required")
+		return
+	}
+	if req.BaseSHA != nil {
+		sha := strings.ToLower(strings.TrimSpace(*req.BaseSHA))
+		if !shaPattern.MatchString(sha) {
+			h.respondError(w, http.StatusBadRequest, "base_sha must be a full 40 character commit SHA")
+			return
+		}
+		req.BaseSHA = &sha
+	}
+
+	template := &models.StarterTemplate{
+		RepositoryID: id,
+		Source:       req.Source,
+		BaseSHA:      req.BaseSHA,
+		Notes:        req.Notes,
+	}
+	if err := models.NewStarterTemplateStore(h.db.Pool).Declare(r.Context(), template); err != nil {
+		h.logger.Error().Err(err).Int64("id", id).Msg("failed to declare starter template")
+		h.respondError(w, http.StatusInternalServerError, "failed to declare starter template")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, templateToResponse(template))
+}
+
+func (h *Handler) DeleteStarterTemplate(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.repositoryIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	deleted, err := models.NewStarterTemplateStore(h.db.Pool).Delete(r.Context(), id)
+	if err != nil {
+		h.logger.Error().Err(err).Int64("id", id).Msg("failed to delete starter template")
+		h.respondError(w, http.StatusInternalServerError, "failed to delete starter template")
+		return
+	}
+	if !deleted {
+		h.respondError(w, http.StatusNotFound, "no starter template declared")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ApproveStarterTemplate is the organizer's sign-off on a declared template.
+// It is mounted on the admin router; the next pre-event check applies it.
+func (h *Handler) ApproveStarterTemplate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := h.repositoryIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req ApproveTemplateRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.respondError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+	}
+
+	store := models.NewStarterTemplateStore(h.db.Pool)
+	approved, err := store.Approve(ctx, id, strings.TrimSpace(req.ApprovedBy))
+	if err != nil {
+		h.logger.Error().Err(err).Int64("id", id).Msg("failed to approve starter template")
+		h.respondError(w, http.StatusInternalServerError, "failed to approve starter template")
+		return
+	}
+	if !approved {
+		h.respondError(w, http.StatusNotFound, "no starter template declared")
+		return
+	}
+
+	template, err := store.Get(ctx, id)
+	if err != nil || template == nil {
+		h.logger.Error().Err(err).Int64("id", id).Msg("failed to get starter template")
+		h.respondError(w, http.StatusInternalServerError, "failed to get starter template")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, templateToResponse(template))
+}
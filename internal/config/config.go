@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -24,10 +25,103 @@ type Config struct {
 	// Database
 	DatabaseURL string
 
+	// Admin
+	AdminToken string
+
+	// Job queue
+	QueueWorkers             int
+	QueueMaxAttempts         int
+	QueueRetryBaseSeconds    int
+	QueueDrainTimeoutSeconds int
+
+	// Scheduler intervals; 0 disables the periodic run (manual trigger only)
+	StreakCheckIntervalMinutes        int
+	LicenseCheckIntervalMinutes       int
+	RosterCheckIntervalMinutes        int
+	SubmissionCheckIntervalMinutes    int
+	PreEventCheckIntervalMinutes      int
+	SharedHistoryCheckIntervalMinutes int
+	OriginCheckIntervalMinutes        int
+	SimilarityCheckIntervalMinutes    int
+	RewriteCheckIntervalMinutes       int
+	CodeDumpCheckIntervalMinutes      int
+	ScriptedCheckIntervalMinutes      int
+	TimezoneCheckIntervalMinutes      int
+
+	// Submissions
+	// SubmissionGraceMinutes is how long after an event's deadline pushes
+	// still count towards the submission.
+	SubmissionGraceMinutes int
+
+	// Lifecycle
+	// LifecycleDataPolicy controls what happens to a repository's commits,
+	// contributors and alerts once it is removed or deleted: "archive" keeps
+	// them (hidden from default API listings), "purge" deletes them.
+	LifecycleDataPolicy string
+
+	// Backfill
+	BackfillMaxCommits int
+
 	// Detection thresholds
 	BackdateSuspiciousHours int
 	BackdateCriticalHours   int
-	StreakInactivityHours   int
+	// FutureDatedToleranceMinutes is how far after its push a commit may be
+	// dated, to allow for clock drift, before it is flagged as future dated.
+	FutureDatedToleranceMinutes int
+	StreakInactivityHours       int
+	// StreakInactiveHours of inactivity break the streak; StreakRecoveryHours
+	// is how long a recovered repository stays recovered before it is active.
+	StreakInactiveHours int
+	StreakRecoveryHours int
+	// BotAllowlist holds logins and emails of automation accounts that may
+	// commit to a repository without being on its team roster.
+	BotAllowlist []string
+	// PreEventCriticalPercent is the share of commits or added lines
+	// authored before the event start at which pre-event code is critical.
+	PreEventCriticalPercent int
+	// SimilarityThresholdPercent is the code similarity between two
+	// repositories of an event at which both are flagged.
+	SimilarityThresholdPercent int
+	// RewriteGapMinutes is how long after its author date a commit may be
+	// committed before it counts as rebased or amended, and how far apart
+	// the author dates of commits sharing a committer timestamp must be for
+	// a bulk rewrite. RewriteBulkMinCommits is the smallest such group.
+	RewriteGapMinutes     int
+	RewriteBulkMinCommits int
+	// CodeDumpCommitLines is the lines of code a single commit may add
+	// before it is a code dump outright. Commits adding at least
+	// CodeDumpMinLines are also dumps at CodeDumpRelativeFactor times the
+	// median commit of their repository or event, and so are pushes
+	// delivering CodeDumpPushPercent of a repository holding at least
+	// CodeDumpMinRepoLines lines of code.
+	CodeDumpCommitLines    int
+	CodeDumpMinLines       int
+	CodeDumpRelativeFactor int
+	CodeDumpPushPercent    int
+	CodeDumpMinRepoLines   int
+	// ScriptedRegularMinCommits commits by one author spaced at the same
+	// interval, give or take ScriptedRegularToleranceSeconds, look scripted,
+	// as do ScriptedBurstMinCommits commits authored at most
+	// ScriptedBurstSeconds apart and ScriptedDuplicateMinCommits commits
+	// sharing a message.
+	ScriptedRegularMinCommits       int
+	ScriptedRegularToleranceSeconds int
+	ScriptedBurstSeconds            int
+	ScriptedBurstMinCommits         int
+	ScriptedDuplicateMinCommits     int
+	// TimezoneSwitchMinMinutes is the UTC offset change between two commits
+	// less than TimezoneSwitchWindowHours apart that flags a contributor.
+	// Commits to an on-site event's repositories more than
+	// TimezoneOffsiteToleranceMinutes off the event's time zone are flagged
+	// once there are TimezoneOffsiteMinCommits of them.
+	TimezoneSwitchMinMinutes        int
+	TimezoneSwitchWindowHours       int
+	TimezoneOffsiteToleranceMinutes int
+	TimezoneOffsiteMinCommits       int
+
+	// CustomRulesFile is the path of an optional JSON file of organizer
+	// defined alert rules.
+	CustomRulesFile string
 }
 
 func Load() (*Config, error) {
@@ -35,16 +129,59 @@ func Load() (*Config, error) {
 	_ = godotenv.Load()
 
 	cfg := &Config{
-		Port:                    getEnv("PORT", "8080"),
-		BaseURL:                 getEnv("BASE_URL", "http://localhost:8080"),
-		ClientID:                os.Getenv("GITHUB_APP_CLIENT_ID"),
-		ClientSecret:            os.Getenv("GITHUB_APP_CLIENT_SECRET"),
-		WebhookSecret:           getEnv("GITHUB_WEBHOOK_SECRET", ""),
-		PrivateKeyPath:          getEnv("GITHUB_PRIVATE_KEY_PATH", ""),
-		DatabaseURL:             getEnv("DATABASE_URL", ""),
-		BackdateSuspiciousHours: getEnvInt("BACKDATE_SUSPICIOUS_HOURS", 24),
-		BackdateCriticalHours:   getEnvInt("BACKDATE_CRITICAL_HOURS", 72),
-		StreakInactivityHours:   getEnvInt("STREAK_INACTIVITY_HOURS", 72),
+		Port:                              getEnv("PORT", "8080"),
+		BaseURL:                           getEnv("BASE_URL", "http://localhost:8080"),
+		ClientID:                          os.Getenv("GITHUB_APP_CLIENT_ID"),
+		ClientSecret:                      os.Getenv("GITHUB_APP_CLIENT_SECRET"),
+		WebhookSecret:                     getEnv("GITHUB_WEBHOOK_SECRET", ""),
+		PrivateKeyPath:                    getEnv("GITHUB_PRIVATE_KEY_PATH", ""),
+		DatabaseURL:                       getEnv("DATABASE_URL", ""),
+		AdminToken:                        getEnv("ADMIN_TOKEN", ""),
+		QueueWorkers:                      getEnvInt("QUEUE_WORKERS", 4),
+		QueueMaxAttempts:                  getEnvInt("QUEUE_MAX_ATTEMPTS", 8),
+		QueueRetryBaseSeconds:             getEnvInt("QUEUE_RETRY_BASE_SECONDS", 5),
+		QueueDrainTimeoutSeconds:          getEnvInt("QUEUE_DRAIN_TIMEOUT_SECONDS", 30),
+		StreakCheckIntervalMinutes:        getEnvInt("STREAK_CHECK_INTERVAL_MINUTES", 60),
+		LicenseCheckIntervalMinutes:       getEnvInt("LICENSE_CHECK_INTERVAL_MINUTES", 1440),
+		RosterCheckIntervalMinutes:        getEnvInt("ROSTER_CHECK_INTERVAL_MINUTES", 60),
+		SubmissionCheckIntervalMinutes:    getEnvInt("SUBMISSION_CHECK_INTERVAL_MINUTES", 5),
+		PreEventCheckIntervalMinutes:      getEnvInt("PRE_EVENT_CHECK_INTERVAL_MINUTES", 60),
+		SharedHistoryCheckIntervalMinutes: getEnvInt("SHARED_HISTORY_CHECK_INTERVAL_MINUTES", 60),
+		OriginCheckIntervalMinutes:        getEnvInt("ORIGIN_CHECK_INTERVAL_MINUTES", 1440),
+		SimilarityCheckIntervalMinutes:    getEnvInt("SIMILARITY_CHECK_INTERVAL_MINUTES", 30),
+		RewriteCheckIntervalMinutes:       getEnvInt("REWRITE_CHECK_INTERVAL_MINUTES", 15),
+		CodeDumpCheckIntervalMinutes:      getEnvInt("CODE_DUMP_CHECK_INTERVAL_MINUTES", 60),
+		ScriptedCheckIntervalMinutes:      getEnvInt("SCRIPTED_CHECK_INTERVAL_MINUTES", 30),
+		TimezoneCheckIntervalMinutes:      getEnvInt("TIMEZONE_CHECK_INTERVAL_MINUTES", 60),
+		SubmissionGraceMinutes:            getEnvInt("SUBMISSION_GRACE_MINUTES", 5),
+		LifecycleDataPolicy:               getEnv("LIFECYCLE_DATA_POLICY", "archive"),
+		BackfillMaxCommits:                getEnvInt("BACKFILL_MAX_COMMITS", 5000),
+		BackdateSuspiciousHours:           getEnvInt("BACKDATE_SUSPICIOUS_HOURS", 24),
+		BackdateCriticalHours:             getEnvInt("BACKDATE_CRITICAL_HOURS", 72),
+		FutureDatedToleranceMinutes:       getEnvInt("FUTURE_DATED_TOLERANCE_MINUTES", 30),
+		StreakInactivityHours:             getEnvInt("STREAK_INACTIVITY_HOURS", 72),
+		StreakInactiveHours:               getEnvInt("STREAK_INACTIVE_HOURS", 168),
+		StreakRecoveryHours:               getEnvInt("STREAK_RECOVERY_HOURS", 24),
+		BotAllowlist:                      getEnvList("BOT_ALLOWLIST", "dependabot[bot],github-actions[bot],web-flow,noreply@github.com"),
+		PreEventCriticalPercent:           getEnvInt("PRE_EVENT_CRITICAL_PERCENT", 50),
+		SimilarityThresholdPercent:        getEnvInt("SIMILARITY_THRESHOLD_PERCENT", 40),
+		RewriteGapMinutes:                 getEnvInt("REWRITE_GAP_MINUTES", 60),
+		RewriteBulkMinCommits:             getEnvInt("REWRITE_BULK_MIN_COMMITS", 5),
+		CodeDumpCommitLines:               getEnvInt("CODE_DUMP_COMMIT_LINES", 10000),
+		CodeDumpMinLines:                  getEnvInt("CODE_DUMP_MIN_LINES", 2000),
+		CodeDumpRelativeFactor:            getEnvInt("CODE_DUMP_RELATIVE_FACTOR", 10),
+		CodeDumpPushPercent:               getEnvInt("CODE_DUMP_PUSH_PERCENT", 80),
+		CodeDumpMinRepoLines:              getEnvInt("CODE_DUMP_MIN_REPO_LINES", 5000),
+		ScriptedRegularMinCommits:         getEnvInt("SCRIPTED_REGULAR_MIN_COMMITS", 8),
+		ScriptedRegularToleranceSeconds:   getEnvInt("SCRIPTED_REGULAR_TOLERANCE_SECONDS", 5),
+		ScriptedBurstSeconds:              getEnvInt("SCRIPTED_BURST_SECONDS", 1),
+		ScriptedBurstMinCommits:           getEnvInt("SCRIPTED_BURST_MIN_COMMITS", 5),
+		ScriptedDuplicateMinCommits:       getEnvInt("SCRIPTED_DUPLICATE_MIN_COMMITS", 5),
+		TimezoneSwitchMinMinutes:          getEnvInt("TIMEZONE_SWITCH_MIN_MINUTES", 60),
+		TimezoneSwitchWindowHours:         getEnvInt("TIMEZONE_SWITCH_WINDOW_HOURS", 12),
+		TimezoneOffsiteToleranceMinutes:   getEnvInt("TIMEZONE_OFFSITE_TOLERANCE_MINUTES", 60),
+		TimezoneOffsiteMinCommits:         getEnvInt("TIMEZONE_OFFSITE_MIN_COMMITS", 3),
+		CustomRulesFile:                   getEnv("CUSTOM_RULES_FILE", ""),
 	}
 
 	// Parse App ID
@@ -58,6 +195,14 @@ func Load() (*Config, error) {
 	}
 	cfg.AppID = appID
 
+	if cfg.StreakInactiveHours <= cfg.StreakInactivityHours {
+		return nil, fmt.Errorf("STREAK_INACTIVE_HOURS (%d) must be greater than STREAK_INACTIVITY_HOURS (%d)", cfg.StreakInactiveHours, cfg.StreakInactivityHours)
+	}
+
+	if cfg.LifecycleDataPolicy != "archive" && cfg.LifecycleDataPolicy != "purge" {
+		return nil, fmt.Errorf("invalid LIFECYCLE_DATA_POLICY %q (expected archive or purge)", cfg.LifecycleDataPolicy)
+	}
+
 	// Load private key if path is specified
 	if cfg.PrivateKeyPath != "" {
 		// Check if file exists first
@@ -85,6 +230,17 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvList splits a comma-separated variable, dropping empty entries.
+func getEnvList(key, defaultValue string) []string {
+	var list []string
+	for _, item := range strings.Split(getEnv(key, defaultValue), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
 func getEnvInt(key string, defaultValue int) int {
 	if value := os.Getenv(key); value != "" {
 		if i, err := strconv.Atoi(value); err == nil {
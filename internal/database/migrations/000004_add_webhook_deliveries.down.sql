@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_push_events_delivery;
+ALTER TABLE push_events DROP COLUMN IF EXISTS delivery_id;
+DROP INDEX IF EXISTS idx_webhook_deliveries_status;
+DROP INDEX IF EXISTS idx_webhook_deliveries_received;
+DROP TABLE IF EXISTS webhook_deliveries;
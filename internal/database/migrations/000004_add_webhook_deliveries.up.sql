@@ -0,0 +1,21 @@
+-- Webhook deliveries table: Raw payload log for idempotency and replay
+CREATE TABLE webhook_deliveries (
+    id BIGSERIAL PRIMARY KEY,
+    delivery_id VARCHAR(64) UNIQUE NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    payload BYTEA NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'received',
+    attempts INT DEFAULT 0,
+    last_error TEXT,
+    received_at TIMESTAMPTZ DEFAULT NOW(),
+    processed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_webhook_deliveries_received ON webhook_deliveries(received_at);
+CREATE INDEX idx_webhook_deliveries_status ON webhook_deliveries(status);
+
+-- Link push events to the delivery that produced them so replays don't duplicate them
+ALTER TABLE push_events ADD COLUMN delivery_id VARCHAR(64);
+CREATE UNIQUE INDEX idx_push_events_delivery ON push_events(delivery_id) WHERE delivery_id IS NOT NULL;
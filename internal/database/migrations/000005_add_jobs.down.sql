@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_jobs_kind;
+DROP INDEX IF EXISTS idx_jobs_status;
+DROP INDEX IF EXISTS idx_jobs_ready;
+DROP TABLE IF EXISTS jobs;
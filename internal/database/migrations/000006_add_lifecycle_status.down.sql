@@ -0,0 +1,13 @@
+DROP INDEX IF EXISTS idx_repositories_status;
+DROP INDEX IF EXISTS idx_installations_status;
+
+ALTER TABLE repositories
+    DROP COLUMN IF EXISTS deleted_at,
+    DROP COLUMN IF EXISTS removed_at,
+    DROP COLUMN IF EXISTS suspended_at,
+    DROP COLUMN IF EXISTS status;
+
+ALTER TABLE installations
+    DROP COLUMN IF EXISTS deleted_at,
+    DROP COLUMN IF EXISTS suspended_at,
+    DROP COLUMN IF EXISTS status;
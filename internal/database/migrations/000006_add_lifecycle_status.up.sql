@@ -0,0 +1,15 @@
+-- Lifecycle states for installations and repositories
+-- (active, suspended, removed, deleted)
+ALTER TABLE installations
+    ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'active',
+    ADD COLUMN suspended_at TIMESTAMPTZ,
+    ADD COLUMN deleted_at TIMESTAMPTZ;
+
+ALTER TABLE repositories
+    ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'active',
+    ADD COLUMN suspended_at TIMESTAMPTZ,
+    ADD COLUMN removed_at TIMESTAMPTZ,
+    ADD COLUMN deleted_at TIMESTAMPTZ;
+
+CREATE INDEX idx_installations_status ON installations(status);
+CREATE INDEX idx_repositories_status ON repositories(status);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_commit_files_commit;
+DROP TABLE IF EXISTS commit_files;
+DROP INDEX IF EXISTS idx_commits_unenriched;
+ALTER TABLE commits
+    DROP COLUMN IF EXISTS enriched_at,
+    DROP COLUMN IF EXISTS files_changed;
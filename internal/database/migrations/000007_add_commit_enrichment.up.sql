@@ -0,0 +1,20 @@
+-- Commit enrichment: stats and file lists fetched from the GitHub API
+ALTER TABLE commits
+    ADD COLUMN files_changed INT DEFAULT 0,
+    ADD COLUMN enriched_at TIMESTAMPTZ;
+
+CREATE INDEX idx_commits_unenriched ON commits(repository_id) WHERE enriched_at IS NULL;
+
+CREATE TABLE commit_files (
+    id BIGSERIAL PRIMARY KEY,
+    commit_id BIGINT REFERENCES commits(id) ON DELETE CASCADE,
+    filename TEXT NOT NULL,
+    previous_filename TEXT,
+    status VARCHAR(20),
+    additions INT DEFAULT 0,
+    deletions INT DEFAULT 0,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE(commit_id, filename)
+);
+
+CREATE INDEX idx_commit_files_commit ON commit_files(commit_id);
@@ -0,0 +1,8 @@
+ALTER TABLE repositories
+    DROP COLUMN IF EXISTS backfill_error,
+    DROP COLUMN IF EXISTS backfill_completed_at,
+    DROP COLUMN IF EXISTS backfill_started_at,
+    DROP COLUMN IF EXISTS backfill_commits,
+    DROP COLUMN IF EXISTS backfill_status;
+
+ALTER TABLE commits DROP COLUMN IF EXISTS source;
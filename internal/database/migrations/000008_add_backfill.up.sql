@@ -0,0 +1,10 @@
+-- Commit source: push (webhook payload or compare API) or backfill (history walk)
+ALTER TABLE commits ADD COLUMN source VARCHAR(20) NOT NULL DEFAULT 'push';
+
+-- Per-repository history backfill progress
+ALTER TABLE repositories
+    ADD COLUMN backfill_status VARCHAR(20),
+    ADD COLUMN backfill_commits INT NOT NULL DEFAULT 0,
+    ADD COLUMN backfill_started_at TIMESTAMPTZ,
+    ADD COLUMN backfill_completed_at TIMESTAMPTZ,
+    ADD COLUMN backfill_error TEXT;
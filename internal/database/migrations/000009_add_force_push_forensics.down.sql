@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_force_push_commits_repo;
+DROP INDEX IF EXISTS idx_force_push_commits_push_event;
+DROP TABLE IF EXISTS force_push_commits;
+ALTER TABLE push_events
+    DROP COLUMN IF EXISTS forensics_completed_at,
+    DROP COLUMN IF EXISTS forensics_status;
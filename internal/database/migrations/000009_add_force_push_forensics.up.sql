@@ -0,0 +1,26 @@
+-- Force-push forensics: commits rewritten away by a forced push and the
+-- commits that replaced them
+ALTER TABLE push_events
+    ADD COLUMN forensics_status VARCHAR(20),
+    ADD COLUMN forensics_completed_at TIMESTAMPTZ;
+
+CREATE TABLE force_push_commits (
+    id BIGSERIAL PRIMARY KEY,
+    push_event_id BIGINT NOT NULL REFERENCES push_events(id) ON DELETE CASCADE,
+    repository_id BIGINT REFERENCES repositories(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL,
+    sha VARCHAR(40) NOT NULL,
+    message TEXT,
+    author_name VARCHAR(255),
+    author_email VARCHAR(255),
+    author_date TIMESTAMPTZ,
+    committer_date TIMESTAMPTZ,
+    replaces_sha VARCHAR(40),
+    author_changed BOOLEAN DEFAULT FALSE,
+    author_date_changed BOOLEAN DEFAULT FALSE,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE(push_event_id, kind, sha)
+);
+
+CREATE INDEX idx_force_push_commits_push_event ON force_push_commits(push_event_id);
+CREATE INDEX idx_force_push_commits_repo ON force_push_commits(repository_id);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_scheduler_runs_task;
+DROP TABLE IF EXISTS scheduler_runs;
@@ -0,0 +1,12 @@
+-- Scheduler runs: history of periodic and manually triggered tasks
+CREATE TABLE scheduler_runs (
+    id BIGSERIAL PRIMARY KEY,
+    task VARCHAR(100) NOT NULL,
+    trigger VARCHAR(20) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'running',
+    error TEXT,
+    started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_scheduler_runs_task ON scheduler_runs(task, started_at DESC);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_streak_transitions_repo;
+DROP TABLE IF EXISTS streak_transitions;
+ALTER TABLE repositories
+    DROP COLUMN IF EXISTS longest_streak_days,
+    DROP COLUMN IF EXISTS streak_changed_at,
+    DROP COLUMN IF EXISTS streak_started_at;
@@ -0,0 +1,23 @@
+-- Streak state machine: active -> at_risk -> inactive -> recovered -> active
+ALTER TABLE repositories
+    ADD COLUMN streak_started_at TIMESTAMPTZ,
+    ADD COLUMN streak_changed_at TIMESTAMPTZ,
+    ADD COLUMN longest_streak_days INT NOT NULL DEFAULT 0;
+
+UPDATE repositories r SET streak_started_at = COALESCE(
+    (SELECT MIN(c.pushed_at) FROM commits c WHERE c.repository_id = r.id),
+    r.last_activity_at
+);
+
+CREATE TABLE streak_transitions (
+    id BIGSERIAL PRIMARY KEY,
+    repository_id BIGINT REFERENCES repositories(id) ON DELETE CASCADE,
+    from_status VARCHAR(50) NOT NULL,
+    to_status VARCHAR(50) NOT NULL,
+    reason VARCHAR(50) NOT NULL,
+    streak_days INT NOT NULL DEFAULT 0,
+    last_activity_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_streak_transitions_repo ON streak_transitions(repository_id, created_at DESC);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_repositories_event;
+ALTER TABLE repositories DROP COLUMN IF EXISTS event_id;
+DROP TABLE IF EXISTS events;
@@ -0,0 +1,16 @@
+-- Hackathon events: the time window repositories are judged against
+CREATE TABLE events (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    starts_at TIMESTAMPTZ NOT NULL,
+    ends_at TIMESTAMPTZ NOT NULL,
+    submission_deadline TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    CHECK (ends_at > starts_at)
+);
+
+ALTER TABLE repositories ADD COLUMN event_id BIGINT REFERENCES events(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_repositories_event ON repositories(event_id);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_contributors_team_member;
+ALTER TABLE contributors DROP COLUMN IF EXISTS team_member_id;
+DROP INDEX IF EXISTS idx_repositories_team;
+ALTER TABLE repositories DROP COLUMN IF EXISTS team_id;
+DROP TABLE IF EXISTS team_members;
+DROP TABLE IF EXISTS teams;
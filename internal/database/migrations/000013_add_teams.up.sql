@@ -0,0 +1,31 @@
+-- Team rosters: who is supposed to be working on a repository
+CREATE TABLE teams (
+    id BIGSERIAL PRIMARY KEY,
+    event_id BIGINT REFERENCES events(id) ON DELETE SET NULL,
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_teams_event ON teams(event_id);
+
+-- Members are identified by GitHub login; emails are the commit identities
+-- they are known to use, stored lowercased
+CREATE TABLE team_members (
+    id BIGSERIAL PRIMARY KEY,
+    team_id BIGINT NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+    github_login VARCHAR(255) NOT NULL,
+    name VARCHAR(255),
+    emails TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_team_members_login ON team_members(team_id, LOWER(github_login));
+CREATE INDEX idx_team_members_emails ON team_members USING GIN (emails);
+
+ALTER TABLE repositories ADD COLUMN team_id BIGINT REFERENCES teams(id) ON DELETE SET NULL;
+CREATE INDEX idx_repositories_team ON repositories(team_id);
+
+ALTER TABLE contributors ADD COLUMN team_member_id BIGINT REFERENCES team_members(id) ON DELETE SET NULL;
+CREATE INDEX idx_contributors_team_member ON contributors(team_member_id);
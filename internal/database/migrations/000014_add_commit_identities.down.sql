@@ -0,0 +1,5 @@
+ALTER TABLE commits
+    DROP COLUMN IF EXISTS committer_login,
+    DROP COLUMN IF EXISTS committer_email,
+    DROP COLUMN IF EXISTS committer_name,
+    DROP COLUMN IF EXISTS author_login;
@@ -0,0 +1,7 @@
+-- Committer identity and GitHub logins, so commits can be checked against
+-- the team roster
+ALTER TABLE commits
+    ADD COLUMN author_login VARCHAR(255),
+    ADD COLUMN committer_name VARCHAR(255),
+    ADD COLUMN committer_email VARCHAR(255),
+    ADD COLUMN committer_login VARCHAR(255);
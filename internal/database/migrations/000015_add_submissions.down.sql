@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_submissions_repo;
+DROP TABLE IF EXISTS submissions;
+ALTER TABLE repositories DROP COLUMN IF EXISTS submission_frozen_at;
+ALTER TABLE push_events DROP COLUMN IF EXISTS post_deadline;
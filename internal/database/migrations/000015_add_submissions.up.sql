@@ -0,0 +1,19 @@
+-- Submission freeze: the branch heads of each enrolled repository at the
+-- event deadline, plus a flag on pushes received after it
+ALTER TABLE push_events ADD COLUMN post_deadline BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE repositories ADD COLUMN submission_frozen_at TIMESTAMPTZ;
+
+CREATE TABLE submissions (
+    id BIGSERIAL PRIMARY KEY,
+    event_id BIGINT NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    repository_id BIGINT NOT NULL REFERENCES repositories(id) ON DELETE CASCADE,
+    ref VARCHAR(255) NOT NULL,
+    head_sha VARCHAR(40) NOT NULL,
+    push_event_id BIGINT REFERENCES push_events(id) ON DELETE SET NULL,
+    pushed_at TIMESTAMPTZ,
+    cutoff_at TIMESTAMPTZ NOT NULL,
+    frozen_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE(event_id, repository_id, ref)
+);
+
+CREATE INDEX idx_submissions_repo ON submissions(repository_id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS starter_templates;
+ALTER TABLE repositories DROP COLUMN IF EXISTS github_created_at;
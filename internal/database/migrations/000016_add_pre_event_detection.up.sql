@@ -0,0 +1,15 @@
+-- Pre-event code detection: the repository's creation date on GitHub, and
+-- starter templates teams declare so organizers can exempt their history
+ALTER TABLE repositories ADD COLUMN github_created_at TIMESTAMPTZ;
+
+CREATE TABLE starter_templates (
+    id BIGSERIAL PRIMARY KEY,
+    repository_id BIGINT NOT NULL UNIQUE REFERENCES repositories(id) ON DELETE CASCADE,
+    source VARCHAR(255) NOT NULL,
+    base_sha VARCHAR(40),
+    notes TEXT,
+    approved_by VARCHAR(255),
+    approved_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
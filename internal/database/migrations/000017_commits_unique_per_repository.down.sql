@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_commits_sha;
+ALTER TABLE commits DROP CONSTRAINT IF EXISTS commits_repository_sha_key;
+-- Keep the earliest copy of each commit so the global constraint can return
+DELETE FROM commits c
+USING commits o
+WHERE c.sha = o.sha AND c.id > o.id;
+ALTER TABLE commits ADD CONSTRAINT commits_sha_key UNIQUE (sha);
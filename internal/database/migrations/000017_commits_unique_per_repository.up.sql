@@ -0,0 +1,5 @@
+-- Commit SHAs are unique per repository rather than globally, so history
+-- shared between repositories is stored for each of them
+ALTER TABLE commits DROP CONSTRAINT IF EXISTS commits_sha_key;
+ALTER TABLE commits ADD CONSTRAINT commits_repository_sha_key UNIQUE (repository_id, sha);
+CREATE INDEX idx_commits_sha ON commits(sha);
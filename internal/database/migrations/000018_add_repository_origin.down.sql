@@ -0,0 +1,8 @@
+ALTER TABLE repositories
+    DROP COLUMN IF EXISTS origin_checked_at,
+    DROP COLUMN IF EXISTS template_repository,
+    DROP COLUMN IF EXISTS template_repository_id,
+    DROP COLUMN IF EXISTS fork_source,
+    DROP COLUMN IF EXISTS fork_parent,
+    DROP COLUMN IF EXISTS fork_parent_id,
+    DROP COLUMN IF EXISTS is_fork;
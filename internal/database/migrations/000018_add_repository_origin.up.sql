@@ -0,0 +1,10 @@
+-- Where a repository came from on GitHub: the fork it was made from and the
+-- template it was generated from, as reported by the repositories API
+ALTER TABLE repositories
+    ADD COLUMN is_fork BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN fork_parent_id BIGINT,
+    ADD COLUMN fork_parent VARCHAR(255),
+    ADD COLUMN fork_source VARCHAR(255),
+    ADD COLUMN template_repository_id BIGINT,
+    ADD COLUMN template_repository VARCHAR(255),
+    ADD COLUMN origin_checked_at TIMESTAMPTZ;
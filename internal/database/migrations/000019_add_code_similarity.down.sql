@@ -0,0 +1,5 @@
+ALTER TABLE events DROP COLUMN IF EXISTS similarity_computed_at;
+DROP INDEX IF EXISTS idx_similarity_pairs_event;
+DROP TABLE IF EXISTS similarity_pairs;
+DROP TABLE IF EXISTS code_snapshot_files;
+DROP TABLE IF EXISTS code_snapshots;
@@ -0,0 +1,38 @@
+-- Code similarity: winnowed fingerprints of each repository's submitted
+-- source, and the pairwise comparison of the repositories of an event
+CREATE TABLE code_snapshots (
+    id BIGSERIAL PRIMARY KEY,
+    event_id BIGINT NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    repository_id BIGINT NOT NULL REFERENCES repositories(id) ON DELETE CASCADE,
+    ref VARCHAR(255) NOT NULL,
+    head_sha VARCHAR(40) NOT NULL,
+    files INTEGER NOT NULL DEFAULT 0,
+    fingerprints INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE(event_id, repository_id)
+);
+
+CREATE TABLE code_snapshot_files (
+    id BIGSERIAL PRIMARY KEY,
+    snapshot_id BIGINT NOT NULL REFERENCES code_snapshots(id) ON DELETE CASCADE,
+    path TEXT NOT NULL,
+    fingerprints BIGINT[] NOT NULL,
+    UNIQUE(snapshot_id, path)
+);
+
+CREATE TABLE similarity_pairs (
+    id BIGSERIAL PRIMARY KEY,
+    event_id BIGINT NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    repository_a_id BIGINT NOT NULL REFERENCES repositories(id) ON DELETE CASCADE,
+    repository_b_id BIGINT NOT NULL REFERENCES repositories(id) ON DELETE CASCADE,
+    similarity DOUBLE PRECISION NOT NULL,
+    jaccard DOUBLE PRECISION NOT NULL,
+    shared_fingerprints INTEGER NOT NULL,
+    top_files JSONB NOT NULL DEFAULT '[]',
+    computed_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE(event_id, repository_a_id, repository_b_id)
+);
+
+CREATE INDEX idx_similarity_pairs_event ON similarity_pairs(event_id, similarity DESC);
+
+ALTER TABLE events ADD COLUMN similarity_computed_at TIMESTAMPTZ;
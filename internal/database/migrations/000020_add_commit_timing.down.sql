@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_commits_future_dated;
+
+ALTER TABLE commits DROP COLUMN IF EXISTS future_minutes;
+ALTER TABLE commits DROP COLUMN IF EXISTS is_future_dated;
+
+ALTER TABLE push_events DROP COLUMN IF EXISTS github_pushed_at;
@@ -0,0 +1,8 @@
+-- Backdate detection measures commits against GitHub's own push timestamp
+-- and flags commits dated after it
+ALTER TABLE push_events ADD COLUMN github_pushed_at TIMESTAMPTZ;
+
+ALTER TABLE commits ADD COLUMN is_future_dated BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE commits ADD COLUMN future_minutes INT;
+
+CREATE INDEX idx_commits_future_dated ON commits(is_future_dated) WHERE is_future_dated = TRUE;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_commits_committer_date;
+
+ALTER TABLE commits DROP COLUMN IF EXISTS committer_date_verified;
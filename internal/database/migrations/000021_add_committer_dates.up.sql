@@ -0,0 +1,7 @@
+-- Push payloads carry a single timestamp, so committer dates are only real
+-- once fetched from the API. Backfilled commits always came from the API.
+ALTER TABLE commits ADD COLUMN committer_date_verified BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE commits SET committer_date_verified = TRUE WHERE source = 'backfill';
+
+CREATE INDEX idx_commits_committer_date ON commits(repository_id, committer_date) WHERE committer_date_verified = TRUE;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_rule_settings_repo;
+DROP INDEX IF EXISTS idx_rule_settings_event;
+DROP INDEX IF EXISTS idx_rule_settings_scope;
+DROP TABLE IF EXISTS rule_settings;
@@ -0,0 +1,18 @@
+-- Per-scope overrides of detection rule defaults. A row with neither
+-- event_id nor repository_id applies globally.
+CREATE TABLE rule_settings (
+    id BIGSERIAL PRIMARY KEY,
+    rule VARCHAR(100) NOT NULL,
+    event_id BIGINT REFERENCES events(id) ON DELETE CASCADE,
+    repository_id BIGINT REFERENCES repositories(id) ON DELETE CASCADE,
+    enabled BOOLEAN,
+    severity VARCHAR(20),
+    params JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    CHECK (event_id IS NULL OR repository_id IS NULL)
+);
+
+CREATE UNIQUE INDEX idx_rule_settings_scope ON rule_settings(rule, COALESCE(event_id, 0), COALESCE(repository_id, 0));
+CREATE INDEX idx_rule_settings_event ON rule_settings(event_id) WHERE event_id IS NOT NULL;
+CREATE INDEX idx_rule_settings_repo ON rule_settings(repository_id) WHERE repository_id IS NOT NULL;
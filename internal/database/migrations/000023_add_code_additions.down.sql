@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_commits_code_additions;
+
+ALTER TABLE commits DROP COLUMN IF EXISTS code_additions;
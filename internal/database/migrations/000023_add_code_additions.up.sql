@@ -0,0 +1,5 @@
+-- Lines added by a commit outside vendored, generated and lock files. Filled
+-- in by the code dump rule from commit_files once the commit is enriched.
+ALTER TABLE commits ADD COLUMN code_additions INT;
+
+CREATE INDEX idx_commits_code_additions ON commits(repository_id, code_additions) WHERE code_additions IS NOT NULL;
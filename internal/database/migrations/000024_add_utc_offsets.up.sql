@@ -0,0 +1,4 @@
+-- The UTC offset the author's clock was set to, in minutes east of UTC.
+-- Push payloads keep it; the commits API reports dates in UTC, so it stays
+-- NULL for commits fetched from there.
+ALTER TABLE commits ADD COLUMN author_utc_offset SMALLINT;
@@ -0,0 +1,2 @@
+-- The repaired owners are correct; there is nothing to undo.
+SELECT 1;
@@ -0,0 +1,14 @@
+-- Installation payloads carry no repository owner, so repositories added
+-- through them were stored without one. Take it from the full name.
+UPDATE repositories SET
+    backfill_status = CASE
+        WHEN backfill_status = 'completed' AND backfill_commits = 0 THEN 'failed'
+        ELSE backfill_status
+    END,
+    backfill_error = CASE
+        WHEN backfill_status = 'completed' AND backfill_commits = 0 THEN 'repository owner was missing; rerun the backfill'
+        ELSE backfill_error
+    END,
+    owner = split_part(full_name, '/', 1),
+    name = split_part(full_name, '/', 2)
+WHERE owner = '' AND full_name LIKE '%/%';
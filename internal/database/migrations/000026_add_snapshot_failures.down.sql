@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS code_snapshot_failures;
+ALTER TABLE code_snapshots DROP COLUMN IF EXISTS truncated;
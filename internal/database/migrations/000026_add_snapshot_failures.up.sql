@@ -0,0 +1,15 @@
+-- Archives larger than the download cap are fingerprinted up to the cap
+ALTER TABLE code_snapshots ADD COLUMN truncated BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Submissions whose snapshot failed, retried with growing delays rather
+-- than on every similarity run
+CREATE TABLE code_snapshot_failures (
+    event_id BIGINT NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    repository_id BIGINT NOT NULL REFERENCES repositories(id) ON DELETE CASCADE,
+    head_sha VARCHAR(40) NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 1,
+    last_error TEXT NOT NULL,
+    retry_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (event_id, repository_id)
+);
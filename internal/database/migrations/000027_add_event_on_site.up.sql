@@ -0,0 +1,3 @@
+-- On-site events expect commits from the venue's time zone. Databases that
+-- applied 000024 before this column moved here already have it.
+ALTER TABLE events ADD COLUMN IF NOT EXISTS on_site BOOLEAN NOT NULL DEFAULT FALSE;
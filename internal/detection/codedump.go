@@ -0,0 +1,284 @@
+package detection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/harshpatel5940/gitvigil/internal/models"
+	"github.com/harshpatel5940/gitvigil/internal/rules"
+)
+
+// minMedianCommits is how many counted commits a median needs before
+// commits are compared against it.
+const minMedianCommits = 5
+
+// Reasons a commit counts as a code dump.
+const (
+	dumpAbsolute          = "absolute"
+	dumpRepositoryHistory = "repository_history"
+	dumpEventMedian       = "event_median"
+)
+
+// CheckCodeDumps looks for code written elsewhere and committed in bulk.
+// Lines are counted from enriched file stats, leaving out vendored,
+// generated and lock files. A commit is a code dump when it adds
+// commit_lines lines or more, or at least min_lines and relative_factor
+// times the median commit of the repository or of the rest of its event.
+// A push of at least min_lines delivering push_percent or more of the code
+// in a repository holding min_repo_lines or more is a code dump too. The
+// share is taken against the repository as it stands, so a push alert is
+// resolved once the code written since brings the share back under
+// push_percent. Dumps reaching commit_lines are critical.
+func (d *Detector) CheckCodeDumps(ctx context.Context, repo *models.Repository, s *rules.Settings) error {
+	if err := d.countCodeAdditions(ctx, repo.ID); err != nil {
+		return fmt.Errorf("failed to count code additions: %w", err)
+	}
+
+	commitStore := models.NewCommitStore(d.db.Pool)
+	commitLines := s.Int("commit_lines")
+	minLines := s.Int("min_lines")
+
+	var errs []error
+	large, err := commitStore.ListLargeCommits(ctx, repo.ID, min(minLines, commitLines))
+	if err != nil {
+		errs = append(errs, err)
+	} else if len(large) > 0 {
+		medians, err := commitStore.GetCodeAdditionMedians(ctx, repo.ID, repo.EventID)
+		if err != nil {
+			return err
+		}
+		for _, c := range large {
+			reasons := dumpReasons(c.CodeAdditions, medians, s)
+			if len(reasons) == 0 {
+				continue
+			}
+			if err := d.raiseCommitDump(ctx, repo, c, medians, reasons, s); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	pushes, err := commitStore.ListPushAdditions(ctx, repo.ID, minLines)
+	if err != nil {
+		return errors.Join(append(errs, err)...)
+	}
+	for _, p := range pushes {
+		if isPushDump(p, s) {
+			err = d.raisePushDump(ctx, repo, p, s)
+		} else {
+			err = d.resolvePushDump(ctx, repo, p, s)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// isPushDump reports whether a push delivered push_percent or more of a
+// repository large enough to judge.
+func isPushDump(p *models.PushAdditions, s *rules.Settings) bool {
+	if p.RepoAdditions == 0 || p.RepoAdditions < s.Int("min_repo_lines") {
+		return false
+	}
+	return p.CodeAdditions*100 >= s.Int("push_percent")*p.RepoAdditions
+}
+
+// countCodeAdditions stores the code additions of the repository's enriched
+// commits that were not counted yet.
+func (d *Detector) countCodeAdditions(ctx context.Context, repoID int64) error {
+	commitStore := models.NewCommitStore(d.db.Pool)
+	files, err := commitStore.ListUncountedFiles(ctx, repoID)
+	if err != nil || len(files) == 0 {
+		return err
+	}
+
+	counts := make(map[int64]int, len(files))
+	for commitID, commitFiles := range files {
+		n := 0
+		for _, f := range commitFiles {
+			if isSourceFile(f.Filename) {
+				n += f.Additions
+			}
+		}
+		counts[commitID] = n
+	}
+	return commitStore.SetCodeAdditions(ctx, counts)
+}
+
+// dumpReasons returns why a commit adding lines lines of code is a code
+// dump, if it is one.
+func dumpReasons(lines int, m *models.CodeAdditionMedians, s *rules.Settings) []string {
+	var reasons []string
+	if lines >= s.Int("commit_lines") {
+		reasons = append(reasons, dumpAbsolute)
+	}
+	if lines < s.Int("min_lines") {
+		return reasons
+	}
+	factor := s.Float("relative_factor")
+	if m.RepositoryCommits >= minMedianCommits && float64(lines) >= factor*max(m.Repository, 1) {
+		reasons = append(reasons, dumpRepositoryHistory)
+	}
+	if m.EventCommits >= minMedianCommits && float64(lines) >= factor*max(m.Event, 1) {
+		reasons = append(reasons, dumpEventMedian)
+	}
+	return reasons
+}
+
+// raiseCommitDump creates the code_dump alert for a commit unless it was
+// raised before.
+func (d *Detector) raiseCommitDump(ctx context.Context, repo *models.Repository, c *models.CodeSize, m *models.CodeAdditionMedians, reasons []string, s *rules.Settings) error {
+	alertStore := models.NewAlertStore(d.db.Pool)
+	existing, err := alertStore.FindByMetadata(ctx, repo.ID, models.AlertCodeDump, "sha", c.SHA)
+	if err != nil || existing != nil {
+		return err
+	}
+
+	severity := models.SeverityWarning
+	if c.CodeAdditions >= s.Int("commit_lines") {
+		severity = models.SeverityCritical
+	}
+	severity = s.SeverityOr(severity)
+
+	sha := c.SHA
+	alert := &models.Alert{
+		RepositoryID: repo.ID,
+		CommitSHA:    &sha,
+		AlertType:    models.AlertCodeDump,
+		Severity:     severity,
+		Title:        "Mass code dump detected",
+		Description: fmt.Sprintf("Commit added %d lines of code across %d file(s) (%s)",
+			c.CodeAdditions, c.FilesChanged, strings.Join(reasons, ", ")),
+		Metadata: map[string]interface{}{
+			"kind":               "commit",
+			"sha":                c.SHA,
+			"pushed_at":          c.PushedAt,
+			"additions":          c.Additions,
+			"code_additions":     c.CodeAdditions,
+			"files_changed":      c.FilesChanged,
+			"reasons":            reasons,
+			"repository_median":  m.Repository,
+			"repository_commits": m.RepositoryCommits,
+			"event_median":       m.Event,
+			"event_commits":      m.EventCommits,
+			"commit_lines":       s.Int("commit_lines"),
+			"relative_factor":    s.Float("relative_factor"),
+			"excluded_additions": c.Additions - c.CodeAdditions,
+		},
+	}
+	if err := alertStore.Create(ctx, alert); err != nil {
+		return err
+	}
+
+	d.logger.Warn().
+		Str("repo", repo.FullName).
+		Str("sha", c.SHA).
+		Int("code_additions", c.CodeAdditions).
+		Strs("reasons", reasons).
+		Msg("code dump detected")
+	return nil
+}
+
+// raisePushDump creates the code_dump alert for a push delivering most of a
+// repository's code, or refreshes it as more of the push is enriched.
+func (d *Detector) raisePushDump(ctx context.Context, repo *models.Repository, p *models.PushAdditions, s *rules.Settings) error {
+	key := pushKey(p)
+	percent := p.CodeAdditions * 100 / p.RepoAdditions
+
+	severity := models.SeverityWarning
+	if p.CodeAdditions >= s.Int("commit_lines") {
+		severity = models.SeverityCritical
+	}
+	severity = s.SeverityOr(severity)
+
+	metadata := pushDumpMetadata(p, s)
+
+	alertStore := models.NewAlertStore(d.db.Pool)
+	existing, err := alertStore.FindByMetadata(ctx, repo.ID, models.AlertCodeDump, "push", key)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		// JSON numbers decode as float64
+		lines, _ := existing.Metadata["code_additions"].(float64)
+		total, _ := existing.Metadata["repo_additions"].(float64)
+		if int(lines) == p.CodeAdditions && int(total) == p.RepoAdditions {
+			return nil
+		}
+		return alertStore.MergeMetadata(ctx, existing.ID, &severity, metadata)
+	}
+
+	alert := &models.Alert{
+		RepositoryID: repo.ID,
+		AlertType:    models.AlertCodeDump,
+		Severity:     severity,
+		Title:        "Repository code arrived in one push",
+		Description: fmt.Sprintf("A push at %s delivered %d lines of code in %d commit(s), %d%% of the repository",
+			key, p.CodeAdditions, p.Commits, percent),
+		Metadata: metadata,
+	}
+	if len(p.SampleSHAs) > 0 {
+		alert.CommitSHA = &p.SampleSHAs[0]
+	}
+	if err := alertStore.Create(ctx, alert); err != nil {
+		return err
+	}
+
+	d.logger.Warn().
+		Str("repo", repo.FullName).
+		Str("pushed_at", key).
+		Int("code_additions", p.CodeAdditions).
+		Int("percent", percent).
+		Msg("single push code dump detected")
+	return nil
+}
+
+// resolvePushDump acknowledges the open alert of a push that no longer
+// makes up push_percent of the repository, refreshing its figures first.
+func (d *Detector) resolvePushDump(ctx context.Context, repo *models.Repository, p *models.PushAdditions, s *rules.Settings) error {
+	alertStore := models.NewAlertStore(d.db.Pool)
+	existing, err := alertStore.FindByMetadata(ctx, repo.ID, models.AlertCodeDump, "push", pushKey(p))
+	if err != nil || existing == nil || existing.Acknowledged {
+		return err
+	}
+	if err := alertStore.MergeMetadata(ctx, existing.ID, nil, pushDumpMetadata(p, s)); err != nil {
+		return err
+	}
+	if err := alertStore.Acknowledge(ctx, existing.ID); err != nil {
+		return err
+	}
+
+	d.logger.Info().
+		Str("repo", repo.FullName).
+		Str("pushed_at", pushKey(p)).
+		Int("repo_additions", p.RepoAdditions).
+		Msg("single push code dump resolved as the repository grew")
+	return nil
+}
+
+// pushKey identifies a push in its code_dump alert's metadata.
+func pushKey(p *models.PushAdditions) string {
+	return p.PushedAt.UTC().Format(time.RFC3339)
+}
+
+func pushDumpMetadata(p *models.PushAdditions, s *rules.Settings) map[string]interface{} {
+	percent := 0
+	if p.RepoAdditions > 0 {
+		percent = p.CodeAdditions * 100 / p.RepoAdditions
+	}
+	return map[string]interface{}{
+		"kind":           "push",
+		"push":           pushKey(p),
+		"commits":        p.Commits,
+		"code_additions": p.CodeAdditions,
+		"repo_additions": p.RepoAdditions,
+		"percent":        percent,
+		"push_percent":   s.Int("push_percent"),
+		"min_repo_lines": s.Int("min_repo_lines"),
+		"shas":           p.SampleSHAs,
+	}
+}
@@ -2,12 +2,15 @@ package detection
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/harshpatel5940/gitvigil/internal/config"
 	"github.com/harshpatel5940/gitvigil/internal/database"
 	ghclient "github.com/harshpatel5940/gitvigil/internal/github"
 	"github.com/harshpatel5940/gitvigil/internal/models"
+	"github.com/harshpatel5940/gitvigil/internal/rules"
 	"github.com/rs/zerolog"
 )
 
@@ -15,37 +18,24 @@ type Detector struct {
 	cfg    *config.Config
 	db     *database.DB
 	gh     *ghclient.AppClient
+	rules  *rules.Engine
 	logger zerolog.Logger
 }
 
 func NewDetector(cfg *config.Config, db *database.DB, gh *ghclient.AppClient, logger zerolog.Logger) *Detector {
-	return &Detector{
+	d := &Detector{
 		cfg:    cfg,
 		db:     db,
 		gh:     gh,
 		logger: logger.With().Str("component", "detector").Logger(),
 	}
+	d.rules = rules.NewEngine(d.newRegistry(), db.Pool, logger)
+	return d
 }
 
-type BackdateResult struct {
-	CommitSHA       string
-	AuthorDate      time.Time
-	PushedAt        time.Time
-	DifferenceHours int
-	IsSuspicious    bool
-	IsCritical      bool
-}
-
-func (d *Detector) AnalyzeBackdate(authorDate, pushedAt time.Time) *BackdateResult {
-	diffHours := int(pushedAt.Sub(authorDate).Hours())
-
-	return &BackdateResult{
-		AuthorDate:      authorDate,
-		PushedAt:        pushedAt,
-		DifferenceHours: diffHours,
-		IsSuspicious:    diffHours > d.cfg.BackdateSuspiciousHours,
-		IsCritical:      diffHours > d.cfg.BackdateCriticalHours,
-	}
+// Rules returns the rule engine running the detector's checks.
+func (d *Detector) Rules() *rules.Engine {
+	return d.rules
 }
 
 func (d *Detector) CheckLicense(ctx context.Context, installationID int64, owner, repo string) (bool, string, error) {
@@ -57,7 +47,10 @@ func (d *Detector) CheckLicense(ctx context.Context, installationID int64, owner
 	license, _, err := client.Repositories.License(ctx, owner, repo)
 	if err != nil {
 		// 404 means no license
-		return false, "", nil
+		if ghclient.IsNotFound(err) {
+			return false, "", nil
+		}
+		return false, "", err
 	}
 
 	if license.License != nil {
@@ -67,50 +60,112 @@ func (d *Detector) CheckLicense(ctx context.Context, installationID int64, owner
 	return false, "", nil
 }
 
-func (d *Detector) CheckStreaks(ctx context.Context) error {
+// CheckStreaks applies the time-driven streak transitions: active or
+// recovered repositories become at_risk and then inactive as they stay idle,
+// and recovered repositories settle back to active after the recovery
+// period. Repositories the streak rule is disabled for are left alone.
+// Transitions caused by new activity happen during push ingestion.
+func (d *Detector) CheckStreaks(ctx context.Context, e *rules.Engine) error {
 	repoStore := models.NewRepositoryStore(d.db.Pool)
-	alertStore := models.NewAlertStore(d.db.Pool)
+	streakStore := models.NewStreakStore(d.db.Pool)
+
+	repos, err := repoStore.ListActive(ctx)
+	if err != nil {
+		return err
+	}
 
-	repos, err := repoStore.ListAtRisk(ctx, d.cfg.StreakInactivityHours)
+	events, err := d.loadEvents(ctx, repos)
 	if err != nil {
 		return err
 	}
 
+	now := time.Now()
+	var errs []error
 	for _, repo := range repos {
-		// Update streak status
-		if err := repoStore.UpdateStreakStatus(ctx, repo.ID, "at_risk"); err != nil {
-			d.logger.Error().Err(err).Int64("repo_id", repo.ID).Msg("failed to update streak status")
+		var event *models.Event
+		if repo.EventID != nil {
+			event = events[*repo.EventID]
+		}
+
+		s, err := e.Settings(ctx, RuleStreak, repo.ID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", repo.FullName, err))
+			continue
+		}
+		if !s.Enabled {
 			continue
 		}
 
-		// Create alert
-		alert := &models.Alert{
-			RepositoryID: repo.ID,
-			AlertType:    models.AlertStreakAtRisk,
-			Severity:     models.SeverityWarning,
-			Title:        "Activity streak at risk",
-			Description:  "Repository has been inactive for more than 72 hours",
-			Metadata: map[string]interface{}{
-				"last_activity_at": repo.LastActivityAt,
-				"inactivity_hours": d.cfg.StreakInactivityHours,
-			},
+		next := EvaluateStreak(repo, event, now, s)
+		if next == repo.StreakStatus {
+			continue
 		}
 
-		if err := alertStore.Create(ctx, alert); err != nil {
-			d.logger.Error().Err(err).Int64("repo_id", repo.ID).Msg("failed to create streak alert")
+		reason := models.StreakReasonInactivity
+		if next == models.StreakActive {
+			reason = models.StreakReasonSettled
+		}
+
+		if _, err := streakStore.Transition(ctx, d.db.Pool, repo.ID, repo.StreakStatus, next, reason, now); err != nil {
+			d.logger.Error().Err(err).Int64("repo_id", repo.ID).Msg("failed to update streak status")
+			errs = append(errs, fmt.Errorf("%s: %w", repo.FullName, err))
+			continue
 		}
 
 		d.logger.Info().
 			Str("repo", repo.FullName).
-			Time("last_activity", *repo.LastActivityAt).
-			Msg("repository marked as at risk")
+			Str("from", string(repo.StreakStatus)).
+			Str("to", string(next)).
+			Msg("streak status changed")
 	}
 
-	return nil
+	return errors.Join(errs...)
+}
+
+// EvaluateStreak returns the streak status a repository should have at now,
+// considering only elapsed time. Inactive repositories stay inactive until
+// new activity arrives. A repository enrolled in an event is only tracked
+// while the event is running, so its streak is frozen before the start and
+// after the end. The thresholds are the streak rule's settings.
+func EvaluateStreak(repo *models.Repository, event *models.Event, now time.Time, s *rules.Settings) models.StreakStatus {
+	if repo.LastActivityAt == nil || repo.StreakStatus == models.StreakInactive {
+		return repo.StreakStatus
+	}
+	if event != nil && !event.Contains(now) {
+		return repo.StreakStatus
+	}
+
+	idle := now.Sub(*repo.LastActivityAt)
+	switch {
+	case idle > time.Duration(s.Int("inactive_hours"))*time.Hour:
+		return models.StreakInactive
+	case idle > time.Duration(s.Int("at_risk_hours"))*time.Hour:
+		return models.StreakAtRisk
+	case repo.StreakStatus == models.StreakRecovered && repo.StreakChangedAt != nil &&
+		now.Sub(*repo.StreakChangedAt) > time.Duration(s.Int("recovery_hours"))*time.Hour:
+		return models.StreakActive
+	}
+	return repo.StreakStatus
 }
 
-func (d *Detector) ValidateLicenseForRepo(ctx context.Context, repoID, installationID int64, owner, name string) error {
-	hasLicense, spdxID, err := d.CheckLicense(ctx, installationID, owner, name)
+// loadEvents returns the events the repositories are enrolled in, keyed by ID.
+func (d *Detector) loadEvents(ctx context.Context, repos []*models.Repository) (map[int64]*models.Event, error) {
+	var ids []int64
+	seen := make(map[int64]bool)
+	for _, repo := range repos {
+		if repo.EventID != nil && !seen[*repo.EventID] {
+			seen[*repo.EventID] = true
+			ids = append(ids, *repo.EventID)
+		}
+	}
+	return models.NewEventStore(d.db.Pool).ListByIDs(ctx, ids)
+}
+
+// ValidateLicenseForRepo records the repository's license and raises a
+// no_license alert when it has none.
+func (d *Detector) ValidateLicenseForRepo(ctx context.Context, repo *models.Repository, s *rules.Settings) error {
+	repoID := repo.ID
+	hasLicense, spdxID, err := d.CheckLicense(ctx, repo.InstallationID, repo.Owner, repo.Name)
 	if err != nil {
 		return err
 	}
@@ -125,13 +180,21 @@ func (d *Detector) ValidateLicenseForRepo(ctx context.Context, repoID, installat
 		return err
 	}
 
-	// Create alert if no license
+	// Create alert if no license, unless one is still open from an earlier check
 	if !hasLicense {
 		alertStore := models.NewAlertStore(d.db.Pool)
+		open, err := alertStore.HasUnacknowledged(ctx, repoID, models.AlertNoLicense)
+		if err != nil {
+			return err
+		}
+		if open {
+			return nil
+		}
+
 		alert := &models.Alert{
 			RepositoryID: repoID,
 			AlertType:    models.AlertNoLicense,
-			Severity:     models.SeverityInfo,
+			Severity:     s.SeverityOr(models.SeverityInfo),
 			Title:        "No license file found",
 			Description:  "Repository does not have a LICENSE file",
 		}
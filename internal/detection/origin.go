@@ -0,0 +1,194 @@
+package detection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v68/github"
+	"github.com/harshpatel5940/gitvigil/internal/models"
+	"github.com/harshpatel5940/gitvigil/internal/rules"
+)
+
+// Kinds of origin an external_origin alert reports.
+const (
+	originFork     = "fork"
+	originTemplate = "template"
+)
+
+// CheckOrigin fetches the repository from GitHub and records the fork parent
+// and template it was created from. When the repository is enrolled in an
+// event and the upstream is not a repository of that same event, it raises
+// an external_origin alert: critical for forks, warning for templates. A
+// template matching the repository's approved starter template is allowed.
+// An alert whose upstream was since enrolled in the event or approved is
+// resolved.
+func (d *Detector) CheckOrigin(ctx context.Context, repo *models.Repository, s *rules.Settings) error {
+	client, err := d.gh.GetInstallationClient(repo.InstallationID)
+	if err != nil {
+		return err
+	}
+
+	ghRepo, _, err := client.Repositories.Get(ctx, repo.Owner, repo.Name)
+	if err != nil {
+		return err
+	}
+
+	origin := &models.RepositoryOrigin{IsFork: ghRepo.GetFork()}
+	if parent := ghRepo.GetParent(); parent != nil {
+		origin.ParentID = githubID(parent)
+		origin.Parent = fullName(parent)
+	}
+	if source := ghRepo.GetSource(); source != nil {
+		origin.Source = fullName(source)
+	}
+	if template := ghRepo.GetTemplateRepository(); template != nil {
+		origin.TemplateID = githubID(template)
+		origin.Template = fullName(template)
+	}
+
+	var createdAt *time.Time
+	if ghRepo.CreatedAt != nil && !ghRepo.CreatedAt.IsZero() {
+		createdAt = &ghRepo.CreatedAt.Time
+	}
+	if err := models.NewRepositoryStore(d.db.Pool).UpdateOrigin(ctx, repo.ID, origin, createdAt); err != nil {
+		return err
+	}
+
+	if repo.EventID == nil || (origin.Parent == nil && origin.Template == nil) {
+		return nil
+	}
+
+	// Upstreams enrolled in the same event are part of the event
+	peers, err := models.NewEventStore(d.db.Pool).ListRepositories(ctx, *repo.EventID)
+	if err != nil {
+		return err
+	}
+	inEvent := make(map[int64]bool, len(peers))
+	for _, peer := range peers {
+		inEvent[peer.GitHubID] = true
+	}
+
+	var errs []error
+	if origin.Parent != nil {
+		if inEvent[deref64(origin.ParentID)] {
+			err = d.resolveExternalOrigin(ctx, repo, *origin.Parent, "upstream enrolled in the event")
+		} else {
+			err = d.raiseExternalOrigin(ctx, repo, originFork, origin.ParentID, *origin.Parent, origin.Source, s)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if origin.Template != nil {
+		template, err := models.NewStarterTemplateStore(d.db.Pool).Get(ctx, repo.ID)
+		if err != nil {
+			return errors.Join(append(errs, err)...)
+		}
+		approved := template.Approved() && strings.EqualFold(strings.TrimSpace(template.Source), *origin.Template)
+		switch {
+		case inEvent[deref64(origin.TemplateID)]:
+			err = d.resolveExternalOrigin(ctx, repo, *origin.Template, "upstream enrolled in the event")
+		case approved:
+			err = d.resolveExternalOrigin(ctx, repo, *origin.Template, "approved starter template")
+		default:
+			err = d.raiseExternalOrigin(ctx, repo, originTemplate, origin.TemplateID, *origin.Template, nil, s)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// raiseExternalOrigin creates the repository's alert for an upstream unless
+// one was already raised for it.
+func (d *Detector) raiseExternalOrigin(ctx context.Context, repo *models.Repository, kind string, upstreamID *int64, upstream string, source *string, s *rules.Settings) error {
+	alertStore := models.NewAlertStore(d.db.Pool)
+	existing, err := alertStore.FindByMetadata(ctx, repo.ID, models.AlertExternalOrigin, "upstream", upstream)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return nil
+	}
+
+	alert := &models.Alert{
+		RepositoryID: repo.ID,
+		AlertType:    models.AlertExternalOrigin,
+		Severity:     models.SeverityCritical,
+		Title:        "Repository forked from outside the event",
+		Description:  fmt.Sprintf("Repository is a fork of %s, which is not part of the event", upstream),
+		Metadata: map[string]interface{}{
+			"kind":        kind,
+			"upstream":    upstream,
+			"upstream_id": upstreamID,
+			"source":      source,
+			"event_id":    repo.EventID,
+		},
+	}
+	if kind == originTemplate {
+		alert.Severity = models.SeverityWarning
+		alert.Title = "Repository generated from a template outside the event"
+		alert.Description = fmt.Sprintf("Repository was generated from the template %s, which is not part of the event", upstream)
+	}
+	alert.Severity = s.SeverityOr(alert.Severity)
+	if err := alertStore.Create(ctx, alert); err != nil {
+		return err
+	}
+
+	d.logger.Info().
+		Str("repo", repo.FullName).
+		Str("kind", kind).
+		Str("upstream", upstream).
+		Msg("external origin detected")
+	return nil
+}
+
+// resolveExternalOrigin acknowledges the repository's open alert for an
+// upstream that is now allowed, noting why.
+func (d *Detector) resolveExternalOrigin(ctx context.Context, repo *models.Repository, upstream, reason string) error {
+	alertStore := models.NewAlertStore(d.db.Pool)
+	existing, err := alertStore.FindByMetadata(ctx, repo.ID, models.AlertExternalOrigin, "upstream", upstream)
+	if err != nil || existing == nil || existing.Acknowledged {
+		return err
+	}
+	if err := alertStore.MergeMetadata(ctx, existing.ID, nil, map[string]interface{}{"resolution": reason}); err != nil {
+		return err
+	}
+	if err := alertStore.Acknowledge(ctx, existing.ID); err != nil {
+		return err
+	}
+
+	d.logger.Info().
+		Str("repo", repo.FullName).
+		Str("upstream", upstream).
+		Str("reason", reason).
+		Msg("external origin resolved")
+	return nil
+}
+
+func githubID(r *github.Repository) *int64 {
+	if r.ID == nil {
+		return nil
+	}
+	id := r.GetID()
+	return &id
+}
+
+func fullName(r *github.Repository) *string {
+	name := r.GetFullName()
+	if name == "" {
+		return nil
+	}
+	return &name
+}
+
+func deref64(v *int64) int64 {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
@@ -0,0 +1,185 @@
+package detection
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/harshpatel5940/gitvigil/internal/database"
+	"github.com/harshpatel5940/gitvigil/internal/models"
+	"github.com/harshpatel5940/gitvigil/internal/rules"
+)
+
+// PreEventReport is a repository's pre-event code measured against its event.
+type PreEventReport struct {
+	Stats              *models.PreEventStats
+	Template           *models.StarterTemplate
+	CreatedBeforeEvent bool
+}
+
+// Flagged reports whether anything predates the event that no approved
+// starter template accounts for.
+func (p *PreEventReport) Flagged() bool {
+	return p.Stats.PreEventCommits > 0 || p.CreatedBeforeEvent
+}
+
+// MeasurePreEventCode compares the repository's commit author dates and
+// GitHub creation date against the start of its event. An approved starter
+// template exempts its history and the repository's creation date. It
+// returns nil for repositories outside any event.
+func MeasurePreEventCode(ctx context.Context, db *database.DB, repo *models.Repository, event *models.Event) (*PreEventReport, error) {
+	if event == nil {
+		return nil, nil
+	}
+
+	templateStore := models.NewStarterTemplateStore(db.Pool)
+	template, err := templateStore.Get(ctx, repo.ID)
+	if err != nil {
+		return nil, err
+	}
+	exemptBefore, err := templateStore.ExemptBefore(ctx, template, event)
+	if err != nil {
+		return nil, err
+	}
+
+	_, to := models.EventWindow(event)
+	stats, err := models.NewCommitStore(db.Pool).GetPreEventStats(ctx, repo.ID, event.StartsAt, to, exemptBefore)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PreEventReport{
+		Stats:              stats,
+		Template:           template,
+		CreatedBeforeEvent: repo.GitHubCreatedAt != nil && repo.GitHubCreatedAt.Before(event.StartsAt) && !template.Approved(),
+	}, nil
+}
+
+// CheckPreEventCode raises one pre_event_code alert per repository and event
+// when commits were authored, or the repository created, before the event
+// started. It is critical once the pre-event share of commits or added lines
+// reaches the rule's critical_percent. Later checks refresh the alert as
+// figures change, and acknowledge it once an approved starter template
+// accounts for everything it reported.
+func (d *Detector) CheckPreEventCode(ctx context.Context, repo *models.Repository, s *rules.Settings) error {
+	event, err := models.NewEventStore(d.db.Pool).GetForRepository(ctx, repo)
+	if err != nil {
+		return err
+	}
+	report, err := MeasurePreEventCode(ctx, d.db, repo, event)
+	if err != nil || report == nil {
+		return err
+	}
+
+	stats := report.Stats
+	metadata := map[string]interface{}{
+		"event_id":              event.ID,
+		"event_starts_at":       event.StartsAt,
+		"repository_created_at": repo.GitHubCreatedAt,
+		"created_before_event":  report.CreatedBeforeEvent,
+		"pre_event_commits":     stats.PreEventCommits,
+		"exempt_commits":        stats.ExemptCommits,
+		"total_commits":         stats.TotalCommits,
+		"commit_percent":        roundPercent(stats.CommitPercent()),
+		"pre_event_additions":   stats.PreEventAdditions,
+		"total_additions":       stats.TotalAdditions,
+		"line_percent":          roundPercent(stats.LinePercent()),
+		"earliest_author_date":  stats.EarliestAuthorAt,
+		"commits":               stats.SampleSHAs,
+		"starter_template":      nil,
+	}
+	if report.Template != nil {
+		metadata["starter_template"] = map[string]interface{}{
+			"source":   report.Template.Source,
+			"base_sha": report.Template.BaseSHA,
+			"approved": report.Template.Approved(),
+		}
+	}
+
+	severity := models.SeverityWarning
+	if critical := s.Float("critical_percent"); stats.CommitPercent() >= critical || stats.LinePercent() >= critical {
+		severity = models.SeverityCritical
+	}
+	severity = s.SeverityOr(severity)
+
+	alertStore := models.NewAlertStore(d.db.Pool)
+	existing, err := alertStore.FindByMetadata(ctx, repo.ID, models.AlertPreEventCode, "event_id", strconv.FormatInt(event.ID, 10))
+	if err != nil {
+		return err
+	}
+
+	if !report.Flagged() {
+		if existing == nil || existing.Acknowledged {
+			return nil
+		}
+		// An approved template now covers what the alert reported
+		if err := alertStore.MergeMetadata(ctx, existing.ID, nil, metadata); err != nil {
+			return err
+		}
+		return alertStore.Acknowledge(ctx, existing.ID)
+	}
+
+	if existing != nil {
+		if !preEventChanged(existing.Metadata, metadata) && existing.Severity == severity {
+			return nil
+		}
+		return alertStore.MergeMetadata(ctx, existing.ID, &severity, metadata)
+	}
+
+	var reasons []string
+	if stats.PreEventCommits > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d of %d commits (%.0f%%) were authored before the event started", stats.PreEventCommits, stats.TotalCommits, stats.CommitPercent()))
+	}
+	if report.CreatedBeforeEvent {
+		reasons = append(reasons, "the repository was created on GitHub before the event started")
+	}
+
+	alert := &models.Alert{
+		RepositoryID: repo.ID,
+		AlertType:    models.AlertPreEventCode,
+		Severity:     severity,
+		Title:        "Pre-event code detected",
+		Description:  strings.Join(reasons, "; "),
+		Metadata:     metadata,
+	}
+	if len(stats.SampleSHAs) > 0 {
+		alert.CommitSHA = &stats.SampleSHAs[0]
+	}
+	if err := alertStore.Create(ctx, alert); err != nil {
+		return err
+	}
+
+	d.logger.Info().
+		Str("repo", repo.FullName).
+		Int("pre_event_commits", stats.PreEventCommits).
+		Bool("created_before_event", report.CreatedBeforeEvent).
+		Msg("pre-event code detected")
+	return nil
+}
+
+// preEventChanged reports whether the figures on an existing alert differ
+// from a fresh measurement.
+func preEventChanged(old, fresh map[string]interface{}) bool {
+	for _, key := range []string{"pre_event_commits", "exempt_commits", "total_commits", "pre_event_additions", "total_additions"} {
+		// JSON numbers decode as float64
+		prev, _ := old[key].(float64)
+		var cur float64
+		switch v := fresh[key].(type) {
+		case int:
+			cur = float64(v)
+		case int64:
+			cur = float64(v)
+		}
+		if prev != cur {
+			return true
+		}
+	}
+	prevCreated, _ := old["created_before_event"].(bool)
+	return prevCreated != fresh["created_before_event"]
+}
+
+func roundPercent(p float64) float64 {
+	return math.Round(p*10) / 10
+}
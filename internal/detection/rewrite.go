@@ -0,0 +1,151 @@
+package detection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/harshpatel5940/gitvigil/internal/models"
+	"github.com/harshpatel5940/gitvigil/internal/rules"
+)
+
+// CheckRewrittenHistory compares the verified committer dates of a
+// repository's commits against their author dates. Commits committed well
+// after they were authored were rebased or amended and are summarized in one
+// rewritten_commits alert per repository. Groups of commits sharing a single
+// committer timestamp while their author dates are spread out were rewritten
+// in one go, as by a rebase or a scripted `git commit --date` run; each group
+// gets a bulk_date_rewrite alert, critical when the author dates reach back
+// further than the rule's critical_hours.
+func (d *Detector) CheckRewrittenHistory(ctx context.Context, repo *models.Repository, s *rules.Settings) error {
+	commitStore := models.NewCommitStore(d.db.Pool)
+	gap := time.Duration(s.Int("gap_minutes")) * time.Minute
+
+	var errs []error
+	rewritten, err := commitStore.GetRewrittenCommits(ctx, repo.ID, gap)
+	if err != nil {
+		errs = append(errs, err)
+	} else if rewritten.Commits > 0 {
+		if err := d.raiseRewrittenCommits(ctx, repo, rewritten, s); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	bursts, err := commitStore.ListCommitterBursts(ctx, repo.ID, s.Int("bulk_min_commits"), gap)
+	if err != nil {
+		return errors.Join(append(errs, err)...)
+	}
+	for _, b := range bursts {
+		if err := d.raiseBulkDateRewrite(ctx, repo, b, s); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// raiseRewrittenCommits creates or refreshes the repository's
+// rewritten_commits alert.
+func (d *Detector) raiseRewrittenCommits(ctx context.Context, repo *models.Repository, r *models.RewrittenCommits, s *rules.Settings) error {
+	metadata := map[string]interface{}{
+		"rewritten_commits": r.Commits,
+		"max_gap_hours":     r.MaxGapHours,
+		"gap_minutes":       s.Int("gap_minutes"),
+		"commits":           r.SampleSHAs,
+	}
+
+	alertStore := models.NewAlertStore(d.db.Pool)
+	existing, err := alertStore.FindLatest(ctx, repo.ID, models.AlertRewrittenCommits)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		// JSON numbers decode as float64
+		if count, _ := existing.Metadata["rewritten_commits"].(float64); int(count) == r.Commits {
+			return nil
+		}
+		return alertStore.MergeMetadata(ctx, existing.ID, nil, metadata)
+	}
+
+	alert := &models.Alert{
+		RepositoryID: repo.ID,
+		AlertType:    models.AlertRewrittenCommits,
+		Severity:     s.SeverityOr(models.SeverityInfo),
+		Title:        "Rebased or amended commits detected",
+		Description:  fmt.Sprintf("%d commit(s) were committed more than %d minute(s) after they were authored", r.Commits, s.Int("gap_minutes")),
+		Metadata:     metadata,
+	}
+	if len(r.SampleSHAs) > 0 {
+		alert.CommitSHA = &r.SampleSHAs[0]
+	}
+	if err := alertStore.Create(ctx, alert); err != nil {
+		return err
+	}
+
+	d.logger.Info().
+		Str("repo", repo.FullName).
+		Int("commits", r.Commits).
+		Int("max_gap_hours", r.MaxGapHours).
+		Msg("rewritten commits detected")
+	return nil
+}
+
+// raiseBulkDateRewrite creates the alert for a group of commits sharing a
+// committer timestamp, or refreshes it as more of the group arrives.
+func (d *Detector) raiseBulkDateRewrite(ctx context.Context, repo *models.Repository, b *models.CommitterBurst, s *rules.Settings) error {
+	key := b.CommitterDate.UTC().Format(time.RFC3339)
+	spreadHours := int(b.LatestAuthor.Sub(b.EarliestAuthor).Hours())
+	reachHours := int(b.CommitterDate.Sub(b.EarliestAuthor).Hours())
+
+	severity := models.SeverityWarning
+	if reachHours > s.Int("critical_hours") {
+		severity = models.SeverityCritical
+	}
+	severity = s.SeverityOr(severity)
+
+	metadata := map[string]interface{}{
+		"committed_at":         key,
+		"commits":              b.Commits,
+		"earliest_author_date": b.EarliestAuthor,
+		"latest_author_date":   b.LatestAuthor,
+		"author_spread_hours":  spreadHours,
+		"backdate_hours":       reachHours,
+		"shas":                 b.SampleSHAs,
+	}
+
+	alertStore := models.NewAlertStore(d.db.Pool)
+	existing, err := alertStore.FindByMetadata(ctx, repo.ID, models.AlertBulkDateRewrite, "committed_at", key)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		if count, _ := existing.Metadata["commits"].(float64); int(count) == b.Commits {
+			return nil
+		}
+		return alertStore.MergeMetadata(ctx, existing.ID, &severity, metadata)
+	}
+
+	alert := &models.Alert{
+		RepositoryID: repo.ID,
+		AlertType:    models.AlertBulkDateRewrite,
+		Severity:     severity,
+		Title:        "Commit dates rewritten in bulk",
+		Description: fmt.Sprintf("%d commits were committed at %s with author dates spread over %d hour(s)",
+			b.Commits, key, spreadHours),
+		Metadata: metadata,
+	}
+	if len(b.SampleSHAs) > 0 {
+		alert.CommitSHA = &b.SampleSHAs[0]
+	}
+	if err := alertStore.Create(ctx, alert); err != nil {
+		return err
+	}
+
+	d.logger.Warn().
+		Str("repo", repo.FullName).
+		Str("committed_at", key).
+		Int("commits", b.Commits).
+		Int("author_spread_hours", spreadHours).
+		Msg("bulk commit date rewrite detected")
+	return nil
+}
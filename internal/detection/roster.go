@@ -0,0 +1,392 @@
+package detection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/harshpatel5940/gitvigil/internal/models"
+	"github.com/harshpatel5940/gitvigil/internal/rules"
+)
+
+// maxIdentityCommits caps the commit SHAs listed on an unregistered
+// contributor alert; commit_count always carries the full number.
+const maxIdentityCommits = 50
+
+// Roles an identity can play on a commit.
+const (
+	roleAuthor    = "author"
+	roleCommitter = "committer"
+)
+
+// roster is a team's members indexed by lowercased login and email.
+type roster struct {
+	logins map[string]bool
+	emails map[string]bool
+}
+
+func newRoster(members []*models.TeamMember) *roster {
+	r := &roster{logins: make(map[string]bool), emails: make(map[string]bool)}
+	for _, m := range members {
+		r.logins[strings.ToLower(m.GitHubLogin)] = true
+		for _, email := range m.Emails {
+			r.emails[strings.ToLower(email)] = true
+		}
+	}
+	return r
+}
+
+// contains matches a login or email against the roster. A GitHub noreply
+// email also matches the member whose login it embeds.
+func (r *roster) contains(login, email string) bool {
+	return (login != "" && r.logins[strings.ToLower(login)]) ||
+		(email != "" && r.emails[strings.ToLower(email)]) ||
+		r.logins[noreplyLogin(email)]
+}
+
+// unregisteredIdentity collects the commits of one identity missing from the
+// roster. aliases are the other logins and emails merged into it; covered is
+// set when any of them is on the roster or the bot allowlist.
+type unregisteredIdentity struct {
+	key, login, email, name string
+	aliases                 []string
+	covered                 bool
+	roles                   []string
+	shas                    []string
+	seen                    map[string]bool
+	first, last             time.Time
+}
+
+// identitySighting is a login and email seen on a commit in one role.
+type identitySighting struct {
+	commit                   *models.CommitIdentity
+	role, login, email, name string
+	covered                  bool
+}
+
+// identityKeys returns the lowercased keys a sighting is known by: its
+// login, its email, and the login embedded in a noreply email.
+func identityKeys(login, email string) []string {
+	var keys []string
+	if login != "" {
+		keys = append(keys, strings.ToLower(login))
+	}
+	if noreply := noreplyLogin(email); noreply != "" {
+		keys = append(keys, noreply)
+	}
+	if email != "" {
+		keys = append(keys, strings.ToLower(email))
+	}
+	return keys
+}
+
+// mergeIdentities groups sightings sharing a login, an email or a
+// noreply-derived login into one identity each, in order of first sighting.
+// A person committing both with and without a linked account is one
+// identity. It is keyed by its first login, or its first email without one,
+// and is covered as a whole when any sighting is on the roster or allowlist.
+func mergeIdentities(sightings []*identitySighting) []*unregisteredIdentity {
+	parent := make(map[string]string)
+	var find func(key string) string
+	find = func(key string) string {
+		if _, ok := parent[key]; !ok {
+			parent[key] = key
+		}
+		if parent[key] != key {
+			parent[key] = find(parent[key])
+		}
+		return parent[key]
+	}
+	for _, sg := range sightings {
+		keys := identityKeys(sg.login, sg.email)
+		for _, key := range keys[1:] {
+			parent[find(key)] = find(keys[0])
+		}
+	}
+
+	var identities []*unregisteredIdentity
+	byRoot := make(map[string]*unregisteredIdentity)
+	keys := make(map[*unregisteredIdentity][]string)
+	for _, sg := range sightings {
+		sgKeys := identityKeys(sg.login, sg.email)
+		root := find(sgKeys[0])
+		u, ok := byRoot[root]
+		if !ok {
+			u = &unregisteredIdentity{seen: make(map[string]bool)}
+			byRoot[root] = u
+			identities = append(identities, u)
+		}
+		for _, key := range sgKeys {
+			if !slices.Contains(keys[u], key) {
+				keys[u] = append(keys[u], key)
+			}
+		}
+		if sg.covered {
+			u.covered = true
+			continue
+		}
+		if u.login == "" {
+			u.login = sg.login
+		}
+		if u.email == "" {
+			u.email = sg.email
+		}
+		if sg.name != "" {
+			u.name = sg.name
+		}
+		u.add(sg.commit, sg.role)
+	}
+
+	for _, u := range identities {
+		u.key = strings.ToLower(u.login)
+		if u.key == "" {
+			u.key = strings.ToLower(u.email)
+		}
+		if u.covered {
+			u.aliases = keys[u]
+			continue
+		}
+		for _, key := range keys[u] {
+			if key != u.key {
+				u.aliases = append(u.aliases, key)
+			}
+		}
+		slices.Sort(u.aliases)
+	}
+	return identities
+}
+
+func (u *unregisteredIdentity) add(c *models.CommitIdentity, role string) {
+	if !slices.Contains(u.roles, role) {
+		u.roles = append(u.roles, role)
+	}
+	if u.seen[c.SHA] {
+		return
+	}
+	u.seen[c.SHA] = true
+	u.shas = append(u.shas, c.SHA)
+	if u.first.IsZero() || c.PushedAt.Before(u.first) {
+		u.first = c.PushedAt
+	}
+	if c.PushedAt.After(u.last) {
+		u.last = c.PushedAt
+	}
+}
+
+// CheckUnregisteredContributors compares the author and committer of every
+// commit pushed within the repository's event window against its team
+// roster. Each identity matching no member by login or email, and not an
+// allowlisted bot, gets one unregistered_contributor alert, which later
+// checks update as more of its commits arrive, and resolve once the roster
+// lists the identity. Logins and emails seen together on any commit are one
+// identity. Repositories without a team are skipped.
+func (d *Detector) CheckUnregisteredContributors(ctx context.Context, repo *models.Repository, s *rules.Settings) error {
+	if repo.TeamID == nil {
+		return nil
+	}
+
+	members, err := models.NewTeamStore(d.db.Pool).ListMembers(ctx, *repo.TeamID)
+	if err != nil {
+		return err
+	}
+	team := newRoster(members)
+
+	event, err := models.NewEventStore(d.db.Pool).GetForRepository(ctx, repo)
+	if err != nil {
+		return err
+	}
+	from, to := models.EventWindow(event)
+
+	commits, err := models.NewCommitStore(d.db.Pool).ListIdentities(ctx, repo.ID, from, to)
+	if err != nil {
+		return err
+	}
+
+	// Sightings on the roster or allowlist are kept so they cover the
+	// logins and emails seen together with them
+	var sightings []*identitySighting
+	collect := func(c *models.CommitIdentity, role, login, email, name string) {
+		if login == "" && email == "" {
+			return
+		}
+		sightings = append(sightings, &identitySighting{
+			commit: c, role: role, login: login, email: email, name: name,
+			covered: team.contains(login, email) || d.isAllowlistedBot(login, email),
+		})
+	}
+
+	for _, c := range commits {
+		collect(c, roleAuthor, deref(c.AuthorLogin), c.AuthorEmail, c.AuthorName)
+		collect(c, roleCommitter, deref(c.CommitterLogin), deref(c.CommitterEmail), deref(c.CommitterName))
+	}
+
+	var errs []error
+	resolutions := make(map[string]string)
+	for _, u := range mergeIdentities(sightings) {
+		if u.covered {
+			for _, key := range u.aliases {
+				resolutions[key] = "identity seen with a login or email on the roster or bot allowlist"
+			}
+			continue
+		}
+		if err := d.raiseUnregistered(ctx, repo, u, s); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", u.key, err))
+		}
+		for _, alias := range u.aliases {
+			resolutions[alias] = "identity merged into " + u.key
+		}
+	}
+	if err := d.resolveRegistered(ctx, repo, team, resolutions); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// resolveRegistered acknowledges the open unregistered_contributor alerts
+// whose identity the roster or the bot allowlist now covers, and those
+// resolutions gives a reason for by identity key: identities merged into
+// another, or seen together with a covered one.
+func (d *Detector) resolveRegistered(ctx context.Context, repo *models.Repository, team *roster, resolutions map[string]string) error {
+	alertStore := models.NewAlertStore(d.db.Pool)
+	open, err := alertStore.ListUnacknowledged(ctx, repo.ID, models.AlertUnregisteredContributor)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, alert := range open {
+		key, _ := alert.Metadata["identity"].(string)
+		login, _ := alert.Metadata["login"].(string)
+		email, _ := alert.Metadata["email"].(string)
+		var resolution string
+		switch {
+		case team.contains(login, email):
+			resolution = "identity added to the team roster"
+		case d.isAllowlistedBot(login, email):
+			resolution = "identity on the bot allowlist"
+		case resolutions[key] != "":
+			resolution = resolutions[key]
+		default:
+			continue
+		}
+		err := alertStore.MergeMetadata(ctx, alert.ID, nil, map[string]interface{}{"resolution": resolution})
+		if err == nil {
+			err = alertStore.Acknowledge(ctx, alert.ID)
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		d.logger.Info().
+			Str("repo", repo.FullName).
+			Interface("identity", alert.Metadata["identity"]).
+			Msg("unregistered contributor resolved")
+	}
+	return errors.Join(errs...)
+}
+
+// raiseUnregistered creates the identity's alert, or refreshes the existing
+// one when its commit count has changed.
+func (d *Detector) raiseUnregistered(ctx context.Context, repo *models.Repository, u *unregisteredIdentity, s *rules.Settings) error {
+	shas := u.shas
+	if len(shas) > maxIdentityCommits {
+		shas = shas[:maxIdentityCommits]
+	}
+	metadata := map[string]interface{}{
+		"identity":      u.key,
+		"login":         u.login,
+		"email":         u.email,
+		"aliases":       u.aliases,
+		"name":          u.name,
+		"roles":         u.roles,
+		"commit_count":  len(u.shas),
+		"commits":       shas,
+		"first_seen_at": u.first,
+		"last_seen_at":  u.last,
+	}
+
+	alertStore := models.NewAlertStore(d.db.Pool)
+	existing, err := alertStore.FindByMetadata(ctx, repo.ID, models.AlertUnregisteredContributor, "identity", u.key)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		// JSON numbers decode as float64
+		if count, _ := existing.Metadata["commit_count"].(float64); int(count) == len(u.shas) {
+			return nil
+		}
+		return alertStore.MergeMetadata(ctx, existing.ID, nil, metadata)
+	}
+
+	identity := u.login
+	if identity == "" {
+		identity = u.email
+	}
+	alert := &models.Alert{
+		RepositoryID: repo.ID,
+		CommitSHA:    &u.shas[0],
+		AlertType:    models.AlertUnregisteredContributor,
+		Severity:     s.SeverityOr(models.SeverityWarning),
+		Title:        "Commits by unregistered contributor",
+		Description:  fmt.Sprintf("%s is not on the team roster but appears as %s of %d commit(s)", identity, strings.Join(u.roles, " and "), len(u.shas)),
+		Metadata:     metadata,
+	}
+	if err := alertStore.Create(ctx, alert); err != nil {
+		return err
+	}
+
+	d.logger.Info().
+		Str("repo", repo.FullName).
+		Str("identity", u.key).
+		Int("commits", len(u.shas)).
+		Msg("unregistered contributor detected")
+	return nil
+}
+
+// isAllowlistedBot reports whether a login or email belongs to an account on
+// the bot allowlist. Entries match with or without a "[bot]" suffix, and also
+// match GitHub noreply addresses such as
+// 49699333+dependabot[bot]@users.noreply.github.com.
+func (d *Detector) isAllowlistedBot(login, email string) bool {
+	login = botName(login)
+	email = strings.ToLower(email)
+
+	noreplyName := botName(noreplyLogin(email))
+
+	for _, entry := range d.cfg.BotAllowlist {
+		entry = strings.ToLower(entry)
+		name := botName(entry)
+		if (email != "" && email == entry) || (login != "" && login == name) || (noreplyName != "" && noreplyName == name) {
+			return true
+		}
+	}
+	return false
+}
+
+// noreplyLogin returns the login embedded in a GitHub noreply address such as
+// 12345+octocat@users.noreply.github.com, or "" for any other email.
+func noreplyLogin(email string) string {
+	local, ok := strings.CutSuffix(strings.ToLower(email), "@users.noreply.github.com")
+	if !ok {
+		return ""
+	}
+	if i := strings.IndexByte(local, '+'); i >= 0 {
+		local = local[i+1:]
+	}
+	return local
+}
+
+func botName(s string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(s)), "[bot]")
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
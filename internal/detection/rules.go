@@ -0,0 +1,375 @@
+package detection
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/harshpatel5940/gitvigil/internal/config"
+	ghclient "github.com/harshpatel5940/gitvigil/internal/github"
+	"github.com/harshpatel5940/gitvigil/internal/models"
+	"github.com/harshpatel5940/gitvigil/internal/rules"
+)
+
+// Names of the built-in rules.
+const (
+	RuleBackdate                = "backdate"
+	RuleFutureDated             = "future_dated"
+	RuleForcePush               = "force_push"
+	RulePostDeadlinePush        = "post_deadline_push"
+	RuleStreak                  = "streak"
+	RuleUnregisteredContributor = "unregistered_contributor"
+	RulePreEventCode            = "pre_event_code"
+	RuleSharedHistory           = "shared_history"
+	RuleRewrittenHistory        = "rewritten_history"
+	RuleLicense                 = "license"
+	RuleExternalOrigin          = "external_origin"
+	RuleCodeSimilarity          = "code_similarity"
+	RuleCodeDump                = "code_dump"
+	RuleScriptedHistory         = "scripted_history"
+	RuleTimezoneAnomaly         = "timezone_anomaly"
+)
+
+// ruleInfo implements the descriptive part of rules.Rule.
+type ruleInfo struct {
+	name        string
+	description string
+	triggers    []rules.Trigger
+	alertType   models.AlertType
+	severity    models.Severity
+	params      rules.Params
+}
+
+func (r *ruleInfo) Name() string                     { return r.name }
+func (r *ruleInfo) Description() string              { return r.description }
+func (r *ruleInfo) Triggers() []rules.Trigger        { return r.triggers }
+func (r *ruleInfo) AlertType() models.AlertType      { return r.alertType }
+func (r *ruleInfo) DefaultSeverity() models.Severity { return r.severity }
+func (r *ruleInfo) DefaultParams() rules.Params      { return r.params }
+
+// repositoryRule is a rules.RepositoryRule backed by a detector check.
+type repositoryRule struct {
+	ruleInfo
+	check func(ctx context.Context, repo *models.Repository, s *rules.Settings) error
+}
+
+func (r *repositoryRule) CheckRepository(ctx context.Context, repo *models.Repository, s *rules.Settings) error {
+	return r.check(ctx, repo, s)
+}
+
+// scheduledRule is a rules.ScheduledRule backed by a detector run.
+type scheduledRule struct {
+	ruleInfo
+	run func(ctx context.Context, e *rules.Engine) error
+}
+
+func (r *scheduledRule) Run(ctx context.Context, e *rules.Engine) error {
+	return r.run(ctx, e)
+}
+
+// streakRule is the streak tracker, whose thresholds must stay in order.
+type streakRule struct{ scheduledRule }
+
+// CheckParams applies the check config.Load makes on the environment
+// defaults to every setting.
+func (r *streakRule) CheckParams(p rules.Params) error {
+	if p["inactive_hours"] <= p["at_risk_hours"] {
+		return fmt.Errorf("inactive_hours (%g) must be greater than at_risk_hours (%g)", p["inactive_hours"], p["at_risk_hours"])
+	}
+	return nil
+}
+
+// newRegistry registers the built-in rules followed by the custom rules
+// file, if one is configured. A custom rules file that fails to load is
+// logged and left out rather than taking the built-in rules down with it.
+func (d *Detector) newRegistry() *rules.Registry {
+	reg := d.builtinRegistry()
+	if d.cfg.CustomRulesFile == "" {
+		return reg
+	}
+
+	custom, err := rules.LoadCustomRules(d.cfg.CustomRulesFile)
+	if err == nil {
+		err = reg.RegisterCustom(custom)
+	}
+	if err != nil {
+		d.logger.Error().Err(err).Str("file", d.cfg.CustomRulesFile).Msg("failed to load custom rules")
+		return reg
+	}
+	d.logger.Info().Int("rules", len(custom)).Str("file", d.cfg.CustomRulesFile).Msg("custom rules loaded")
+	if d.gh == nil && len(reg.ForTrigger(rules.TriggerEnrichment)) > 0 {
+		d.logger.Warn().Msg("custom rules reading additions, deletions or files never run without a GitHub App to enrich commits")
+	}
+	return reg
+}
+
+// LoadCustomRules compiles a custom rules file and checks its rule names
+// against the built-in rules. It needs no database, so rules files can be
+// validated offline.
+func LoadCustomRules(cfg *config.Config, gh *ghclient.AppClient, path string) ([]*rules.CustomRule, error) {
+	custom, err := rules.LoadCustomRules(path)
+	if err != nil {
+		return nil, err
+	}
+	d := &Detector{cfg: cfg, gh: gh}
+	if err := d.builtinRegistry().RegisterCustom(custom); err != nil {
+		return nil, err
+	}
+	return custom, nil
+}
+
+// builtinRegistry registers the built-in rules with defaults taken from the
+// configuration. Rules that need the GitHub API are only registered with a
+// GitHub App client.
+func (d *Detector) builtinRegistry() *rules.Registry {
+	reg := rules.NewRegistry()
+	onPush := []rules.Trigger{rules.TriggerPush, rules.TriggerSchedule}
+
+	reg.Register(&backdateRule{ruleInfo{
+		name:        RuleBackdate,
+		description: "Commits authored long before they were pushed; critical beyond critical_hours",
+		triggers:    []rules.Trigger{rules.TriggerCommit},
+		alertType:   models.AlertBackdateSuspicious,
+		severity:    models.SeverityWarning,
+		params: rules.Params{
+			"suspicious_hours": float64(d.cfg.BackdateSuspiciousHours),
+			"critical_hours":   float64(d.cfg.BackdateCriticalHours),
+		},
+	}})
+	reg.Register(&futureDatedRule{ruleInfo{
+		name:        RuleFutureDated,
+		description: "Commits dated after the push that delivered them",
+		triggers:    []rules.Trigger{rules.TriggerCommit},
+		alertType:   models.AlertFutureDated,
+		severity:    models.SeverityWarning,
+		params:      rules.Params{"tolerance_minutes": float64(d.cfg.FutureDatedToleranceMinutes)},
+	}})
+	reg.Register(&forcePushRule{ruleInfo{
+		name:        RuleForcePush,
+		description: "Pushes that rewrite a branch's history",
+		triggers:    []rules.Trigger{rules.TriggerPush},
+		alertType:   models.AlertForcePush,
+		severity:    models.SeverityWarning,
+	}})
+	reg.Register(&postDeadlinePushRule{ruleInfo{
+		name:        RulePostDeadlinePush,
+		description: "Pushes made after the event's submission deadline and grace period",
+		triggers:    []rules.Trigger{rules.TriggerPush},
+		alertType:   models.AlertPostDeadlinePush,
+		severity:    models.SeverityCritical,
+	}})
+	reg.Register(&streakRule{scheduledRule{ruleInfo{
+		name:        RuleStreak,
+		description: "Repositories idle for at_risk_hours or inactive_hours; alert severity follows the streak status",
+		triggers:    []rules.Trigger{rules.TriggerSchedule},
+		alertType:   models.AlertStreakAtRisk,
+		params: rules.Params{
+			"at_risk_hours":  float64(d.cfg.StreakInactivityHours),
+			"inactive_hours": float64(d.cfg.StreakInactiveHours),
+			"recovery_hours": float64(d.cfg.StreakRecoveryHours),
+		},
+	}, d.CheckStreaks}})
+	reg.Register(&repositoryRule{ruleInfo{
+		name:        RuleUnregisteredContributor,
+		description: "Commit authors and committers missing from the team roster",
+		triggers:    onPush,
+		alertType:   models.AlertUnregisteredContributor,
+		severity:    models.SeverityWarning,
+	}, d.CheckUnregisteredContributors})
+	reg.Register(&repositoryRule{ruleInfo{
+		name:        RulePreEventCode,
+		description: "Commits authored before the event started; critical at critical_percent of commits or lines",
+		triggers:    onPush,
+		alertType:   models.AlertPreEventCode,
+		severity:    models.SeverityWarning,
+		params:      rules.Params{"critical_percent": float64(d.cfg.PreEventCriticalPercent)},
+	}, d.CheckPreEventCode})
+	reg.Register(&repositoryRule{ruleInfo{
+		name:        RuleSharedHistory,
+		description: "Commits that also appear in another enrolled repository",
+		triggers:    onPush,
+		alertType:   models.AlertSharedHistory,
+		severity:    models.SeverityCritical,
+	}, d.CheckSharedHistory})
+	reg.Register(&repositoryRule{ruleInfo{
+		name:        RuleRewrittenHistory,
+		description: "Rebased or amended commits, and commit dates rewritten in bulk",
+		triggers:    onPush,
+		alertType:   models.AlertRewrittenCommits,
+		severity:    models.SeverityInfo,
+		params: rules.Params{
+			"gap_minutes":      float64(d.cfg.RewriteGapMinutes),
+			"bulk_min_commits": float64(d.cfg.RewriteBulkMinCommits),
+			"critical_hours":   float64(d.cfg.BackdateCriticalHours),
+		},
+	}, d.CheckRewrittenHistory})
+	reg.Register(&repositoryRule{ruleInfo{
+		name:        RuleScriptedHistory,
+		description: "Contributors' commits spaced at clockwork intervals, authored in bursts within seconds, or repeating one message",
+		triggers:    onPush,
+		alertType:   models.AlertScriptedHistory,
+		severity:    models.SeverityWarning,
+		params: rules.Params{
+			"regular_min_commits":       float64(d.cfg.ScriptedRegularMinCommits),
+			"regular_tolerance_seconds": float64(d.cfg.ScriptedRegularToleranceSeconds),
+			"burst_seconds":             float64(d.cfg.ScriptedBurstSeconds),
+			"burst_min_commits":         float64(d.cfg.ScriptedBurstMinCommits),
+			"duplicate_min_commits":     float64(d.cfg.ScriptedDuplicateMinCommits),
+		},
+	}, d.CheckScriptedHistory})
+	reg.Register(&repositoryRule{ruleInfo{
+		name:        RuleTimezoneAnomaly,
+		description: "Contributors switching UTC offsets within switch_window_hours, and on-site teams committing from outside the event's time zone",
+		triggers:    onPush,
+		alertType:   models.AlertTimezoneAnomaly,
+		severity:    models.SeverityWarning,
+		params: rules.Params{
+			"switch_min_minutes":        float64(d.cfg.TimezoneSwitchMinMinutes),
+			"switch_window_hours":       float64(d.cfg.TimezoneSwitchWindowHours),
+			"offsite_tolerance_minutes": float64(d.cfg.TimezoneOffsiteToleranceMinutes),
+			"offsite_min_commits":       float64(d.cfg.TimezoneOffsiteMinCommits),
+		},
+	}, d.CheckTimezones})
+
+	if d.gh == nil {
+		return reg
+	}
+
+	onInstall := []rules.Trigger{rules.TriggerInstallation, rules.TriggerSchedule}
+	reg.Register(&repositoryRule{ruleInfo{
+		name:        RuleLicense,
+		description: "Repositories without a license",
+		triggers:    onInstall,
+		alertType:   models.AlertNoLicense,
+		severity:    models.SeverityInfo,
+	}, d.ValidateLicenseForRepo})
+	reg.Register(&repositoryRule{ruleInfo{
+		name:        RuleExternalOrigin,
+		description: "Repositories forked or generated from a repository outside their event",
+		triggers:    onInstall,
+		alertType:   models.AlertExternalOrigin,
+		severity:    models.SeverityCritical,
+	}, d.CheckOrigin})
+	reg.Register(&scheduledRule{ruleInfo{
+		name:        RuleCodeSimilarity,
+		description: "Submitted source matching another repository of the event by threshold_percent or more",
+		triggers:    []rules.Trigger{rules.TriggerSchedule},
+		alertType:   models.AlertCodeSimilarity,
+		severity:    models.SeverityCritical,
+		params:      rules.Params{"threshold_percent": float64(d.cfg.SimilarityThresholdPercent)},
+	}, d.AnalyzeSimilarity})
+	reg.Register(&repositoryRule{ruleInfo{
+		name:        RuleCodeDump,
+		description: "Commits adding commit_lines lines of code or relative_factor times the repository or event median, and pushes delivering push_percent of a repository of min_repo_lines or more",
+		triggers:    []rules.Trigger{rules.TriggerEnrichment, rules.TriggerSchedule},
+		alertType:   models.AlertCodeDump,
+		severity:    models.SeverityWarning,
+		params: rules.Params{
+			"commit_lines":    float64(d.cfg.CodeDumpCommitLines),
+			"min_lines":       float64(d.cfg.CodeDumpMinLines),
+			"relative_factor": float64(d.cfg.CodeDumpRelativeFactor),
+			"push_percent":    float64(d.cfg.CodeDumpPushPercent),
+			"min_repo_lines":  float64(d.cfg.CodeDumpMinRepoLines),
+		},
+	}, d.CheckCodeDumps})
+
+	return reg
+}
+
+// backdateRule flags commits authored long before they were pushed.
+type backdateRule struct{ ruleInfo }
+
+func (r *backdateRule) EvaluateCommit(c *rules.Commit, s *rules.Settings) []*rules.Finding {
+	if c.BackdateHours <= s.Int("suspicious_hours") {
+		return nil
+	}
+
+	f := &rules.Finding{
+		CommitSHA: c.SHA,
+		Title:     "Backdated commit detected",
+		// An author date far older than the committer date was rewritten,
+		// whereas matching dates mean the commit sat unpushed
+		Description: "Commit author date is significantly older than push time",
+		Metadata: map[string]interface{}{
+			"author_date":         c.AuthorDate,
+			"committer_date":      c.CommitterDate,
+			"pushed_at":           c.PushedAt,
+			"backdate_hours":      c.BackdateHours,
+			"committer_gap_hours": c.CommitterGapHours,
+			"source":              c.Source,
+		},
+	}
+	if c.CommitterGapHours > s.Int("suspicious_hours") {
+		f.Description = fmt.Sprintf("Commit author date is %d hour(s) older than its committer date", c.CommitterGapHours)
+	}
+	if c.BackdateHours > s.Int("critical_hours") {
+		f.AlertType = models.AlertBackdateCritical
+		f.Severity = models.SeverityCritical
+	}
+	return []*rules.Finding{f}
+}
+
+// futureDatedRule flags commits dated after their push.
+type futureDatedRule struct{ ruleInfo }
+
+func (r *futureDatedRule) EvaluateCommit(c *rules.Commit, s *rules.Settings) []*rules.Finding {
+	if c.FutureMinutes <= s.Int("tolerance_minutes") {
+		return nil
+	}
+	return []*rules.Finding{{
+		CommitSHA:   c.SHA,
+		Title:       "Future-dated commit detected",
+		Description: fmt.Sprintf("Commit is dated %d minute(s) after it was pushed", c.FutureMinutes),
+		Metadata: map[string]interface{}{
+			"author_date":    c.AuthorDate,
+			"committer_date": c.CommitterDate,
+			"pushed_at":      c.PushedAt,
+			"future_minutes": c.FutureMinutes,
+			"source":         c.Source,
+		},
+	}}
+}
+
+// forcePushRule flags forced pushes. The forensics job later fills in the
+// rewritten commits.
+type forcePushRule struct{ ruleInfo }
+
+func (r *forcePushRule) EvaluatePush(p *rules.Push, s *rules.Settings) []*rules.Finding {
+	if !p.Forced {
+		return nil
+	}
+	return []*rules.Finding{{
+		Title:       "Force push detected",
+		Description: "Repository history was rewritten",
+		Metadata: map[string]interface{}{
+			"ref":       p.Ref,
+			"before":    p.Before,
+			"after":     p.After,
+			"pusher":    p.Pusher,
+			"forensics": models.ForensicsPending,
+		},
+	}}
+}
+
+// postDeadlinePushRule flags pushes after the submission freeze.
+type postDeadlinePushRule struct{ ruleInfo }
+
+func (r *postDeadlinePushRule) EvaluatePush(p *rules.Push, s *rules.Settings) []*rules.Finding {
+	if !p.PostDeadline {
+		return nil
+	}
+	return []*rules.Finding{{
+		Title:       "Push after submission deadline",
+		Description: fmt.Sprintf("%s was pushed %d minute(s) after the submission deadline", p.Ref, p.MinutesLate),
+		Metadata: map[string]interface{}{
+			"event_id":      p.EventID,
+			"ref":           p.Ref,
+			"before":        p.Before,
+			"after":         p.After,
+			"pusher":        p.Pusher,
+			"commits":       p.Commits,
+			"deadline":      p.Deadline,
+			"grace_minutes": p.GraceMinutes,
+			"minutes_late":  p.MinutesLate,
+		},
+	}}
+}
@@ -0,0 +1,326 @@
+package detection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/harshpatel5940/gitvigil/internal/models"
+	"github.com/harshpatel5940/gitvigil/internal/rules"
+)
+
+// maxEvidence caps the commits attached to a scripted_history alert.
+const maxEvidence = 50
+
+// Timing patterns left by scripted histories.
+const (
+	patternRegularSpacing    = "regular_spacing"
+	patternBurst             = "burst"
+	patternDuplicateMessages = "duplicate_messages"
+)
+
+// timingPattern is a group of one contributor's commits showing a pattern.
+type timingPattern struct {
+	kind    string
+	commits []*models.AuthorTiming
+	// interval is the spacing of a regular_spacing run.
+	interval time.Duration
+}
+
+// intervalStats summarizes the gaps between a contributor's consecutive
+// author dates.
+type intervalStats struct {
+	Commits int
+	Median  float64
+	Mean    float64
+	Stddev  float64
+	// CV is the coefficient of variation, Stddev over Mean. Human commit
+	// gaps vary wildly; a CV near zero means clockwork spacing.
+	CV float64
+}
+
+// CheckScriptedHistory looks for histories fabricated by a script in the
+// author dates of each contributor's commits: runs of regular_min_commits
+// or more commits spaced at the same interval give or take
+// regular_tolerance_seconds, bursts of burst_min_commits or more commits
+// authored at most burst_seconds apart, and commit messages repeated
+// duplicate_min_commits times or more. Each pattern gets a scripted_history
+// alert listing its commits.
+func (d *Detector) CheckScriptedHistory(ctx context.Context, repo *models.Repository, s *rules.Settings) error {
+	timings, err := models.NewCommitStore(d.db.Pool).ListAuthorTimings(ctx, repo.ID)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, commits := range groupByAuthor(timings) {
+		if d.isBotAuthor(commits[0]) {
+			continue
+		}
+
+		var patterns []*timingPattern
+		patterns = append(patterns, regularRuns(commits, s)...)
+		patterns = append(patterns, bursts(commits, s)...)
+		patterns = append(patterns, duplicateMessages(commits, s)...)
+		if len(patterns) == 0 {
+			continue
+		}
+
+		stats := authorIntervals(commits)
+		for _, p := range patterns {
+			if err := d.raiseScriptedHistory(ctx, repo, p, stats, s); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// groupByAuthor splits commits ordered by author into one slice per author
+// email.
+func groupByAuthor(timings []*models.AuthorTiming) [][]*models.AuthorTiming {
+	var groups [][]*models.AuthorTiming
+	for i, c := range timings {
+		if i == 0 || !strings.EqualFold(c.AuthorEmail, timings[i-1].AuthorEmail) {
+			groups = append(groups, nil)
+		}
+		groups[len(groups)-1] = append(groups[len(groups)-1], c)
+	}
+	return groups
+}
+
+// isBotAuthor reports whether a commit's author is on the bot allowlist.
+func (d *Detector) isBotAuthor(c *models.AuthorTiming) bool {
+	login := ""
+	if c.AuthorLogin != nil {
+		login = *c.AuthorLogin
+	}
+	return d.isAllowlistedBot(login, c.AuthorEmail)
+}
+
+// regularRuns returns the runs of commits whose author dates are spaced at
+// the same interval, within the tolerance. Spacing short enough to count
+// as a burst is left to bursts.
+func regularRuns(commits []*models.AuthorTiming, s *rules.Settings) []*timingPattern {
+	minCommits := s.Int("regular_min_commits")
+	tolerance := time.Duration(s.Int("regular_tolerance_seconds")) * time.Second
+	burst := time.Duration(s.Int("burst_seconds")) * time.Second
+
+	var runs []*timingPattern
+	for start := 0; start < len(commits)-1; {
+		interval := commits[start+1].AuthorDate.Sub(commits[start].AuthorDate)
+		if interval <= burst {
+			start++
+			continue
+		}
+		end := start + 1
+		for end+1 < len(commits) && absDuration(commits[end+1].AuthorDate.Sub(commits[end].AuthorDate)-interval) <= tolerance {
+			end++
+		}
+		if end-start+1 < minCommits {
+			start++
+			continue
+		}
+		runs = append(runs, &timingPattern{kind: patternRegularSpacing, commits: commits[start : end+1], interval: interval})
+		// The last commit of a run may start the next one
+		start = end
+	}
+	return runs
+}
+
+// bursts returns the groups of commits authored at most burst_seconds
+// after the one before.
+func bursts(commits []*models.AuthorTiming, s *rules.Settings) []*timingPattern {
+	minCommits := s.Int("burst_min_commits")
+	window := time.Duration(s.Int("burst_seconds")) * time.Second
+
+	var groups []*timingPattern
+	start := 0
+	for i := 1; i <= len(commits); i++ {
+		if i < len(commits) && commits[i].AuthorDate.Sub(commits[i-1].AuthorDate) <= window {
+			continue
+		}
+		if i-start >= minCommits {
+			groups = append(groups, &timingPattern{kind: patternBurst, commits: commits[start:i]})
+		}
+		start = i
+	}
+	return groups
+}
+
+// duplicateMessages returns the groups of commits sharing a message. Merge
+// commits are left out since git writes their messages.
+func duplicateMessages(commits []*models.AuthorTiming, s *rules.Settings) []*timingPattern {
+	byMessage := make(map[string][]*models.AuthorTiming)
+	var order []string
+	for _, c := range commits {
+		message := strings.TrimSpace(c.Message)
+		if message == "" || strings.HasPrefix(message, "Merge ") {
+			continue
+		}
+		if _, ok := byMessage[message]; !ok {
+			order = append(order, message)
+		}
+		byMessage[message] = append(byMessage[message], c)
+	}
+
+	var groups []*timingPattern
+	for _, message := range order {
+		if len(byMessage[message]) >= s.Int("duplicate_min_commits") {
+			groups = append(groups, &timingPattern{kind: patternDuplicateMessages, commits: byMessage[message]})
+		}
+	}
+	return groups
+}
+
+// authorIntervals computes the interval statistics of a contributor's
+// commits, in seconds.
+func authorIntervals(commits []*models.AuthorTiming) *intervalStats {
+	stats := &intervalStats{Commits: len(commits)}
+	if len(commits) < 2 {
+		return stats
+	}
+
+	gaps := make([]float64, len(commits)-1)
+	var sum float64
+	for i := 1; i < len(commits); i++ {
+		gaps[i-1] = commits[i].AuthorDate.Sub(commits[i-1].AuthorDate).Seconds()
+		sum += gaps[i-1]
+	}
+	stats.Mean = sum / float64(len(gaps))
+
+	var squares float64
+	for _, g := range gaps {
+		squares += (g - stats.Mean) * (g - stats.Mean)
+	}
+	stats.Stddev = math.Sqrt(squares / float64(len(gaps)))
+	if stats.Mean > 0 {
+		stats.CV = math.Round(stats.Stddev/stats.Mean*1000) / 1000
+	}
+
+	slices.Sort(gaps)
+	mid := len(gaps) / 2
+	stats.Median = gaps[mid]
+	if len(gaps)%2 == 0 {
+		stats.Median = (gaps[mid-1] + gaps[mid]) / 2
+	}
+	return stats
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// raiseScriptedHistory creates the alert for a timing pattern, or
+// refreshes it as the pattern grows.
+func (d *Detector) raiseScriptedHistory(ctx context.Context, repo *models.Repository, p *timingPattern, stats *intervalStats, s *rules.Settings) error {
+	first, last := p.commits[0], p.commits[len(p.commits)-1]
+	key := p.kind + ":" + first.SHA
+
+	severity := models.SeverityWarning
+	var title, description string
+	switch p.kind {
+	case patternRegularSpacing:
+		title = "Commits spaced at regular intervals"
+		description = fmt.Sprintf("%d commits by %s were authored exactly %s apart", len(p.commits), first.AuthorEmail, p.interval)
+	case patternBurst:
+		title = "Burst of commits authored within seconds"
+		description = fmt.Sprintf("%d commits by %s were authored within %s", len(p.commits), first.AuthorEmail,
+			last.AuthorDate.Sub(first.AuthorDate))
+	case patternDuplicateMessages:
+		severity = models.SeverityInfo
+		title = "Commits with identical messages"
+		description = fmt.Sprintf("%d commits by %s share the message %q", len(p.commits), first.AuthorEmail,
+			truncateMessage(first.Message))
+	}
+	severity = s.SeverityOr(severity)
+
+	evidence := make([]map[string]interface{}, 0, min(len(p.commits), maxEvidence))
+	for i, c := range p.commits[:min(len(p.commits), maxEvidence)] {
+		e := map[string]interface{}{
+			"sha":         c.SHA,
+			"author_date": c.AuthorDate,
+		}
+		if i > 0 {
+			e["interval_seconds"] = int(c.AuthorDate.Sub(p.commits[i-1].AuthorDate).Seconds())
+		}
+		evidence = append(evidence, e)
+	}
+
+	metadata := map[string]interface{}{
+		"pattern":      key,
+		"kind":         p.kind,
+		"author_email": first.AuthorEmail,
+		"author_name":  first.AuthorName,
+		"commits":      len(p.commits),
+		"first_date":   first.AuthorDate,
+		"last_date":    last.AuthorDate,
+		"evidence":     evidence,
+		"author_intervals": map[string]interface{}{
+			"commits":        stats.Commits,
+			"median_seconds": stats.Median,
+			"mean_seconds":   stats.Mean,
+			"stddev_seconds": stats.Stddev,
+			"cv":             stats.CV,
+		},
+	}
+	switch p.kind {
+	case patternRegularSpacing:
+		metadata["interval_seconds"] = int(p.interval.Seconds())
+		metadata["tolerance_seconds"] = s.Int("regular_tolerance_seconds")
+	case patternDuplicateMessages:
+		metadata["message"] = first.Message
+	}
+
+	alertStore := models.NewAlertStore(d.db.Pool)
+	existing, err := alertStore.FindByMetadata(ctx, repo.ID, models.AlertScriptedHistory, "pattern", key)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		// JSON numbers decode as float64
+		if count, _ := existing.Metadata["commits"].(float64); int(count) == len(p.commits) {
+			return nil
+		}
+		return alertStore.MergeMetadata(ctx, existing.ID, &severity, metadata)
+	}
+
+	sha := first.SHA
+	alert := &models.Alert{
+		RepositoryID: repo.ID,
+		CommitSHA:    &sha,
+		AlertType:    models.AlertScriptedHistory,
+		Severity:     severity,
+		Title:        title,
+		Description:  description,
+		Metadata:     metadata,
+	}
+	if err := alertStore.Create(ctx, alert); err != nil {
+		return err
+	}
+
+	d.logger.Warn().
+		Str("repo", repo.FullName).
+		Str("pattern", p.kind).
+		Str("author", first.AuthorEmail).
+		Int("commits", len(p.commits)).
+		Msg("scripted history detected")
+	return nil
+}
+
+// truncateMessage returns the first line of a commit message, shortened
+// for alert descriptions.
+func truncateMessage(message string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	if runes := []rune(line); len(runes) > 72 {
+		return string(runes[:72]) + "…"
+	}
+	return line
+}
@@ -0,0 +1,105 @@
+package detection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/harshpatel5940/gitvigil/internal/models"
+	"github.com/harshpatel5940/gitvigil/internal/rules"
+)
+
+// sharedPeer is one side of a pair of repositories sharing commits.
+type sharedPeer struct {
+	id       int64
+	fullName string
+	eventID  *int64
+}
+
+// CheckSharedHistory looks for commits of an enrolled repository that also
+// appear in other enrolled repositories, as happens with a shared fork or
+// copied history. Each pair of repositories gets a critical shared_history
+// alert on both sides the rule is enabled for, which later checks update as
+// the overlap grows.
+func (d *Detector) CheckSharedHistory(ctx context.Context, repo *models.Repository, s *rules.Settings) error {
+	if repo.EventID == nil {
+		return nil
+	}
+
+	shared, err := models.NewCommitStore(d.db.Pool).ListSharedHistory(ctx, repo.ID)
+	if err != nil {
+		return err
+	}
+
+	self := sharedPeer{id: repo.ID, fullName: repo.FullName, eventID: repo.EventID}
+	var errs []error
+	for _, h := range shared {
+		other := sharedPeer{id: h.RepositoryID, fullName: h.FullName, eventID: h.EventID}
+		if err := d.raiseSharedHistory(ctx, self, other, h, s); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", repo.FullName, err))
+		}
+
+		otherSettings, err := d.rules.Settings(ctx, RuleSharedHistory, other.id)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", other.fullName, err))
+			continue
+		}
+		if !otherSettings.Enabled {
+			continue
+		}
+		if err := d.raiseSharedHistory(ctx, other, self, h, otherSettings); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", other.fullName, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// raiseSharedHistory creates or refreshes repo's alert about the commits it
+// shares with other.
+func (d *Detector) raiseSharedHistory(ctx context.Context, repo, other sharedPeer, h *models.SharedHistory, s *rules.Settings) error {
+	sameEvent := repo.eventID != nil && other.eventID != nil && *repo.eventID == *other.eventID
+	metadata := map[string]interface{}{
+		"other_repository_id":  other.id,
+		"other_repository":     other.fullName,
+		"same_event":           sameEvent,
+		"shared_commits":       h.SharedCommits,
+		"commits":              h.SampleSHAs,
+		"earliest_author_date": h.EarliestAuthor,
+	}
+
+	alertStore := models.NewAlertStore(d.db.Pool)
+	existing, err := alertStore.FindByMetadata(ctx, repo.id, models.AlertSharedHistory, "other_repository_id", strconv.FormatInt(other.id, 10))
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		// JSON numbers decode as float64
+		if count, _ := existing.Metadata["shared_commits"].(float64); int(count) == h.SharedCommits {
+			return nil
+		}
+		return alertStore.MergeMetadata(ctx, existing.ID, nil, metadata)
+	}
+
+	alert := &models.Alert{
+		RepositoryID: repo.id,
+		AlertType:    models.AlertSharedHistory,
+		Severity:     s.SeverityOr(models.SeverityCritical),
+		Title:        "Commit history shared with another repository",
+		Description:  fmt.Sprintf("%d commit(s) also appear in %s", h.SharedCommits, other.fullName),
+		Metadata:     metadata,
+	}
+	if len(h.SampleSHAs) > 0 {
+		alert.CommitSHA = &h.SampleSHAs[0]
+	}
+	if err := alertStore.Create(ctx, alert); err != nil {
+		return err
+	}
+
+	d.logger.Warn().
+		Str("repo", repo.fullName).
+		Str("other_repo", other.fullName).
+		Int("shared_commits", h.SharedCommits).
+		Msg("shared commit history detected")
+	return nil
+}
@@ -0,0 +1,415 @@
+package detection
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v68/github"
+	"github.com/harshpatel5940/gitvigil/internal/analysis"
+	ghclient "github.com/harshpatel5940/gitvigil/internal/github"
+	"github.com/harshpatel5940/gitvigil/internal/models"
+	"github.com/harshpatel5940/gitvigil/internal/rules"
+)
+
+const (
+	// maxSnapshotFileBytes skips generated bundles and data files.
+	maxSnapshotFileBytes = 256 << 10
+	// maxArchiveBytes caps how much of a repository tarball is read; the
+	// files before the cap make up a truncated snapshot.
+	maxArchiveBytes = 200 << 20
+	// topSimilarFiles is how many matching file pairs each pair keeps.
+	topSimilarFiles = 10
+)
+
+// ignoredDirs, ignoredFiles and generatedSuffixes hold dependencies, build
+// output, lock files and generated code, which teams share without copying
+// each other and which are not written by hand. They are left out of code
+// similarity and code dump line counts.
+var (
+	ignoredDirs = map[string]bool{
+		".git": true, "node_modules": true, "vendor": true, "dist": true, "build": true,
+		"target": true, "out": true, ".next": true, "__pycache__": true, ".venv": true, "venv": true,
+		"third_party": true, "bower_components": true, "coverage": true, "generated": true,
+	}
+	ignoredFiles = map[string]bool{
+		"package-lock.json": true, "yarn.lock": true, "pnpm-lock.yaml": true, "bun.lockb": true,
+		"go.sum": true, "cargo.lock": true, "poetry.lock": true, "pipfile.lock": true, "composer.lock": true, "gemfile.lock": true,
+	}
+	generatedSuffixes = []string{
+		".min.js", ".min.css", ".map", ".pb.go", "_pb2.py", ".g.dart", ".generated.ts", ".generated.cs", "_generated.go",
+	}
+)
+
+var archiveClient = &http.Client{Timeout: 5 * time.Minute}
+
+// AnalyzeSimilarity fingerprints the source of every frozen submission the
+// rule is enabled for and not yet snapshotted, then recomputes the pairwise
+// similarity of each event with new snapshots. Each repository of a pair at
+// or above its threshold_percent gets a code_similarity alert. A rate limit
+// stops the snapshotting early; the next run resumes it. Other failures are
+// recorded so the submission is retried with backoff.
+func (d *Detector) AnalyzeSimilarity(ctx context.Context, e *rules.Engine) error {
+	store := models.NewSimilarityStore(d.db.Pool)
+	pending, err := store.ListPending(ctx)
+	if err != nil {
+		return err
+	}
+
+	settings := &similaritySettings{engine: e, cache: make(map[int64]*rules.Settings)}
+	var errs []error
+	for _, p := range pending {
+		s, err := settings.get(ctx, p.RepositoryID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", p.FullName, err))
+			continue
+		}
+		if !s.Enabled {
+			continue
+		}
+		if err := d.snapshotSubmission(ctx, p); err != nil {
+			if _, limited := ghclient.RateLimitDelay(err); limited {
+				errs = append(errs, fmt.Errorf("%s: %w", p.FullName, err))
+				break
+			}
+			d.logger.Error().Err(err).Str("repo", p.FullName).Msg("failed to snapshot submission")
+			errs = append(errs, fmt.Errorf("%s: %w", p.FullName, err))
+			if err := store.RecordFailure(ctx, p, err.Error()); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", p.FullName, err))
+			}
+		}
+	}
+
+	events, err := store.ListStaleEvents(ctx)
+	if err != nil {
+		return errors.Join(append(errs, err)...)
+	}
+	for _, eventID := range events {
+		if err := d.compareEvent(ctx, eventID, settings); err != nil {
+			d.logger.Error().Err(err).Int64("event_id", eventID).Msg("failed to compute code similarity")
+			errs = append(errs, fmt.Errorf("event %d: %w", eventID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// similaritySettings caches the code_similarity settings of the repositories
+// seen during a run.
+type similaritySettings struct {
+	engine *rules.Engine
+	cache  map[int64]*rules.Settings
+}
+
+func (c *similaritySettings) get(ctx context.Context, repoID int64) (*rules.Settings, error) {
+	if s, ok := c.cache[repoID]; ok {
+		return s, nil
+	}
+	s, err := c.engine.Settings(ctx, RuleCodeSimilarity, repoID)
+	if err != nil {
+		return nil, err
+	}
+	c.cache[repoID] = s
+	return s, nil
+}
+
+// snapshotSubmission downloads the submitted commit's tarball and stores the
+// fingerprints of its source files.
+func (d *Detector) snapshotSubmission(ctx context.Context, p *models.PendingSnapshot) error {
+	client, err := d.gh.GetInstallationClient(p.InstallationID)
+	if err != nil {
+		return err
+	}
+
+	link, _, err := client.Repositories.GetArchiveLink(ctx, p.Owner, p.Name, github.Tarball, &github.RepositoryContentGetOptions{Ref: p.HeadSHA}, 1)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := archiveClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("archive download failed: %s", resp.Status)
+	}
+
+	files, truncated, err := fingerprintTarball(resp.Body, maxArchiveBytes)
+	if err != nil {
+		return err
+	}
+
+	snap := &models.CodeSnapshot{
+		EventID:      p.EventID,
+		RepositoryID: p.RepositoryID,
+		Ref:          p.Ref,
+		HeadSHA:      p.HeadSHA,
+		Truncated:    truncated,
+	}
+	if err := models.NewSimilarityStore(d.db.Pool).SaveSnapshot(ctx, snap, files); err != nil {
+		return err
+	}
+
+	d.logger.Info().
+		Str("repo", p.FullName).
+		Str("sha", p.HeadSHA).
+		Int("files", snap.Files).
+		Int("fingerprints", snap.Fingerprints).
+		Bool("truncated", snap.Truncated).
+		Msg("submission snapshot taken")
+	return nil
+}
+
+// errArchiveTooLarge stops reading an archive at its size cap.
+var errArchiveTooLarge = errors.New("archive exceeds the size cap")
+
+// cappedReader reads at most n bytes, then fails with errArchiveTooLarge
+// and remembers that it did, since gzip and tar may wrap the error.
+type cappedReader struct {
+	r      io.Reader
+	n      int64
+	capped bool
+}
+
+func (c *cappedReader) Read(p []byte) (int, error) {
+	if c.n <= 0 {
+		c.capped = true
+		return 0, errArchiveTooLarge
+	}
+	if int64(len(p)) > c.n {
+		p = p[:c.n]
+	}
+	n, err := c.r.Read(p)
+	c.n -= int64(n)
+	return n, err
+}
+
+// fingerprintTarball fingerprints the source files of a GitHub tarball,
+// whose entries all sit under one top-level directory. Reading stops after
+// limit bytes; the files complete by then are returned and reported as
+// truncated.
+func fingerprintTarball(r io.Reader, limit int64) ([]*models.SnapshotFile, bool, error) {
+	cr := &cappedReader{r: r, n: limit}
+	gz, err := gzip.NewReader(cr)
+	if err != nil {
+		return nil, false, err
+	}
+	defer gz.Close()
+
+	var files []*models.SnapshotFile
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if cr.capped {
+			return files, true, nil
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		if hdr.Typeflag != tar.TypeReg || hdr.Size > maxSnapshotFileBytes {
+			continue
+		}
+
+		_, name, ok := strings.Cut(hdr.Name, "/")
+		if !ok || !isSourceFile(name) {
+			continue
+		}
+
+		content, err := io.ReadAll(tr)
+		if cr.capped {
+			return files, true, nil
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		// Binary files contain NUL bytes early on
+		if bytes.IndexByte(content[:min(len(content), 8000)], 0) >= 0 {
+			continue
+		}
+
+		hashes := analysis.Fingerprint(content)
+		if len(hashes) == 0 {
+			continue
+		}
+		fingerprints := make([]int64, len(hashes))
+		for i, h := range hashes {
+			fingerprints[i] = int64(h)
+		}
+		files = append(files, &models.SnapshotFile{Path: name, Fingerprints: fingerprints})
+	}
+	return files, false, nil
+}
+
+func isSourceFile(name string) bool {
+	dirs := strings.Split(path.Dir(name), "/")
+	for _, dir := range dirs {
+		if ignoredDirs[strings.ToLower(dir)] {
+			return false
+		}
+	}
+	base := strings.ToLower(path.Base(name))
+	if ignoredFiles[base] {
+		return false
+	}
+	for _, suffix := range generatedSuffixes {
+		if strings.HasSuffix(base, suffix) {
+			return false
+		}
+	}
+	return true
+}
+
+// compareEvent recomputes the pairwise similarity of an event's snapshots
+// and raises alerts on each side of a pair at or above that repository's
+// threshold.
+func (d *Detector) compareEvent(ctx context.Context, eventID int64, settings *similaritySettings) error {
+	store := models.NewSimilarityStore(d.db.Pool)
+	files, err := store.ListFiles(ctx, eventID)
+	if err != nil {
+		return err
+	}
+
+	// Files arrive ordered by repository
+	var snapshots []analysis.SnapshotFingerprints
+	for _, f := range files {
+		if len(snapshots) == 0 || snapshots[len(snapshots)-1].RepositoryID != f.RepositoryID {
+			snapshots = append(snapshots, analysis.SnapshotFingerprints{RepositoryID: f.RepositoryID})
+		}
+		hashes := make([]uint64, len(f.Fingerprints))
+		for i, h := range f.Fingerprints {
+			hashes[i] = uint64(h)
+		}
+		last := &snapshots[len(snapshots)-1]
+		last.Files = append(last.Files, analysis.FileFingerprints{Path: f.Path, Hashes: hashes})
+	}
+
+	results := analysis.CompareSnapshots(snapshots, topSimilarFiles)
+	pairs := make([]*models.SimilarityPair, 0, len(results))
+	for _, r := range results {
+		pair := &models.SimilarityPair{
+			EventID:            eventID,
+			RepositoryAID:      r.RepositoryA,
+			RepositoryBID:      r.RepositoryB,
+			Similarity:         roundPercent(r.Similarity),
+			Jaccard:            roundPercent(r.Jaccard),
+			SharedFingerprints: r.Shared,
+			TopFiles:           make([]models.SimilarFile, 0, len(r.TopFiles)),
+		}
+		for _, f := range r.TopFiles {
+			pair.TopFiles = append(pair.TopFiles, models.SimilarFile{
+				FileA:      f.FileA,
+				FileB:      f.FileB,
+				Shared:     f.Shared,
+				Similarity: roundPercent(f.Similarity),
+			})
+		}
+		pairs = append(pairs, pair)
+	}
+	if err := store.ReplacePairs(ctx, eventID, pairs); err != nil {
+		return err
+	}
+
+	stored, err := store.ListPairs(ctx, eventID, 0)
+	if err != nil {
+		return err
+	}
+
+	flagged := 0
+	var errs []error
+	for _, p := range stored {
+		sides := []struct {
+			repoID, otherID int64
+			other           string
+		}{
+			{p.RepositoryAID, p.RepositoryBID, p.RepositoryB},
+			{p.RepositoryBID, p.RepositoryAID, p.RepositoryA},
+		}
+		raised := false
+		for _, side := range sides {
+			s, err := settings.get(ctx, side.repoID)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if !s.Enabled || p.Similarity < s.Float("threshold_percent") {
+				continue
+			}
+			raised = true
+			if err := d.raiseCodeSimilarity(ctx, side.repoID, side.otherID, side.other, p, s); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if raised {
+			flagged++
+		}
+	}
+
+	d.logger.Info().
+		Int64("event_id", eventID).
+		Int("repositories", len(snapshots)).
+		Int("pairs", len(pairs)).
+		Int("flagged", flagged).
+		Msg("code similarity computed")
+	return errors.Join(errs...)
+}
+
+// raiseCodeSimilarity creates or refreshes repoID's alert about its
+// similarity to otherID.
+func (d *Detector) raiseCodeSimilarity(ctx context.Context, repoID, otherID int64, other string, p *models.SimilarityPair, s *rules.Settings) error {
+	// Show the files from this repository's side first
+	files := make([]models.SimilarFile, 0, len(p.TopFiles))
+	for _, f := range p.TopFiles {
+		if repoID == p.RepositoryBID {
+			f.FileA, f.FileB = f.FileB, f.FileA
+		}
+		files = append(files, f)
+	}
+
+	metadata := map[string]interface{}{
+		"event_id":            p.EventID,
+		"other_repository_id": otherID,
+		"other_repository":    other,
+		"similarity":          p.Similarity,
+		"jaccard":             p.Jaccard,
+		"shared_fingerprints": p.SharedFingerprints,
+		"threshold":           s.Float("threshold_percent"),
+		"top_files":           files,
+	}
+
+	alertStore := models.NewAlertStore(d.db.Pool)
+	existing, err := alertStore.FindByMetadata(ctx, repoID, models.AlertCodeSimilarity, "other_repository_id", strconv.FormatInt(otherID, 10))
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		if similarity, _ := existing.Metadata["similarity"].(float64); similarity == p.Similarity {
+			return nil
+		}
+		return alertStore.MergeMetadata(ctx, existing.ID, nil, metadata)
+	}
+
+	return alertStore.Create(ctx, &models.Alert{
+		RepositoryID: repoID,
+		AlertType:    models.AlertCodeSimilarity,
+		Severity:     s.SeverityOr(models.SeverityCritical),
+		Title:        "Source code similar to another team's",
+		Description:  fmt.Sprintf("%.0f%% of the submitted code matches %s", p.Similarity, other),
+		Metadata:     metadata,
+	})
+}
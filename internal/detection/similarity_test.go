@@ -0,0 +1,108 @@
+package detection
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"math/rand"
+	"testing"
+)
+
+// tarball builds a gzipped tarball in GitHub's layout, every entry under
+// one top-level directory.
+func tarball(t *testing.T, files map[string][]byte, order []string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, name := range order {
+		content := files[name]
+		hdr := &tar.Header{Name: "owner-repo-abc123/" + name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// randomSource returns n bytes of barely compressible text.
+func randomSource(rng *rand.Rand, n int) []byte {
+	const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789(){};=+-*/ \n"
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = letters[rng.Intn(len(letters))]
+	}
+	return b
+}
+
+func TestFingerprintTarballLimit(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	files := map[string][]byte{
+		"main.go":              randomSource(rng, 4<<10),
+		"node_modules/x/y.js":  randomSource(rng, 4<<10),
+		"src/big.go":           randomSource(rng, maxSnapshotFileBytes-1),
+		"assets/logo.png":      append([]byte{0x89, 'P', 'N', 'G', 0}, randomSource(rng, 1<<10)...),
+		"src/after_the_cap.go": randomSource(rng, 4<<10),
+	}
+	archive := tarball(t, files, []string{"main.go", "node_modules/x/y.js", "assets/logo.png", "src/big.go", "src/after_the_cap.go"})
+
+	tests := []struct {
+		name      string
+		limit     int64
+		want      []string
+		truncated bool
+	}{
+		{"whole archive", int64(len(archive)) + 1024, []string{"main.go", "src/big.go", "src/after_the_cap.go"}, false},
+		{"cut inside a file", int64(len(archive)) / 2, []string{"main.go"}, true},
+		{"cut at the start", 64, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, truncated, err := fingerprintTarball(bytes.NewReader(archive), tt.limit)
+			if err != nil {
+				t.Fatalf("fingerprintTarball: %v", err)
+			}
+			if truncated != tt.truncated {
+				t.Errorf("truncated = %v, want %v", truncated, tt.truncated)
+			}
+			var paths []string
+			for _, f := range got {
+				if len(f.Fingerprints) == 0 {
+					t.Errorf("%s has no fingerprints", f.Path)
+				}
+				paths = append(paths, f.Path)
+			}
+			if len(paths) != len(tt.want) {
+				t.Fatalf("files = %v, want %v", paths, tt.want)
+			}
+			for i := range paths {
+				if paths[i] != tt.want[i] {
+					t.Errorf("files = %v, want %v", paths, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestFingerprintTarballCorrupt(t *testing.T) {
+	archive := tarball(t, map[string][]byte{"main.go": randomSource(rand.New(rand.NewSource(2)), 8<<10)}, []string{"main.go"})
+
+	// A broken archive is an error, not a truncated snapshot
+	corrupt := append([]byte{}, archive[:len(archive)/2]...)
+	if _, truncated, err := fingerprintTarball(bytes.NewReader(corrupt), maxArchiveBytes); err == nil || truncated {
+		t.Errorf("short archive: truncated = %v, err = %v, want an error", truncated, err)
+	}
+	if _, _, err := fingerprintTarball(bytes.NewReader([]byte("not a tarball")), maxArchiveBytes); err == nil {
+		t.Error("garbage: want an error")
+	}
+}
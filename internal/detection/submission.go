@@ -0,0 +1,38 @@
+package detection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/harshpatel5940/gitvigil/internal/models"
+)
+
+// FreezeSubmissions records the submission of every enrolled repository
+// whose event deadline plus the grace period has passed: the head of each
+// branch as of the cutoff, taken from the pushes received by then.
+func (d *Detector) FreezeSubmissions(ctx context.Context) error {
+	store := models.NewSubmissionStore(d.db.Pool)
+	due, err := store.ListDue(ctx, d.cfg.SubmissionGraceMinutes, time.Now())
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, sub := range due {
+		branches, err := store.Freeze(ctx, sub.EventID, sub.RepositoryID, sub.CutoffAt)
+		if err != nil {
+			d.logger.Error().Err(err).Str("repo", sub.FullName).Msg("failed to freeze submission")
+			errs = append(errs, fmt.Errorf("%s: %w", sub.FullName, err))
+			continue
+		}
+		d.logger.Info().
+			Str("repo", sub.FullName).
+			Int64("event_id", sub.EventID).
+			Int("branches", branches).
+			Time("cutoff", sub.CutoffAt).
+			Msg("submission frozen")
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,285 @@
+package detection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/harshpatel5940/gitvigil/internal/models"
+	"github.com/harshpatel5940/gitvigil/internal/rules"
+)
+
+// Timezone anomalies, stored in the alert's anomaly metadata with the
+// contributor they concern.
+const (
+	anomalyOffsetSwitch = "offset_switch"
+	anomalyOffSite      = "off_site"
+)
+
+// offsetSwitch is a pair of consecutive commits by one author made with
+// clocks set to different UTC offsets.
+type offsetSwitch struct {
+	from, to *models.AuthorTiming
+}
+
+// offSiteAuthor is an author of an on-site event's repository committing
+// from outside the event's time zone.
+type offSiteAuthor struct {
+	email   string
+	offsets map[int]int
+	commits []*models.AuthorTiming
+}
+
+// CheckTimezones compares the UTC offsets commits were authored with, as
+// kept in push payloads, against each contributor's typical offset. A
+// contributor whose offset changes by switch_min_minutes or more between
+// commits less than switch_window_hours apart is committing from more than
+// one machine or place, which gets an offset_switch alert. For on-site
+// events, offsite_min_commits or more commits made during the event with an
+// offset more than offsite_tolerance_minutes from the event's time zone get
+// an off_site alert. Containers and cloud editors default to UTC, so the
+// switch check ignores UTC commits by authors who usually commit with
+// another offset; UTC commits still count as off-site for an event
+// elsewhere.
+func (d *Detector) CheckTimezones(ctx context.Context, repo *models.Repository, s *rules.Settings) error {
+	commitStore := models.NewCommitStore(d.db.Pool)
+	profiles, err := commitStore.ListOffsetProfiles(ctx, repo.ID)
+	if err != nil || len(profiles) == 0 {
+		return err
+	}
+	timings, err := commitStore.ListAuthorTimings(ctx, repo.ID)
+	if err != nil {
+		return err
+	}
+	event, err := models.NewEventStore(d.db.Pool).GetForRepository(ctx, repo)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	var offSite []*offSiteAuthor
+	for _, commits := range groupByAuthor(timings) {
+		profile := profiles[strings.ToLower(commits[0].AuthorEmail)]
+		if profile == nil || d.isBotAuthor(commits[0]) {
+			continue
+		}
+
+		if switches := offsetSwitches(commits, profile, s); len(switches) > 0 {
+			if err := d.raiseOffsetSwitch(ctx, repo, profile, switches, s); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if event != nil && event.OnSite {
+			if a := offSiteCommits(commits, event, s); a != nil {
+				offSite = append(offSite, a)
+			}
+		}
+	}
+
+	total := 0
+	for _, a := range offSite {
+		total += len(a.commits)
+	}
+	if total > 0 && total >= s.Int("offsite_min_commits") {
+		if err := d.raiseOffSite(ctx, repo, event, offSite, total, s); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// offsetSwitches returns the consecutive commits of one author whose UTC
+// offsets differ by switch_min_minutes or more within switch_window_hours.
+// Commits of unknown offset are skipped. So are UTC commits when the author
+// usually commits with another offset, so moving between a laptop and a
+// cloud editor is not a switch; authors who really work in UTC are checked
+// like any other.
+func offsetSwitches(commits []*models.AuthorTiming, profile *models.OffsetProfile, s *rules.Settings) []*offsetSwitch {
+	minShift := s.Int("switch_min_minutes")
+	window := time.Duration(s.Int("switch_window_hours")) * time.Hour
+
+	var switches []*offsetSwitch
+	var prev *models.AuthorTiming
+	for _, c := range commits {
+		if c.UTCOffset == nil || (*c.UTCOffset == 0 && profile.Typical != 0) {
+			continue
+		}
+		if prev != nil && abs(*c.UTCOffset-*prev.UTCOffset) >= minShift && c.AuthorDate.Sub(prev.AuthorDate) < window {
+			switches = append(switches, &offsetSwitch{from: prev, to: c})
+		}
+		prev = c
+	}
+	return switches
+}
+
+// offSiteCommits returns an author's commits made during an on-site event
+// from outside its time zone, or nil when there are none.
+func offSiteCommits(commits []*models.AuthorTiming, event *models.Event, s *rules.Settings) *offSiteAuthor {
+	loc := event.Location()
+	tolerance := s.Int("offsite_tolerance_minutes")
+
+	var a *offSiteAuthor
+	for _, c := range commits {
+		if c.UTCOffset == nil || !event.Contains(c.AuthorDate) {
+			continue
+		}
+		_, expected := c.AuthorDate.In(loc).Zone()
+		if abs(*c.UTCOffset-expected/60) <= tolerance {
+			continue
+		}
+		if a == nil {
+			a = &offSiteAuthor{email: c.AuthorEmail, offsets: make(map[int]int)}
+		}
+		a.offsets[*c.UTCOffset]++
+		a.commits = append(a.commits, c)
+	}
+	return a
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// offsetCounts formats an offset histogram for alert metadata.
+func offsetCounts(offsets map[int]int) map[string]int {
+	counts := make(map[string]int, len(offsets))
+	for offset, n := range offsets {
+		counts[models.FormatUTCOffset(offset)] = n
+	}
+	return counts
+}
+
+// raiseOffsetSwitch creates the alert for a contributor switching UTC
+// offsets, or refreshes it as more switches arrive.
+func (d *Detector) raiseOffsetSwitch(ctx context.Context, repo *models.Repository, profile *models.OffsetProfile, switches []*offsetSwitch, s *rules.Settings) error {
+	key := anomalyOffsetSwitch + ":" + profile.AuthorEmail
+	severity := s.SeverityOr(models.SeverityWarning)
+
+	evidence := make([]map[string]interface{}, 0, min(len(switches), maxEvidence))
+	for _, sw := range switches[:min(len(switches), maxEvidence)] {
+		evidence = append(evidence, map[string]interface{}{
+			"from_sha":      sw.from.SHA,
+			"from_offset":   models.FormatUTCOffset(*sw.from.UTCOffset),
+			"to_sha":        sw.to.SHA,
+			"to_offset":     models.FormatUTCOffset(*sw.to.UTCOffset),
+			"to_date":       sw.to.AuthorDate,
+			"minutes_apart": int(sw.to.AuthorDate.Sub(sw.from.AuthorDate).Minutes()),
+		})
+	}
+
+	metadata := map[string]interface{}{
+		"anomaly":        key,
+		"author_email":   switches[0].to.AuthorEmail,
+		"author_name":    switches[0].to.AuthorName,
+		"switches":       len(switches),
+		"typical_offset": models.FormatUTCOffset(profile.Typical),
+		"offsets":        offsetCounts(profile.Offsets),
+		"evidence":       evidence,
+	}
+
+	alertStore := models.NewAlertStore(d.db.Pool)
+	existing, err := alertStore.FindByMetadata(ctx, repo.ID, models.AlertTimezoneAnomaly, "anomaly", key)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		// JSON numbers decode as float64
+		if count, _ := existing.Metadata["switches"].(float64); int(count) == len(switches) {
+			return nil
+		}
+		return alertStore.MergeMetadata(ctx, existing.ID, &severity, metadata)
+	}
+
+	sha := switches[0].to.SHA
+	alert := &models.Alert{
+		RepositoryID: repo.ID,
+		CommitSHA:    &sha,
+		AlertType:    models.AlertTimezoneAnomaly,
+		Severity:     severity,
+		Title:        "Contributor switched time zones",
+		Description: fmt.Sprintf("%s switched UTC offsets %d time(s) between commits less than %d hour(s) apart; usually %s",
+			switches[0].to.AuthorEmail, len(switches), s.Int("switch_window_hours"), models.FormatUTCOffset(profile.Typical)),
+		Metadata: metadata,
+	}
+	if err := alertStore.Create(ctx, alert); err != nil {
+		return err
+	}
+
+	d.logger.Warn().
+		Str("repo", repo.FullName).
+		Str("author", profile.AuthorEmail).
+		Int("switches", len(switches)).
+		Msg("time zone switch detected")
+	return nil
+}
+
+// raiseOffSite creates the repository's off_site alert, or refreshes it as
+// more off-site commits arrive.
+func (d *Detector) raiseOffSite(ctx context.Context, repo *models.Repository, event *models.Event, authors []*offSiteAuthor, total int, s *rules.Settings) error {
+	sort.Slice(authors, func(i, j int) bool { return len(authors[i].commits) > len(authors[j].commits) })
+
+	var shas []string
+	contributors := make([]map[string]interface{}, 0, len(authors))
+	for _, a := range authors {
+		contributors = append(contributors, map[string]interface{}{
+			"author_email": a.email,
+			"commits":      len(a.commits),
+			"offsets":      offsetCounts(a.offsets),
+		})
+		for _, c := range a.commits {
+			if len(shas) < maxEvidence {
+				shas = append(shas, c.SHA)
+			}
+		}
+	}
+
+	severity := s.SeverityOr(models.SeverityWarning)
+	metadata := map[string]interface{}{
+		"anomaly":           anomalyOffSite,
+		"event_id":          event.ID,
+		"event_timezone":    event.Timezone,
+		"commits":           total,
+		"tolerance_minutes": s.Int("offsite_tolerance_minutes"),
+		"contributors":      contributors,
+		"shas":              shas,
+	}
+
+	alertStore := models.NewAlertStore(d.db.Pool)
+	existing, err := alertStore.FindByMetadata(ctx, repo.ID, models.AlertTimezoneAnomaly, "anomaly", anomalyOffSite)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		if count, _ := existing.Metadata["commits"].(float64); int(count) == total {
+			return nil
+		}
+		return alertStore.MergeMetadata(ctx, existing.ID, &severity, metadata)
+	}
+
+	alert := &models.Alert{
+		RepositoryID: repo.ID,
+		CommitSHA:    &shas[0],
+		AlertType:    models.AlertTimezoneAnomaly,
+		Severity:     severity,
+		Title:        "Commits from outside the event's time zone",
+		Description: fmt.Sprintf("%d commit(s) by %d contributor(s) during the on-site event were made outside %s",
+			total, len(authors), event.Timezone),
+		Metadata: metadata,
+	}
+	if err := alertStore.Create(ctx, alert); err != nil {
+		return err
+	}
+
+	d.logger.Warn().
+		Str("repo", repo.FullName).
+		Int("commits", total).
+		Int("contributors", len(authors)).
+		Msg("off-site commits detected")
+	return nil
+}
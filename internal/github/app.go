@@ -1,8 +1,10 @@
 package github
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/bradleyfalzon/ghinstallation/v2"
 	"github.com/google/go-github/v68/github"
@@ -51,3 +53,37 @@ func (a *AppClient) AppClient() *github.Client {
 func (a *AppClient) AppID() int64 {
 	return a.appID
 }
+
+// RateLimitDelay reports how long to wait before retrying a request that
+// failed because of a primary or secondary rate limit.
+func RateLimitDelay(err error) (time.Duration, bool) {
+	var rateErr *github.RateLimitError
+	if errors.As(err, &rateErr) {
+		delay := time.Until(rateErr.Rate.Reset.Time)
+		if delay < time.Minute {
+			delay = time.Minute
+		}
+		return delay, true
+	}
+
+	var abuseErr *github.AbuseRateLimitError
+	if errors.As(err, &abuseErr) {
+		if delay := abuseErr.GetRetryAfter(); delay > 0 {
+			return delay, true
+		}
+		return time.Minute, true
+	}
+
+	return 0, false
+}
+
+// IsNotFound reports whether err is a GitHub 404 or 422 response, which is
+// what the API returns for commits and refs that no longer exist.
+func IsNotFound(err error) bool {
+	var respErr *github.ErrorResponse
+	if errors.As(err, &respErr) && respErr.Response != nil {
+		return respErr.Response.StatusCode == http.StatusNotFound ||
+			respErr.Response.StatusCode == http.StatusUnprocessableEntity
+	}
+	return false
+}
@@ -2,8 +2,10 @@ package models
 
 import (
 	"context"
+	"errors"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -15,7 +17,44 @@ const (
 	AlertForcePush          AlertType = "force_push"
 	AlertNoLicense          AlertType = "no_license"
 	AlertStreakAtRisk       AlertType = "streak_at_risk"
+	AlertStreakInactive     AlertType = "streak_inactive"
+	AlertStreakRecovered    AlertType = "streak_recovered"
 	AlertNonConventional    AlertType = "non_conventional_commit"
+	// AlertUnregisteredContributor flags commits by someone outside the team roster.
+	AlertUnregisteredContributor AlertType = "unregistered_contributor"
+	// AlertPostDeadlinePush flags a push received after the submission freeze.
+	AlertPostDeadlinePush AlertType = "post_deadline_push"
+	// AlertPreEventCode flags history written before the event started.
+	AlertPreEventCode AlertType = "pre_event_code"
+	// AlertSharedHistory flags commits that also appear in another enrolled
+	// repository.
+	AlertSharedHistory AlertType = "shared_history"
+	// AlertExternalOrigin flags a repository forked or generated from a
+	// repository outside its event.
+	AlertExternalOrigin AlertType = "external_origin"
+	// AlertCodeSimilarity flags submitted source matching another repository
+	// of the same event.
+	AlertCodeSimilarity AlertType = "code_similarity"
+	// AlertFutureDated flags a commit dated after the push that delivered it.
+	AlertFutureDated AlertType = "future_dated"
+	// AlertRewrittenCommits flags commits rebased or amended long after they
+	// were authored.
+	AlertRewrittenCommits AlertType = "rewritten_commits"
+	// AlertBulkDateRewrite flags many commits committed at the same instant
+	// with spread out author dates, as left by scripted date rewrites.
+	AlertBulkDateRewrite AlertType = "bulk_date_rewrite"
+	// AlertCustomRule is raised by an organizer-defined rule; its metadata
+	// names the rule.
+	AlertCustomRule AlertType = "custom_rule"
+	// AlertCodeDump flags a commit or push adding far more code than the
+	// repository and its event usually see at once.
+	AlertCodeDump AlertType = "code_dump"
+	// AlertScriptedHistory flags commit timing that looks generated by a
+	// script: clockwork spacing, bursts within seconds or repeated messages.
+	AlertScriptedHistory AlertType = "scripted_history"
+	// AlertTimezoneAnomaly flags a contributor switching UTC offsets or an
+	// on-site team committing from outside the event's time zone.
+	AlertTimezoneAnomaly AlertType = "timezone_anomaly"
 )
 
 type Severity string
@@ -119,3 +158,109 @@ func (s *AlertStore) Acknowledge(ctx context.Context, id int64) error {
 	`, id)
 	return err
 }
+
+// MergeMetadata adds keys to an alert's metadata, overwriting existing ones,
+// and raises its severity when severity is non-nil.
+func (s *AlertStore) MergeMetadata(ctx context.Context, id int64, severity *Severity, metadata map[string]interface{}) error {
+	_, err := s.pool.Exec(ctx, `
+		UPDATE alerts SET
+			metadata = COALESCE(metadata, '{}'::jsonb) || $3::jsonb,
+			severity = COALESCE($2, severity)
+		WHERE id = $1
+	`, id, severity, metadata)
+	return err
+}
+
+// FindByMetadata returns the most recent alert of the given type whose
+// metadata has key set to value, or nil when there is none. Detectors that
+// raise one alert per subject use it to update that alert instead of raising
+// another.
+func (s *AlertStore) FindByMetadata(ctx context.Context, repoID int64, alertType AlertType, key, value string) (*Alert, error) {
+	var a Alert
+	err := s.pool.QueryRow(ctx, `
+		SELECT id, repository_id, commit_sha, push_event_id, alert_type, severity,
+		       title, description, metadata, acknowledged, created_at
+		FROM alerts
+		WHERE repository_id = $1 AND alert_type = $2 AND metadata->>$3 = $4
+		ORDER BY created_at DESC
+		LIMIT 1
+	`, repoID, alertType, key, value).Scan(
+		&a.ID, &a.RepositoryID, &a.CommitSHA, &a.PushEventID, &a.AlertType,
+		&a.Severity, &a.Title, &a.Description, &a.Metadata, &a.Acknowledged, &a.CreatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// FindLatest returns the most recent alert of the given type, or nil when
+// there is none. Detectors that raise one alert per repository use it to
+// update that alert instead of raising another.
+func (s *AlertStore) FindLatest(ctx context.Context, repoID int64, alertType AlertType) (*Alert, error) {
+	var a Alert
+	err := s.pool.QueryRow(ctx, `
+		SELECT id, repository_id, commit_sha, push_event_id, alert_type, severity,
+		       title, description, metadata, acknowledged, created_at
+		FROM alerts
+		WHERE repository_id = $1 AND alert_type = $2
+		ORDER BY created_at DESC
+		LIMIT 1
+	`, repoID, alertType).Scan(
+		&a.ID, &a.RepositoryID, &a.CommitSHA, &a.PushEventID, &a.AlertType,
+		&a.Severity, &a.Title, &a.Description, &a.Metadata, &a.Acknowledged, &a.CreatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// HasUnacknowledged reports whether a repository already has an open alert of
+// the given type, so periodic checks don't raise the same alert every run.
+func (s *AlertStore) HasUnacknowledged(ctx context.Context, repoID int64, alertType AlertType) (bool, error) {
+	var exists bool
+	err := s.pool.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM alerts
+			WHERE repository_id = $1 AND alert_type = $2 AND acknowledged = FALSE
+		)
+	`, repoID, alertType).Scan(&exists)
+	return exists, err
+}
+
+// ListUnacknowledged returns a repository's open alerts of the given type,
+// oldest first, so periodic checks can resolve those that no longer apply.
+func (s *AlertStore) ListUnacknowledged(ctx context.Context, repoID int64, alertType AlertType) ([]*Alert, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, repository_id, commit_sha, push_event_id, alert_type, severity,
+		       title, description, metadata, acknowledged, created_at
+		FROM alerts
+		WHERE repository_id = $1 AND alert_type = $2 AND acknowledged = FALSE
+		ORDER BY created_at
+	`, repoID, alertType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var alerts []*Alert
+	for rows.Next() {
+		var a Alert
+		err := rows.Scan(
+			&a.ID, &a.RepositoryID, &a.CommitSHA, &a.PushEventID, &a.AlertType,
+			&a.Severity, &a.Title, &a.Description, &a.Metadata, &a.Acknowledged, &a.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, &a)
+	}
+	return alerts, rows.Err()
+}
@@ -0,0 +1,161 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ListUncountedFiles returns the files of a repository's enriched commits
+// whose code additions were not counted yet, keyed by commit ID. Commits
+// without files are present with no entries.
+func (s *CommitStore) ListUncountedFiles(ctx context.Context, repoID int64) (map[int64][]*CommitFile, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT c.id, f.filename, COALESCE(f.additions, 0)
+		FROM commits c
+		LEFT JOIN commit_files f ON f.commit_id = c.id
+		WHERE c.repository_id = $1 AND c.enriched_at IS NOT NULL AND c.code_additions IS NULL
+	`, repoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	files := make(map[int64][]*CommitFile)
+	for rows.Next() {
+		var commitID int64
+		var filename *string
+		var additions int
+		if err := rows.Scan(&commitID, &filename, &additions); err != nil {
+			return nil, err
+		}
+		if filename == nil {
+			files[commitID] = nil
+			continue
+		}
+		files[commitID] = append(files[commitID], &CommitFile{CommitID: commitID, Filename: *filename, Additions: additions})
+	}
+	return files, rows.Err()
+}
+
+// SetCodeAdditions stores the counted code additions of commits by ID.
+func (s *CommitStore) SetCodeAdditions(ctx context.Context, counts map[int64]int) error {
+	batch := &pgx.Batch{}
+	for id, n := range counts {
+		batch.Queue(`UPDATE commits SET code_additions = $2 WHERE id = $1`, id, n)
+	}
+	return s.pool.SendBatch(ctx, batch).Close()
+}
+
+// CodeSize is the size of a counted commit.
+type CodeSize struct {
+	SHA           string
+	PushedAt      time.Time
+	Additions     int
+	CodeAdditions int
+	FilesChanged  int
+}
+
+// ListLargeCommits returns a repository's counted commits adding at least
+// minLines lines of code, largest first.
+func (s *CommitStore) ListLargeCommits(ctx context.Context, repoID int64, minLines int) ([]*CodeSize, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT sha, pushed_at, COALESCE(additions, 0), code_additions, COALESCE(files_changed, 0)
+		FROM commits
+		WHERE repository_id = $1 AND code_additions >= $2
+		ORDER BY code_additions DESC, sha
+	`, repoID, minLines)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sizes []*CodeSize
+	for rows.Next() {
+		var c CodeSize
+		if err := rows.Scan(&c.SHA, &c.PushedAt, &c.Additions, &c.CodeAdditions, &c.FilesChanged); err != nil {
+			return nil, err
+		}
+		sizes = append(sizes, &c)
+	}
+	return sizes, rows.Err()
+}
+
+// CodeAdditionMedians are the median code additions per commit of a
+// repository and of the other repositories of its event, with the number
+// of counted commits behind each.
+type CodeAdditionMedians struct {
+	Repository        float64
+	RepositoryCommits int
+	Event             float64
+	EventCommits      int
+}
+
+// GetCodeAdditionMedians returns the median code additions per commit of a
+// repository and, when eventID is set, of the rest of its event.
+func (s *CommitStore) GetCodeAdditionMedians(ctx context.Context, repoID int64, eventID *int64) (*CodeAdditionMedians, error) {
+	var m CodeAdditionMedians
+	err := s.pool.QueryRow(ctx, `
+		SELECT
+			COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY c.code_additions) FILTER (WHERE c.repository_id = $1), 0),
+			COUNT(*) FILTER (WHERE c.repository_id = $1),
+			COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY c.code_additions) FILTER (WHERE c.repository_id <> $1), 0),
+			COUNT(*) FILTER (WHERE c.repository_id <> $1)
+		FROM commits c
+		JOIN repositories r ON r.id = c.repository_id
+		WHERE c.code_additions IS NOT NULL
+		  AND (c.repository_id = $1 OR ($2::bigint IS NOT NULL AND r.event_id = $2))
+	`, repoID, eventID).Scan(&m.Repository, &m.RepositoryCommits, &m.Event, &m.EventCommits)
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// PushAdditions is the code a repository received in one push, against all
+// the code counted in the repository.
+type PushAdditions struct {
+	PushedAt      time.Time
+	Commits       int
+	CodeAdditions int
+	RepoAdditions int
+	SampleSHAs    []string
+}
+
+// ListPushAdditions returns the code additions of each push delivering at
+// least minLines lines of code to a repository, largest first, against all
+// the code counted in the repository now. Commits are grouped into pushes
+// by their push timestamp; backfilled history is left out since it was
+// never pushed while monitored.
+func (s *CommitStore) ListPushAdditions(ctx context.Context, repoID int64, minLines int) ([]*PushAdditions, error) {
+	rows, err := s.pool.Query(ctx, `
+		WITH pushes AS (
+			SELECT pushed_at, COUNT(*) AS commits, SUM(code_additions) AS code_additions,
+			       (array_agg(sha ORDER BY code_additions DESC, sha))[1:$3] AS shas
+			FROM commits
+			WHERE repository_id = $1 AND source = 'push' AND code_additions IS NOT NULL
+			GROUP BY pushed_at
+		)
+		SELECT p.pushed_at, p.commits, p.code_additions,
+		       (SELECT COALESCE(SUM(code_additions), 0) FROM commits WHERE repository_id = $1),
+		       p.shas
+		FROM pushes p
+		WHERE p.code_additions >= $2
+		ORDER BY p.code_additions DESC, p.pushed_at
+	`, repoID, minLines, maxSharedSamples)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pushes []*PushAdditions
+	for rows.Next() {
+		var p PushAdditions
+		if err := rows.Scan(&p.PushedAt, &p.Commits, &p.CodeAdditions, &p.RepoAdditions, &p.SampleSHAs); err != nil {
+			return nil, err
+		}
+		pushes = append(pushes, &p)
+	}
+	return pushes, rows.Err()
+}
@@ -2,8 +2,10 @@ package models
 
 import (
 	"context"
+	"errors"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -19,14 +21,27 @@ type Commit struct {
 	PushedAt          time.Time
 	Additions         int
 	Deletions         int
+	FilesChanged      int
 	IsConventional    bool
 	ConventionalType  *string
 	ConventionalScope *string
 	IsBackdated       bool
 	BackdateHours     *int
+	EnrichedAt        *time.Time
 	CreatedAt         time.Time
 }
 
+type CommitFile struct {
+	ID               int64
+	CommitID         int64
+	Filename         string
+	PreviousFilename *string
+	Status           string
+	Additions        int
+	Deletions        int
+	CreatedAt        time.Time
+}
+
 type CommitStore struct {
 	pool *pgxpool.Pool
 }
@@ -38,9 +53,9 @@ func NewCommitStore(pool *pgxpool.Pool) *CommitStore {
 func (s *CommitStore) ListByRepository(ctx context.Context, repoID int64, limit int) ([]*Commit, error) {
 	rows, err := s.pool.Query(ctx, `
 		SELECT id, repository_id, sha, message, author_email, author_name,
-		       author_date, committer_date, pushed_at, additions, deletions,
+		       author_date, committer_date, pushed_at, additions, deletions, files_changed,
 		       is_conventional, conventional_type, conventional_scope,
-		       is_backdated, backdate_hours, created_at
+		       is_backdated, backdate_hours, enriched_at, created_at
 		FROM commits WHERE repository_id = $1
 		ORDER BY pushed_at DESC
 		LIMIT $2
@@ -55,9 +70,9 @@ func (s *CommitStore) ListByRepository(ctx context.Context, repoID int64, limit
 		var c Commit
 		err := rows.Scan(
 			&c.ID, &c.RepositoryID, &c.SHA, &c.Message, &c.AuthorEmail, &c.AuthorName,
-			&c.AuthorDate, &c.CommitterDate, &c.PushedAt, &c.Additions, &c.Deletions,
+			&c.AuthorDate, &c.CommitterDate, &c.PushedAt, &c.Additions, &c.Deletions, &c.FilesChanged,
 			&c.IsConventional, &c.ConventionalType, &c.ConventionalScope,
-			&c.IsBackdated, &c.BackdateHours, &c.CreatedAt,
+			&c.IsBackdated, &c.BackdateHours, &c.EnrichedAt, &c.CreatedAt,
 		)
 		if err != nil {
 			return nil, err
@@ -89,6 +104,433 @@ func (s *CommitStore) GetStats(ctx context.Context, repoID int64) (*CommitStats,
 	return &stats, nil
 }
 
+// GetStatsBetween is GetStats restricted to commits pushed within a window.
+// Nil bounds leave that side of the window open.
+func (s *CommitStore) GetStatsBetween(ctx context.Context, repoID int64, from, to *time.Time) (*CommitStats, error) {
+	var stats CommitStats
+
+	err := s.pool.QueryRow(ctx, `
+		SELECT
+			COUNT(*) as total_commits,
+			COUNT(*) FILTER (WHERE is_backdated) as backdated_count,
+			COUNT(*) FILTER (WHERE is_conventional) as conventional_count,
+			COALESCE(SUM(additions), 0) as total_additions,
+			COALESCE(SUM(deletions), 0) as total_deletions
+		FROM commits
+		WHERE repository_id = $1
+		  AND ($2::timestamptz IS NULL OR pushed_at >= $2)
+		  AND ($3::timestamptz IS NULL OR pushed_at <= $3)
+	`, repoID, from, to).Scan(
+		&stats.TotalCommits, &stats.BackdatedCount, &stats.ConventionalCount,
+		&stats.TotalAdditions, &stats.TotalDeletions,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &stats, nil
+}
+
+// PreEventStats measures how much of a repository's history was authored
+// before its event started. Commits covered by an approved starter template
+// are counted in ExemptCommits and left out of the pre-event figures.
+type PreEventStats struct {
+	TotalCommits      int
+	PreEventCommits   int
+	ExemptCommits     int
+	TotalAdditions    int64
+	PreEventAdditions int64
+	EarliestAuthorAt  *time.Time
+	// SampleSHAs lists up to maxPreEventSamples pre-event commits, oldest first.
+	SampleSHAs []string
+}
+
+const maxPreEventSamples = 50
+
+// CommitPercent is the share of commits authored before the event.
+func (p *PreEventStats) CommitPercent() float64 {
+	if p.TotalCommits == 0 {
+		return 0
+	}
+	return float64(p.PreEventCommits) * 100 / float64(p.TotalCommits)
+}
+
+// LinePercent is the share of added lines from commits authored before the
+// event. Only enriched commits carry line counts.
+func (p *PreEventStats) LinePercent() float64 {
+	if p.TotalAdditions == 0 {
+		return 0
+	}
+	return float64(p.PreEventAdditions) * 100 / float64(p.TotalAdditions)
+}
+
+// GetPreEventStats compares the author dates of commits pushed up to the end
+// of the window against start. Commits authored at or before exemptBefore,
+// when non-nil, are exempt.
+func (s *CommitStore) GetPreEventStats(ctx context.Context, repoID int64, start time.Time, to, exemptBefore *time.Time) (*PreEventStats, error) {
+	var stats PreEventStats
+	err := s.pool.QueryRow(ctx, `
+		WITH c AS (
+			SELECT sha, author_date, additions,
+			       author_date < $2 AND NOT ($4::timestamptz IS NOT NULL AND author_date <= $4) as pre_event,
+			       author_date < $2 AND $4::timestamptz IS NOT NULL AND author_date <= $4 as exempt
+			FROM commits
+			WHERE repository_id = $1
+			  AND ($3::timestamptz IS NULL OR pushed_at <= $3)
+		)
+		SELECT
+			COUNT(*),
+			COUNT(*) FILTER (WHERE pre_event),
+			COUNT(*) FILTER (WHERE exempt),
+			COALESCE(SUM(additions), 0),
+			COALESCE(SUM(additions) FILTER (WHERE pre_event), 0),
+			MIN(author_date) FILTER (WHERE pre_event),
+			COALESCE((array_agg(sha ORDER BY author_date) FILTER (WHERE pre_event))[1:$5], '{}')
+		FROM c
+	`, repoID, start, to, exemptBefore, maxPreEventSamples).Scan(
+		&stats.TotalCommits, &stats.PreEventCommits, &stats.ExemptCommits,
+		&stats.TotalAdditions, &stats.PreEventAdditions,
+		&stats.EarliestAuthorAt, &stats.SampleSHAs,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// SharedHistory is the set of commits a repository shares with another
+// enrolled repository.
+type SharedHistory struct {
+	RepositoryID   int64
+	FullName       string
+	EventID        *int64
+	SharedCommits  int
+	EarliestAuthor time.Time
+	// SampleSHAs lists up to maxSharedSamples shared commits, oldest first.
+	SampleSHAs []string
+}
+
+const maxSharedSamples = 50
+
+// ListSharedHistory returns, for each other repository enrolled in an event
+// that holds any of the repository's commit SHAs, the commits they share.
+func (s *CommitStore) ListSharedHistory(ctx context.Context, repoID int64) ([]*SharedHistory, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT r.id, r.full_name, r.event_id, COUNT(*), MIN(c.author_date),
+		       (array_agg(c.sha ORDER BY c.author_date, c.sha))[1:$2]
+		FROM commits c
+		JOIN commits o ON o.sha = c.sha AND o.repository_id <> c.repository_id
+		JOIN repositories r ON r.id = o.repository_id
+		WHERE c.repository_id = $1 AND r.event_id IS NOT NULL
+		GROUP BY r.id, r.full_name, r.event_id
+		ORDER BY r.full_name
+	`, repoID, maxSharedSamples)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var shared []*SharedHistory
+	for rows.Next() {
+		var h SharedHistory
+		err := rows.Scan(&h.RepositoryID, &h.FullName, &h.EventID, &h.SharedCommits, &h.EarliestAuthor, &h.SampleSHAs)
+		if err != nil {
+			return nil, err
+		}
+		shared = append(shared, &h)
+	}
+	return shared, rows.Err()
+}
+
+// RewrittenCommits summarizes the commits of a repository whose committer
+// date is well after their author date, as left by a rebase or amend.
+type RewrittenCommits struct {
+	Commits     int
+	MaxGapHours int
+	// SampleSHAs lists up to maxSharedSamples rewritten commits, largest
+	// gap first.
+	SampleSHAs []string
+}
+
+// GetRewrittenCommits counts the repository's commits with a verified
+// committer date more than minGap after their author date.
+func (s *CommitStore) GetRewrittenCommits(ctx context.Context, repoID int64, minGap time.Duration) (*RewrittenCommits, error) {
+	var r RewrittenCommits
+	err := s.pool.QueryRow(ctx, `
+		SELECT COUNT(*),
+		       COALESCE(FLOOR(MAX(EXTRACT(EPOCH FROM (committer_date - author_date))) / 3600), 0)::int,
+		       COALESCE((array_agg(sha ORDER BY committer_date - author_date DESC, sha))[1:$3], '{}')
+		FROM commits
+		WHERE repository_id = $1
+		  AND committer_date_verified
+		  AND committer_date - author_date > $2 * INTERVAL '1 second'
+	`, repoID, minGap.Seconds(), maxSharedSamples).Scan(&r.Commits, &r.MaxGapHours, &r.SampleSHAs)
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// CommitterBurst is a group of commits sharing one committer timestamp.
+type CommitterBurst struct {
+	CommitterDate  time.Time
+	Commits        int
+	EarliestAuthor time.Time
+	LatestAuthor   time.Time
+	SampleSHAs     []string
+}
+
+// ListCommitterBursts returns the groups of at least minCommits commits with
+// the same verified committer timestamp whose author dates span more than
+// minSpread, the mark of history rewritten in one go.
+func (s *CommitStore) ListCommitterBursts(ctx context.Context, repoID int64, minCommits int, minSpread time.Duration) ([]*CommitterBurst, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT committer_date, COUNT(*), MIN(author_date), MAX(author_date),
+		       (array_agg(sha ORDER BY author_date, sha))[1:$4]
+		FROM commits
+		WHERE repository_id = $1 AND committer_date_verified
+		GROUP BY committer_date
+		HAVING COUNT(*) >= $2 AND MAX(author_date) - MIN(author_date) > $3 * INTERVAL '1 second'
+		ORDER BY committer_date
+	`, repoID, minCommits, minSpread.Seconds(), maxSharedSamples)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var bursts []*CommitterBurst
+	for rows.Next() {
+		var b CommitterBurst
+		if err := rows.Scan(&b.CommitterDate, &b.Commits, &b.EarliestAuthor, &b.LatestAuthor, &b.SampleSHAs); err != nil {
+			return nil, err
+		}
+		bursts = append(bursts, &b)
+	}
+	return bursts, rows.Err()
+}
+
+// CommitIdentity is the author and committer identity of a commit.
+type CommitIdentity struct {
+	SHA            string
+	AuthorName     string
+	AuthorEmail    string
+	AuthorLogin    *string
+	CommitterName  *string
+	CommitterEmail *string
+	CommitterLogin *string
+	PushedAt       time.Time
+}
+
+// ListIdentities returns the identities of commits pushed within a window,
+// oldest first.
+func (s *CommitStore) ListIdentities(ctx context.Context, repoID int64, from, to *time.Time) ([]*CommitIdentity, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT sha, author_name, author_email, author_login,
+		       committer_name, committer_email, committer_login, pushed_at
+		FROM commits
+		WHERE repository_id = $1
+		  AND ($2::timestamptz IS NULL OR pushed_at >= $2)
+		  AND ($3::timestamptz IS NULL OR pushed_at <= $3)
+		ORDER BY pushed_at, id
+	`, repoID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var identities []*CommitIdentity
+	for rows.Next() {
+		var c CommitIdentity
+		err := rows.Scan(
+			&c.SHA, &c.AuthorName, &c.AuthorEmail, &c.AuthorLogin,
+			&c.CommitterName, &c.CommitterEmail, &c.CommitterLogin, &c.PushedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		identities = append(identities, &c)
+	}
+	return identities, rows.Err()
+}
+
+// AuthorTiming is a commit's author and author date.
+type AuthorTiming struct {
+	SHA         string
+	AuthorName  string
+	AuthorEmail string
+	AuthorLogin *string
+	AuthorDate  time.Time
+	// UTCOffset is the author's UTC offset in minutes, when known.
+	UTCOffset *int
+	Message   string
+}
+
+// ListAuthorTimings returns a repository's commits grouped by author email
+// and ordered by author date within each author.
+func (s *CommitStore) ListAuthorTimings(ctx context.Context, repoID int64) ([]*AuthorTiming, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT sha, author_name, author_email, author_login, author_date, author_utc_offset, COALESCE(message, '')
+		FROM commits
+		WHERE repository_id = $1
+		ORDER BY LOWER(author_email), author_date, id
+	`, repoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var timings []*AuthorTiming
+	for rows.Next() {
+		var c AuthorTiming
+		if err := rows.Scan(&c.SHA, &c.AuthorName, &c.AuthorEmail, &c.AuthorLogin, &c.AuthorDate, &c.UTCOffset, &c.Message); err != nil {
+			return nil, err
+		}
+		timings = append(timings, &c)
+	}
+	return timings, rows.Err()
+}
+
+// RecordFilter selects stored commits or pushes by repository and by push
+// time. Nil fields leave that criterion open; a Limit of zero means no limit.
+type RecordFilter struct {
+	RepositoryID *int64
+	From, To     *time.Time
+	Limit        int
+}
+
+// CommitRecord is a stored commit with every field rules can read.
+type CommitRecord struct {
+	RepositoryID   int64
+	FullName       string
+	SHA            string
+	Message        string
+	AuthorName     string
+	AuthorEmail    string
+	AuthorLogin    string
+	AuthorDate     time.Time
+	CommitterName  string
+	CommitterEmail string
+	CommitterLogin string
+	CommitterDate  time.Time
+	PushedAt       time.Time
+	Source         string
+	DatesVerified  bool
+	BackdateHours  int
+	FutureMinutes  int
+	Additions      int
+	Deletions      int
+	FilesChanged   int
+	Enriched       bool
+}
+
+const commitRecordColumns = `
+	c.repository_id, r.full_name, c.sha, COALESCE(c.message, ''),
+	COALESCE(c.author_name, ''), COALESCE(c.author_email, ''), COALESCE(c.author_login, ''), c.author_date,
+	COALESCE(c.committer_name, ''), COALESCE(c.committer_email, ''), COALESCE(c.committer_login, ''),
+	COALESCE(c.committer_date, c.author_date), c.pushed_at, c.source, c.committer_date_verified,
+	COALESCE(c.backdate_hours, 0), COALESCE(c.future_minutes, 0),
+	COALESCE(c.additions, 0), COALESCE(c.deletions, 0), COALESCE(c.files_changed, 0), c.enriched_at IS NOT NULL`
+
+func scanCommitRecord(row pgx.Row) (*CommitRecord, error) {
+	var c CommitRecord
+	err := row.Scan(
+		&c.RepositoryID, &c.FullName, &c.SHA, &c.Message,
+		&c.AuthorName, &c.AuthorEmail, &c.AuthorLogin, &c.AuthorDate,
+		&c.CommitterName, &c.CommitterEmail, &c.CommitterLogin,
+		&c.CommitterDate, &c.PushedAt, &c.Source, &c.DatesVerified,
+		&c.BackdateHours, &c.FutureMinutes,
+		&c.Additions, &c.Deletions, &c.FilesChanged, &c.Enriched,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// GetRecord returns a commit's record. It takes a Querier so enrichment can
+// read the record inside its transaction.
+func (s *CommitStore) GetRecord(ctx context.Context, q Querier, repoID int64, sha string) (*CommitRecord, error) {
+	return scanCommitRecord(q.QueryRow(ctx, `
+		SELECT `+commitRecordColumns+`
+		FROM commits c
+		JOIN repositories r ON r.id = c.repository_id
+		WHERE c.repository_id = $1 AND c.sha = $2
+	`, repoID, sha))
+}
+
+// ListRecords returns the records of the commits matching filter, oldest
+// push first.
+func (s *CommitStore) ListRecords(ctx context.Context, filter RecordFilter) ([]*CommitRecord, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT `+commitRecordColumns+`
+		FROM commits c
+		JOIN repositories r ON r.id = c.repository_id
+		WHERE ($1::bigint IS NULL OR c.repository_id = $1)
+		  AND ($2::timestamptz IS NULL OR c.pushed_at >= $2)
+		  AND ($3::timestamptz IS NULL OR c.pushed_at <= $3)
+		ORDER BY c.pushed_at, c.id
+		LIMIT NULLIF($4, 0)
+	`, filter.RepositoryID, filter.From, filter.To, filter.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []*CommitRecord
+	for rows.Next() {
+		c, err := scanCommitRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, c)
+	}
+	return records, rows.Err()
+}
+
+// ContributorDay is one contributor's activity on one calendar day.
+type ContributorDay struct {
+	Contributor string
+	Date        time.Time
+	Commits     int
+	Additions   int
+	Deletions   int
+}
+
+// ListContributorDays buckets commits pushed within a window by contributor
+// and by calendar day in the given time zone. Contributors are identified by
+// GitHub login where known, otherwise by email.
+func (s *CommitStore) ListContributorDays(ctx context.Context, repoID int64, timezone string, from, to *time.Time) ([]*ContributorDay, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT
+			COALESCE(ct.github_login, c.author_email) as contributor,
+			(c.pushed_at AT TIME ZONE $2)::date as day,
+			COUNT(*) as commits,
+			COALESCE(SUM(c.additions), 0) as additions,
+			COALESCE(SUM(c.deletions), 0) as deletions
+		FROM commits c
+		LEFT JOIN contributors ct ON ct.repository_id = c.repository_id AND ct.email = c.author_email
+		WHERE c.repository_id = $1
+		  AND ($3::timestamptz IS NULL OR c.pushed_at >= $3)
+		  AND ($4::timestamptz IS NULL OR c.pushed_at <= $4)
+		GROUP BY 1, 2
+		ORDER BY 2, 1
+	`, repoID, timezone, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var days []*ContributorDay
+	for rows.Next() {
+		var d ContributorDay
+		if err := rows.Scan(&d.Contributor, &d.Date, &d.Commits, &d.Additions, &d.Deletions); err != nil {
+			return nil, err
+		}
+		days = append(days, &d)
+	}
+	return days, rows.Err()
+}
+
 type CommitStats struct {
 	TotalCommits      int
 	BackdatedCount    int
@@ -106,3 +548,126 @@ func (s *CommitStore) CountBackdated(ctx context.Context, repoID int64) (suspici
 	`, repoID).Scan(&suspicious, &critical)
 	return
 }
+
+func (s *CommitStore) GetBySHA(ctx context.Context, repoID int64, sha string) (*Commit, error) {
+	var c Commit
+	err := s.pool.QueryRow(ctx, `
+		SELECT id, repository_id, sha, message, author_email, author_name,
+		       author_date, committer_date, pushed_at, additions, deletions, files_changed,
+		       is_conventional, conventional_type, conventional_scope,
+		       is_backdated, backdate_hours, enriched_at, created_at
+		FROM commits WHERE repository_id = $1 AND sha = $2
+	`, repoID, sha).Scan(
+		&c.ID, &c.RepositoryID, &c.SHA, &c.Message, &c.AuthorEmail, &c.AuthorName,
+		&c.AuthorDate, &c.CommitterDate, &c.PushedAt, &c.Additions, &c.Deletions, &c.FilesChanged,
+		&c.IsConventional, &c.ConventionalType, &c.ConventionalScope,
+		&c.IsBackdated, &c.BackdateHours, &c.EnrichedAt, &c.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// ApplyEnrichment stores fetched stats, files and committer date for a
+// commit and rolls the line counts up into the author's contributor totals
+// and daily stats. A zero committerDate leaves the stored one. It is a no-op
+// returning false if the commit was already enriched. evaluate, when set,
+// runs inside the transaction before it commits, so the commit is only
+// marked enriched once it succeeds.
+func (s *CommitStore) ApplyEnrichment(ctx context.Context, commit *Commit, additions, deletions int, files []*CommitFile, committerDate time.Time, evaluate func(tx pgx.Tx) error) (bool, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	var verified *time.Time
+	if !committerDate.IsZero() {
+		verified = &committerDate
+	}
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE commits SET
+			additions = $2, deletions = $3, files_changed = $4, enriched_at = NOW(),
+			committer_date = COALESCE($5, committer_date),
+			committer_date_verified = committer_date_verified OR $5 IS NOT NULL
+		WHERE id = $1 AND enriched_at IS NULL
+	`, commit.ID, additions, deletions, len(files), verified)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	batch := &pgx.Batch{}
+	for _, f := range files {
+		batch.Queue(`
+			INSERT INTO commit_files (commit_id, filename, previous_filename, status, additions, deletions)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (commit_id, filename) DO NOTHING
+		`, commit.ID, f.Filename, f.PreviousFilename, f.Status, f.Additions, f.Deletions)
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return false, err
+	}
+
+	var contributorID int64
+	err = tx.QueryRow(ctx, `
+		UPDATE contributors SET
+			total_additions = total_additions + $3,
+			total_deletions = total_deletions + $4,
+			updated_at = NOW()
+		WHERE repository_id = $1 AND email = $2
+		RETURNING id
+	`, commit.RepositoryID, commit.AuthorEmail, additions, deletions).Scan(&contributorID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return false, err
+	}
+
+	// The commit itself was counted when it was ingested
+	if contributorID != 0 {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO daily_stats (repository_id, contributor_id, stat_date, commit_count, additions, deletions)
+			VALUES ($1, $2, $3, 0, $4, $5)
+			ON CONFLICT (repository_id, contributor_id, stat_date) DO UPDATE SET
+				additions = daily_stats.additions + $4,
+				deletions = daily_stats.deletions + $5
+		`, commit.RepositoryID, contributorID, commit.AuthorDate.UTC().Format("2006-01-02"), additions, deletions)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if evaluate != nil {
+		if err := evaluate(tx); err != nil {
+			return false, err
+		}
+	}
+
+	return true, tx.Commit(ctx)
+}
+
+func (s *CommitStore) ListFiles(ctx context.Context, commitID int64) ([]*CommitFile, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, commit_id, filename, previous_filename, status, additions, deletions, created_at
+		FROM commit_files WHERE commit_id = $1
+		ORDER BY filename
+	`, commitID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var files []*CommitFile
+	for rows.Next() {
+		var f CommitFile
+		err := rows.Scan(&f.ID, &f.CommitID, &f.Filename, &f.PreviousFilename, &f.Status, &f.Additions, &f.Deletions, &f.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, &f)
+	}
+	return files, nil
+}
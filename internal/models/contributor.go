@@ -18,8 +18,10 @@ type Contributor struct {
 	TotalDeletions int64
 	FirstCommitAt  *time.Time
 	LastCommitAt   *time.Time
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
+	// TeamMemberID links the contributor to the roster member it belongs to.
+	TeamMemberID *int64
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
 }
 
 type ContributorStore struct {
@@ -34,7 +36,7 @@ func (s *ContributorStore) ListByRepository(ctx context.Context, repoID int64) (
 	rows, err := s.pool.Query(ctx, `
 		SELECT id, repository_id, github_login, email, name, total_commits,
 		       total_additions, total_deletions, first_commit_at, last_commit_at,
-		       created_at, updated_at
+		       team_member_id, created_at, updated_at
 		FROM contributors WHERE repository_id = $1
 		ORDER BY total_commits DESC
 	`, repoID)
@@ -49,7 +51,7 @@ func (s *ContributorStore) ListByRepository(ctx context.Context, repoID int64) (
 		err := rows.Scan(
 			&c.ID, &c.RepositoryID, &c.GitHubLogin, &c.Email, &c.Name,
 			&c.TotalCommits, &c.TotalAdditions, &c.TotalDeletions,
-			&c.FirstCommitAt, &c.LastCommitAt, &c.CreatedAt, &c.UpdatedAt,
+			&c.FirstCommitAt, &c.LastCommitAt, &c.TeamMemberID, &c.CreatedAt, &c.UpdatedAt,
 		)
 		if err != nil {
 			return nil, err
@@ -0,0 +1,214 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type DeliveryStatus string
+
+const (
+	DeliveryReceived   DeliveryStatus = "received"
+	DeliveryProcessing DeliveryStatus = "processing"
+	DeliveryProcessed  DeliveryStatus = "processed"
+	DeliveryFailed     DeliveryStatus = "failed"
+)
+
+type Delivery struct {
+	ID          int64
+	DeliveryID  string
+	EventType   string
+	Payload     []byte
+	Status      DeliveryStatus
+	Attempts    int
+	LastError   *string
+	ReceivedAt  time.Time
+	ProcessedAt *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+type DeliveryStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewDeliveryStore(pool *pgxpool.Pool) *DeliveryStore {
+	return &DeliveryStore{pool: pool}
+}
+
+// Record stores a delivery if it has not been seen before, or moves a
+// failed one back to received. It returns true when the delivery needs a
+// processing job; otherwise it returns false and fills d with the existing
+// row, whose job is pending, running or done.
+func (s *DeliveryStore) Record(ctx context.Context, d *Delivery) (bool, error) {
+	err := s.pool.QueryRow(ctx, `
+		INSERT INTO webhook_deliveries (delivery_id, event_type, payload, received_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (delivery_id) DO UPDATE SET status = 'received', updated_at = NOW()
+		WHERE webhook_deliveries.status = 'failed'
+		RETURNING id, status, attempts, created_at, updated_at
+	`, d.DeliveryID, d.EventType, d.Payload, d.ReceivedAt,
+	).Scan(&d.ID, &d.Status, &d.Attempts, &d.CreatedAt, &d.UpdatedAt)
+	if err == nil {
+		return true, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return false, err
+	}
+
+	existing, err := s.GetByDeliveryID(ctx, d.DeliveryID)
+	if err != nil {
+		return false, err
+	}
+	*d = *existing
+	return false, nil
+}
+
+func (s *DeliveryStore) GetByDeliveryID(ctx context.Context, deliveryID string) (*Delivery, error) {
+	var d Delivery
+	err := s.pool.QueryRow(ctx, `
+		SELECT id, delivery_id, event_type, payload, status, attempts, last_error,
+		       received_at, processed_at, created_at, updated_at
+		FROM webhook_deliveries WHERE delivery_id = $1
+	`, deliveryID).Scan(
+		&d.ID, &d.DeliveryID, &d.EventType, &d.Payload, &d.Status, &d.Attempts, &d.LastError,
+		&d.ReceivedAt, &d.ProcessedAt, &d.CreatedAt, &d.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// ListByTimeRange returns deliveries received in [from, to), oldest first.
+func (s *DeliveryStore) ListByTimeRange(ctx context.Context, from, to time.Time) ([]*Delivery, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, delivery_id, event_type, payload, status, attempts, last_error,
+		       received_at, processed_at, created_at, updated_at
+		FROM webhook_deliveries
+		WHERE received_at >= $1 AND received_at < $2
+		ORDER BY received_at
+	`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []*Delivery
+	for rows.Next() {
+		var d Delivery
+		err := rows.Scan(
+			&d.ID, &d.DeliveryID, &d.EventType, &d.Payload, &d.Status, &d.Attempts, &d.LastError,
+			&d.ReceivedAt, &d.ProcessedAt, &d.CreatedAt, &d.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, &d)
+	}
+	return deliveries, nil
+}
+
+// ListIDsByTimeRange returns the IDs of deliveries received in [from, to),
+// oldest first.
+func (s *DeliveryStore) ListIDsByTimeRange(ctx context.Context, from, to time.Time) ([]string, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT delivery_id
+		FROM webhook_deliveries
+		WHERE received_at >= $1 AND received_at < $2
+		ORDER BY received_at
+	`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// ListRecent returns the most recent deliveries without payloads, optionally
+// filtered by status.
+func (s *DeliveryStore) ListRecent(ctx context.Context, status DeliveryStatus, limit int) ([]*Delivery, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, delivery_id, event_type, status, attempts, last_error,
+		       received_at, processed_at, created_at, updated_at
+		FROM webhook_deliveries
+		WHERE $1 = '' OR status = $1
+		ORDER BY received_at DESC
+		LIMIT $2
+	`, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []*Delivery
+	for rows.Next() {
+		var d Delivery
+		err := rows.Scan(
+			&d.ID, &d.DeliveryID, &d.EventType, &d.Status, &d.Attempts, &d.LastError,
+			&d.ReceivedAt, &d.ProcessedAt, &d.CreatedAt, &d.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, &d)
+	}
+	return deliveries, nil
+}
+
+// deliveryStaleAfter matches the job queue's stale timeout: a delivery
+// processing for longer belongs to a crashed worker whose job runs again.
+const deliveryStaleAfter = 15 * time.Minute
+
+// Claim marks a delivery as processing unless it was processed already or
+// another job is processing it. It returns false when the caller should
+// leave the delivery alone.
+func (s *DeliveryStore) Claim(ctx context.Context, id int64) (bool, error) {
+	tag, err := s.pool.Exec(ctx, `
+		UPDATE webhook_deliveries SET status = 'processing', attempts = attempts + 1, updated_at = NOW()
+		WHERE id = $1
+		  AND (status IN ('received', 'failed')
+		       OR (status = 'processing' AND updated_at < NOW() - make_interval(secs => $2)))
+	`, id, deliveryStaleAfter.Seconds())
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (s *DeliveryStore) MarkProcessing(ctx context.Context, id int64) error {
+	_, err := s.pool.Exec(ctx, `
+		UPDATE webhook_deliveries SET status = 'processing', attempts = attempts + 1, updated_at = NOW()
+		WHERE id = $1
+	`, id)
+	return err
+}
+
+func (s *DeliveryStore) MarkProcessed(ctx context.Context, id int64) error {
+	_, err := s.pool.Exec(ctx, `
+		UPDATE webhook_deliveries SET status = 'processed', last_error = NULL, processed_at = NOW(), updated_at = NOW()
+		WHERE id = $1
+	`, id)
+	return err
+}
+
+func (s *DeliveryStore) MarkFailed(ctx context.Context, id int64, errMsg string) error {
+	_, err := s.pool.Exec(ctx, `
+		UPDATE webhook_deliveries SET status = 'failed', last_error = $2, updated_at = NOW()
+		WHERE id = $1
+	`, id, errMsg)
+	return err
+}
@@ -0,0 +1,239 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Event is a hackathon. Enrolled repositories are analysed against its
+// window rather than their whole history.
+type Event struct {
+	ID       int64
+	Name     string
+	Timezone string
+	// OnSite is set for in-person events, whose teams are expected to
+	// commit from the event's time zone.
+	OnSite   bool
+	StartsAt time.Time
+	EndsAt   time.Time
+	// SubmissionDeadline defaults to EndsAt when unset.
+	SubmissionDeadline *time.Time
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+}
+
+type EventWithStats struct {
+	Event
+	RepositoriesCount int
+}
+
+// Location returns the event's time zone, falling back to UTC for a zone
+// the runtime does not know.
+func (e *Event) Location() *time.Location {
+	loc, err := time.LoadLocation(e.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// Deadline returns the submission deadline, or the end of the event when
+// none was set.
+func (e *Event) Deadline() time.Time {
+	if e.SubmissionDeadline != nil {
+		return *e.SubmissionDeadline
+	}
+	return e.EndsAt
+}
+
+// Contains reports whether t falls within the event window.
+func (e *Event) Contains(t time.Time) bool {
+	return !t.Before(e.StartsAt) && !t.After(e.EndsAt)
+}
+
+// Clamp limits t to the event window, so durations measured "until now"
+// stop growing once the event is over.
+func (e *Event) Clamp(t time.Time) time.Time {
+	if t.Before(e.StartsAt) {
+		return e.StartsAt
+	}
+	if t.After(e.EndsAt) {
+		return e.EndsAt
+	}
+	return t
+}
+
+// EventWindow returns the bounds of an event for window-scoped queries. A
+// repository outside any event has an open window.
+func EventWindow(e *Event) (from, to *time.Time) {
+	if e == nil {
+		return nil, nil
+	}
+	return &e.StartsAt, &e.EndsAt
+}
+
+const eventColumns = `e.id, e.name, e.timezone, e.on_site, e.starts_at, e.ends_at, e.submission_deadline, e.created_at, e.updated_at`
+
+type EventStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewEventStore(pool *pgxpool.Pool) *EventStore {
+	return &EventStore{pool: pool}
+}
+
+func (s *EventStore) Create(ctx context.Context, e *Event) error {
+	return s.pool.QueryRow(ctx, `
+		INSERT INTO events (name, timezone, on_site, starts_at, ends_at, submission_deadline)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at
+	`, e.Name, e.Timezone, e.OnSite, e.StartsAt, e.EndsAt, e.SubmissionDeadline).Scan(&e.ID, &e.CreatedAt, &e.UpdatedAt)
+}
+
+func (s *EventStore) Update(ctx context.Context, e *Event) error {
+	return s.pool.QueryRow(ctx, `
+		UPDATE events SET
+			name = $2, timezone = $3, on_site = $4, starts_at = $5, ends_at = $6, submission_deadline = $7,
+			updated_at = NOW()
+		WHERE id = $1
+		RETURNING created_at, updated_at
+	`, e.ID, e.Name, e.Timezone, e.OnSite, e.StartsAt, e.EndsAt, e.SubmissionDeadline).Scan(&e.CreatedAt, &e.UpdatedAt)
+}
+
+// Delete removes an event. Enrolled repositories are unenrolled rather than
+// deleted.
+func (s *EventStore) Delete(ctx context.Context, id int64) (bool, error) {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM events WHERE id = $1`, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (s *EventStore) GetByID(ctx context.Context, id int64) (*EventWithStats, error) {
+	var e EventWithStats
+	err := s.pool.QueryRow(ctx, `
+		SELECT `+eventColumns+`,
+			(SELECT COUNT(*) FROM repositories r WHERE r.event_id = e.id) as repositories_count
+		FROM events e WHERE e.id = $1
+	`, id).Scan(
+		&e.ID, &e.Name, &e.Timezone, &e.OnSite, &e.StartsAt, &e.EndsAt, &e.SubmissionDeadline,
+		&e.CreatedAt, &e.UpdatedAt, &e.RepositoriesCount,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (s *EventStore) List(ctx context.Context) ([]*EventWithStats, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT `+eventColumns+`,
+			COALESCE(r.repo_count, 0) as repositories_count
+		FROM events e
+		LEFT JOIN (
+			SELECT event_id, COUNT(*) as repo_count
+			FROM repositories
+			WHERE event_id IS NOT NULL
+			GROUP BY event_id
+		) r ON r.event_id = e.id
+		ORDER BY e.starts_at DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*EventWithStats
+	for rows.Next() {
+		var e EventWithStats
+		err := rows.Scan(
+			&e.ID, &e.Name, &e.Timezone, &e.OnSite, &e.StartsAt, &e.EndsAt, &e.SubmissionDeadline,
+			&e.CreatedAt, &e.UpdatedAt, &e.RepositoriesCount,
+		)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, &e)
+	}
+	return events, nil
+}
+
+// GetForRepository returns the event a repository is enrolled in, or nil
+// when it is not enrolled in any.
+func (s *EventStore) GetForRepository(ctx context.Context, repo *Repository) (*Event, error) {
+	if repo.EventID == nil {
+		return nil, nil
+	}
+	e, err := s.GetByID(ctx, *repo.EventID)
+	if err != nil {
+		return nil, err
+	}
+	return &e.Event, nil
+}
+
+// ListByIDs returns the events with the given IDs keyed by ID.
+func (s *EventStore) ListByIDs(ctx context.Context, ids []int64) (map[int64]*Event, error) {
+	events := make(map[int64]*Event, len(ids))
+	if len(ids) == 0 {
+		return events, nil
+	}
+
+	rows, err := s.pool.Query(ctx, `SELECT `+eventColumns+` FROM events e WHERE e.id = ANY($1)`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e Event
+		if err := rows.Scan(&e.ID, &e.Name, &e.Timezone, &e.OnSite, &e.StartsAt, &e.EndsAt, &e.SubmissionDeadline, &e.CreatedAt, &e.UpdatedAt); err != nil {
+			return nil, err
+		}
+		events[e.ID] = &e
+	}
+	return events, rows.Err()
+}
+
+// Enroll enrolls a repository in an event, replacing any earlier enrollment.
+// A nil eventID unenrolls it. Moving to another event clears the submission
+// freeze so the repository is frozen again at the new deadline.
+func (s *EventStore) Enroll(ctx context.Context, repoID int64, eventID *int64) (bool, error) {
+	tag, err := s.pool.Exec(ctx, `
+		UPDATE repositories SET
+			submission_frozen_at = CASE WHEN event_id IS DISTINCT FROM $2 THEN NULL ELSE submission_frozen_at END,
+			event_id = $2,
+			updated_at = NOW()
+		WHERE id = $1
+	`, repoID, eventID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// ListRepositories returns the repositories enrolled in an event.
+func (s *EventStore) ListRepositories(ctx context.Context, eventID int64) ([]*Repository, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT `+repositoryColumns+`
+		FROM repositories r
+		WHERE r.event_id = $1
+		ORDER BY r.full_name
+	`, eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var repos []*Repository
+	for rows.Next() {
+		var r Repository
+		if err := scanRepository(rows, &r); err != nil {
+			return nil, err
+		}
+		repos = append(repos, &r)
+	}
+	return repos, nil
+}
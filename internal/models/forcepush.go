@@ -0,0 +1,179 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ForcePushCommitKind string
+
+const (
+	// ForcePushDropped is a commit reachable from the old head but not the new one.
+	ForcePushDropped ForcePushCommitKind = "dropped"
+	// ForcePushReplacement is a commit reachable from the new head but not the old one.
+	ForcePushReplacement ForcePushCommitKind = "replacement"
+)
+
+type ForensicsStatus string
+
+const (
+	ForensicsPending     ForensicsStatus = "pending"
+	ForensicsCompleted   ForensicsStatus = "completed"
+	ForensicsUnavailable ForensicsStatus = "unavailable"
+)
+
+type ForcePushCommit struct {
+	ID            int64
+	PushEventID   int64
+	RepositoryID  int64
+	Kind          ForcePushCommitKind
+	SHA           string
+	Message       string
+	AuthorName    string
+	AuthorEmail   string
+	AuthorDate    time.Time
+	CommitterDate time.Time
+	// ReplacesSHA links a replacement to the dropped commit it rewrote.
+	ReplacesSHA       *string
+	AuthorChanged     bool
+	AuthorDateChanged bool
+	CreatedAt         time.Time
+}
+
+// ForcePushSummary is a forced push with counts of what it rewrote.
+type ForcePushSummary struct {
+	PushEventID        int64
+	Ref                string
+	BeforeSHA          string
+	AfterSHA           string
+	PusherLogin        string
+	ReceivedAt         time.Time
+	ForensicsStatus    *ForensicsStatus
+	DroppedCount       int
+	ReplacementCount   int
+	RewrittenCount     int
+	AuthorChangedCount int
+	DateChangedCount   int
+}
+
+type ForcePushStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewForcePushStore(pool *pgxpool.Pool) *ForcePushStore {
+	return &ForcePushStore{pool: pool}
+}
+
+func (s *ForcePushStore) SetForensicsStatus(ctx context.Context, pushEventID int64, status ForensicsStatus) error {
+	_, err := s.pool.Exec(ctx, `
+		UPDATE push_events SET
+			forensics_status = $2,
+			forensics_completed_at = CASE WHEN $2 = 'pending' THEN NULL ELSE NOW() END
+		WHERE id = $1
+	`, pushEventID, status)
+	return err
+}
+
+// SaveCommits replaces the recorded commits of a push event and marks its
+// forensics as completed, so rerunning the analysis is safe.
+func (s *ForcePushStore) SaveCommits(ctx context.Context, pushEventID int64, commits []*ForcePushCommit) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM force_push_commits WHERE push_event_id = $1`, pushEventID); err != nil {
+		return err
+	}
+
+	batch := &pgx.Batch{}
+	for _, c := range commits {
+		batch.Queue(`
+			INSERT INTO force_push_commits (push_event_id, repository_id, kind, sha, message, author_name, author_email,
+				author_date, committer_date, replaces_sha, author_changed, author_date_changed)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		`, pushEventID, c.RepositoryID, c.Kind, c.SHA, c.Message, c.AuthorName, c.AuthorEmail,
+			c.AuthorDate, c.CommitterDate, c.ReplacesSHA, c.AuthorChanged, c.AuthorDateChanged)
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE push_events SET forensics_status = 'completed', forensics_completed_at = NOW()
+		WHERE id = $1
+	`, pushEventID); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (s *ForcePushStore) ListByPushEvent(ctx context.Context, pushEventID int64) ([]*ForcePushCommit, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, push_event_id, repository_id, kind, sha, COALESCE(message, ''),
+		       COALESCE(author_name, ''), COALESCE(author_email, ''), author_date, committer_date,
+		       replaces_sha, author_changed, author_date_changed, created_at
+		FROM force_push_commits WHERE push_event_id = $1
+		ORDER BY kind, committer_date
+	`, pushEventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var commits []*ForcePushCommit
+	for rows.Next() {
+		var c ForcePushCommit
+		err := rows.Scan(
+			&c.ID, &c.PushEventID, &c.RepositoryID, &c.Kind, &c.SHA, &c.Message,
+			&c.AuthorName, &c.AuthorEmail, &c.AuthorDate, &c.CommitterDate,
+			&c.ReplacesSHA, &c.AuthorChanged, &c.AuthorDateChanged, &c.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		commits = append(commits, &c)
+	}
+	return commits, nil
+}
+
+// ListByRepository summarizes every forced push of a repository, newest first.
+func (s *ForcePushStore) ListByRepository(ctx context.Context, repoID int64) ([]*ForcePushSummary, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT p.id, COALESCE(p.ref, ''), COALESCE(p.before_sha, ''), COALESCE(p.after_sha, ''),
+		       COALESCE(p.pusher_login, ''), p.received_at, p.forensics_status,
+		       COUNT(f.id) FILTER (WHERE f.kind = 'dropped'),
+		       COUNT(f.id) FILTER (WHERE f.kind = 'replacement'),
+		       COUNT(f.id) FILTER (WHERE f.replaces_sha IS NOT NULL),
+		       COUNT(f.id) FILTER (WHERE f.author_changed),
+		       COUNT(f.id) FILTER (WHERE f.author_date_changed)
+		FROM push_events p
+		LEFT JOIN force_push_commits f ON f.push_event_id = p.id
+		WHERE p.repository_id = $1 AND p.forced = TRUE
+		GROUP BY p.id
+		ORDER BY p.received_at DESC
+	`, repoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var summaries []*ForcePushSummary
+	for rows.Next() {
+		var f ForcePushSummary
+		err := rows.Scan(
+			&f.PushEventID, &f.Ref, &f.BeforeSHA, &f.AfterSHA, &f.PusherLogin, &f.ReceivedAt, &f.ForensicsStatus,
+			&f.DroppedCount, &f.ReplacementCount, &f.RewrittenCount, &f.AuthorChangedCount, &f.DateChangedCount,
+		)
+		if err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, &f)
+	}
+	return summaries, nil
+}
@@ -12,6 +12,9 @@ type Installation struct {
 	InstallationID int64
 	AccountLogin   string
 	AccountType    string
+	Status         LifecycleStatus
+	SuspendedAt    *time.Time
+	DeletedAt      *time.Time
 	CreatedAt      time.Time
 	UpdatedAt      time.Time
 }
@@ -31,10 +34,13 @@ func NewInstallationStore(pool *pgxpool.Pool) *InstallationStore {
 	return &InstallationStore{pool: pool}
 }
 
-func (s *InstallationStore) List(ctx context.Context) ([]*InstallationWithStats, error) {
+// List returns installations with their stats. An empty statuses slice
+// matches every lifecycle status.
+func (s *InstallationStore) List(ctx context.Context, statuses []LifecycleStatus) ([]*InstallationWithStats, error) {
 	rows, err := s.pool.Query(ctx, `
 		SELECT
-			i.id, i.installation_id, i.account_login, i.account_type, i.created_at, i.updated_at,
+			i.id, i.installation_id, i.account_login, i.account_type,
+			i.status, i.suspended_at, i.deleted_at, i.created_at, i.updated_at,
 			COALESCE(r.repo_count, 0) as repo_count,
 			COALESCE(a.alert_count, 0) as alert_count,
 			COALESCE(c.commit_count, 0) as commit_count
@@ -56,8 +62,9 @@ func (s *InstallationStore) List(ctx context.Context) ([]*InstallationWithStats,
 			JOIN repositories r ON r.id = c.repository_id
 			GROUP BY r.installation_id
 		) c ON c.installation_id = i.installation_id
+		WHERE COALESCE(cardinality($1::text[]), 0) = 0 OR i.status = ANY($1)
 		ORDER BY i.account_login
-	`)
+	`, statuses)
 	if err != nil {
 		return nil, err
 	}
@@ -68,7 +75,7 @@ func (s *InstallationStore) List(ctx context.Context) ([]*InstallationWithStats,
 		var i InstallationWithStats
 		err := rows.Scan(
 			&i.ID, &i.InstallationID, &i.AccountLogin, &i.AccountType,
-			&i.CreatedAt, &i.UpdatedAt,
+			&i.Status, &i.SuspendedAt, &i.DeletedAt, &i.CreatedAt, &i.UpdatedAt,
 			&i.RepoCount, &i.AlertCount, &i.CommitCount,
 		)
 		if err != nil {
@@ -83,7 +90,8 @@ func (s *InstallationStore) GetByID(ctx context.Context, installationID int64) (
 	var i InstallationWithStats
 	err := s.pool.QueryRow(ctx, `
 		SELECT
-			i.id, i.installation_id, i.account_login, i.account_type, i.created_at, i.updated_at,
+			i.id, i.installation_id, i.account_login, i.account_type,
+			i.status, i.suspended_at, i.deleted_at, i.created_at, i.updated_at,
 			COALESCE(r.repo_count, 0) as repo_count,
 			COALESCE(a.alert_count, 0) as alert_count,
 			COALESCE(c.commit_count, 0) as commit_count
@@ -108,7 +116,7 @@ func (s *InstallationStore) GetByID(ctx context.Context, installationID int64) (
 		WHERE i.installation_id = $1
 	`, installationID).Scan(
 		&i.ID, &i.InstallationID, &i.AccountLogin, &i.AccountType,
-		&i.CreatedAt, &i.UpdatedAt,
+		&i.Status, &i.SuspendedAt, &i.DeletedAt, &i.CreatedAt, &i.UpdatedAt,
 		&i.RepoCount, &i.AlertCount, &i.CommitCount,
 	)
 	if err != nil {
@@ -116,3 +124,16 @@ func (s *InstallationStore) GetByID(ctx context.Context, installationID int64) (
 	}
 	return &i, nil
 }
+
+// SetStatus moves an installation to a lifecycle status and stamps the matching timestamp.
+func (s *InstallationStore) SetStatus(ctx context.Context, installationID int64, status LifecycleStatus) error {
+	_, err := s.pool.Exec(ctx, `
+		UPDATE installations SET
+			status = $2,
+			suspended_at = CASE WHEN $2 = 'suspended' THEN NOW() WHEN $2 = 'active' THEN NULL ELSE suspended_at END,
+			deleted_at = CASE WHEN $2 = 'deleted' THEN NOW() WHEN $2 = 'active' THEN NULL ELSE deleted_at END,
+			updated_at = NOW()
+		WHERE installation_id = $1
+	`, installationID, status)
+	return err
+}
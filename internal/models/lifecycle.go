@@ -0,0 +1,20 @@
+package models
+
+// LifecycleStatus tracks whether gitvigil can still see an installation or repository.
+type LifecycleStatus string
+
+const (
+	LifecycleActive    LifecycleStatus = "active"
+	LifecycleSuspended LifecycleStatus = "suspended"
+	LifecycleRemoved   LifecycleStatus = "removed"
+	LifecycleDeleted   LifecycleStatus = "deleted"
+)
+
+// IsValid reports whether s is a known lifecycle status.
+func (s LifecycleStatus) IsValid() bool {
+	switch s {
+	case LifecycleActive, LifecycleSuspended, LifecycleRemoved, LifecycleDeleted:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PushRecord is a stored push with every field rules can read. EventID and
+// Deadline are set when the repository is enrolled in an event.
+type PushRecord struct {
+	ID           int64
+	RepositoryID int64
+	FullName     string
+	Ref          string
+	Before       string
+	After        string
+	Forced       bool
+	Pusher       string
+	Commits      int
+	PushedAt     time.Time
+	PostDeadline bool
+	EventID      *int64
+	Deadline     *time.Time
+}
+
+type PushEventStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewPushEventStore(pool *pgxpool.Pool) *PushEventStore {
+	return &PushEventStore{pool: pool}
+}
+
+// ListRecords returns the records of the pushes matching filter, oldest
+// first. Pushes are timed by GitHub's push timestamp where one was stored.
+func (s *PushEventStore) ListRecords(ctx context.Context, filter RecordFilter) ([]*PushRecord, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT p.id, p.repository_id, r.full_name, COALESCE(p.ref, ''),
+		       COALESCE(p.before_sha, ''), COALESCE(p.after_sha, ''), COALESCE(p.forced, FALSE),
+		       COALESCE(p.pusher_login, ''), COALESCE(p.commit_count, 0),
+		       COALESCE(p.github_pushed_at, p.received_at), p.post_deadline,
+		       e.id, COALESCE(e.submission_deadline, e.ends_at)
+		FROM push_events p
+		JOIN repositories r ON r.id = p.repository_id
+		LEFT JOIN events e ON e.id = r.event_id
+		WHERE ($1::bigint IS NULL OR p.repository_id = $1)
+		  AND ($2::timestamptz IS NULL OR COALESCE(p.github_pushed_at, p.received_at) >= $2)
+		  AND ($3::timestamptz IS NULL OR COALESCE(p.github_pushed_at, p.received_at) <= $3)
+		ORDER BY COALESCE(p.github_pushed_at, p.received_at), p.id
+		LIMIT NULLIF($4, 0)
+	`, filter.RepositoryID, filter.From, filter.To, filter.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []*PushRecord
+	for rows.Next() {
+		var p PushRecord
+		err := rows.Scan(
+			&p.ID, &p.RepositoryID, &p.FullName, &p.Ref,
+			&p.Before, &p.After, &p.Forced,
+			&p.Pusher, &p.Commits,
+			&p.PushedAt, &p.PostDeadline,
+			&p.EventID, &p.Deadline,
+		)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, &p)
+	}
+	return records, rows.Err()
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -19,9 +20,62 @@ type Repository struct {
 	LicenseSPDXID  *string
 	LastPushAt     *time.Time
 	LastActivityAt *time.Time
-	StreakStatus   string
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
+	StreakStatus   StreakStatus
+	// StreakStartedAt is when the current streak began; StreakChangedAt is
+	// when StreakStatus last changed.
+	StreakStartedAt   *time.Time
+	StreakChangedAt   *time.Time
+	LongestStreakDays int
+	Status            LifecycleStatus
+	SuspendedAt       *time.Time
+	RemovedAt         *time.Time
+	DeletedAt         *time.Time
+	Backfill          BackfillProgress
+	// EventID is the hackathon the repository is enrolled in and TeamID the
+	// roster it is assigned to, if any.
+	EventID *int64
+	TeamID  *int64
+	// SubmissionFrozenAt is when the repository's submission for its event
+	// was frozen.
+	SubmissionFrozenAt *time.Time
+	// GitHubCreatedAt is when the repository was created on GitHub, as
+	// reported by push payloads.
+	GitHubCreatedAt *time.Time
+	Origin          RepositoryOrigin
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+type BackfillStatus string
+
+const (
+	BackfillPending   BackfillStatus = "pending"
+	BackfillRunning   BackfillStatus = "running"
+	BackfillCompleted BackfillStatus = "completed"
+	BackfillFailed    BackfillStatus = "failed"
+)
+
+// BackfillProgress tracks the import of a repository's pre-installation history.
+type BackfillProgress struct {
+	Status      *BackfillStatus
+	Commits     int
+	StartedAt   *time.Time
+	CompletedAt *time.Time
+	Error       *string
+}
+
+// RepositoryOrigin is the fork parent and template a repository was created
+// from on GitHub. CheckedAt is nil until the repositories API was consulted.
+type RepositoryOrigin struct {
+	IsFork bool
+	// ParentID and TemplateID are GitHub repository IDs. Source is the root
+	// of the fork network when the parent is itself a fork.
+	ParentID   *int64
+	Parent     *string
+	Source     *string
+	TemplateID *int64
+	Template   *string
+	CheckedAt  *time.Time
 }
 
 type RepositoryWithStats struct {
@@ -30,6 +84,35 @@ type RepositoryWithStats struct {
 	CommitsCount int
 }
 
+// repositoryColumns is the select list matching scanRepository. Queries must
+// alias the repositories table as r.
+const repositoryColumns = `
+	r.id, r.github_id, r.installation_id, r.owner, r.name, r.full_name, r.default_branch,
+	r.has_license, r.license_spdx_id, r.last_push_at, r.last_activity_at, r.streak_status,
+	r.streak_started_at, r.streak_changed_at, r.longest_streak_days,
+	r.status, r.suspended_at, r.removed_at, r.deleted_at,
+	r.backfill_status, r.backfill_commits, r.backfill_started_at, r.backfill_completed_at, r.backfill_error,
+	r.event_id, r.team_id, r.submission_frozen_at, r.github_created_at,
+	r.is_fork, r.fork_parent_id, r.fork_parent, r.fork_source, r.template_repository_id, r.template_repository, r.origin_checked_at,
+	r.created_at, r.updated_at`
+
+// scanRepository scans a row selected with repositoryColumns, followed by
+// any extra columns into extra.
+func scanRepository(row pgx.Row, r *Repository, extra ...interface{}) error {
+	dest := []interface{}{
+		&r.ID, &r.GitHubID, &r.InstallationID, &r.Owner, &r.Name, &r.FullName,
+		&r.DefaultBranch, &r.HasLicense, &r.LicenseSPDXID, &r.LastPushAt,
+		&r.LastActivityAt, &r.StreakStatus,
+		&r.StreakStartedAt, &r.StreakChangedAt, &r.LongestStreakDays,
+		&r.Status, &r.SuspendedAt, &r.RemovedAt, &r.DeletedAt,
+		&r.Backfill.Status, &r.Backfill.Commits, &r.Backfill.StartedAt, &r.Backfill.CompletedAt, &r.Backfill.Error,
+		&r.EventID, &r.TeamID, &r.SubmissionFrozenAt, &r.GitHubCreatedAt,
+		&r.Origin.IsFork, &r.Origin.ParentID, &r.Origin.Parent, &r.Origin.Source, &r.Origin.TemplateID, &r.Origin.Template, &r.Origin.CheckedAt,
+		&r.CreatedAt, &r.UpdatedAt,
+	}
+	return row.Scan(append(dest, extra...)...)
+}
+
 type RepositoryStore struct {
 	pool *pgxpool.Pool
 }
@@ -40,17 +123,8 @@ func NewRepositoryStore(pool *pgxpool.Pool) *RepositoryStore {
 
 func (s *RepositoryStore) GetByGitHubID(ctx context.Context, githubID int64) (*Repository, error) {
 	var r Repository
-	err := s.pool.QueryRow(ctx, `
-		SELECT id, github_id, installation_id, owner, name, full_name, default_branch,
-		       has_license, license_spdx_id, last_push_at, last_activity_at, streak_status,
-		       created_at, updated_at
-		FROM repositories WHERE github_id = $1
-	`, githubID).Scan(
-		&r.ID, &r.GitHubID, &r.InstallationID, &r.Owner, &r.Name, &r.FullName,
-		&r.DefaultBranch, &r.HasLicense, &r.LicenseSPDXID, &r.LastPushAt,
-		&r.LastActivityAt, &r.StreakStatus, &r.CreatedAt, &r.UpdatedAt,
-	)
-	if err != nil {
+	row := s.pool.QueryRow(ctx, `SELECT `+repositoryColumns+` FROM repositories r WHERE r.github_id = $1`, githubID)
+	if err := scanRepository(row, &r); err != nil {
 		return nil, err
 	}
 	return &r, nil
@@ -58,17 +132,8 @@ func (s *RepositoryStore) GetByGitHubID(ctx context.Context, githubID int64) (*R
 
 func (s *RepositoryStore) GetByFullName(ctx context.Context, owner, name string) (*Repository, error) {
 	var r Repository
-	err := s.pool.QueryRow(ctx, `
-		SELECT id, github_id, installation_id, owner, name, full_name, default_branch,
-		       has_license, license_spdx_id, last_push_at, last_activity_at, streak_status,
-		       created_at, updated_at
-		FROM repositories WHERE owner = $1 AND name = $2
-	`, owner, name).Scan(
-		&r.ID, &r.GitHubID, &r.InstallationID, &r.Owner, &r.Name, &r.FullName,
-		&r.DefaultBranch, &r.HasLicense, &r.LicenseSPDXID, &r.LastPushAt,
-		&r.LastActivityAt, &r.StreakStatus, &r.CreatedAt, &r.UpdatedAt,
-	)
-	if err != nil {
+	row := s.pool.QueryRow(ctx, `SELECT `+repositoryColumns+` FROM repositories r WHERE r.owner = $1 AND r.name = $2`, owner, name)
+	if err := scanRepository(row, &r); err != nil {
 		return nil, err
 	}
 	return &r, nil
@@ -82,22 +147,16 @@ func (s *RepositoryStore) UpdateLicense(ctx context.Context, id int64, hasLicens
 	return err
 }
 
-func (s *RepositoryStore) UpdateStreakStatus(ctx context.Context, id int64, status string) error {
-	_, err := s.pool.Exec(ctx, `
-		UPDATE repositories SET streak_status = $2, updated_at = NOW()
-		WHERE id = $1
-	`, id, status)
-	return err
-}
-
-func (s *RepositoryStore) ListByInstallation(ctx context.Context, installationID int64) ([]*Repository, error) {
+// ListByInstallation returns an installation's repositories. An empty
+// statuses slice matches every lifecycle status.
+func (s *RepositoryStore) ListByInstallation(ctx context.Context, installationID int64, statuses []LifecycleStatus) ([]*Repository, error) {
 	rows, err := s.pool.Query(ctx, `
-		SELECT id, github_id, installation_id, owner, name, full_name, default_branch,
-		       has_license, license_spdx_id, last_push_at, last_activity_at, streak_status,
-		       created_at, updated_at
-		FROM repositories WHERE installation_id = $1
-		ORDER BY full_name
-	`, installationID)
+		SELECT `+repositoryColumns+`
+		FROM repositories r
+		WHERE r.installation_id = $1
+		  AND (COALESCE(cardinality($2::text[]), 0) = 0 OR r.status = ANY($2))
+		ORDER BY r.full_name
+	`, installationID, statuses)
 	if err != nil {
 		return nil, err
 	}
@@ -106,12 +165,7 @@ func (s *RepositoryStore) ListByInstallation(ctx context.Context, installationID
 	var repos []*Repository
 	for rows.Next() {
 		var r Repository
-		err := rows.Scan(
-			&r.ID, &r.GitHubID, &r.InstallationID, &r.Owner, &r.Name, &r.FullName,
-			&r.DefaultBranch, &r.HasLicense, &r.LicenseSPDXID, &r.LastPushAt,
-			&r.LastActivityAt, &r.StreakStatus, &r.CreatedAt, &r.UpdatedAt,
-		)
-		if err != nil {
+		if err := scanRepository(rows, &r); err != nil {
 			return nil, err
 		}
 		repos = append(repos, &r)
@@ -119,19 +173,21 @@ func (s *RepositoryStore) ListByInstallation(ctx context.Context, installationID
 	return repos, nil
 }
 
-func (s *RepositoryStore) ListAll(ctx context.Context, limit, offset int) ([]*RepositoryWithStats, int, error) {
+// ListAll returns a page of repositories. An empty statuses slice matches
+// every lifecycle status.
+func (s *RepositoryStore) ListAll(ctx context.Context, statuses []LifecycleStatus, limit, offset int) ([]*RepositoryWithStats, int, error) {
 	// Get total count
 	var total int
-	err := s.pool.QueryRow(ctx, `SELECT COUNT(*) FROM repositories`).Scan(&total)
+	err := s.pool.QueryRow(ctx, `
+		SELECT COUNT(*) FROM repositories
+		WHERE COALESCE(cardinality($1::text[]), 0) = 0 OR status = ANY($1)
+	`, statuses).Scan(&total)
 	if err != nil {
 		return nil, 0, err
 	}
 
 	rows, err := s.pool.Query(ctx, `
-		SELECT
-			r.id, r.github_id, r.installation_id, r.owner, r.name, r.full_name, r.default_branch,
-			r.has_license, r.license_spdx_id, r.last_push_at, r.last_activity_at, r.streak_status,
-			r.created_at, r.updated_at,
+		SELECT `+repositoryColumns+`,
 			COALESCE(a.alert_count, 0) as alerts_count,
 			COALESCE(c.commit_count, 0) as commits_count
 		FROM repositories r
@@ -145,9 +201,10 @@ func (s *RepositoryStore) ListAll(ctx context.Context, limit, offset int) ([]*Re
 			FROM commits
 			GROUP BY repository_id
 		) c ON c.repository_id = r.id
+		WHERE COALESCE(cardinality($3::text[]), 0) = 0 OR r.status = ANY($3)
 		ORDER BY r.full_name
 		LIMIT $1 OFFSET $2
-	`, limit, offset)
+	`, limit, offset, statuses)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -156,13 +213,7 @@ func (s *RepositoryStore) ListAll(ctx context.Context, limit, offset int) ([]*Re
 	var repos []*RepositoryWithStats
 	for rows.Next() {
 		var r RepositoryWithStats
-		err := rows.Scan(
-			&r.ID, &r.GitHubID, &r.InstallationID, &r.Owner, &r.Name, &r.FullName,
-			&r.DefaultBranch, &r.HasLicense, &r.LicenseSPDXID, &r.LastPushAt,
-			&r.LastActivityAt, &r.StreakStatus, &r.CreatedAt, &r.UpdatedAt,
-			&r.AlertsCount, &r.CommitsCount,
-		)
-		if err != nil {
+		if err := scanRepository(rows, &r.Repository, &r.AlertsCount, &r.CommitsCount); err != nil {
 			return nil, 0, err
 		}
 		repos = append(repos, &r)
@@ -172,11 +223,8 @@ func (s *RepositoryStore) ListAll(ctx context.Context, limit, offset int) ([]*Re
 
 func (s *RepositoryStore) GetByID(ctx context.Context, id int64) (*RepositoryWithStats, error) {
 	var r RepositoryWithStats
-	err := s.pool.QueryRow(ctx, `
-		SELECT
-			r.id, r.github_id, r.installation_id, r.owner, r.name, r.full_name, r.default_branch,
-			r.has_license, r.license_spdx_id, r.last_push_at, r.last_activity_at, r.streak_status,
-			r.created_at, r.updated_at,
+	row := s.pool.QueryRow(ctx, `
+		SELECT `+repositoryColumns+`,
 			COALESCE(a.alert_count, 0) as alerts_count,
 			COALESCE(c.commit_count, 0) as commits_count
 		FROM repositories r
@@ -191,28 +239,21 @@ func (s *RepositoryStore) GetByID(ctx context.Context, id int64) (*RepositoryWit
 			GROUP BY repository_id
 		) c ON c.repository_id = r.id
 		WHERE r.id = $1
-	`, id).Scan(
-		&r.ID, &r.GitHubID, &r.InstallationID, &r.Owner, &r.Name, &r.FullName,
-		&r.DefaultBranch, &r.HasLicense, &r.LicenseSPDXID, &r.LastPushAt,
-		&r.LastActivityAt, &r.StreakStatus, &r.CreatedAt, &r.UpdatedAt,
-		&r.AlertsCount, &r.CommitsCount,
-	)
-	if err != nil {
+	`, id)
+	if err := scanRepository(row, &r.Repository, &r.AlertsCount, &r.CommitsCount); err != nil {
 		return nil, err
 	}
 	return &r, nil
 }
 
-func (s *RepositoryStore) ListAtRisk(ctx context.Context, inactivityHours int) ([]*Repository, error) {
+// ListActive returns every repository whose installation is active.
+func (s *RepositoryStore) ListActive(ctx context.Context) ([]*Repository, error) {
 	rows, err := s.pool.Query(ctx, `
-		SELECT id, github_id, installation_id, owner, name, full_name, default_branch,
-		       has_license, license_spdx_id, last_push_at, last_activity_at, streak_status,
-		       created_at, updated_at
-		FROM repositories
-		WHERE last_activity_at < NOW() - INTERVAL '1 hour' * $1
-		  AND streak_status = 'active'
-		ORDER BY last_activity_at
-	`, inactivityHours)
+		SELECT `+repositoryColumns+`
+		FROM repositories r
+		WHERE r.status = 'active'
+		ORDER BY r.id
+	`)
 	if err != nil {
 		return nil, err
 	}
@@ -221,15 +262,123 @@ func (s *RepositoryStore) ListAtRisk(ctx context.Context, inactivityHours int) (
 	var repos []*Repository
 	for rows.Next() {
 		var r Repository
-		err := rows.Scan(
-			&r.ID, &r.GitHubID, &r.InstallationID, &r.Owner, &r.Name, &r.FullName,
-			&r.DefaultBranch, &r.HasLicense, &r.LicenseSPDXID, &r.LastPushAt,
-			&r.LastActivityAt, &r.StreakStatus, &r.CreatedAt, &r.UpdatedAt,
-		)
-		if err != nil {
+		if err := scanRepository(rows, &r); err != nil {
 			return nil, err
 		}
 		repos = append(repos, &r)
 	}
 	return repos, nil
 }
+
+// SetStatus moves a repository to a lifecycle status and stamps the matching timestamp.
+func (s *RepositoryStore) SetStatus(ctx context.Context, githubID int64, status LifecycleStatus) error {
+	_, err := s.pool.Exec(ctx, `
+		UPDATE repositories SET
+			status = $2,
+			suspended_at = CASE WHEN $2 = 'suspended' THEN NOW() WHEN $2 = 'active' THEN NULL ELSE suspended_at END,
+			removed_at = CASE WHEN $2 = 'removed' THEN NOW() WHEN $2 = 'active' THEN NULL ELSE removed_at END,
+			deleted_at = CASE WHEN $2 = 'deleted' THEN NOW() WHEN $2 = 'active' THEN NULL ELSE deleted_at END,
+			updated_at = NOW()
+		WHERE github_id = $1
+	`, githubID, status)
+	return err
+}
+
+// SetStatusByInstallation moves every repository of an installation that is
+// currently in one of the from statuses to a new lifecycle status. It returns
+// the IDs of the affected repositories.
+func (s *RepositoryStore) SetStatusByInstallation(ctx context.Context, installationID int64, from []LifecycleStatus, status LifecycleStatus) ([]int64, error) {
+	rows, err := s.pool.Query(ctx, `
+		UPDATE repositories SET
+			status = $3,
+			suspended_at = CASE WHEN $3 = 'suspended' THEN NOW() WHEN $3 = 'active' THEN NULL ELSE suspended_at END,
+			removed_at = CASE WHEN $3 = 'removed' THEN NOW() WHEN $3 = 'active' THEN NULL ELSE removed_at END,
+			deleted_at = CASE WHEN $3 = 'deleted' THEN NOW() WHEN $3 = 'active' THEN NULL ELSE deleted_at END,
+			updated_at = NOW()
+		WHERE installation_id = $1 AND status = ANY($2)
+		RETURNING id
+	`, installationID, from, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// PurgeData deletes everything collected for a repository (alerts, push
+// events, commits, contributors and daily stats) while keeping the
+// repository row itself so its lifecycle history remains visible.
+func (s *RepositoryStore) PurgeData(ctx context.Context, id int64) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	for _, query := range []string{
+		`DELETE FROM alerts WHERE repository_id = $1`,
+		`DELETE FROM push_events WHERE repository_id = $1`,
+		`DELETE FROM commits WHERE repository_id = $1`,
+		`DELETE FROM daily_stats WHERE repository_id = $1`,
+		`DELETE FROM streak_transitions WHERE repository_id = $1`,
+		`DELETE FROM contributors WHERE repository_id = $1`,
+	} {
+		if _, err := tx.Exec(ctx, query, id); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// SetBackfillStatus records a change in backfill state. Starting a run resets
+// the counters, finishing one stamps the completion time.
+func (s *RepositoryStore) SetBackfillStatus(ctx context.Context, id int64, status BackfillStatus, errMsg *string) error {
+	_, err := s.pool.Exec(ctx, `
+		UPDATE repositories SET
+			backfill_status = $2,
+			backfill_error = $3,
+			backfill_commits = CASE WHEN $2 IN ('pending', 'running') THEN 0 ELSE backfill_commits END,
+			backfill_started_at = CASE WHEN $2 = 'running' THEN NOW() ELSE backfill_started_at END,
+			backfill_completed_at = CASE WHEN $2 = 'completed' THEN NOW() WHEN $2 IN ('pending', 'running') THEN NULL ELSE backfill_completed_at END,
+			updated_at = NOW()
+		WHERE id = $1
+	`, id, status, errMsg)
+	return err
+}
+
+// UpdateOrigin records the repository's fork and template origin, along with
+// its GitHub creation date when createdAt is non-nil.
+func (s *RepositoryStore) UpdateOrigin(ctx context.Context, id int64, origin *RepositoryOrigin, createdAt *time.Time) error {
+	_, err := s.pool.Exec(ctx, `
+		UPDATE repositories SET
+			is_fork = $2,
+			fork_parent_id = $3,
+			fork_parent = $4,
+			fork_source = $5,
+			template_repository_id = $6,
+			template_repository = $7,
+			origin_checked_at = NOW(),
+			github_created_at = COALESCE($8, github_created_at),
+			updated_at = NOW()
+		WHERE id = $1
+	`, id, origin.IsFork, origin.ParentID, origin.Parent, origin.Source, origin.TemplateID, origin.Template, createdAt)
+	return err
+}
+
+func (s *RepositoryStore) SetBackfillProgress(ctx context.Context, id int64, commits int) error {
+	_, err := s.pool.Exec(ctx, `
+		UPDATE repositories SET backfill_commits = $2, updated_at = NOW()
+		WHERE id = $1
+	`, id, commits)
+	return err
+}
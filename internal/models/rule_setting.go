@@ -0,0 +1,113 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RuleSetting overrides a detection rule's defaults globally, for an event,
+// or for a repository. Nil fields and missing params keep the less specific
+// value.
+type RuleSetting struct {
+	ID           int64
+	Rule         string
+	EventID      *int64
+	RepositoryID *int64
+	Enabled      *bool
+	Severity     *Severity
+	Params       map[string]float64
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+type RuleSettingStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewRuleSettingStore(pool *pgxpool.Pool) *RuleSettingStore {
+	return &RuleSettingStore{pool: pool}
+}
+
+const ruleSettingColumns = `id, rule, event_id, repository_id, enabled, severity, params, created_at, updated_at`
+
+func scanRuleSettings(rows pgx.Rows) ([]*RuleSetting, error) {
+	var settings []*RuleSetting
+	for rows.Next() {
+		var s RuleSetting
+		err := rows.Scan(&s.ID, &s.Rule, &s.EventID, &s.RepositoryID, &s.Enabled, &s.Severity, &s.Params, &s.CreatedAt, &s.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		settings = append(settings, &s)
+	}
+	return settings, rows.Err()
+}
+
+// ListForRepository returns the settings of every rule that apply to a
+// repository: global ones, its event's and its own. It takes a Querier so
+// ingestion can read them inside its transaction.
+func (s *RuleSettingStore) ListForRepository(ctx context.Context, q Querier, repoID int64) ([]*RuleSetting, error) {
+	rows, err := q.Query(ctx, `
+		SELECT `+ruleSettingColumns+`
+		FROM rule_settings
+		WHERE repository_id = $1
+		   OR event_id = (SELECT event_id FROM repositories WHERE id = $1)
+		   OR (event_id IS NULL AND repository_id IS NULL)
+		ORDER BY rule, id
+	`, repoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanRuleSettings(rows)
+}
+
+// List returns all settings of a rule, global first, then by event and
+// repository.
+func (s *RuleSettingStore) List(ctx context.Context, rule string) ([]*RuleSetting, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT `+ruleSettingColumns+`
+		FROM rule_settings
+		WHERE rule = $1
+		ORDER BY event_id NULLS FIRST, repository_id NULLS FIRST
+	`, rule)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanRuleSettings(rows)
+}
+
+// Upsert creates or replaces the setting for the rule and scope of setting.
+func (s *RuleSettingStore) Upsert(ctx context.Context, setting *RuleSetting) error {
+	if setting.Params == nil {
+		setting.Params = map[string]float64{}
+	}
+	return s.pool.QueryRow(ctx, `
+		INSERT INTO rule_settings (rule, event_id, repository_id, enabled, severity, params)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (rule, COALESCE(event_id, 0), COALESCE(repository_id, 0)) DO UPDATE SET
+			enabled = EXCLUDED.enabled,
+			severity = EXCLUDED.severity,
+			params = EXCLUDED.params,
+			updated_at = NOW()
+		RETURNING id, created_at, updated_at
+	`, setting.Rule, setting.EventID, setting.RepositoryID, setting.Enabled, setting.Severity, setting.Params,
+	).Scan(&setting.ID, &setting.CreatedAt, &setting.UpdatedAt)
+}
+
+// Delete removes the setting for a rule and scope, reporting whether one
+// existed.
+func (s *RuleSettingStore) Delete(ctx context.Context, rule string, eventID, repoID *int64) (bool, error) {
+	tag, err := s.pool.Exec(ctx, `
+		DELETE FROM rule_settings
+		WHERE rule = $1 AND event_id IS NOT DISTINCT FROM $2 AND repository_id IS NOT DISTINCT FROM $3
+	`, rule, eventID, repoID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
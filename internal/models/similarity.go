@@ -0,0 +1,338 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// CodeSnapshot is the fingerprinted source of a repository's submission.
+// A truncated snapshot covers only the start of an archive too large to
+// download whole.
+type CodeSnapshot struct {
+	ID           int64
+	EventID      int64
+	RepositoryID int64
+	Ref          string
+	HeadSHA      string
+	Files        int
+	Fingerprints int
+	Truncated    bool
+	CreatedAt    time.Time
+}
+
+type SnapshotFile struct {
+	RepositoryID int64
+	Path         string
+	Fingerprints []int64
+}
+
+// PendingSnapshot is a frozen submission whose source has not been
+// fingerprinted yet. Ref and HeadSHA are the default branch's submission,
+// or the most recently pushed branch when the default branch has none.
+type PendingSnapshot struct {
+	RepositoryID   int64
+	FullName       string
+	InstallationID int64
+	Owner          string
+	Name           string
+	EventID        int64
+	Ref            string
+	HeadSHA        string
+}
+
+// SimilarFile is one of the best matching file pairs of a SimilarityPair.
+type SimilarFile struct {
+	FileA      string  `json:"file_a"`
+	FileB      string  `json:"file_b"`
+	Shared     int     `json:"shared_fingerprints"`
+	Similarity float64 `json:"similarity"`
+}
+
+// SimilarityPair is the code similarity of two repositories of an event;
+// RepositoryAID is always the lower ID.
+type SimilarityPair struct {
+	ID                 int64
+	EventID            int64
+	RepositoryAID      int64
+	RepositoryA        string
+	RepositoryBID      int64
+	RepositoryB        string
+	Similarity         float64
+	Jaccard            float64
+	SharedFingerprints int
+	TopFiles           []SimilarFile
+	ComputedAt         time.Time
+}
+
+type SimilarityStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewSimilarityStore(pool *pgxpool.Pool) *SimilarityStore {
+	return &SimilarityStore{pool: pool}
+}
+
+// A failed snapshot is retried after snapshotRetryDelay, doubling with each
+// further failure of the same commit up to snapshotRetryMaxDelay.
+const (
+	snapshotRetryDelay    = time.Hour
+	snapshotRetryMaxDelay = 24 * time.Hour
+)
+
+// ListPending returns the frozen submissions of active repositories that
+// have no snapshot for their event yet, leaving out those whose last
+// attempt at the same commit failed and is not due for a retry.
+func (s *SimilarityStore) ListPending(ctx context.Context) ([]*PendingSnapshot, error) {
+	rows, err := s.pool.Query(ctx, `
+		WITH pending AS (
+			SELECT DISTINCT ON (r.id) r.id, r.full_name, r.installation_id, r.owner, r.name, r.event_id, sub.ref, sub.head_sha
+			FROM repositories r
+			JOIN submissions sub ON sub.repository_id = r.id AND sub.event_id = r.event_id
+			WHERE r.status = 'active'
+			  AND r.submission_frozen_at IS NOT NULL
+			  AND NOT EXISTS (
+				SELECT 1 FROM code_snapshots c WHERE c.event_id = r.event_id AND c.repository_id = r.id
+			  )
+			ORDER BY r.id, sub.ref = 'refs/heads/' || r.default_branch DESC, sub.pushed_at DESC NULLS LAST
+		)
+		SELECT p.*
+		FROM pending p
+		WHERE NOT EXISTS (
+			SELECT 1 FROM code_snapshot_failures f
+			WHERE f.event_id = p.event_id AND f.repository_id = p.id
+			  AND f.head_sha = p.head_sha AND f.retry_at > NOW()
+		)
+		ORDER BY p.id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pending []*PendingSnapshot
+	for rows.Next() {
+		var p PendingSnapshot
+		err := rows.Scan(&p.RepositoryID, &p.FullName, &p.InstallationID, &p.Owner, &p.Name, &p.EventID, &p.Ref, &p.HeadSHA)
+		if err != nil {
+			return nil, err
+		}
+		pending = append(pending, &p)
+	}
+	return pending, rows.Err()
+}
+
+// RecordFailure stores a failed snapshot attempt and schedules the next
+// one. Failures of a different commit start the delay over.
+func (s *SimilarityStore) RecordFailure(ctx context.Context, p *PendingSnapshot, message string) error {
+	_, err := s.pool.Exec(ctx, `
+		INSERT INTO code_snapshot_failures (event_id, repository_id, head_sha, attempts, last_error, retry_at)
+		VALUES ($1, $2, $3, 1, $4, NOW() + make_interval(secs => $5))
+		ON CONFLICT (event_id, repository_id) DO UPDATE SET
+			attempts = CASE WHEN code_snapshot_failures.head_sha = EXCLUDED.head_sha
+				THEN code_snapshot_failures.attempts + 1 ELSE 1 END,
+			retry_at = NOW() + CASE WHEN code_snapshot_failures.head_sha = EXCLUDED.head_sha
+				THEN make_interval(secs => LEAST($5 * power(2, code_snapshot_failures.attempts), $6))
+				ELSE make_interval(secs => $5) END,
+			head_sha = EXCLUDED.head_sha,
+			last_error = EXCLUDED.last_error,
+			updated_at = NOW()
+	`, p.EventID, p.RepositoryID, p.HeadSHA, message, snapshotRetryDelay.Seconds(), snapshotRetryMaxDelay.Seconds())
+	return err
+}
+
+// SaveSnapshot stores a snapshot and the fingerprints of its files,
+// replacing any earlier snapshot of the repository for the event and
+// clearing its recorded failures.
+func (s *SimilarityStore) SaveSnapshot(ctx context.Context, snap *CodeSnapshot, files []*SnapshotFile) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	snap.Files = len(files)
+	snap.Fingerprints = 0
+	for _, f := range files {
+		snap.Fingerprints += len(f.Fingerprints)
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM code_snapshots WHERE event_id = $1 AND repository_id = $2`, snap.EventID, snap.RepositoryID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `DELETE FROM code_snapshot_failures WHERE event_id = $1 AND repository_id = $2`, snap.EventID, snap.RepositoryID)
+	if err != nil {
+		return err
+	}
+	err = tx.QueryRow(ctx, `
+		INSERT INTO code_snapshots (event_id, repository_id, ref, head_sha, files, fingerprints, truncated)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`, snap.EventID, snap.RepositoryID, snap.Ref, snap.HeadSHA, snap.Files, snap.Fingerprints, snap.Truncated).Scan(&snap.ID, &snap.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	batch := &pgx.Batch{}
+	for _, f := range files {
+		batch.Queue(`
+			INSERT INTO code_snapshot_files (snapshot_id, path, fingerprints)
+			VALUES ($1, $2, $3)
+		`, snap.ID, f.Path, f.Fingerprints)
+	}
+	if batch.Len() > 0 {
+		if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ListSnapshots returns the snapshots taken for an event.
+func (s *SimilarityStore) ListSnapshots(ctx context.Context, eventID int64) ([]*CodeSnapshot, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, event_id, repository_id, ref, head_sha, files, fingerprints, truncated, created_at
+		FROM code_snapshots
+		WHERE event_id = $1
+		ORDER BY repository_id
+	`, eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var snapshots []*CodeSnapshot
+	for rows.Next() {
+		var c CodeSnapshot
+		err := rows.Scan(&c.ID, &c.EventID, &c.RepositoryID, &c.Ref, &c.HeadSHA, &c.Files, &c.Fingerprints, &c.Truncated, &c.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, &c)
+	}
+	return snapshots, rows.Err()
+}
+
+// ListFiles returns the fingerprinted files of every snapshot of an event.
+func (s *SimilarityStore) ListFiles(ctx context.Context, eventID int64) ([]*SnapshotFile, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT c.repository_id, f.path, f.fingerprints
+		FROM code_snapshot_files f
+		JOIN code_snapshots c ON c.id = f.snapshot_id
+		WHERE c.event_id = $1
+		ORDER BY c.repository_id, f.path
+	`, eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var files []*SnapshotFile
+	for rows.Next() {
+		var f SnapshotFile
+		if err := rows.Scan(&f.RepositoryID, &f.Path, &f.Fingerprints); err != nil {
+			return nil, err
+		}
+		files = append(files, &f)
+	}
+	return files, rows.Err()
+}
+
+// ListStaleEvents returns the events with snapshots taken since their
+// similarity was last computed.
+func (s *SimilarityStore) ListStaleEvents(ctx context.Context) ([]int64, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT e.id
+		FROM events e
+		WHERE EXISTS (
+			SELECT 1 FROM code_snapshots c
+			WHERE c.event_id = e.id
+			  AND (e.similarity_computed_at IS NULL OR c.created_at > e.similarity_computed_at)
+		)
+		ORDER BY e.id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// ReplacePairs stores the event's freshly computed similarity pairs in place
+// of the previous ones and stamps the event as computed.
+func (s *SimilarityStore) ReplacePairs(ctx context.Context, eventID int64, pairs []*SimilarityPair) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM similarity_pairs WHERE event_id = $1`, eventID); err != nil {
+		return err
+	}
+
+	batch := &pgx.Batch{}
+	for _, p := range pairs {
+		batch.Queue(`
+			INSERT INTO similarity_pairs (event_id, repository_a_id, repository_b_id, similarity, jaccard, shared_fingerprints, top_files)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`, eventID, p.RepositoryAID, p.RepositoryBID, p.Similarity, p.Jaccard, p.SharedFingerprints, p.TopFiles)
+	}
+	if batch.Len() > 0 {
+		if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE events SET similarity_computed_at = NOW() WHERE id = $1`, eventID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ListPairs returns an event's similarity pairs at or above minSimilarity,
+// most similar first.
+func (s *SimilarityStore) ListPairs(ctx context.Context, eventID int64, minSimilarity float64) ([]*SimilarityPair, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT p.id, p.event_id, p.repository_a_id, a.full_name, p.repository_b_id, b.full_name,
+		       p.similarity, p.jaccard, p.shared_fingerprints, p.top_files, p.computed_at
+		FROM similarity_pairs p
+		JOIN repositories a ON a.id = p.repository_a_id
+		JOIN repositories b ON b.id = p.repository_b_id
+		WHERE p.event_id = $1 AND p.similarity >= $2
+		ORDER BY p.similarity DESC, p.repository_a_id, p.repository_b_id
+	`, eventID, minSimilarity)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pairs []*SimilarityPair
+	for rows.Next() {
+		var p SimilarityPair
+		err := rows.Scan(
+			&p.ID, &p.EventID, &p.RepositoryAID, &p.RepositoryA, &p.RepositoryBID, &p.RepositoryB,
+			&p.Similarity, &p.Jaccard, &p.SharedFingerprints, &p.TopFiles, &p.ComputedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, &p)
+	}
+	return pairs, rows.Err()
+}
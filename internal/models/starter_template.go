@@ -0,0 +1,121 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// StarterTemplate is starter code a team declared building on. Once an
+// organizer approves it, the template's history no longer counts as
+// pre-event code: commits authored up to BaseSHA, the last template commit,
+// or every pre-event commit when no BaseSHA was given.
+type StarterTemplate struct {
+	ID           int64
+	RepositoryID int64
+	Source       string
+	BaseSHA      *string
+	Notes        *string
+	ApprovedBy   *string
+	ApprovedAt   *time.Time
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+func (t *StarterTemplate) Approved() bool {
+	return t != nil && t.ApprovedAt != nil
+}
+
+type StarterTemplateStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewStarterTemplateStore(pool *pgxpool.Pool) *StarterTemplateStore {
+	return &StarterTemplateStore{pool: pool}
+}
+
+// Get returns the repository's declared template, or nil when there is none.
+func (s *StarterTemplateStore) Get(ctx context.Context, repoID int64) (*StarterTemplate, error) {
+	var t StarterTemplate
+	err := s.pool.QueryRow(ctx, `
+		SELECT id, repository_id, source, base_sha, notes, approved_by, approved_at, created_at, updated_at
+		FROM starter_templates
+		WHERE repository_id = $1
+	`, repoID).Scan(
+		&t.ID, &t.RepositoryID, &t.Source, &t.BaseSHA, &t.Notes,
+		&t.ApprovedBy, &t.ApprovedAt, &t.CreatedAt, &t.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Declare records the repository's template, replacing any earlier
+// declaration. A changed declaration needs approving again.
+func (s *StarterTemplateStore) Declare(ctx context.Context, t *StarterTemplate) error {
+	return s.pool.QueryRow(ctx, `
+		INSERT INTO starter_templates (repository_id, source, base_sha, notes)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (repository_id) DO UPDATE SET
+			source = EXCLUDED.source,
+			base_sha = EXCLUDED.base_sha,
+			notes = EXCLUDED.notes,
+			approved_by = NULL,
+			approved_at = NULL,
+			updated_at = NOW()
+		RETURNING id, approved_by, approved_at, created_at, updated_at
+	`, t.RepositoryID, t.Source, t.BaseSHA, t.Notes).Scan(&t.ID, &t.ApprovedBy, &t.ApprovedAt, &t.CreatedAt, &t.UpdatedAt)
+}
+
+// Approve marks the repository's declared template as approved, reporting
+// whether there was one to approve.
+func (s *StarterTemplateStore) Approve(ctx context.Context, repoID int64, approvedBy string) (bool, error) {
+	tag, err := s.pool.Exec(ctx, `
+		UPDATE starter_templates SET approved_by = NULLIF($2, ''), approved_at = NOW(), updated_at = NOW()
+		WHERE repository_id = $1
+	`, repoID, approvedBy)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (s *StarterTemplateStore) Delete(ctx context.Context, repoID int64) (bool, error) {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM starter_templates WHERE repository_id = $1`, repoID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// ExemptBefore returns the author date up to which pre-event commits are
+// covered by an approved template: the base commit's author date, or the
+// event start when the template names no base commit. It returns nil when
+// the template is not approved or its base commit has not been seen yet.
+func (s *StarterTemplateStore) ExemptBefore(ctx context.Context, t *StarterTemplate, event *Event) (*time.Time, error) {
+	if !t.Approved() {
+		return nil, nil
+	}
+	if t.BaseSHA == nil {
+		return &event.StartsAt, nil
+	}
+
+	var authorDate time.Time
+	err := s.pool.QueryRow(ctx, `
+		SELECT author_date FROM commits WHERE repository_id = $1 AND sha = $2
+	`, t.RepositoryID, *t.BaseSHA).Scan(&authorDate)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &authorDate, nil
+}
@@ -0,0 +1,194 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type StreakStatus string
+
+const (
+	// StreakActive means the repository has recent activity.
+	StreakActive StreakStatus = "active"
+	// StreakAtRisk means the repository has been idle past the at-risk threshold.
+	StreakAtRisk StreakStatus = "at_risk"
+	// StreakInactive means the repository has been idle long enough to break its streak.
+	StreakInactive StreakStatus = "inactive"
+	// StreakRecovered means activity resumed after the repository was at risk
+	// or inactive; it settles back to active after the recovery period.
+	StreakRecovered StreakStatus = "recovered"
+)
+
+// Streak transition reasons.
+const (
+	StreakReasonPush       = "push"
+	StreakReasonInactivity = "inactivity"
+	StreakReasonSettled    = "settled"
+)
+
+type StreakTransition struct {
+	ID             int64
+	RepositoryID   int64
+	FromStatus     StreakStatus
+	ToStatus       StreakStatus
+	Reason         string
+	StreakDays     int
+	LastActivityAt *time.Time
+	CreatedAt      time.Time
+}
+
+// Querier is implemented by both *pgxpool.Pool and pgx.Tx, so a store method
+// can take part in a caller's transaction.
+type Querier interface {
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// StreakDays returns the length in days of the streak running from start to
+// the last activity. A streak with activity on a single day is one day long.
+func StreakDays(start, lastActivity *time.Time) int {
+	if start == nil || lastActivity == nil || lastActivity.Before(*start) {
+		return 0
+	}
+	return int(lastActivity.Sub(*start).Hours()/24) + 1
+}
+
+// CurrentStreakDays returns the repository's running streak, which is zero
+// once the streak has been broken by inactivity.
+func (r *Repository) CurrentStreakDays() int {
+	if r.StreakStatus == StreakInactive {
+		return 0
+	}
+	return StreakDays(r.StreakStartedAt, r.LastActivityAt)
+}
+
+type StreakStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewStreakStore(pool *pgxpool.Pool) *StreakStore {
+	return &StreakStore{pool: pool}
+}
+
+// Transition moves a repository between streak states on q, recording the
+// change in streak_transitions. Recovering from inactive starts a new streak
+// at the time of the change; breaking a streak folds it into the longest one.
+func (s *StreakStore) Transition(ctx context.Context, q Querier, repoID int64, from, to StreakStatus, reason string, at time.Time) (*StreakTransition, error) {
+	t := &StreakTransition{
+		RepositoryID: repoID,
+		FromStatus:   from,
+		ToStatus:     to,
+		Reason:       reason,
+	}
+
+	var started *time.Time
+	err := q.QueryRow(ctx, `
+		SELECT streak_started_at, last_activity_at FROM repositories WHERE id = $1
+	`, repoID).Scan(&started, &t.LastActivityAt)
+	if err != nil {
+		return nil, err
+	}
+	t.StreakDays = StreakDays(started, t.LastActivityAt)
+
+	_, err = q.Exec(ctx, `
+		UPDATE repositories SET
+			streak_status = $2,
+			streak_changed_at = $3,
+			streak_started_at = CASE WHEN $4 = 'inactive' AND $2 = 'recovered' THEN $3 ELSE streak_started_at END,
+			longest_streak_days = CASE WHEN $2 = 'inactive' THEN GREATEST(longest_streak_days, $5) ELSE longest_streak_days END,
+			updated_at = NOW()
+		WHERE id = $1
+	`, repoID, to, at, from, t.StreakDays)
+	if err != nil {
+		return nil, err
+	}
+
+	err = q.QueryRow(ctx, `
+		INSERT INTO streak_transitions (repository_id, from_status, to_status, reason, streak_days, last_activity_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`, repoID, from, to, reason, t.StreakDays, t.LastActivityAt, at).Scan(&t.ID, &t.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if alert := streakAlert(t); alert != nil {
+		_, err = q.Exec(ctx, `
+			INSERT INTO alerts (repository_id, alert_type, severity, title, description, metadata)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`, repoID, alert.AlertType, alert.Severity, alert.Title, alert.Description, alert.Metadata)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return t, nil
+}
+
+// streakAlert returns the alert raised by a transition, or nil when a
+// recovered repository settles back to active.
+func streakAlert(t *StreakTransition) *Alert {
+	metadata := map[string]interface{}{
+		"from":             t.FromStatus,
+		"to":               t.ToStatus,
+		"streak_days":      t.StreakDays,
+		"last_activity_at": t.LastActivityAt,
+	}
+
+	switch t.ToStatus {
+	case StreakAtRisk:
+		return &Alert{
+			AlertType:   AlertStreakAtRisk,
+			Severity:    SeverityWarning,
+			Title:       "Activity streak at risk",
+			Description: "Repository has had no activity past the at-risk threshold",
+			Metadata:    metadata,
+		}
+	case StreakInactive:
+		return &Alert{
+			AlertType:   AlertStreakInactive,
+			Severity:    SeverityCritical,
+			Title:       "Activity streak broken",
+			Description: "Repository has been inactive long enough to break its streak",
+			Metadata:    metadata,
+		}
+	case StreakRecovered:
+		return &Alert{
+			AlertType:   AlertStreakRecovered,
+			Severity:    SeverityInfo,
+			Title:       "Activity resumed",
+			Description: "Repository activity resumed after a period of inactivity",
+			Metadata:    metadata,
+		}
+	}
+	return nil
+}
+
+func (s *StreakStore) ListTransitions(ctx context.Context, repoID int64, limit int) ([]*StreakTransition, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, repository_id, from_status, to_status, reason, streak_days, last_activity_at, created_at
+		FROM streak_transitions WHERE repository_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`, repoID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transitions []*StreakTransition
+	for rows.Next() {
+		var t StreakTransition
+		err := rows.Scan(&t.ID, &t.RepositoryID, &t.FromStatus, &t.ToStatus, &t.Reason, &t.StreakDays, &t.LastActivityAt, &t.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		transitions = append(transitions, &t)
+	}
+	return transitions, nil
+}
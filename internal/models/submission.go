@@ -0,0 +1,187 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// zeroSHA is the after SHA of a push that deleted its ref.
+const zeroSHA = "0000000000000000000000000000000000000000"
+
+// Submission is the head of one branch of a repository when its event's
+// submission deadline passed.
+type Submission struct {
+	ID           int64
+	EventID      int64
+	RepositoryID int64
+	Ref          string
+	HeadSHA      string
+	PushEventID  *int64
+	PushedAt     *time.Time
+	CutoffAt     time.Time
+	FrozenAt     time.Time
+}
+
+// SubmissionExport is one row of an event's submission export: a branch
+// head, or a repository with no frozen branches when Ref is nil.
+type SubmissionExport struct {
+	RepositoryID       int64
+	FullName           string
+	FrozenAt           *time.Time
+	Ref                *string
+	HeadSHA            *string
+	PushedAt           *time.Time
+	CutoffAt           *time.Time
+	PostDeadlinePushes int
+}
+
+// DueSubmission is an enrolled repository whose submission cutoff has passed
+// but that has not been frozen yet.
+type DueSubmission struct {
+	RepositoryID int64
+	FullName     string
+	EventID      int64
+	CutoffAt     time.Time
+}
+
+type SubmissionStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewSubmissionStore(pool *pgxpool.Pool) *SubmissionStore {
+	return &SubmissionStore{pool: pool}
+}
+
+// ListDue returns the enrolled repositories whose event deadline plus the
+// grace period lies before now and that have not been frozen.
+func (s *SubmissionStore) ListDue(ctx context.Context, graceMinutes int, now time.Time) ([]*DueSubmission, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT r.id, r.full_name, e.id, COALESCE(e.submission_deadline, e.ends_at) + $1 * INTERVAL '1 minute'
+		FROM repositories r
+		JOIN events e ON e.id = r.event_id
+		WHERE r.submission_frozen_at IS NULL
+		  AND COALESCE(e.submission_deadline, e.ends_at) + $1 * INTERVAL '1 minute' <= $2
+		ORDER BY r.id
+	`, graceMinutes, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var due []*DueSubmission
+	for rows.Next() {
+		var d DueSubmission
+		if err := rows.Scan(&d.RepositoryID, &d.FullName, &d.EventID, &d.CutoffAt); err != nil {
+			return nil, err
+		}
+		due = append(due, &d)
+	}
+	return due, rows.Err()
+}
+
+// Freeze records the head of every branch of a repository as of cutoff,
+// taken from the last push to each branch made by then; pushes are timed by
+// GitHub's push timestamp where one was stored, as deadline flagging does,
+// so a push delivered late still counts. Branches whose last push deleted
+// them are left out. It marks the repository frozen and
+// returns the number of branches recorded.
+func (s *SubmissionStore) Freeze(ctx context.Context, eventID, repoID int64, cutoff time.Time) (int, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO submissions (event_id, repository_id, ref, head_sha, push_event_id, pushed_at, cutoff_at)
+		SELECT $1, repository_id, ref, after_sha, id, pushed_at, $3
+		FROM (
+			SELECT DISTINCT ON (p.ref) p.id, p.repository_id, p.ref, p.after_sha,
+			       COALESCE(p.github_pushed_at, p.received_at) AS pushed_at
+			FROM push_events p
+			WHERE p.repository_id = $2 AND p.ref LIKE 'refs/heads/%'
+			  AND COALESCE(p.github_pushed_at, p.received_at) <= $3
+			ORDER BY p.ref, COALESCE(p.github_pushed_at, p.received_at) DESC, p.id DESC
+		) heads
+		WHERE after_sha IS NOT NULL AND after_sha <> $4
+		ON CONFLICT (event_id, repository_id, ref) DO NOTHING
+	`, eventID, repoID, cutoff, zeroSHA)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE repositories SET submission_frozen_at = NOW(), updated_at = NOW()
+		WHERE id = $1
+	`, repoID)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(tag.RowsAffected()), tx.Commit(ctx)
+}
+
+func (s *SubmissionStore) ListByRepository(ctx context.Context, eventID, repoID int64) ([]*Submission, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, event_id, repository_id, ref, head_sha, push_event_id, pushed_at, cutoff_at, frozen_at
+		FROM submissions
+		WHERE event_id = $1 AND repository_id = $2
+		ORDER BY ref
+	`, eventID, repoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var submissions []*Submission
+	for rows.Next() {
+		var sub Submission
+		err := rows.Scan(
+			&sub.ID, &sub.EventID, &sub.RepositoryID, &sub.Ref, &sub.HeadSHA,
+			&sub.PushEventID, &sub.PushedAt, &sub.CutoffAt, &sub.FrozenAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		submissions = append(submissions, &sub)
+	}
+	return submissions, nil
+}
+
+// ListByEvent returns the submission export of every repository enrolled in
+// an event, one row per frozen branch. Late pushes are only counted from the
+// event's start, so those of a repository's earlier event are left out.
+func (s *SubmissionStore) ListByEvent(ctx context.Context, eventID int64) ([]*SubmissionExport, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT r.id, r.full_name, r.submission_frozen_at,
+		       sub.ref, sub.head_sha, sub.pushed_at, sub.cutoff_at,
+		       (SELECT COUNT(*) FROM push_events p
+		        WHERE p.repository_id = r.id AND p.post_deadline
+		          AND COALESCE(p.github_pushed_at, p.received_at) >= e.starts_at) as post_deadline_pushes
+		FROM repositories r
+		JOIN events e ON e.id = r.event_id
+		LEFT JOIN submissions sub ON sub.repository_id = r.id AND sub.event_id = r.event_id
+		WHERE r.event_id = $1
+		ORDER BY r.full_name, sub.ref
+	`, eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var exports []*SubmissionExport
+	for rows.Next() {
+		var e SubmissionExport
+		err := rows.Scan(
+			&e.RepositoryID, &e.FullName, &e.FrozenAt,
+			&e.Ref, &e.HeadSHA, &e.PushedAt, &e.CutoffAt, &e.PostDeadlinePushes,
+		)
+		if err != nil {
+			return nil, err
+		}
+		exports = append(exports, &e)
+	}
+	return exports, nil
+}
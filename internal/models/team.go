@@ -0,0 +1,421 @@
+package models
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Team is a roster of the people expected to work on the repositories
+// assigned to it.
+type Team struct {
+	ID        int64
+	EventID   *int64
+	Name      string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type TeamWithStats struct {
+	Team
+	MembersCount      int
+	RepositoriesCount int
+}
+
+// TeamMember is a registered participant, identified by GitHub login and
+// the commit emails they are known to use.
+type TeamMember struct {
+	ID          int64
+	TeamID      int64
+	GitHubLogin string
+	Name        *string
+	Emails      []string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// MemberStats is a roster member's activity in one repository.
+type MemberStats struct {
+	Member        TeamMember
+	Commits       int
+	Additions     int64
+	Deletions     int64
+	FirstCommitAt *time.Time
+	LastCommitAt  *time.Time
+}
+
+// NormalizeEmails lowercases, trims and de-duplicates emails, dropping blanks.
+func NormalizeEmails(emails []string) []string {
+	seen := make(map[string]bool, len(emails))
+	normalized := make([]string, 0, len(emails))
+	for _, e := range emails {
+		e = strings.ToLower(strings.TrimSpace(e))
+		if e == "" || seen[e] {
+			continue
+		}
+		seen[e] = true
+		normalized = append(normalized, e)
+	}
+	return normalized
+}
+
+// linkContributorsSQL points contributors at the roster member of their
+// repository's team matching their login or email, preferring a login match.
+// Contributors of repositories without a team, or with no match, are unlinked.
+// Callers append the condition selecting which contributors to relink.
+const linkContributorsSQL = `
+	UPDATE contributors c SET team_member_id = (
+		SELECT m.id FROM team_members m
+		WHERE m.team_id = r.team_id
+		  AND (LOWER(m.github_login) = LOWER(c.github_login) OR LOWER(c.email) = ANY(m.emails))
+		ORDER BY LOWER(m.github_login) = LOWER(c.github_login) DESC NULLS LAST, m.id
+		LIMIT 1
+	)
+	FROM repositories r
+	WHERE r.id = c.repository_id AND `
+
+const teamMemberColumns = `m.id, m.team_id, m.github_login, m.name, m.emails, m.created_at, m.updated_at`
+
+func scanTeamMember(row pgx.Row, m *TeamMember, extra ...interface{}) error {
+	dest := []interface{}{&m.ID, &m.TeamID, &m.GitHubLogin, &m.Name, &m.Emails, &m.CreatedAt, &m.UpdatedAt}
+	return row.Scan(append(dest, extra...)...)
+}
+
+type TeamStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewTeamStore(pool *pgxpool.Pool) *TeamStore {
+	return &TeamStore{pool: pool}
+}
+
+func (s *TeamStore) Create(ctx context.Context, t *Team) error {
+	return s.pool.QueryRow(ctx, `
+		INSERT INTO teams (event_id, name)
+		VALUES ($1, $2)
+		RETURNING id, created_at, updated_at
+	`, t.EventID, t.Name).Scan(&t.ID, &t.CreatedAt, &t.UpdatedAt)
+}
+
+func (s *TeamStore) Update(ctx context.Context, t *Team) error {
+	return s.pool.QueryRow(ctx, `
+		UPDATE teams SET event_id = $2, name = $3, updated_at = NOW()
+		WHERE id = $1
+		RETURNING created_at, updated_at
+	`, t.ID, t.EventID, t.Name).Scan(&t.CreatedAt, &t.UpdatedAt)
+}
+
+// Delete removes a team and its roster. Its repositories and their
+// contributors are kept but no longer linked to it.
+func (s *TeamStore) Delete(ctx context.Context, id int64) (bool, error) {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM teams WHERE id = $1`, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (s *TeamStore) GetByID(ctx context.Context, id int64) (*TeamWithStats, error) {
+	var t TeamWithStats
+	err := s.pool.QueryRow(ctx, `
+		SELECT t.id, t.event_id, t.name, t.created_at, t.updated_at,
+			(SELECT COUNT(*) FROM team_members m WHERE m.team_id = t.id) as members_count,
+			(SELECT COUNT(*) FROM repositories r WHERE r.team_id = t.id) as repositories_count
+		FROM teams t WHERE t.id = $1
+	`, id).Scan(&t.ID, &t.EventID, &t.Name, &t.CreatedAt, &t.UpdatedAt, &t.MembersCount, &t.RepositoriesCount)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// List returns all teams, or only those of one event when eventID is set.
+func (s *TeamStore) List(ctx context.Context, eventID *int64) ([]*TeamWithStats, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT t.id, t.event_id, t.name, t.created_at, t.updated_at,
+			(SELECT COUNT(*) FROM team_members m WHERE m.team_id = t.id) as members_count,
+			(SELECT COUNT(*) FROM repositories r WHERE r.team_id = t.id) as repositories_count
+		FROM teams t
+		WHERE $1::bigint IS NULL OR t.event_id = $1
+		ORDER BY t.name
+	`, eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var teams []*TeamWithStats
+	for rows.Next() {
+		var t TeamWithStats
+		if err := rows.Scan(&t.ID, &t.EventID, &t.Name, &t.CreatedAt, &t.UpdatedAt, &t.MembersCount, &t.RepositoriesCount); err != nil {
+			return nil, err
+		}
+		teams = append(teams, &t)
+	}
+	return teams, nil
+}
+
+func (s *TeamStore) ListMembers(ctx context.Context, teamID int64) ([]*TeamMember, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT `+teamMemberColumns+`
+		FROM team_members m WHERE m.team_id = $1
+		ORDER BY LOWER(m.github_login)
+	`, teamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []*TeamMember
+	for rows.Next() {
+		var m TeamMember
+		if err := scanTeamMember(rows, &m); err != nil {
+			return nil, err
+		}
+		members = append(members, &m)
+	}
+	return members, nil
+}
+
+func (s *TeamStore) GetMember(ctx context.Context, teamID, memberID int64) (*TeamMember, error) {
+	var m TeamMember
+	row := s.pool.QueryRow(ctx, `SELECT `+teamMemberColumns+` FROM team_members m WHERE m.team_id = $1 AND m.id = $2`, teamID, memberID)
+	if err := scanTeamMember(row, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// CreateMember adds a member to a team and links the team's existing
+// contributors to them.
+func (s *TeamStore) CreateMember(ctx context.Context, m *TeamMember) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO team_members (team_id, github_login, name, emails)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, updated_at
+	`, m.TeamID, m.GitHubLogin, m.Name, NormalizeEmails(m.Emails)).Scan(&m.ID, &m.CreatedAt, &m.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	if err := s.relinkTeam(ctx, tx, m.TeamID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// UpdateMember replaces a member's login, name and emails and relinks the
+// team's contributors.
+func (s *TeamStore) UpdateMember(ctx context.Context, m *TeamMember) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+		UPDATE team_members SET github_login = $3, name = $4, emails = $5, updated_at = NOW()
+		WHERE team_id = $1 AND id = $2
+		RETURNING created_at, updated_at
+	`, m.TeamID, m.ID, m.GitHubLogin, m.Name, NormalizeEmails(m.Emails)).Scan(&m.CreatedAt, &m.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	if err := s.relinkTeam(ctx, tx, m.TeamID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// DeleteMember removes a member. Contributors linked to them fall back to
+// any other member matching their identity.
+func (s *TeamStore) DeleteMember(ctx context.Context, teamID, memberID int64) (bool, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `DELETE FROM team_members WHERE team_id = $1 AND id = $2`, teamID, memberID)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+	if err := s.relinkTeam(ctx, tx, teamID); err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
+
+// ImportMembers upserts members by GitHub login, merging emails into those
+// already known. With replace set, members missing from the import are
+// removed. It returns the number of members created and updated.
+func (s *TeamStore) ImportMembers(ctx context.Context, teamID int64, members []*TeamMember, replace bool) (created, updated int, err error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	logins := make([]string, 0, len(members))
+	for _, m := range members {
+		var inserted bool
+		err := tx.QueryRow(ctx, `
+			INSERT INTO team_members (team_id, github_login, name, emails)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (team_id, LOWER(github_login)) DO UPDATE SET
+				name = COALESCE(EXCLUDED.name, team_members.name),
+				emails = ARRAY(SELECT DISTINCT unnest(team_members.emails || EXCLUDED.emails)),
+				updated_at = NOW()
+			RETURNING id, created_at, updated_at, xmax = 0
+		`, teamID, m.GitHubLogin, m.Name, NormalizeEmails(m.Emails)).Scan(&m.ID, &m.CreatedAt, &m.UpdatedAt, &inserted)
+		if err != nil {
+			return 0, 0, err
+		}
+		m.TeamID = teamID
+		if inserted {
+			created++
+		} else {
+			updated++
+		}
+		logins = append(logins, strings.ToLower(m.GitHubLogin))
+	}
+
+	if replace {
+		_, err := tx.Exec(ctx, `
+			DELETE FROM team_members WHERE team_id = $1 AND NOT (LOWER(github_login) = ANY($2))
+		`, teamID, logins)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+
+	if err := s.relinkTeam(ctx, tx, teamID); err != nil {
+		return 0, 0, err
+	}
+	return created, updated, tx.Commit(ctx)
+}
+
+// AssignRepository maps a repository to a team, replacing any earlier
+// assignment, and relinks its contributors. A nil teamID unassigns it.
+func (s *TeamStore) AssignRepository(ctx context.Context, repoID int64, teamID *int64) (bool, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE repositories SET team_id = $2, updated_at = NOW()
+		WHERE id = $1
+	`, repoID, teamID)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+	if _, err := tx.Exec(ctx, linkContributorsSQL+`r.id = $1`, repoID); err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
+
+// LinkContributors links the given contributors to their roster members on
+// q, so newly discovered contributors are matched as they are created.
+func (s *TeamStore) LinkContributors(ctx context.Context, q Querier, contributorIDs []int64) error {
+	if len(contributorIDs) == 0 {
+		return nil
+	}
+	_, err := q.Exec(ctx, linkContributorsSQL+`c.id = ANY($1)`, contributorIDs)
+	return err
+}
+
+func (s *TeamStore) relinkTeam(ctx context.Context, q Querier, teamID int64) error {
+	_, err := q.Exec(ctx, linkContributorsSQL+`r.team_id = $1`, teamID)
+	return err
+}
+
+// GetForRepository returns the team a repository is assigned to, or nil
+// when it has none.
+func (s *TeamStore) GetForRepository(ctx context.Context, repo *Repository) (*Team, error) {
+	if repo.TeamID == nil {
+		return nil, nil
+	}
+	t, err := s.GetByID(ctx, *repo.TeamID)
+	if err != nil {
+		return nil, err
+	}
+	return &t.Team, nil
+}
+
+// ListRepositories returns the repositories assigned to a team.
+func (s *TeamStore) ListRepositories(ctx context.Context, teamID int64) ([]*Repository, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT `+repositoryColumns+`
+		FROM repositories r
+		WHERE r.team_id = $1
+		ORDER BY r.full_name
+	`, teamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var repos []*Repository
+	for rows.Next() {
+		var r Repository
+		if err := scanRepository(rows, &r); err != nil {
+			return nil, err
+		}
+		repos = append(repos, &r)
+	}
+	return repos, nil
+}
+
+// MemberStats returns the activity of every member of a repository's team
+// in that repository, counting commits pushed within the window. Members
+// who never committed are included with zero counts.
+func (s *TeamStore) MemberStats(ctx context.Context, repoID int64, from, to *time.Time) ([]*MemberStats, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT `+teamMemberColumns+`,
+			COUNT(c.id) as commits,
+			COALESCE(SUM(c.additions), 0) as additions,
+			COALESCE(SUM(c.deletions), 0) as deletions,
+			MIN(c.pushed_at) as first_commit_at,
+			MAX(c.pushed_at) as last_commit_at
+		FROM repositories r
+		JOIN team_members m ON m.team_id = r.team_id
+		LEFT JOIN contributors ct ON ct.repository_id = r.id AND ct.team_member_id = m.id
+		LEFT JOIN commits c ON c.repository_id = r.id AND c.author_email = ct.email
+			AND ($2::timestamptz IS NULL OR c.pushed_at >= $2)
+			AND ($3::timestamptz IS NULL OR c.pushed_at <= $3)
+		WHERE r.id = $1
+		GROUP BY m.id
+		ORDER BY commits DESC, LOWER(m.github_login)
+	`, repoID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []*MemberStats
+	for rows.Next() {
+		var ms MemberStats
+		if err := scanTeamMember(rows, &ms.Member, &ms.Commits, &ms.Additions, &ms.Deletions, &ms.FirstCommitAt, &ms.LastCommitAt); err != nil {
+			return nil, err
+		}
+		stats = append(stats, &ms)
+	}
+	return stats, rows.Err()
+}
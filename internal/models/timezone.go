@@ -0,0 +1,61 @@
+package models
+
+import (
+	"context"
+	"fmt"
+)
+
+// OffsetProfile is how a contributor's commits spread over UTC offsets.
+type OffsetProfile struct {
+	AuthorEmail string
+	// Offsets maps UTC offsets in minutes to the number of commits made
+	// with them.
+	Offsets map[int]int
+	Commits int
+	// Typical is the offset most commits were made with.
+	Typical int
+}
+
+// ListOffsetProfiles returns the UTC offset profile of each contributor of a
+// repository with commits of known offset, keyed by lowercased author email.
+func (s *CommitStore) ListOffsetProfiles(ctx context.Context, repoID int64) (map[string]*OffsetProfile, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT LOWER(author_email), author_utc_offset, COUNT(*)
+		FROM commits
+		WHERE repository_id = $1 AND author_utc_offset IS NOT NULL
+		GROUP BY LOWER(author_email), author_utc_offset
+		ORDER BY LOWER(author_email), COUNT(*) DESC, author_utc_offset
+	`, repoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	profiles := make(map[string]*OffsetProfile)
+	for rows.Next() {
+		var email string
+		var offset, commits int
+		if err := rows.Scan(&email, &offset, &commits); err != nil {
+			return nil, err
+		}
+		p, ok := profiles[email]
+		if !ok {
+			// Rows are ordered by commit count, so the first is typical
+			p = &OffsetProfile{AuthorEmail: email, Offsets: make(map[int]int), Typical: offset}
+			profiles[email] = p
+		}
+		p.Offsets[offset] = commits
+		p.Commits += commits
+	}
+	return profiles, rows.Err()
+}
+
+// FormatUTCOffset formats an offset in minutes as ±hh:mm.
+func FormatUTCOffset(minutes int) string {
+	sign := '+'
+	if minutes < 0 {
+		sign = '-'
+		minutes = -minutes
+	}
+	return fmt.Sprintf("%c%02d:%02d", sign, minutes/60, minutes%60)
+}
@@ -0,0 +1,82 @@
+package queue
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+type jobResponse struct {
+	ID          int64           `json:"id"`
+	Kind        string          `json:"kind"`
+	Payload     json.RawMessage `json:"payload"`
+	Status      Status          `json:"status"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	RunAt       time.Time       `json:"run_at"`
+	LastError   *string         `json:"last_error,omitempty"`
+	CompletedAt *time.Time      `json:"completed_at,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// HandleList lists recent jobs, optionally filtered by ?status= (e.g. dead).
+func (q *Queue) HandleList(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if v, err := strconv.Atoi(l); err == nil && v > 0 && v <= 500 {
+			limit = v
+		}
+	}
+
+	jobs, err := q.List(r.Context(), Status(r.URL.Query().Get("status")), limit)
+	if err != nil {
+		q.logger.Error().Err(err).Msg("failed to list jobs")
+		http.Error(w, "failed to list jobs", http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]jobResponse, 0, len(jobs))
+	for _, j := range jobs {
+		response = append(response, jobResponse{
+			ID:          j.ID,
+			Kind:        j.Kind,
+			Payload:     j.Payload,
+			Status:      j.Status,
+			Attempts:    j.Attempts,
+			MaxAttempts: j.MaxAttempts,
+			RunAt:       j.RunAt,
+			LastError:   j.LastError,
+			CompletedAt: j.CompletedAt,
+			CreatedAt:   j.CreatedAt,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"jobs": response})
+}
+
+// HandleRetry requeues a dead-lettered job.
+func (q *Queue) HandleRetry(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid job ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := q.Retry(r.Context(), id); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, "dead job not found", http.StatusNotFound)
+			return
+		}
+		q.logger.Error().Err(err).Int64("job_id", id).Msg("failed to retry job")
+		http.Error(w, "failed to retry job", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -0,0 +1,428 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+)
+
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusRunning   Status = "running"
+	StatusCompleted Status = "completed"
+	StatusDead      Status = "dead"
+)
+
+// staleAfter is how long a job may stay running before it is assumed to
+// belong to a crashed or stalled worker and is made available again.
+const staleAfter = 15 * time.Minute
+
+// recoverInterval is how often the first worker looks for stale jobs while
+// the queue runs.
+const recoverInterval = time.Minute
+
+type Job struct {
+	ID          int64
+	Kind        string
+	Payload     json.RawMessage
+	Status      Status
+	Attempts    int
+	MaxAttempts int
+	RunAt       time.Time
+	LastError   *string
+	CompletedAt *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// Decode unmarshals the job payload into v.
+func (j *Job) Decode(v interface{}) error {
+	return json.Unmarshal(j.Payload, v)
+}
+
+// retryAfterError asks the queue to run a job again after a delay without
+// counting the failed run as an attempt.
+type retryAfterError struct {
+	delay time.Duration
+	err   error
+}
+
+func (e *retryAfterError) Error() string { return e.err.Error() }
+func (e *retryAfterError) Unwrap() error { return e.err }
+
+// RetryAfter wraps err so the job is deferred by delay instead of failing.
+// Use it for transient conditions such as API rate limits.
+func RetryAfter(delay time.Duration, err error) error {
+	return &retryAfterError{delay: delay, err: err}
+}
+
+// HandlerFunc processes a job. Returning an error schedules a retry with
+// exponential backoff until the job runs out of attempts and is dead-lettered.
+type HandlerFunc func(ctx context.Context, job *Job) error
+
+type Options struct {
+	Workers      int
+	MaxAttempts  int
+	PollInterval time.Duration
+	RetryBase    time.Duration
+	RetryMax     time.Duration
+}
+
+type Queue struct {
+	pool     *pgxpool.Pool
+	opts     Options
+	logger   zerolog.Logger
+	handlers map[string]HandlerFunc
+
+	wake    chan struct{}
+	stop    chan struct{}
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	started bool
+}
+
+func New(pool *pgxpool.Pool, opts Options, logger zerolog.Logger) *Queue {
+	if opts.Workers <= 0 {
+		opts.Workers = 4
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 8
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = time.Second
+	}
+	if opts.RetryBase <= 0 {
+		opts.RetryBase = 5 * time.Second
+	}
+	if opts.RetryMax <= 0 {
+		opts.RetryMax = time.Hour
+	}
+
+	return &Queue{
+		pool:     pool,
+		opts:     opts,
+		logger:   logger.With().Str("component", "queue").Logger(),
+		handlers: make(map[string]HandlerFunc),
+		wake:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+	}
+}
+
+// Register sets the handler for a job kind. It must be called before Start.
+func (q *Queue) Register(kind string, handler HandlerFunc) {
+	q.handlers[kind] = handler
+}
+
+// Enqueue adds a job that is ready to run immediately.
+func (q *Queue) Enqueue(ctx context.Context, kind string, payload interface{}) (int64, error) {
+	return q.EnqueueAt(ctx, kind, payload, time.Now())
+}
+
+// EnqueueAt adds a job that becomes ready at runAt.
+func (q *Queue) EnqueueAt(ctx context.Context, kind string, payload interface{}, runAt time.Time) (int64, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode job payload: %w", err)
+	}
+
+	var id int64
+	err = q.pool.QueryRow(ctx, `
+		INSERT INTO jobs (kind, payload, max_attempts, run_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`, kind, data, q.opts.MaxAttempts, runAt).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	// Wake an idle worker so the job doesn't wait for the next poll
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+
+	return id, nil
+}
+
+// Start launches the worker goroutines.
+func (q *Queue) Start() {
+	if err := q.recoverStale(context.Background()); err != nil {
+		q.logger.Error().Err(err).Msg("failed to recover stale jobs")
+	}
+
+	// Jobs run on their own context so that a shutdown signal lets in-flight
+	// work finish; it is only cancelled when draining times out.
+	ctx, cancel := context.WithCancel(context.Background())
+	q.cancel = cancel
+	q.started = true
+
+	for i := 0; i < q.opts.Workers; i++ {
+		q.wg.Add(1)
+		go q.work(ctx, i)
+	}
+
+	q.logger.Info().Int("workers", q.opts.Workers).Msg("job queue started")
+}
+
+// Stop stops claiming new jobs and waits for in-flight jobs to finish. If ctx
+// expires first, running jobs are cancelled and left to be retried.
+func (q *Queue) Stop(ctx context.Context) error {
+	if !q.started {
+		return nil
+	}
+
+	close(q.stop)
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		q.cancel()
+		q.logger.Info().Msg("job queue drained")
+		return nil
+	case <-ctx.Done():
+		q.cancel()
+		<-done
+		return fmt.Errorf("job queue drain timed out: %w", ctx.Err())
+	}
+}
+
+func (q *Queue) work(ctx context.Context, id int) {
+	defer q.wg.Done()
+
+	logger := q.logger.With().Int("worker", id).Logger()
+	ticker := time.NewTicker(q.opts.PollInterval)
+	defer ticker.Stop()
+
+	// Start already recovered stale jobs once
+	nextRecover := time.Now().Add(recoverInterval)
+	for {
+		select {
+		case <-q.stop:
+			return
+		default:
+		}
+
+		// Jobs stalled while the process is up are recovered by one worker
+		if id == 0 && !time.Now().Before(nextRecover) {
+			if err := q.recoverStale(ctx); err != nil {
+				logger.Error().Err(err).Msg("failed to recover stale jobs")
+			}
+			nextRecover = time.Now().Add(recoverInterval)
+		}
+
+		job, err := q.claim(ctx)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to claim job")
+		}
+		if job != nil {
+			q.run(ctx, job, logger)
+			continue
+		}
+
+		select {
+		case <-q.stop:
+			return
+		case <-q.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+func (q *Queue) claim(ctx context.Context) (*Job, error) {
+	var j Job
+	err := q.pool.QueryRow(ctx, `
+		UPDATE jobs SET status = 'running', attempts = attempts + 1, locked_at = NOW(), updated_at = NOW()
+		WHERE id = (
+			SELECT id FROM jobs
+			WHERE status = 'pending' AND run_at <= NOW()
+			ORDER BY run_at, id
+			FOR UPDATE SKIP LOCKED
+			LIMIT 1
+		)
+		RETURNING id, kind, payload, status, attempts, max_attempts, run_at, created_at, updated_at
+	`).Scan(&j.ID, &j.Kind, &j.Payload, &j.Status, &j.Attempts, &j.MaxAttempts, &j.RunAt, &j.CreatedAt, &j.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+func (q *Queue) run(ctx context.Context, job *Job, logger zerolog.Logger) {
+	logger = logger.With().Int64("job_id", job.ID).Str("kind", job.Kind).Int("attempt", job.Attempts).Logger()
+
+	// Record the outcome even if the job context was cancelled on shutdown,
+	// or the job would stay running until recovered as stale
+	statusCtx := context.Background()
+
+	handler, ok := q.handlers[job.Kind]
+	if !ok {
+		logger.Error().Msg("no handler registered for job kind")
+		q.markDead(statusCtx, job, "no handler registered for job kind "+job.Kind, logger)
+		return
+	}
+
+	start := time.Now()
+	err := q.safeRun(ctx, handler, job)
+	if err == nil {
+		if _, err := q.pool.Exec(statusCtx, `
+			UPDATE jobs SET status = 'completed', last_error = NULL, locked_at = NULL, completed_at = NOW(), updated_at = NOW()
+			WHERE id = $1
+		`, job.ID); err != nil {
+			logger.Error().Err(err).Msg("failed to mark job completed")
+		}
+		logger.Debug().Dur("duration", time.Since(start)).Msg("job completed")
+		return
+	}
+
+	var deferred *retryAfterError
+	if errors.As(err, &deferred) {
+		logger.Info().Err(err).Dur("retry_in", deferred.delay).Msg("job deferred")
+		q.deferJob(statusCtx, job, err.Error(), deferred.delay, logger)
+		return
+	}
+
+	if job.Attempts >= job.MaxAttempts {
+		logger.Error().Err(err).Msg("job failed permanently, moving to dead letter")
+		q.markDead(statusCtx, job, err.Error(), logger)
+		return
+	}
+
+	delay := q.backoff(job.Attempts)
+	logger.Warn().Err(err).Dur("retry_in", delay).Msg("job failed, scheduling retry")
+	q.reschedule(statusCtx, job, err.Error(), delay, logger)
+}
+
+// safeRun calls the handler, turning a panic into an error so one bad job
+// can't take down a worker.
+func (q *Queue) safeRun(ctx context.Context, handler HandlerFunc, job *Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return handler(ctx, job)
+}
+
+func (q *Queue) reschedule(ctx context.Context, job *Job, errMsg string, delay time.Duration, logger zerolog.Logger) {
+	if _, err := q.pool.Exec(ctx, `
+		UPDATE jobs SET status = 'pending', last_error = $2, locked_at = NULL, run_at = $3, updated_at = NOW()
+		WHERE id = $1
+	`, job.ID, errMsg, time.Now().Add(delay)); err != nil {
+		logger.Error().Err(err).Msg("failed to schedule job retry")
+	}
+}
+
+// deferJob reschedules a job and gives back the attempt it just used.
+func (q *Queue) deferJob(ctx context.Context, job *Job, errMsg string, delay time.Duration, logger zerolog.Logger) {
+	if _, err := q.pool.Exec(ctx, `
+		UPDATE jobs SET status = 'pending', attempts = GREATEST(attempts - 1, 0), last_error = $2, locked_at = NULL, run_at = $3, updated_at = NOW()
+		WHERE id = $1
+	`, job.ID, errMsg, time.Now().Add(delay)); err != nil {
+		logger.Error().Err(err).Msg("failed to defer job")
+	}
+}
+
+func (q *Queue) markDead(ctx context.Context, job *Job, errMsg string, logger zerolog.Logger) {
+	if _, err := q.pool.Exec(ctx, `
+		UPDATE jobs SET status = 'dead', last_error = $2, locked_at = NULL, updated_at = NOW()
+		WHERE id = $1
+	`, job.ID, errMsg); err != nil {
+		logger.Error().Err(err).Msg("failed to mark job dead")
+	}
+}
+
+// backoff returns the exponential delay before the given attempt is retried,
+// with up to 20% jitter so retries of a burst of failures spread out.
+func (q *Queue) backoff(attempt int) time.Duration {
+	delay := q.opts.RetryBase
+	for i := 1; i < attempt && delay < q.opts.RetryMax; i++ {
+		delay *= 2
+	}
+	if delay > q.opts.RetryMax {
+		delay = q.opts.RetryMax
+	}
+	jitter := time.Duration(rand.Int63n(int64(delay)/5 + 1))
+	return delay + jitter
+}
+
+func (q *Queue) recoverStale(ctx context.Context) error {
+	tag, err := q.pool.Exec(ctx, `
+		UPDATE jobs SET status = 'pending', locked_at = NULL, updated_at = NOW()
+		WHERE status = 'running' AND locked_at < $1
+	`, time.Now().Add(-staleAfter))
+	if err != nil {
+		return err
+	}
+	if n := tag.RowsAffected(); n > 0 {
+		q.logger.Warn().Int64("jobs", n).Msg("recovered stale running jobs")
+	}
+	return nil
+}
+
+// List returns the most recent jobs, optionally filtered by status.
+func (q *Queue) List(ctx context.Context, status Status, limit int) ([]*Job, error) {
+	rows, err := q.pool.Query(ctx, `
+		SELECT id, kind, payload, status, attempts, max_attempts, run_at, last_error,
+		       completed_at, created_at, updated_at
+		FROM jobs
+		WHERE $1 = '' OR status = $1
+		ORDER BY updated_at DESC
+		LIMIT $2
+	`, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []*Job
+	for rows.Next() {
+		var j Job
+		err := rows.Scan(
+			&j.ID, &j.Kind, &j.Payload, &j.Status, &j.Attempts, &j.MaxAttempts, &j.RunAt,
+			&j.LastError, &j.CompletedAt, &j.CreatedAt, &j.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, &j)
+	}
+	return jobs, nil
+}
+
+// Retry moves a dead job back to pending with a fresh set of attempts.
+func (q *Queue) Retry(ctx context.Context, id int64) error {
+	tag, err := q.pool.Exec(ctx, `
+		UPDATE jobs SET status = 'pending', attempts = 0, run_at = NOW(), locked_at = NULL, updated_at = NOW()
+		WHERE id = $1 AND status = 'dead'
+	`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
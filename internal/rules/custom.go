@@ -0,0 +1,389 @@
+package rules
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/harshpatel5940/gitvigil/internal/models"
+	"github.com/harshpatel5940/gitvigil/internal/rules/expr"
+)
+
+// maxTitleLength is the length of the alerts.title column.
+const maxTitleLength = 255
+
+var customRuleName = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// commitSchema lists the fields custom commit rules can read.
+var commitSchema = expr.Schema{
+	"sha":                 expr.String,
+	"message":             expr.String,
+	"author":              expr.String,
+	"author_name":         expr.String,
+	"author_email":        expr.String,
+	"author_login":        expr.String,
+	"author_date":         expr.Time,
+	"committer_name":      expr.String,
+	"committer_email":     expr.String,
+	"committer_login":     expr.String,
+	"committer_date":      expr.Time,
+	"pushed_at":           expr.Time,
+	"source":              expr.String,
+	"dates_verified":      expr.Bool,
+	"backdate_hours":      expr.Number,
+	"committer_gap_hours": expr.Number,
+	"future_minutes":      expr.Number,
+	"additions":           expr.Number,
+	"deletions":           expr.Number,
+	"files":               expr.Number,
+}
+
+// statFields are the commit fields only known once a commit is enriched.
+var statFields = []string{"additions", "deletions", "files"}
+
+// pushSchema lists the fields custom push rules can read.
+var pushSchema = expr.Schema{
+	"ref":           expr.String,
+	"branch":        expr.String,
+	"before":        expr.String,
+	"after":         expr.String,
+	"forced":        expr.Bool,
+	"pusher":        expr.String,
+	"commits":       expr.Number,
+	"pushed_at":     expr.Time,
+	"post_deadline": expr.Bool,
+	"minutes_late":  expr.Number,
+}
+
+// customRuleSpec is one rule of a custom rules file.
+type customRuleSpec struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	On          string          `json:"on"`
+	When        string          `json:"when"`
+	Severity    models.Severity `json:"severity"`
+	Title       string          `json:"title"`
+	Message     string          `json:"message"`
+}
+
+// CustomRule is an organizer-defined rule from a custom rules file. It raises
+// a custom_rule alert for every commit or push its expression matches, with a
+// title and message rendered from the matched fields. Commit rules reading
+// additions, deletions or files wait for the commit to be enriched.
+type CustomRule struct {
+	spec     customRuleSpec
+	program  *expr.Program
+	triggers []Trigger
+	title    *template.Template
+	message  *template.Template
+}
+
+func (r *CustomRule) Name() string                     { return r.spec.Name }
+func (r *CustomRule) Description() string              { return r.spec.Description }
+func (r *CustomRule) Triggers() []Trigger              { return r.triggers }
+func (r *CustomRule) AlertType() models.AlertType      { return models.AlertCustomRule }
+func (r *CustomRule) DefaultSeverity() models.Severity { return r.spec.Severity }
+func (r *CustomRule) DefaultParams() Params            { return nil }
+
+// Expression returns the rule's condition as written.
+func (r *CustomRule) Expression() string {
+	return r.spec.When
+}
+
+// Fields returns the fields the rule's condition reads.
+func (r *CustomRule) Fields() []string {
+	return r.program.Fields()
+}
+
+// customCommitRule and customPushRule give a CustomRule the evaluation
+// method matching what it is declared on.
+type customCommitRule struct{ *CustomRule }
+
+func (r customCommitRule) EvaluateCommit(c *Commit, s *Settings) []*Finding {
+	f := r.evaluate(commitEnv(c))
+	if f == nil {
+		return nil
+	}
+	f.CommitSHA = c.SHA
+	return []*Finding{f}
+}
+
+type customPushRule struct{ *CustomRule }
+
+func (r customPushRule) EvaluatePush(p *Push, s *Settings) []*Finding {
+	f := r.evaluate(pushEnv(p))
+	if f == nil {
+		return nil
+	}
+	return []*Finding{f}
+}
+
+// evaluate returns the rule's finding when env matches. An expression that
+// fails to evaluate, such as one dividing by zero, does not match.
+func (r *CustomRule) evaluate(env expr.Env) *Finding {
+	matched, err := r.program.Eval(env)
+	if err != nil || !matched {
+		return nil
+	}
+
+	data := templateData(env)
+	title, err := render(r.title, data)
+	if err != nil {
+		title = r.spec.Name
+	}
+	description := r.spec.Description
+	if r.message != nil {
+		if message, err := render(r.message, data); err == nil {
+			description = message
+		}
+	}
+
+	fields := make(map[string]interface{}, len(r.program.Fields()))
+	for _, name := range r.program.Fields() {
+		fields[name] = data[name]
+	}
+	return &Finding{
+		Title:       truncate(title, maxTitleLength),
+		Description: description,
+		Metadata: map[string]interface{}{
+			"rule":       r.spec.Name,
+			"expression": r.spec.When,
+			"fields":     fields,
+		},
+	}
+}
+
+// LoadCustomRules reads and compiles a custom rules file, a JSON document of
+// the form:
+//
+//	{"rules": [{
+//	  "name": "late_night_bulk_commit",
+//	  "description": "Commits touching more than 200 files between 2 and 6am",
+//	  "on": "commit",
+//	  "when": "files > 200 && hour(author_date) >= 2 && hour(author_date) < 6",
+//	  "severity": "warning",
+//	  "title": "{{short .sha}} touched {{.files}} files at {{.author_date}}",
+//	  "message": "Optional; defaults to the description"
+//	}]}
+//
+// Every problem in the file is reported, not just the first.
+func LoadCustomRules(path string) ([]*CustomRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file struct {
+		Rules []customRuleSpec `json:"rules"`
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&file); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	var custom []*CustomRule
+	var errs []error
+	seen := make(map[string]bool)
+	for i, spec := range file.Rules {
+		rule, err := compileCustomRule(spec)
+		if err == nil && seen[spec.Name] {
+			err = fmt.Errorf("duplicate rule name")
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("rule %d (%s): %w", i+1, spec.Name, err))
+			continue
+		}
+		seen[spec.Name] = true
+		custom = append(custom, rule)
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("%s: %w", path, errors.Join(errs...))
+	}
+	return custom, nil
+}
+
+func compileCustomRule(spec customRuleSpec) (*CustomRule, error) {
+	if !customRuleName.MatchString(spec.Name) {
+		return nil, fmt.Errorf("name must be lowercase letters, digits and underscores")
+	}
+	if strings.TrimSpace(spec.Title) == "" {
+		return nil, fmt.Errorf("title is required")
+	}
+	switch spec.Severity {
+	case "":
+		spec.Severity = models.SeverityWarning
+	case models.SeverityInfo, models.SeverityWarning, models.SeverityCritical:
+	default:
+		return nil, fmt.Errorf("severity must be info, warning or critical")
+	}
+
+	rule := &CustomRule{spec: spec}
+	var schema expr.Schema
+	switch spec.On {
+	case string(TriggerCommit):
+		schema = commitSchema
+	case string(TriggerPush):
+		schema = pushSchema
+		rule.triggers = []Trigger{TriggerPush}
+	default:
+		return nil, fmt.Errorf(`on must be "commit" or "push"`)
+	}
+
+	program, err := expr.Compile(spec.When, schema)
+	if err != nil {
+		return nil, fmt.Errorf("when: %w", err)
+	}
+	rule.program = program
+	if spec.On == string(TriggerCommit) {
+		rule.triggers = []Trigger{TriggerCommit}
+		for _, name := range program.Fields() {
+			if slices.Contains(statFields, name) {
+				rule.triggers = []Trigger{TriggerEnrichment}
+				break
+			}
+		}
+	}
+
+	// Render the templates once against zero values so a misspelt field
+	// fails now rather than on the first match
+	sample := make(expr.Env, len(schema))
+	for name, t := range schema {
+		sample[name] = zeroValue(t)
+	}
+	if rule.title, err = parseTemplate("title", spec.Title, sample); err != nil {
+		return nil, err
+	}
+	if spec.Message != "" {
+		if rule.message, err = parseTemplate("message", spec.Message, sample); err != nil {
+			return nil, err
+		}
+	}
+	return rule, nil
+}
+
+// Wrap returns the rule as the CommitRule or PushRule its declaration asks
+// for, ready to register.
+func (r *CustomRule) Wrap() Rule {
+	if r.spec.On == string(TriggerPush) {
+		return customPushRule{r}
+	}
+	return customCommitRule{r}
+}
+
+var templateFuncs = template.FuncMap{
+	// short abbreviates a commit SHA
+	"short": func(sha string) string { return truncate(sha, 7) },
+}
+
+func parseTemplate(name, text string, sample expr.Env) (*template.Template, error) {
+	t, err := template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if _, err := render(t, templateData(sample)); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return t, nil
+}
+
+func render(t *template.Template, data map[string]interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// templateData formats field values for templates and alert metadata:
+// whole numbers without a fraction and times in RFC 3339.
+func templateData(env expr.Env) map[string]interface{} {
+	data := make(map[string]interface{}, len(env))
+	for name, v := range env {
+		switch v := v.(type) {
+		case float64:
+			if v == math.Trunc(v) {
+				data[name] = int64(v)
+				continue
+			}
+			data[name] = v
+		case time.Time:
+			data[name] = v.UTC().Format(time.RFC3339)
+		default:
+			data[name] = v
+		}
+	}
+	return data
+}
+
+func zeroValue(t expr.Type) interface{} {
+	switch t {
+	case expr.Number:
+		return 0.0
+	case expr.Bool:
+		return false
+	case expr.Time:
+		return time.Time{}
+	}
+	return ""
+}
+
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
+
+func commitEnv(c *Commit) expr.Env {
+	author := c.AuthorLogin
+	if author == "" {
+		author = c.AuthorName
+	}
+	return expr.Env{
+		"sha":                 c.SHA,
+		"message":             c.Message,
+		"author":              author,
+		"author_name":         c.AuthorName,
+		"author_email":        c.AuthorEmail,
+		"author_login":        c.AuthorLogin,
+		"author_date":         c.AuthorDate,
+		"committer_name":      c.CommitterName,
+		"committer_email":     c.CommitterEmail,
+		"committer_login":     c.CommitterLogin,
+		"committer_date":      c.CommitterDate,
+		"pushed_at":           c.PushedAt,
+		"source":              c.Source,
+		"dates_verified":      c.DatesVerified,
+		"backdate_hours":      float64(c.BackdateHours),
+		"committer_gap_hours": float64(c.CommitterGapHours),
+		"future_minutes":      float64(max(c.FutureMinutes, 0)),
+		"additions":           float64(c.Additions),
+		"deletions":           float64(c.Deletions),
+		"files":               float64(c.FilesChanged),
+	}
+}
+
+func pushEnv(p *Push) expr.Env {
+	return expr.Env{
+		"ref":           p.Ref,
+		"branch":        strings.TrimPrefix(p.Ref, "refs/heads/"),
+		"before":        p.Before,
+		"after":         p.After,
+		"forced":        p.Forced,
+		"pusher":        p.Pusher,
+		"commits":       float64(p.Commits),
+		"pushed_at":     p.PushedAt,
+		"post_deadline": p.PostDeadline,
+		"minutes_late":  float64(p.MinutesLate),
+	}
+}
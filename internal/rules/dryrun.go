@@ -0,0 +1,122 @@
+package rules
+
+import (
+	"context"
+	"time"
+
+	"github.com/harshpatel5940/gitvigil/internal/models"
+)
+
+// DryRunMatch is a stored commit or push a rule matched during a dry run.
+type DryRunMatch struct {
+	Rule       string
+	Repository string
+	// CommitSHA is set for commit matches, PushEventID for push matches.
+	CommitSHA   string
+	PushEventID int64
+	At          time.Time
+	Severity    models.Severity
+	Title       string
+}
+
+// DryRunResult reports what custom rules would have raised on stored data.
+type DryRunResult struct {
+	Commits int
+	Pushes  int
+	Matches []*DryRunMatch
+	// Disabled counts matches left out because the rule is disabled for the
+	// repository.
+	Disabled int
+}
+
+// DryRun evaluates custom rules against the stored commits and pushes
+// matching filter without raising any alert. Each repository's rule
+// settings apply as they would to new data, and rules waiting for
+// enrichment skip commits that were never enriched. graceMinutes is the
+// submission grace period pushes are measured with.
+func (e *Engine) DryRun(ctx context.Context, custom []*CustomRule, filter models.RecordFilter, graceMinutes int) (*DryRunResult, error) {
+	result := &DryRunResult{}
+	settings := make(map[int64]*RepositorySettings)
+	settingsFor := func(repoID int64) (*RepositorySettings, error) {
+		if rs, ok := settings[repoID]; ok {
+			return rs, nil
+		}
+		rs, err := e.RepositorySettings(ctx, e.pool, repoID)
+		if err != nil {
+			return nil, err
+		}
+		settings[repoID] = rs
+		return rs, nil
+	}
+
+	var commitRules, pushRules []*CustomRule
+	for _, rule := range custom {
+		if rule.spec.On == string(TriggerPush) {
+			pushRules = append(pushRules, rule)
+		} else {
+			commitRules = append(commitRules, rule)
+		}
+	}
+
+	// match records a finding unless the rule is disabled for the repository
+	match := func(rule *CustomRule, repoID int64, f *Finding, m *DryRunMatch) error {
+		rs, err := settingsFor(repoID)
+		if err != nil {
+			return err
+		}
+		s := rs.For(rule)
+		if !s.Enabled {
+			result.Disabled++
+			return nil
+		}
+		alert := newAlert(rule, s, repoID, f)
+		m.Rule = rule.Name()
+		m.Severity = alert.Severity
+		m.Title = alert.Title
+		result.Matches = append(result.Matches, m)
+		return nil
+	}
+
+	if len(commitRules) > 0 {
+		records, err := models.NewCommitStore(e.pool).ListRecords(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		result.Commits = len(records)
+		for _, rec := range records {
+			c := CommitFromRecord(rec)
+			for _, rule := range commitRules {
+				if rule.triggers[0] == TriggerEnrichment && !rec.Enriched {
+					continue
+				}
+				for _, f := range (customCommitRule{rule}).EvaluateCommit(c, nil) {
+					m := &DryRunMatch{Repository: rec.FullName, CommitSHA: rec.SHA, At: rec.PushedAt}
+					if err := match(rule, rec.RepositoryID, f, m); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+	}
+
+	if len(pushRules) > 0 {
+		records, err := models.NewPushEventStore(e.pool).ListRecords(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		result.Pushes = len(records)
+		for _, rec := range records {
+			p := PushFromRecord(rec, graceMinutes)
+			for _, rule := range pushRules {
+				for _, f := range (customPushRule{rule}).EvaluatePush(p, nil) {
+					m := &DryRunMatch{Repository: rec.FullName, PushEventID: rec.ID, At: rec.PushedAt}
+					if err := match(rule, rec.RepositoryID, f, m); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+	}
+
+	return result, nil
+}
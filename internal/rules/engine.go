@@ -0,0 +1,235 @@
+package rules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	ghclient "github.com/harshpatel5940/gitvigil/internal/github"
+	"github.com/harshpatel5940/gitvigil/internal/models"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+)
+
+// Engine runs registered rules with their settings resolved per repository.
+type Engine struct {
+	registry *Registry
+	pool     *pgxpool.Pool
+	settings *models.RuleSettingStore
+	logger   zerolog.Logger
+}
+
+func NewEngine(registry *Registry, pool *pgxpool.Pool, logger zerolog.Logger) *Engine {
+	return &Engine{
+		registry: registry,
+		pool:     pool,
+		settings: models.NewRuleSettingStore(pool),
+		logger:   logger.With().Str("component", "rules").Logger(),
+	}
+}
+
+func (e *Engine) Registry() *Registry {
+	return e.registry
+}
+
+// RepositorySettings are the overrides applying to one repository, from
+// which each rule's settings are resolved.
+type RepositorySettings struct {
+	overrides []*models.RuleSetting
+}
+
+// For resolves a rule's settings for the repository.
+func (rs *RepositorySettings) For(rule Rule) *Settings {
+	return Resolve(rule, rs.overrides)
+}
+
+// RepositorySettings loads the overrides applying to a repository through q,
+// which may be a transaction.
+func (e *Engine) RepositorySettings(ctx context.Context, q models.Querier, repoID int64) (*RepositorySettings, error) {
+	overrides, err := e.settings.ListForRepository(ctx, q, repoID)
+	if err != nil {
+		return nil, err
+	}
+	return &RepositorySettings{overrides: overrides}, nil
+}
+
+// Settings resolves the named rule's settings for a repository.
+func (e *Engine) Settings(ctx context.Context, name string, repoID int64) (*Settings, error) {
+	rule := e.registry.Get(name)
+	if rule == nil {
+		return nil, fmt.Errorf("unknown rule %q", name)
+	}
+	rs, err := e.RepositorySettings(ctx, e.pool, repoID)
+	if err != nil {
+		return nil, err
+	}
+	return rs.For(rule), nil
+}
+
+// EvaluateCommit runs the enabled commit rules consuming trigger, either
+// TriggerCommit or TriggerEnrichment, on a commit and returns the alerts to
+// raise.
+func (e *Engine) EvaluateCommit(rs *RepositorySettings, trigger Trigger, c *Commit) []*models.Alert {
+	var alerts []*models.Alert
+	for _, rule := range e.registry.ForTrigger(trigger) {
+		cr, ok := rule.(CommitRule)
+		if !ok {
+			continue
+		}
+		s := rs.For(rule)
+		if !s.Enabled {
+			continue
+		}
+		for _, f := range cr.EvaluateCommit(c, s) {
+			alerts = append(alerts, newAlert(rule, s, c.RepositoryID, f))
+		}
+	}
+	return alerts
+}
+
+// CommitFindings runs the named commit rule on a commit whether or not it is
+// enabled for the repository, for facts that must not change when the rule's
+// alerts are turned off. An unknown rule finds nothing.
+func (e *Engine) CommitFindings(rs *RepositorySettings, name string, c *Commit) []*Finding {
+	cr, ok := e.registry.Get(name).(CommitRule)
+	if !ok {
+		return nil
+	}
+	return cr.EvaluateCommit(c, rs.For(cr))
+}
+
+// EvaluatePush runs the enabled push rules on a push and returns the alerts
+// to raise.
+func (e *Engine) EvaluatePush(rs *RepositorySettings, p *Push) []*models.Alert {
+	var alerts []*models.Alert
+	for _, rule := range e.registry.ForTrigger(TriggerPush) {
+		pr, ok := rule.(PushRule)
+		if !ok {
+			continue
+		}
+		s := rs.For(rule)
+		if !s.Enabled {
+			continue
+		}
+		for _, f := range pr.EvaluatePush(p, s) {
+			alert := newAlert(rule, s, p.RepositoryID, f)
+			alert.PushEventID = &p.PushEventID
+			alerts = append(alerts, alert)
+		}
+	}
+	return alerts
+}
+
+// newAlert turns a finding into an alert. A severity override wins over the
+// finding's own severity, which wins over the rule's default.
+func newAlert(rule Rule, s *Settings, repoID int64, f *Finding) *models.Alert {
+	alert := &models.Alert{
+		RepositoryID: repoID,
+		AlertType:    rule.AlertType(),
+		Severity:     rule.DefaultSeverity(),
+		Title:        f.Title,
+		Description:  f.Description,
+		Metadata:     f.Metadata,
+	}
+	if f.AlertType != "" {
+		alert.AlertType = f.AlertType
+	}
+	if f.Severity != "" {
+		alert.Severity = f.Severity
+	}
+	alert.Severity = s.SeverityOr(alert.Severity)
+	if f.CommitSHA != "" {
+		sha := f.CommitSHA
+		alert.CommitSHA = &sha
+	}
+	return alert
+}
+
+// CheckRepository runs the repository rules consuming trigger that are
+// enabled for the repository. Failures are logged and collected so one rule
+// does not keep the others from running.
+func (e *Engine) CheckRepository(ctx context.Context, trigger Trigger, repo *models.Repository) error {
+	rs, err := e.RepositorySettings(ctx, e.pool, repo.ID)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, rule := range e.registry.ForTrigger(trigger) {
+		rr, ok := rule.(RepositoryRule)
+		if !ok {
+			continue
+		}
+		s := rs.For(rule)
+		if !s.Enabled {
+			continue
+		}
+		if err := rr.CheckRepository(ctx, repo, s); err != nil {
+			e.logger.Error().Err(err).Str("rule", rule.Name()).Str("repo", repo.FullName).Msg("rule check failed")
+			errs = append(errs, fmt.Errorf("%s: %w", rule.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Run runs a scheduled rule. A repository rule is checked on every active
+// repository it is enabled for; a rate limit stops the run early, leaving
+// the rest to the next run.
+func (e *Engine) Run(ctx context.Context, name string) error {
+	rule := e.registry.Get(name)
+	if rule == nil {
+		return fmt.Errorf("unknown rule %q", name)
+	}
+
+	switch r := rule.(type) {
+	case ScheduledRule:
+		return r.Run(ctx, e)
+	case RepositoryRule:
+		return e.runRepositoryRule(ctx, r)
+	}
+	return fmt.Errorf("rule %q cannot run on schedule", name)
+}
+
+func (e *Engine) runRepositoryRule(ctx context.Context, rule RepositoryRule) error {
+	repos, err := models.NewRepositoryStore(e.pool).ListActive(ctx)
+	if err != nil {
+		return err
+	}
+
+	checked := 0
+	var errs []error
+	for _, repo := range repos {
+		rs, err := e.RepositorySettings(ctx, e.pool, repo.ID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", repo.FullName, err))
+			continue
+		}
+		s := rs.For(rule)
+		if !s.Enabled {
+			continue
+		}
+
+		checked++
+		if err := rule.CheckRepository(ctx, repo, s); err != nil {
+			if _, limited := ghclient.RateLimitDelay(err); limited {
+				return errors.Join(append(errs, fmt.Errorf("%s: %w", repo.FullName, err))...)
+			}
+			e.logger.Error().Err(err).Str("rule", rule.Name()).Str("repo", repo.FullName).Msg("rule check failed")
+			errs = append(errs, fmt.Errorf("%s: %w", repo.FullName, err))
+		}
+	}
+
+	e.logger.Info().
+		Str("rule", rule.Name()).
+		Int("repositories", checked).
+		Int("failed", len(errs)).
+		Msg("rule run completed")
+	return errors.Join(errs...)
+}
+
+// Task returns a scheduler task running the named rule.
+func (e *Engine) Task(name string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		return e.Run(ctx, name)
+	}
+}
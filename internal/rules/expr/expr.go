@@ -0,0 +1,294 @@
+// Package expr is the small expression language custom alert rules are
+// written in. An expression combines fields, literals, arithmetic,
+// comparisons, boolean operators and a few functions, for example:
+//
+//	files > 200 && hour(author_date, "Asia/Kolkata") >= 2 && hour(author_date, "Asia/Kolkata") < 6
+//
+// Expressions are type checked against a Schema when compiled, so a rules
+// file with a typo is rejected before it ever runs.
+package expr
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Type is the type of a field or expression.
+type Type int
+
+const (
+	Number Type = iota + 1
+	String
+	Bool
+	Time
+)
+
+func (t Type) String() string {
+	switch t {
+	case Number:
+		return "number"
+	case String:
+		return "string"
+	case Bool:
+		return "bool"
+	case Time:
+		return "time"
+	}
+	return "unknown"
+}
+
+// Schema lists the fields an expression may read with their types.
+type Schema map[string]Type
+
+// Env holds field values: float64 for numbers, string, bool and time.Time.
+type Env map[string]interface{}
+
+// Program is a compiled boolean expression.
+type Program struct {
+	source string
+	root   node
+	fields []string
+}
+
+// Compile parses and type checks a boolean expression over schema.
+func Compile(source string, schema Schema) (*Program, error) {
+	tokens, err := lex(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens, schema: schema, fields: make(map[string]bool)}
+	root, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, errorAt(tok.pos, "unexpected %q", tok.text)
+	}
+	if root.typ() != Bool {
+		return nil, errorAt(0, "expression is a %s, not a condition", root.typ())
+	}
+
+	fields := make([]string, 0, len(p.fields))
+	for name := range p.fields {
+		fields = append(fields, name)
+	}
+	sort.Strings(fields)
+	return &Program{source: source, root: root, fields: fields}, nil
+}
+
+// Eval evaluates the program. Fields missing from env are an error.
+func (p *Program) Eval(env Env) (bool, error) {
+	v, err := p.root.eval(env)
+	if err != nil {
+		return false, err
+	}
+	return v.(bool), nil
+}
+
+// Fields returns the fields the program reads, sorted.
+func (p *Program) Fields() []string {
+	return p.fields
+}
+
+func (p *Program) String() string {
+	return p.source
+}
+
+// node is a type checked expression.
+type node interface {
+	typ() Type
+	eval(env Env) (interface{}, error)
+}
+
+type literal struct {
+	t Type
+	v interface{}
+}
+
+func (n *literal) typ() Type                     { return n.t }
+func (n *literal) eval(Env) (interface{}, error) { return n.v, nil }
+
+type field struct {
+	name string
+	t    Type
+}
+
+func (n *field) typ() Type { return n.t }
+
+func (n *field) eval(env Env) (interface{}, error) {
+	v, ok := env[n.name]
+	if !ok {
+		return nil, fmt.Errorf("field %s has no value", n.name)
+	}
+	switch v := v.(type) {
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case *time.Time:
+		if v == nil {
+			return time.Time{}, nil
+		}
+		return *v, nil
+	}
+	return v, nil
+}
+
+type not struct{ x node }
+
+func (n *not) typ() Type { return Bool }
+
+func (n *not) eval(env Env) (interface{}, error) {
+	v, err := n.x.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	return !v.(bool), nil
+}
+
+type negate struct{ x node }
+
+func (n *negate) typ() Type { return Number }
+
+func (n *negate) eval(env Env) (interface{}, error) {
+	v, err := n.x.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	return -v.(float64), nil
+}
+
+// logical is a short-circuiting && or ||.
+type logical struct {
+	and         bool
+	left, right node
+}
+
+func (n *logical) typ() Type { return Bool }
+
+func (n *logical) eval(env Env) (interface{}, error) {
+	l, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if l.(bool) != n.and {
+		return l, nil
+	}
+	return n.right.eval(env)
+}
+
+type arithmetic struct {
+	op          string
+	left, right node
+}
+
+func (n *arithmetic) typ() Type { return Number }
+
+func (n *arithmetic) eval(env Env) (interface{}, error) {
+	l, r, err := evalPair(env, n.left, n.right)
+	if err != nil {
+		return nil, err
+	}
+	a, b := l.(float64), r.(float64)
+	switch n.op {
+	case "+":
+		return a + b, nil
+	case "-":
+		return a - b, nil
+	case "*":
+		return a * b, nil
+	}
+	if b == 0 {
+		return nil, fmt.Errorf("division by zero")
+	}
+	return a / b, nil
+}
+
+type comparison struct {
+	op          string
+	left, right node
+}
+
+func (n *comparison) typ() Type { return Bool }
+
+func (n *comparison) eval(env Env) (interface{}, error) {
+	l, r, err := evalPair(env, n.left, n.right)
+	if err != nil {
+		return nil, err
+	}
+
+	var c int
+	switch a := l.(type) {
+	case float64:
+		c = compare(a, r.(float64))
+	case string:
+		c = compare(a, r.(string))
+	case time.Time:
+		c = a.Compare(r.(time.Time))
+	case bool:
+		c = 1
+		if a == r.(bool) {
+			c = 0
+		}
+	}
+
+	switch n.op {
+	case "==":
+		return c == 0, nil
+	case "!=":
+		return c != 0, nil
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	}
+	return c >= 0, nil
+}
+
+func compare[T float64 | string](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func evalPair(env Env, left, right node) (interface{}, interface{}, error) {
+	l, err := left.eval(env)
+	if err != nil {
+		return nil, nil, err
+	}
+	r, err := right.eval(env)
+	if err != nil {
+		return nil, nil, err
+	}
+	return l, r, nil
+}
+
+type call struct {
+	fn   *function
+	args []node
+	// consts holds values prepared at compile time from literal arguments,
+	// such as a compiled regular expression or a loaded time zone.
+	consts []interface{}
+}
+
+func (n *call) typ() Type { return n.fn.result }
+
+func (n *call) eval(env Env) (interface{}, error) {
+	args := make([]interface{}, len(n.args))
+	for i, arg := range n.args {
+		v, err := arg.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	return n.fn.call(args, n.consts), nil
+}
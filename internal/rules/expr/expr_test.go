@@ -0,0 +1,220 @@
+package expr
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testSchema = Schema{
+	"additions":   Number,
+	"deletions":   Number,
+	"files":       Number,
+	"message":     String,
+	"verified":    Bool,
+	"author_date": Time,
+}
+
+var testEnv = Env{
+	"additions":   120,
+	"deletions":   int64(30),
+	"files":       4.0,
+	"message":     "Add login\tpage",
+	"verified":    false,
+	"author_date": time.Date(2024, 6, 1, 22, 30, 0, 0, time.UTC),
+}
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   bool
+	}{
+		{"product before sum", "2 + 3 * 4 == 14", true},
+		{"division before difference", "10 - 6 / 2 == 7", true},
+		{"left associative difference", "10 - 4 - 3 == 3", true},
+		{"left associative division", "24 / 4 / 2 == 3", true},
+		{"parentheses", "(2 + 3) * 4 == 20", true},
+		{"unary minus", "-2 * -3 == 6", true},
+		{"and before or", "true || false && false", true},
+		{"and before or on the left", "false && true || true", true},
+		{"parenthesized or", "(true || false) && false", false},
+		{"not binds the comparison", "!files > 10", true},
+		{"double not", "!!verified", false},
+		{"word operators", "files > 1 and not verified or false", true},
+		{"ints and int64s are numbers", "additions - deletions == 90", true},
+		{"string comparison", `message == "Add login	page"`, true},
+		{"tab escape", `message == "Add login\tpage"`, true},
+		{"newline escape", `len("a\nb") == 3`, true},
+		{"escaped quote", `len("say \"hi\"") == 8`, true},
+		{"escaped backslash", `len("a\\b") == 3`, true},
+		{"single quotes", `contains(message, 'login')`, true},
+		{"escaped single quote", `len('it\'s') == 4`, true},
+		{"underscores in numbers", "1_000 == 1000", true},
+		{"leading dot", ".5 * 4 == 2", true},
+		{"bool comparison", "verified == false", true},
+		{"time comparison", `author_date > date("2024-06-01")`, true},
+		{"hour in utc", "hour(author_date) == 22", true},
+		{"hour in a time zone", `hour(author_date, "Asia/Kolkata") == 4`, true},
+		{"hours between", `hours_between(date("2024-06-01"), author_date) == 22.5`, true},
+		{"matches", `matches(lower(message), "^add ")`, true},
+		{"short circuit skips division by zero", "files < 0 && additions / 0 > 1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Compile(tt.source, testSchema)
+			if err != nil {
+				t.Fatalf("Compile(%q): %v", tt.source, err)
+			}
+			got, err := p.Eval(testEnv)
+			if err != nil {
+				t.Fatalf("Eval(%q): %v", tt.source, err)
+			}
+			if got != tt.want {
+				t.Errorf("Eval(%q) = %v, want %v", tt.source, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		// msg is part of the error message and pos its column
+		msg string
+		pos int
+	}{
+		{"empty", "", "unexpected end of expression", 1},
+		{"not a condition", "additions + 1", "is a number, not a condition", 1},
+		{"string condition", `message`, "is a string, not a condition", 1},
+		{"compare number with string", `files == "4"`, "cannot compare number with string", 7},
+		{"compare time with number", "author_date > 5", "cannot compare time with number", 13},
+		{"order conditions", "verified < true", "only be compared with == and !=", 10},
+		{"add strings", `message + "x" == "y"`, "+ needs numbers on both sides", 9},
+		{"negate string", `-message == "x"`, "- needs a number, found string", 1},
+		{"and with number", "files && verified", "&& needs conditions on both sides", 7},
+		{"or with string", "verified || message", "|| needs conditions on both sides", 10},
+		{"not number", "!files", "! needs a condition, found number", 1},
+		{"unknown field", "lines > 10", `unknown field "lines"`, 1},
+		{"unknown field in call", "hour(pushed_at) > 1", `unknown field "pushed_at"`, 6},
+		{"unknown function", "size(message) > 1", `unknown function "size"`, 1},
+		{"too few arguments", "contains(message)", "contains takes 2 argument(s), found 1", 1},
+		{"too many arguments", `hour(author_date, "UTC", 1) > 1`, "hour takes 1 to 2 arguments, found 3", 1},
+		{"argument type", "len(files) > 1", "argument 1 of len must be a string, found number", 1},
+		{"field as literal argument", "matches(message, message)", "must be a string literal", 1},
+		{"invalid pattern", `matches(message, "(")`, "invalid pattern", 1},
+		{"unknown time zone", `hour(author_date, "Mars/Olympus") > 1`, `unknown time zone "Mars/Olympus"`, 1},
+		{"invalid date", `author_date > date("June 1st")`, "neither RFC 3339 nor YYYY-MM-DD", 15},
+		{"unterminated string", `message == "abc`, "unterminated string", 12},
+		{"unterminated after escape", `message == "abc\"`, "unterminated string", 12},
+		{"trailing backslash", `message == "abc\`, "unterminated string", 12},
+		{"unterminated single quote", "message == 'abc", "unterminated string", 12},
+		{"invalid number", "files > 1.2.3", `invalid number "1.2.3"`, 9},
+		{"unexpected character", "files > 1 ; true", "unexpected character ';'", 11},
+		{"single ampersand", "verified & verified", "unexpected character '&'", 10},
+		{"trailing token", "files > 1 files", `unexpected "files"`, 11},
+		{"trailing parenthesis", "verified)", `unexpected ")"`, 9},
+		{"chained comparison", "1 < files < 10", `unexpected "<"`, 11},
+		{"unclosed parenthesis", "(verified", `expected ")" at end of expression`, 10},
+		{"unclosed call", "contains(message, \"a\"", `expected ")" at end of expression`, 22},
+		{"missing operand", "files >", "unexpected end of expression", 8},
+		{"dangling operator", "verified &&", "unexpected end of expression", 12},
+		{"leading operator", "* files > 1", `unexpected "*"`, 1},
+		{"empty argument", "contains(message, )", `unexpected ")"`, 19},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Compile(tt.source, testSchema)
+			if err == nil {
+				t.Fatalf("Compile(%q) succeeded, want an error", tt.source)
+			}
+			var e *Error
+			if !errors.As(err, &e) {
+				t.Fatalf("Compile(%q) error %v is a %T, want *Error", tt.source, err, err)
+			}
+			if !strings.Contains(e.Msg, tt.msg) {
+				t.Errorf("Compile(%q) error %q, want it to contain %q", tt.source, e.Msg, tt.msg)
+			}
+			if e.Pos != tt.pos {
+				t.Errorf("Compile(%q) error at column %d, want %d", tt.source, e.Pos, tt.pos)
+			}
+		})
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		env    Env
+		msg    string
+	}{
+		{"missing field", "files > 1", Env{}, "field files has no value"},
+		{"division by zero", "additions / (files - 4) > 1", testEnv, "division by zero"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Compile(tt.source, testSchema)
+			if err != nil {
+				t.Fatalf("Compile(%q): %v", tt.source, err)
+			}
+			if _, err := p.Eval(tt.env); err == nil || !strings.Contains(err.Error(), tt.msg) {
+				t.Errorf("Eval(%q) error %v, want it to contain %q", tt.source, err, tt.msg)
+			}
+		})
+	}
+}
+
+// TestCompileDoesNotPanic feeds malformed and truncated expressions to the
+// compiler; every one must come back as an error or a program.
+func TestCompileDoesNotPanic(t *testing.T) {
+	sources := []string{
+		"(", ")", "((", "()", "!", "-", "--", "!-", "&&", "||", ",", "\"", "'", "\\",
+		"hour(", "hour()", "hour(,)", "hour(author_date,", "date()", `date(1)`,
+		"matches()", "matches(message)", `matches("a", message)`, "len(", "lower)",
+		"files >", "> files", "files >=", "files = 1", "files ! 1", "true true",
+		"1 2", "\"a\" \"b\"", "((((((((((verified))))))))))", "!!!!!!!!verified",
+		"- - - - files > 0", "files > 1e10", "files > 1.", "files > .",
+		"é > 1", "message == \"ünïcödé\"", "\x00", "files\x00 > 1",
+	}
+	for _, source := range sources {
+		t.Run(source, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Fatalf("Compile(%q) panicked: %v", source, r)
+				}
+			}()
+			p, err := Compile(source, testSchema)
+			if err == nil {
+				// Whatever compiles must evaluate without panicking too
+				_, _ = p.Eval(testEnv)
+			}
+		})
+	}
+
+	// Every prefix of a valid expression is a truncation to survive
+	full := `files > 200 && hour(author_date, "Asia/Kolkata") >= 2 || !contains(lower(message), 'wip\'s')`
+	for i := range full {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Fatalf("Compile(%q) panicked: %v", full[:i], r)
+				}
+			}()
+			_, _ = Compile(full[:i], testSchema)
+		}()
+	}
+}
+
+func TestFields(t *testing.T) {
+	p, err := Compile(`files > 1 && contains(message, "x") && files < 10 && hour(author_date) > 1`, testSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Join(p.Fields(), ",")
+	if want := "author_date,files,message"; got != want {
+		t.Errorf("Fields() = %s, want %s", got, want)
+	}
+}
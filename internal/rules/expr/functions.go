@@ -0,0 +1,134 @@
+package expr
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// function is a built-in function. Arguments past required are optional.
+type function struct {
+	params   []Type
+	required int
+	result   Type
+	// prepare checks literal arguments at compile time and returns the
+	// values call receives as consts.
+	prepare func(args []node) ([]interface{}, error)
+	call    func(args, consts []interface{}) interface{}
+}
+
+var functions = map[string]*function{
+	// hour, minute and weekday read a time in UTC or the named time zone;
+	// weekday counts from 0 for Sunday
+	"hour":    clockFunction(func(t time.Time) int { return t.Hour() }),
+	"minute":  clockFunction(func(t time.Time) int { return t.Minute() }),
+	"weekday": clockFunction(func(t time.Time) int { return int(t.Weekday()) }),
+	// hours_between(a, b) is how many hours b is after a
+	"hours_between": {
+		params:   []Type{Time, Time},
+		required: 2,
+		result:   Number,
+		call: func(args, _ []interface{}) interface{} {
+			return args[1].(time.Time).Sub(args[0].(time.Time)).Hours()
+		},
+	},
+	// date("2024-06-01") or date("2024-06-01T09:00:00+05:30") is a fixed time
+	"date": {
+		params:   []Type{String},
+		required: 1,
+		result:   Time,
+		prepare: func(args []node) ([]interface{}, error) {
+			s, err := literalString(args[0], "date")
+			if err != nil {
+				return nil, err
+			}
+			for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+				if t, err := time.Parse(layout, s); err == nil {
+					return []interface{}{t}, nil
+				}
+			}
+			return nil, fmt.Errorf("date %q is neither RFC 3339 nor YYYY-MM-DD", s)
+		},
+		call: func(_, consts []interface{}) interface{} { return consts[0] },
+	},
+	"lower": {
+		params:   []Type{String},
+		required: 1,
+		result:   String,
+		call:     func(args, _ []interface{}) interface{} { return strings.ToLower(args[0].(string)) },
+	},
+	"len": {
+		params:   []Type{String},
+		required: 1,
+		result:   Number,
+		call:     func(args, _ []interface{}) interface{} { return float64(len([]rune(args[0].(string)))) },
+	},
+	"contains":    stringPredicate(strings.Contains),
+	"starts_with": stringPredicate(strings.HasPrefix),
+	"ends_with":   stringPredicate(strings.HasSuffix),
+	// matches(s, "regexp") takes a literal pattern, compiled once
+	"matches": {
+		params:   []Type{String, String},
+		required: 2,
+		result:   Bool,
+		prepare: func(args []node) ([]interface{}, error) {
+			pattern, err := literalString(args[1], "matches")
+			if err != nil {
+				return nil, err
+			}
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern: %w", err)
+			}
+			return []interface{}{re}, nil
+		},
+		call: func(args, consts []interface{}) interface{} {
+			return consts[0].(*regexp.Regexp).MatchString(args[0].(string))
+		},
+	},
+}
+
+func clockFunction(part func(time.Time) int) *function {
+	return &function{
+		params:   []Type{Time, String},
+		required: 1,
+		result:   Number,
+		prepare: func(args []node) ([]interface{}, error) {
+			if len(args) < 2 {
+				return []interface{}{time.UTC}, nil
+			}
+			name, err := literalString(args[1], "time zone")
+			if err != nil {
+				return nil, err
+			}
+			loc, err := time.LoadLocation(name)
+			if err != nil {
+				return nil, fmt.Errorf("unknown time zone %q", name)
+			}
+			return []interface{}{loc}, nil
+		},
+		call: func(args, consts []interface{}) interface{} {
+			return float64(part(args[0].(time.Time).In(consts[0].(*time.Location))))
+		},
+	}
+}
+
+func stringPredicate(fn func(s, sub string) bool) *function {
+	return &function{
+		params:   []Type{String, String},
+		required: 2,
+		result:   Bool,
+		call: func(args, _ []interface{}) interface{} {
+			return fn(args[0].(string), args[1].(string))
+		},
+	}
+}
+
+func literalString(n node, what string) (string, error) {
+	lit, ok := n.(*literal)
+	if !ok || lit.t != String {
+		return "", fmt.Errorf("%s must be a string literal", what)
+	}
+	return lit.v.(string), nil
+}
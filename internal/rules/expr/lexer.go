@@ -0,0 +1,134 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokString
+	tokIdent
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	// num and str hold the value of number and string literals
+	num float64
+	str string
+	pos int
+}
+
+// Keywords spelled out as words are aliases of the symbolic operators.
+var wordOps = map[string]string{"and": "&&", "or": "||", "not": "!"}
+
+var twoCharOps = []string{"&&", "||", "==", "!=", "<=", ">="}
+
+// lex splits an expression into tokens. Positions are byte offsets, reported
+// one-based in errors.
+func lex(src string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(src) {
+		c := rune(src[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+
+		case unicode.IsDigit(c) || (c == '.' && i+1 < len(src) && unicode.IsDigit(rune(src[i+1]))):
+			start := i
+			for i < len(src) && (unicode.IsDigit(rune(src[i])) || src[i] == '.' || src[i] == '_') {
+				i++
+			}
+			n, err := strconv.ParseFloat(strings.ReplaceAll(src[start:i], "_", ""), 64)
+			if err != nil {
+				return nil, errorAt(start, "invalid number %q", src[start:i])
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: src[start:i], num: n, pos: start})
+
+		case c == '"' || c == '\'':
+			start := i
+			i++
+			var sb strings.Builder
+			closed := false
+			for i < len(src) {
+				if rune(src[i]) == c {
+					closed = true
+					i++
+					break
+				}
+				if src[i] == '\\' && i+1 < len(src) {
+					i++
+					switch src[i] {
+					case 'n':
+						sb.WriteByte('\n')
+					case 't':
+						sb.WriteByte('\t')
+					default:
+						sb.WriteByte(src[i])
+					}
+					i++
+					continue
+				}
+				sb.WriteByte(src[i])
+				i++
+			}
+			if !closed {
+				return nil, errorAt(start, "unterminated string")
+			}
+			tokens = append(tokens, token{kind: tokString, text: src[start:i], str: sb.String(), pos: start})
+
+		case c == '_' || unicode.IsLetter(c):
+			start := i
+			for i < len(src) && (src[i] == '_' || unicode.IsLetter(rune(src[i])) || unicode.IsDigit(rune(src[i]))) {
+				i++
+			}
+			word := src[start:i]
+			if op, ok := wordOps[strings.ToLower(word)]; ok {
+				tokens = append(tokens, token{kind: tokOp, text: op, pos: start})
+				continue
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: word, pos: start})
+
+		default:
+			op := ""
+			for _, candidate := range twoCharOps {
+				if strings.HasPrefix(src[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" && strings.ContainsRune("()!,<>+-*/", c) {
+				op = string(c)
+			}
+			if op == "" {
+				return nil, errorAt(i, "unexpected character %q", c)
+			}
+			tokens = append(tokens, token{kind: tokOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(src)}), nil
+}
+
+// Error is a compile error at a position in the expression.
+type Error struct {
+	// Pos is the one-based column of the error.
+	Pos int
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("column %d: %s", e.Pos, e.Msg)
+}
+
+func errorAt(pos int, format string, args ...interface{}) error {
+	return &Error{Pos: pos + 1, Msg: fmt.Sprintf(format, args...)}
+}
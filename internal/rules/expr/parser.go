@@ -0,0 +1,244 @@
+package expr
+
+// parser is a recursive descent parser that type checks as it builds nodes.
+// Precedence from lowest: ||, &&, !, comparisons, + -, * /, unary minus.
+type parser struct {
+	tokens []token
+	i      int
+	schema Schema
+	fields map[string]bool
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.i]
+	if tok.kind != tokEOF {
+		p.i++
+	}
+	return tok
+}
+
+func (p *parser) acceptOp(ops ...string) (token, bool) {
+	tok := p.peek()
+	if tok.kind != tokOp {
+		return tok, false
+	}
+	for _, op := range ops {
+		if tok.text == op {
+			return p.next(), true
+		}
+	}
+	return tok, false
+}
+
+func (p *parser) expectOp(op string) error {
+	if _, ok := p.acceptOp(op); !ok {
+		tok := p.peek()
+		if tok.kind == tokEOF {
+			return errorAt(tok.pos, "expected %q at end of expression", op)
+		}
+		return errorAt(tok.pos, "expected %q, found %q", op, tok.text)
+	}
+	return nil
+}
+
+func (p *parser) parseExpr() (node, error) {
+	return p.parseLogical("||", p.parseAnd)
+}
+
+func (p *parser) parseAnd() (node, error) {
+	return p.parseLogical("&&", p.parseNot)
+}
+
+func (p *parser) parseLogical(op string, operand func() (node, error)) (node, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok, ok := p.acceptOp(op)
+		if !ok {
+			return left, nil
+		}
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		if left.typ() != Bool || right.typ() != Bool {
+			return nil, errorAt(tok.pos, "%s needs conditions on both sides, found %s and %s", op, left.typ(), right.typ())
+		}
+		left = &logical{and: op == "&&", left: left, right: right}
+	}
+}
+
+func (p *parser) parseNot() (node, error) {
+	tok, ok := p.acceptOp("!")
+	if !ok {
+		return p.parseComparison()
+	}
+	x, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	if x.typ() != Bool {
+		return nil, errorAt(tok.pos, "! needs a condition, found %s", x.typ())
+	}
+	return &not{x: x}, nil
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	tok, ok := p.acceptOp("==", "!=", "<", "<=", ">", ">=")
+	if !ok {
+		return left, nil
+	}
+	right, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+
+	if left.typ() != right.typ() {
+		return nil, errorAt(tok.pos, "cannot compare %s with %s", left.typ(), right.typ())
+	}
+	if left.typ() == Bool && tok.text != "==" && tok.text != "!=" {
+		return nil, errorAt(tok.pos, "conditions can only be compared with == and !=")
+	}
+	return &comparison{op: tok.text, left: left, right: right}, nil
+}
+
+func (p *parser) parseSum() (node, error) {
+	return p.parseArithmetic([]string{"+", "-"}, p.parseProduct)
+}
+
+func (p *parser) parseProduct() (node, error) {
+	return p.parseArithmetic([]string{"*", "/"}, p.parseUnary)
+}
+
+func (p *parser) parseArithmetic(ops []string, operand func() (node, error)) (node, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok, ok := p.acceptOp(ops...)
+		if !ok {
+			return left, nil
+		}
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		if left.typ() != Number || right.typ() != Number {
+			return nil, errorAt(tok.pos, "%s needs numbers on both sides, found %s and %s", tok.text, left.typ(), right.typ())
+		}
+		left = &arithmetic{op: tok.text, left: left, right: right}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	tok, ok := p.acceptOp("-")
+	if !ok {
+		return p.parsePrimary()
+	}
+	x, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	if x.typ() != Number {
+		return nil, errorAt(tok.pos, "- needs a number, found %s", x.typ())
+	}
+	return &negate{x: x}, nil
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokNumber:
+		return &literal{t: Number, v: tok.num}, nil
+	case tokString:
+		return &literal{t: String, v: tok.str}, nil
+	case tokIdent:
+		switch tok.text {
+		case "true":
+			return &literal{t: Bool, v: true}, nil
+		case "false":
+			return &literal{t: Bool, v: false}, nil
+		}
+		if _, ok := p.acceptOp("("); ok {
+			return p.parseCall(tok)
+		}
+		t, ok := p.schema[tok.text]
+		if !ok {
+			return nil, errorAt(tok.pos, "unknown field %q", tok.text)
+		}
+		p.fields[tok.text] = true
+		return &field{name: tok.text, t: t}, nil
+	case tokOp:
+		if tok.text == "(" {
+			x, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectOp(")"); err != nil {
+				return nil, err
+			}
+			return x, nil
+		}
+		return nil, errorAt(tok.pos, "unexpected %q", tok.text)
+	}
+	return nil, errorAt(tok.pos, "unexpected end of expression")
+}
+
+// parseCall parses the arguments of a call to name, whose opening
+// parenthesis was consumed.
+func (p *parser) parseCall(name token) (node, error) {
+	fn, ok := functions[name.text]
+	if !ok {
+		return nil, errorAt(name.pos, "unknown function %q", name.text)
+	}
+
+	var args []node
+	if _, ok := p.acceptOp(")"); !ok {
+		for {
+			arg, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if _, ok := p.acceptOp(","); !ok {
+				break
+			}
+		}
+		if err := p.expectOp(")"); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(args) < fn.required || len(args) > len(fn.params) {
+		if fn.required == len(fn.params) {
+			return nil, errorAt(name.pos, "%s takes %d argument(s), found %d", name.text, fn.required, len(args))
+		}
+		return nil, errorAt(name.pos, "%s takes %d to %d arguments, found %d", name.text, fn.required, len(fn.params), len(args))
+	}
+	for i, arg := range args {
+		if arg.typ() != fn.params[i] {
+			return nil, errorAt(name.pos, "argument %d of %s must be a %s, found %s", i+1, name.text, fn.params[i], arg.typ())
+		}
+	}
+
+	c := &call{fn: fn, args: args}
+	if fn.prepare != nil {
+		consts, err := fn.prepare(args)
+		if err != nil {
+			return nil, errorAt(name.pos, "%s: %v", name.text, err)
+		}
+		c.consts = consts
+	}
+	return c, nil
+}
@@ -0,0 +1,64 @@
+package rules
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Registry holds the rules known to the engine in registration order.
+type Registry struct {
+	rules map[string]Rule
+	order []string
+}
+
+func NewRegistry() *Registry {
+	return &Registry{rules: make(map[string]Rule)}
+}
+
+// Register adds a rule, replacing any rule registered under the same name.
+func (r *Registry) Register(rule Rule) {
+	if _, ok := r.rules[rule.Name()]; !ok {
+		r.order = append(r.order, rule.Name())
+	}
+	r.rules[rule.Name()] = rule
+}
+
+// RegisterCustom registers custom rules after the built-in ones. A custom
+// rule may not take the name of a rule already registered; if any does,
+// none are registered.
+func (r *Registry) RegisterCustom(custom []*CustomRule) error {
+	for _, rule := range custom {
+		if _, ok := r.rules[rule.Name()]; ok {
+			return fmt.Errorf("custom rule %s: name is taken by a built-in rule", rule.Name())
+		}
+	}
+	for _, rule := range custom {
+		r.Register(rule.Wrap())
+	}
+	return nil
+}
+
+// Get returns the rule with the given name, or nil.
+func (r *Registry) Get(name string) Rule {
+	return r.rules[name]
+}
+
+// List returns every rule in registration order.
+func (r *Registry) List() []Rule {
+	list := make([]Rule, 0, len(r.order))
+	for _, name := range r.order {
+		list = append(list, r.rules[name])
+	}
+	return list
+}
+
+// ForTrigger returns the rules consuming a trigger in registration order.
+func (r *Registry) ForTrigger(trigger Trigger) []Rule {
+	var list []Rule
+	for _, rule := range r.List() {
+		if slices.Contains(rule.Triggers(), trigger) {
+			list = append(list, rule)
+		}
+	}
+	return list
+}
@@ -0,0 +1,201 @@
+// Package rules is the detection rule engine. Every detection is a Rule
+// registered in a Registry; the Engine runs the rules consuming each trigger
+// with the settings resolved for the repository at hand, so rules can be
+// enabled, disabled and parameterized per event or repository.
+package rules
+
+import (
+	"context"
+	"time"
+
+	"github.com/harshpatel5940/gitvigil/internal/models"
+)
+
+// Trigger is an occurrence a rule consumes.
+type Trigger string
+
+const (
+	// TriggerCommit rules evaluate each newly stored commit.
+	TriggerCommit Trigger = "commit"
+	// TriggerEnrichment rules evaluate each commit once its line and file
+	// counts have been fetched from the API.
+	TriggerEnrichment Trigger = "enrichment"
+	// TriggerPush rules evaluate each new push; repository rules consuming
+	// it run once the push's commits are stored.
+	TriggerPush Trigger = "push"
+	// TriggerSchedule rules run periodically from the scheduler.
+	TriggerSchedule Trigger = "schedule"
+	// TriggerInstallation rules run once a newly installed repository has
+	// been backfilled.
+	TriggerInstallation Trigger = "installation"
+)
+
+// Rule is a detection. Every rule implements one of CommitRule, PushRule,
+// RepositoryRule or ScheduledRule to be evaluated.
+type Rule interface {
+	// Name identifies the rule in settings and on the API.
+	Name() string
+	Description() string
+	Triggers() []Trigger
+	// AlertType is the type of the alerts the rule raises.
+	AlertType() models.AlertType
+	// DefaultSeverity is the severity of the rule's alerts unless a setting
+	// overrides it. Rules whose alerts carry their own severity return ""
+	// and cannot be overridden.
+	DefaultSeverity() models.Severity
+	// DefaultParams lists every parameter the rule accepts with its default.
+	DefaultParams() Params
+}
+
+// ParamChecker is implemented by rules whose parameters constrain each
+// other. CheckParams is given the parameters a repository resolves to.
+type ParamChecker interface {
+	CheckParams(p Params) error
+}
+
+// CommitRule evaluates a single commit as it is ingested.
+type CommitRule interface {
+	Rule
+	EvaluateCommit(c *Commit, s *Settings) []*Finding
+}
+
+// PushRule evaluates a single push as it is ingested.
+type PushRule interface {
+	Rule
+	EvaluatePush(p *Push, s *Settings) []*Finding
+}
+
+// RepositoryRule checks a whole repository and raises or refreshes its own
+// alerts. On schedule the engine runs it for every active repository it is
+// enabled for.
+type RepositoryRule interface {
+	Rule
+	CheckRepository(ctx context.Context, repo *models.Repository, s *Settings) error
+}
+
+// ScheduledRule runs across repositories by itself, resolving each
+// repository's settings through the engine.
+type ScheduledRule interface {
+	Rule
+	Run(ctx context.Context, e *Engine) error
+}
+
+// Commit is a newly stored commit with its timing measured against the push
+// that delivered it.
+type Commit struct {
+	RepositoryID   int64
+	SHA            string
+	Message        string
+	AuthorName     string
+	AuthorEmail    string
+	AuthorLogin    string
+	AuthorDate     time.Time
+	CommitterName  string
+	CommitterEmail string
+	CommitterLogin string
+	CommitterDate  time.Time
+	Source         string
+	// DatesVerified is set when the committer date came from the API.
+	DatesVerified bool
+	// PushedAt is GitHub's timestamp for the push, or the committer date of
+	// a backfilled commit.
+	PushedAt time.Time
+	// BackdateHours is how long before PushedAt the commit was authored.
+	BackdateHours int
+	// CommitterGapHours is how much older the author date is than the
+	// committer date.
+	CommitterGapHours int
+	// FutureMinutes is how far the commit's latest date is ahead of its push,
+	// or of its ingestion when backfilled; zero or negative when it is not.
+	FutureMinutes int
+	// Additions, Deletions and FilesChanged are only known to enrichment
+	// rules; commit rules see zero.
+	Additions    int
+	Deletions    int
+	FilesChanged int
+}
+
+// CommitFromRecord is the commit as rules see it, from its stored record.
+func CommitFromRecord(c *models.CommitRecord) *Commit {
+	return &Commit{
+		RepositoryID:      c.RepositoryID,
+		SHA:               c.SHA,
+		Message:           c.Message,
+		AuthorName:        c.AuthorName,
+		AuthorEmail:       c.AuthorEmail,
+		AuthorLogin:       c.AuthorLogin,
+		AuthorDate:        c.AuthorDate,
+		CommitterName:     c.CommitterName,
+		CommitterEmail:    c.CommitterEmail,
+		CommitterLogin:    c.CommitterLogin,
+		CommitterDate:     c.CommitterDate,
+		Source:            c.Source,
+		DatesVerified:     c.DatesVerified,
+		PushedAt:          c.PushedAt,
+		BackdateHours:     c.BackdateHours,
+		CommitterGapHours: int(c.CommitterDate.Sub(c.AuthorDate).Hours()),
+		FutureMinutes:     c.FutureMinutes,
+		Additions:         c.Additions,
+		Deletions:         c.Deletions,
+		FilesChanged:      c.FilesChanged,
+	}
+}
+
+// Push is a newly received push.
+type Push struct {
+	RepositoryID int64
+	PushEventID  int64
+	Ref          string
+	Before       string
+	After        string
+	Forced       bool
+	Pusher       string
+	Commits      int
+	PushedAt     time.Time
+	// EventID and Deadline are set when the repository is enrolled in an
+	// event. PostDeadline marks a push after the deadline plus the grace
+	// period, MinutesLate minutes after the deadline itself.
+	EventID      *int64
+	Deadline     *time.Time
+	GraceMinutes int
+	PostDeadline bool
+	MinutesLate  int
+}
+
+// PushFromRecord is the push as rules see it, from its stored record.
+func PushFromRecord(p *models.PushRecord, graceMinutes int) *Push {
+	push := &Push{
+		RepositoryID: p.RepositoryID,
+		PushEventID:  p.ID,
+		Ref:          p.Ref,
+		Before:       p.Before,
+		After:        p.After,
+		Forced:       p.Forced,
+		Pusher:       p.Pusher,
+		Commits:      p.Commits,
+		PushedAt:     p.PushedAt,
+		EventID:      p.EventID,
+		Deadline:     p.Deadline,
+		PostDeadline: p.PostDeadline,
+	}
+	if p.Deadline != nil {
+		push.GraceMinutes = graceMinutes
+		if p.PostDeadline {
+			push.MinutesLate = int(p.PushedAt.Sub(*p.Deadline).Minutes())
+		}
+	}
+	return push
+}
+
+// Finding is an alert a commit or push rule asks the engine to raise.
+type Finding struct {
+	// AlertType defaults to the rule's alert type.
+	AlertType models.AlertType
+	// Severity defaults to the rule's severity; a configured severity
+	// override replaces it either way.
+	Severity    models.Severity
+	CommitSHA   string
+	Title       string
+	Description string
+	Metadata    map[string]interface{}
+}
@@ -0,0 +1,166 @@
+package rules
+
+import (
+	"fmt"
+	"maps"
+
+	"github.com/harshpatel5940/gitvigil/internal/models"
+)
+
+// Params are a rule's numeric parameters by name.
+type Params map[string]float64
+
+// Settings are a rule's effective settings for one repository.
+type Settings struct {
+	Enabled bool
+	// Severity is the configured severity override, or "" when none applies.
+	Severity models.Severity
+	Params   Params
+}
+
+// Int returns a parameter truncated to an integer.
+func (s *Settings) Int(key string) int {
+	return int(s.Params[key])
+}
+
+// Float returns a parameter.
+func (s *Settings) Float(key string) float64 {
+	return s.Params[key]
+}
+
+// SeverityOr returns the severity override, or severity when there is none.
+func (s *Settings) SeverityOr(severity models.Severity) models.Severity {
+	if s.Severity != "" {
+		return s.Severity
+	}
+	return severity
+}
+
+// scopeOrder ranks overrides from least to most specific.
+func scopeOrder(o *models.RuleSetting) int {
+	switch {
+	case o.RepositoryID != nil:
+		return 2
+	case o.EventID != nil:
+		return 1
+	}
+	return 0
+}
+
+// Resolve applies a rule's overrides on top of its defaults: global
+// overrides first, then the event's, then the repository's. Unset fields of
+// an override leave the less specific value in place.
+func Resolve(rule Rule, overrides []*models.RuleSetting) *Settings {
+	s := &Settings{Enabled: true, Params: maps.Clone(rule.DefaultParams())}
+	if s.Params == nil {
+		s.Params = Params{}
+	}
+	for scope := 0; scope <= 2; scope++ {
+		for _, o := range overrides {
+			if o.Rule != rule.Name() || scopeOrder(o) != scope {
+				continue
+			}
+			if o.Enabled != nil {
+				s.Enabled = *o.Enabled
+			}
+			if o.Severity != nil && rule.DefaultSeverity() != "" {
+				s.Severity = *o.Severity
+			}
+			for key, value := range o.Params {
+				if _, known := s.Params[key]; known {
+					s.Params[key] = value
+				}
+			}
+		}
+	}
+	return s
+}
+
+// Validate checks an override against the rule it configures.
+func Validate(rule Rule, o *models.RuleSetting) error {
+	if o.EventID != nil && o.RepositoryID != nil {
+		return fmt.Errorf("a setting applies to an event or a repository, not both")
+	}
+	if o.Severity != nil {
+		if rule.DefaultSeverity() == "" {
+			return fmt.Errorf("rule %s does not support a severity override", rule.Name())
+		}
+		switch *o.Severity {
+		case models.SeverityInfo, models.SeverityWarning, models.SeverityCritical:
+		default:
+			return fmt.Errorf("severity must be info, warning or critical")
+		}
+	}
+	defaults := rule.DefaultParams()
+	for key := range o.Params {
+		if _, ok := defaults[key]; !ok {
+			return fmt.Errorf("rule %s has no parameter %q", rule.Name(), key)
+		}
+	}
+	return nil
+}
+
+// ValidateResolved checks the parameters o resolves to alongside the rule's
+// existing settings, for o's own scope and every scope already configured,
+// when the rule constrains its parameters together. A broader setting can
+// thus not break a narrower one, nor the reverse. repoEvents maps each
+// repository with a setting, o's included, to the event it is enrolled in.
+func ValidateResolved(rule Rule, o *models.RuleSetting, existing []*models.RuleSetting, repoEvents map[int64]*int64) error {
+	checker, ok := rule.(ParamChecker)
+	if !ok {
+		return nil
+	}
+
+	overrides := []*models.RuleSetting{o}
+	for _, e := range existing {
+		if e.Rule == rule.Name() && !sameScope(e, o) {
+			overrides = append(overrides, e)
+		}
+	}
+
+	for _, scope := range overrides {
+		eventID := scope.EventID
+		if scope.RepositoryID != nil {
+			eventID = repoEvents[*scope.RepositoryID]
+		}
+		var applying []*models.RuleSetting
+		for _, other := range overrides {
+			if appliesTo(other, eventID, scope.RepositoryID) {
+				applying = append(applying, other)
+			}
+		}
+		if err := checker.CheckParams(Resolve(rule, applying).Params); err != nil {
+			return fmt.Errorf("%s: %w", scopeName(scope), err)
+		}
+	}
+	return nil
+}
+
+func sameScope(a, b *models.RuleSetting) bool {
+	return equalID(a.EventID, b.EventID) && equalID(a.RepositoryID, b.RepositoryID)
+}
+
+// appliesTo reports whether o applies to the scope of eventID and repoID.
+func appliesTo(o *models.RuleSetting, eventID, repoID *int64) bool {
+	switch {
+	case o.RepositoryID != nil:
+		return equalID(o.RepositoryID, repoID)
+	case o.EventID != nil:
+		return equalID(o.EventID, eventID)
+	}
+	return true
+}
+
+func equalID(a, b *int64) bool {
+	return (a == nil && b == nil) || (a != nil && b != nil && *a == *b)
+}
+
+func scopeName(o *models.RuleSetting) string {
+	switch {
+	case o.RepositoryID != nil:
+		return fmt.Sprintf("repository %d", *o.RepositoryID)
+	case o.EventID != nil:
+		return fmt.Sprintf("event %d", *o.EventID)
+	}
+	return "global setting"
+}
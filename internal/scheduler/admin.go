@@ -0,0 +1,124 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type runResponse struct {
+	ID         int64      `json:"id"`
+	Task       string     `json:"task"`
+	Trigger    Trigger    `json:"trigger"`
+	Status     RunStatus  `json:"status"`
+	Error      *string    `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	DurationMs *int64     `json:"duration_ms,omitempty"`
+}
+
+type taskResponse struct {
+	Name            string       `json:"name"`
+	IntervalSeconds int64        `json:"interval_seconds"`
+	Running         bool         `json:"running"`
+	NextRunAt       *time.Time   `json:"next_run_at,omitempty"`
+	LastRun         *runResponse `json:"last_run,omitempty"`
+}
+
+func toRunResponse(r *Run) *runResponse {
+	resp := &runResponse{
+		ID:         r.ID,
+		Task:       r.Task,
+		Trigger:    r.Trigger,
+		Status:     r.Status,
+		Error:      r.Error,
+		StartedAt:  r.StartedAt,
+		FinishedAt: r.FinishedAt,
+	}
+	if r.FinishedAt != nil {
+		ms := r.FinishedAt.Sub(r.StartedAt).Milliseconds()
+		resp.DurationMs = &ms
+	}
+	return resp
+}
+
+// HandleListTasks lists registered tasks with their schedule and last run.
+func (s *Scheduler) HandleListTasks(w http.ResponseWriter, r *http.Request) {
+	tasks, err := s.Tasks(r.Context())
+	if err != nil {
+		s.logger.Error().Err(err).Msg("failed to list tasks")
+		http.Error(w, "failed to list tasks", http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]taskResponse, 0, len(tasks))
+	for _, t := range tasks {
+		tr := taskResponse{
+			Name:            t.Name,
+			IntervalSeconds: int64(t.Interval.Seconds()),
+			Running:         t.Running,
+			NextRunAt:       t.NextRun,
+		}
+		if t.LastRun != nil {
+			tr.LastRun = toRunResponse(t.LastRun)
+		}
+		response = append(response, tr)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"tasks": response})
+}
+
+// HandleListRuns lists recent runs, optionally filtered by ?task=.
+func (s *Scheduler) HandleListRuns(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if v, err := strconv.Atoi(l); err == nil && v > 0 && v <= 500 {
+			limit = v
+		}
+	}
+
+	runs, err := s.Runs(r.Context(), r.URL.Query().Get("task"), limit)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("failed to list task runs")
+		http.Error(w, "failed to list task runs", http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]*runResponse, 0, len(runs))
+	for _, run := range runs {
+		response = append(response, toRunResponse(run))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"runs": response})
+}
+
+// HandleTrigger runs the task named in the URL immediately.
+func (s *Scheduler) HandleTrigger(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	runID, err := s.Trigger(name)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrUnknownTask):
+			http.Error(w, "task not found", http.StatusNotFound)
+		case errors.Is(err, ErrAlreadyRunning):
+			http.Error(w, "task is already running", http.StatusConflict)
+		case errors.Is(err, ErrNotStarted), errors.Is(err, ErrStopping):
+			http.Error(w, "scheduler is not running", http.StatusServiceUnavailable)
+		default:
+			s.logger.Error().Err(err).Str("task", name).Msg("failed to trigger task")
+			http.Error(w, "failed to trigger task", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{"run_id": runID})
+}
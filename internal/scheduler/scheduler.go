@@ -0,0 +1,383 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+)
+
+type RunStatus string
+
+const (
+	RunRunning   RunStatus = "running"
+	RunSucceeded RunStatus = "succeeded"
+	RunFailed    RunStatus = "failed"
+)
+
+type Trigger string
+
+const (
+	TriggerSchedule Trigger = "schedule"
+	TriggerManual   Trigger = "manual"
+)
+
+var (
+	ErrUnknownTask    = errors.New("unknown task")
+	ErrAlreadyRunning = errors.New("task is already running")
+	ErrNotStarted     = errors.New("scheduler is not running")
+	ErrStopping       = errors.New("scheduler is stopping")
+)
+
+// tickInterval is how often the scheduler checks for due tasks.
+const tickInterval = 10 * time.Second
+
+// TaskFunc is a periodic task. Returning an error marks the run as failed;
+// the task is still run again at its next interval.
+type TaskFunc func(ctx context.Context) error
+
+type task struct {
+	name     string
+	interval time.Duration
+	fn       TaskFunc
+	running  bool
+	nextRun  time.Time
+}
+
+type Run struct {
+	ID         int64
+	Task       string
+	Trigger    Trigger
+	Status     RunStatus
+	Error      *string
+	StartedAt  time.Time
+	FinishedAt *time.Time
+}
+
+// TaskStatus describes a registered task and its most recent run.
+type TaskStatus struct {
+	Name     string
+	Interval time.Duration
+	Running  bool
+	NextRun  *time.Time
+	LastRun  *Run
+}
+
+// Scheduler runs registered tasks in-process at fixed intervals and records
+// every run in scheduler_runs.
+type Scheduler struct {
+	pool   *pgxpool.Pool
+	logger zerolog.Logger
+
+	mu     sync.Mutex
+	tasks  map[string]*task
+	order  []string
+	ctx    context.Context
+	cancel context.CancelFunc
+	stop   chan struct{}
+	// stopping is set under mu once Stop begins; no run may join wg after.
+	stopping bool
+	wg       sync.WaitGroup
+}
+
+func New(pool *pgxpool.Pool, logger zerolog.Logger) *Scheduler {
+	return &Scheduler{
+		pool:   pool,
+		logger: logger.With().Str("component", "scheduler").Logger(),
+		tasks:  make(map[string]*task),
+		stop:   make(chan struct{}),
+	}
+}
+
+// Register adds a task. An interval of zero or less disables periodic runs,
+// leaving the task available for manual triggering only. It must be called
+// before Start.
+func (s *Scheduler) Register(name string, interval time.Duration, fn TaskFunc) {
+	s.tasks[name] = &task{name: name, interval: interval, fn: fn}
+	s.order = append(s.order, name)
+}
+
+// Start schedules each task relative to its last recorded run, so a restart
+// does not rerun everything at once, and starts the scheduling loop.
+func (s *Scheduler) Start() {
+	ctx := context.Background()
+	if err := s.recoverInterrupted(ctx); err != nil {
+		s.logger.Error().Err(err).Msg("failed to recover interrupted runs")
+	}
+
+	now := time.Now()
+	for _, t := range s.tasks {
+		if t.interval <= 0 {
+			continue
+		}
+		t.nextRun = now
+		last, err := s.lastRun(ctx, t.name)
+		if err != nil {
+			s.logger.Error().Err(err).Str("task", t.name).Msg("failed to load last run")
+			continue
+		}
+		if last != nil {
+			t.nextRun = last.StartedAt.Add(t.interval)
+		}
+	}
+
+	// Runs get their own context so a shutdown signal lets them finish
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+
+	s.wg.Add(1)
+	go s.loop()
+
+	s.logger.Info().Int("tasks", len(s.tasks)).Msg("scheduler started")
+}
+
+// Stop stops scheduling and waits for running tasks. If ctx expires first,
+// their context is cancelled.
+func (s *Scheduler) Stop(ctx context.Context) error {
+	if s.cancel == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	s.stopping = true
+	s.mu.Unlock()
+
+	close(s.stop)
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		s.cancel()
+		s.logger.Info().Msg("scheduler stopped")
+		return nil
+	case <-ctx.Done():
+		s.cancel()
+		<-done
+		return fmt.Errorf("scheduler stop timed out: %w", ctx.Err())
+	}
+}
+
+func (s *Scheduler) loop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+
+	for {
+		s.runDue()
+
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) runDue() {
+	now := time.Now()
+	for _, name := range s.order {
+		s.mu.Lock()
+		t := s.tasks[name]
+		due := t.interval > 0 && !t.running && !now.Before(t.nextRun)
+		s.mu.Unlock()
+
+		if !due {
+			continue
+		}
+		if _, err := s.start(t, TriggerSchedule); err != nil && !errors.Is(err, ErrAlreadyRunning) && !errors.Is(err, ErrStopping) {
+			s.logger.Error().Err(err).Str("task", name).Msg("failed to start task")
+		}
+	}
+}
+
+// Trigger runs a task now, outside its schedule. It returns the ID of the
+// run, which completes in the background, or ErrStopping once Stop has
+// begun.
+func (s *Scheduler) Trigger(name string) (int64, error) {
+	t, ok := s.tasks[name]
+	if !ok {
+		return 0, ErrUnknownTask
+	}
+	if s.ctx == nil {
+		return 0, ErrNotStarted
+	}
+	return s.start(t, TriggerManual)
+}
+
+// start records a run and executes it in the background. The run joins the
+// wait group under the mutex, so Stop never waits while runs are added.
+func (s *Scheduler) start(t *task, trigger Trigger) (int64, error) {
+	s.mu.Lock()
+	if s.stopping {
+		s.mu.Unlock()
+		return 0, ErrStopping
+	}
+	if t.running {
+		s.mu.Unlock()
+		return 0, ErrAlreadyRunning
+	}
+	t.running = true
+	s.wg.Add(1)
+	s.mu.Unlock()
+
+	var runID int64
+	var startedAt time.Time
+	err := s.pool.QueryRow(s.ctx, `
+		INSERT INTO scheduler_runs (task, trigger)
+		VALUES ($1, $2)
+		RETURNING id, started_at
+	`, t.name, trigger).Scan(&runID, &startedAt)
+	if err != nil {
+		s.mu.Lock()
+		t.running = false
+		s.mu.Unlock()
+		s.wg.Done()
+		return 0, err
+	}
+
+	go s.execute(t, runID, startedAt, trigger)
+
+	return runID, nil
+}
+
+func (s *Scheduler) execute(t *task, runID int64, startedAt time.Time, trigger Trigger) {
+	defer s.wg.Done()
+
+	logger := s.logger.With().Str("task", t.name).Int64("run_id", runID).Str("trigger", string(trigger)).Logger()
+	logger.Info().Msg("task started")
+
+	err := s.safeRun(t)
+
+	status := RunSucceeded
+	var errMsg *string
+	if err != nil {
+		status = RunFailed
+		msg := err.Error()
+		errMsg = &msg
+		logger.Error().Err(err).Dur("duration", time.Since(startedAt)).Msg("task failed")
+	} else {
+		logger.Info().Dur("duration", time.Since(startedAt)).Msg("task completed")
+	}
+
+	// Record the outcome even if the run context was cancelled on shutdown
+	if _, dbErr := s.pool.Exec(context.Background(), `
+		UPDATE scheduler_runs SET status = $2, error = $3, finished_at = NOW()
+		WHERE id = $1
+	`, runID, status, errMsg); dbErr != nil {
+		logger.Error().Err(dbErr).Msg("failed to record task run")
+	}
+
+	s.mu.Lock()
+	t.running = false
+	if t.interval > 0 {
+		t.nextRun = startedAt.Add(t.interval)
+	}
+	s.mu.Unlock()
+}
+
+// safeRun calls the task, turning a panic into an error so one bad task
+// can't take down the scheduler.
+func (s *Scheduler) safeRun(t *task) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("task panicked: %v", r)
+		}
+	}()
+	return t.fn(s.ctx)
+}
+
+// recoverInterrupted fails runs left running by a previous process.
+func (s *Scheduler) recoverInterrupted(ctx context.Context) error {
+	tag, err := s.pool.Exec(ctx, `
+		UPDATE scheduler_runs SET status = 'failed', error = 'interrupted by shutdown', finished_at = NOW()
+		WHERE status = 'running'
+	`)
+	if err != nil {
+		return err
+	}
+	if n := tag.RowsAffected(); n > 0 {
+		s.logger.Warn().Int64("runs", n).Msg("marked interrupted task runs as failed")
+	}
+	return nil
+}
+
+func (s *Scheduler) lastRun(ctx context.Context, name string) (*Run, error) {
+	var r Run
+	err := s.pool.QueryRow(ctx, `
+		SELECT id, task, trigger, status, error, started_at, finished_at
+		FROM scheduler_runs WHERE task = $1
+		ORDER BY started_at DESC
+		LIMIT 1
+	`, name).Scan(&r.ID, &r.Task, &r.Trigger, &r.Status, &r.Error, &r.StartedAt, &r.FinishedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// Tasks returns the registered tasks in registration order with their most
+// recent runs.
+func (s *Scheduler) Tasks(ctx context.Context) ([]*TaskStatus, error) {
+	statuses := make([]*TaskStatus, 0, len(s.order))
+	for _, name := range s.order {
+		last, err := s.lastRun(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+
+		s.mu.Lock()
+		t := s.tasks[name]
+		status := &TaskStatus{
+			Name:     t.name,
+			Interval: t.interval,
+			Running:  t.running,
+			LastRun:  last,
+		}
+		if t.interval > 0 && !t.nextRun.IsZero() {
+			next := t.nextRun
+			status.NextRun = &next
+		}
+		s.mu.Unlock()
+
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Runs returns the most recent runs, optionally filtered by task.
+func (s *Scheduler) Runs(ctx context.Context, name string, limit int) ([]*Run, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, task, trigger, status, error, started_at, finished_at
+		FROM scheduler_runs
+		WHERE $1 = '' OR task = $1
+		ORDER BY started_at DESC
+		LIMIT $2
+	`, name, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []*Run
+	for rows.Next() {
+		var r Run
+		if err := rows.Scan(&r.ID, &r.Task, &r.Trigger, &r.Status, &r.Error, &r.StartedAt, &r.FinishedAt); err != nil {
+			return nil, err
+		}
+		runs = append(runs, &r)
+	}
+	return runs, nil
+}
@@ -4,44 +4,130 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"net/http"
 	"strings"
 	"time"
 
+	"github.com/harshpatel5940/gitvigil/internal/analysis"
+	"github.com/harshpatel5940/gitvigil/internal/config"
 	"github.com/harshpatel5940/gitvigil/internal/database"
+	"github.com/harshpatel5940/gitvigil/internal/detection"
 	"github.com/harshpatel5940/gitvigil/internal/models"
 	"github.com/rs/zerolog"
 )
 
 type Handler struct {
+	cfg    *config.Config
 	db     *database.DB
 	logger zerolog.Logger
 }
 
-func NewHandler(db *database.DB, logger zerolog.Logger) *Handler {
+func NewHandler(cfg *config.Config, db *database.DB, logger zerolog.Logger) *Handler {
 	return &Handler{
+		cfg:    cfg,
 		db:     db,
 		logger: logger.With().Str("component", "scorecard").Logger(),
 	}
 }
 
 type Scorecard struct {
-	Repository      RepositoryInfo     `json:"repository"`
-	OverallScore    int                `json:"overall_score"`
-	OverallStatus   string             `json:"overall_status"`
-	Checks          []CheckResult      `json:"checks"`
-	Alerts          []AlertSummary     `json:"alerts"`
-	Contributors    []ContributorStats `json:"contributors"`
-	ActivitySummary ActivitySummary    `json:"activity_summary"`
-	GeneratedAt     time.Time          `json:"generated_at"`
+	Repository          RepositoryInfo                      `json:"repository"`
+	Event               *EventInfo                          `json:"event,omitempty"`
+	Team                *TeamReport                         `json:"team,omitempty"`
+	Submission          *SubmissionReport                   `json:"submission,omitempty"`
+	PreEvent            *PreEventReport                     `json:"pre_event,omitempty"`
+	OverallScore        int                                 `json:"overall_score"`
+	OverallStatus       string                              `json:"overall_status"`
+	Checks              []CheckResult                       `json:"checks"`
+	Alerts              []AlertSummary                      `json:"alerts"`
+	ForcePushes         []ForcePushReport                   `json:"force_pushes"`
+	Contributors        []ContributorStats                  `json:"contributors"`
+	ContributorPatterns []analysis.ContributorVolumePattern `json:"contributor_patterns"`
+	Volume              *analysis.VolumeAnalysis            `json:"volume"`
+	ActivitySummary     ActivitySummary                     `json:"activity_summary"`
+	GeneratedAt         time.Time                           `json:"generated_at"`
+}
+
+// EventInfo is the hackathon the scorecard was evaluated against.
+type EventInfo struct {
+	ID                 int64     `json:"id"`
+	Name               string    `json:"name"`
+	Timezone           string    `json:"timezone"`
+	OnSite             bool      `json:"on_site"`
+	StartsAt           time.Time `json:"starts_at"`
+	EndsAt             time.Time `json:"ends_at"`
+	SubmissionDeadline time.Time `json:"submission_deadline"`
+}
+
+// TeamReport measures the repository's activity against its team roster.
+type TeamReport struct {
+	ID                       int64                          `json:"id"`
+	Name                     string                         `json:"name"`
+	Members                  []MemberReport                 `json:"members"`
+	Distribution             *analysis.DistributionAnalysis `json:"distribution"`
+	UnregisteredContributors []string                       `json:"unregistered_contributors"`
+}
+
+// SubmissionReport is the repository's frozen submission for its event.
+// FrozenAt is nil until the submission deadline plus grace period passes.
+type SubmissionReport struct {
+	FrozenAt           *time.Time        `json:"frozen_at"`
+	Branches           []SubmittedBranch `json:"branches"`
+	PostDeadlinePushes int               `json:"post_deadline_pushes"`
+}
+
+// PreEventReport is how much of the repository predates its event.
+type PreEventReport struct {
+	RepositoryCreatedAt *time.Time           `json:"repository_created_at"`
+	CreatedBeforeEvent  bool                 `json:"created_before_event"`
+	PreEventCommits     int                  `json:"pre_event_commits"`
+	ExemptCommits       int                  `json:"exempt_commits"`
+	TotalCommits        int                  `json:"total_commits"`
+	CommitPercent       float64              `json:"commit_percent"`
+	PreEventAdditions   int64                `json:"pre_event_additions"`
+	TotalAdditions      int64                `json:"total_additions"`
+	LinePercent         float64              `json:"line_percent"`
+	EarliestAuthorDate  *time.Time           `json:"earliest_author_date,omitempty"`
+	StarterTemplate     *StarterTemplateInfo `json:"starter_template,omitempty"`
+}
+
+type StarterTemplateInfo struct {
+	Source   string  `json:"source"`
+	BaseSHA  *string `json:"base_sha"`
+	Approved bool    `json:"approved"`
+}
+
+type SubmittedBranch struct {
+	Ref      string     `json:"ref"`
+	HeadSHA  string     `json:"head_sha"`
+	PushedAt *time.Time `json:"pushed_at,omitempty"`
+	CutoffAt time.Time  `json:"cutoff_at"`
+}
+
+type MemberReport struct {
+	Login     string `json:"login"`
+	Commits   int    `json:"commits"`
+	Additions int64  `json:"additions"`
+	Deletions int64  `json:"deletions"`
 }
 
 type RepositoryInfo struct {
-	Owner      string `json:"owner"`
-	Name       string `json:"name"`
-	FullName   string `json:"full_name"`
-	HasLicense bool   `json:"has_license"`
-	LicenseID  string `json:"license_spdx_id,omitempty"`
+	Owner      string      `json:"owner"`
+	Name       string      `json:"name"`
+	FullName   string      `json:"full_name"`
+	HasLicense bool        `json:"has_license"`
+	LicenseID  string      `json:"license_spdx_id,omitempty"`
+	Status     string      `json:"status"`
+	Origin     *OriginInfo `json:"origin,omitempty"`
+}
+
+// OriginInfo is the upstream a repository was forked or generated from.
+type OriginInfo struct {
+	IsFork     bool    `json:"is_fork"`
+	ForkParent *string `json:"fork_parent,omitempty"`
+	ForkSource *string `json:"fork_source,omitempty"`
+	Template   *string `json:"template,omitempty"`
 }
 
 type CheckResult struct {
@@ -58,6 +144,20 @@ type AlertSummary struct {
 	LatestAt time.Time `json:"latest_at,omitempty"`
 }
 
+type ForcePushReport struct {
+	Ref                string    `json:"ref"`
+	Before             string    `json:"before"`
+	After              string    `json:"after"`
+	Pusher             string    `json:"pusher"`
+	PushedAt           time.Time `json:"pushed_at"`
+	Forensics          string    `json:"forensics"`
+	DroppedCommits     int       `json:"dropped_commits"`
+	ReplacementCommits int       `json:"replacement_commits"`
+	RewrittenCommits   int       `json:"rewritten_commits"`
+	AuthorsChanged     int       `json:"authors_changed"`
+	AuthorDatesChanged int       `json:"author_dates_changed"`
+}
+
 type ContributorStats struct {
 	Login               string  `json:"login"`
 	TotalCommits        int     `json:"total_commits"`
@@ -65,14 +165,22 @@ type ContributorStats struct {
 	Deletions           int64   `json:"deletions"`
 	CommitFrequency     float64 `json:"commit_frequency"`
 	ContributionPattern string  `json:"contribution_pattern"`
+	// TypicalUTCOffset is the offset most of the contributor's pushed
+	// commits were authored with, and UTCOffsets how many they used.
+	TypicalUTCOffset string `json:"typical_utc_offset,omitempty"`
+	UTCOffsets       int    `json:"utc_offsets,omitempty"`
 }
 
 type ActivitySummary struct {
 	TotalCommits      int       `json:"total_commits"`
 	LastActivityAt    time.Time `json:"last_activity_at"`
 	StreakStatus      string    `json:"streak_status"`
+	StreakDays        int       `json:"streak_days"`
+	LongestStreakDays int       `json:"longest_streak_days"`
 	DaysSinceActivity int       `json:"days_since_activity"`
 	ForcePushCount    int       `json:"force_push_count"`
+	DroppedCommits    int       `json:"dropped_commits"`
+	RewrittenDates    int       `json:"rewritten_author_dates"`
 	BackdateCount     int       `json:"backdate_count"`
 }
 
@@ -122,9 +230,30 @@ func (h *Handler) buildScorecard(ctx context.Context, repo *models.Repository) (
 	commitStore := models.NewCommitStore(h.db.Pool)
 	alertStore := models.NewAlertStore(h.db.Pool)
 	contributorStore := models.NewContributorStore(h.db.Pool)
+	forcePushStore := models.NewForcePushStore(h.db.Pool)
+	eventStore := models.NewEventStore(h.db.Pool)
+	teamStore := models.NewTeamStore(h.db.Pool)
+	submissionStore := models.NewSubmissionStore(h.db.Pool)
+
+	// Get the event window the repository is judged against
+	event, err := eventStore.GetForRepository(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	from, to := models.EventWindow(event)
 
 	// Get commit stats
-	commitStats, err := commitStore.GetStats(ctx, repo.ID)
+	commitStats, err := commitStore.GetStatsBetween(ctx, repo.ID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get daily activity for volume analysis
+	timezone := "UTC"
+	if event != nil {
+		timezone = event.Timezone
+	}
+	contributorDays, err := commitStore.ListContributorDays(ctx, repo.ID, timezone, from, to)
 	if err != nil {
 		return nil, err
 	}
@@ -140,9 +269,62 @@ func (h *Handler) buildScorecard(ctx context.Context, repo *models.Repository) (
 	if err != nil {
 		return nil, err
 	}
+	offsetProfiles, err := commitStore.ListOffsetProfiles(ctx, repo.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get force push forensics
+	forcePushes, err := forcePushStore.ListByRepository(ctx, repo.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get the team roster and each member's activity
+	team, err := teamStore.GetForRepository(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	var teamReport *TeamReport
+	if team != nil {
+		memberStats, err := teamStore.MemberStats(ctx, repo.ID, from, to)
+		if err != nil {
+			return nil, err
+		}
+		teamReport = h.buildTeamReport(team, memberStats, contributors)
+	}
+
+	// Get the frozen submission
+	var submissionReport *SubmissionReport
+	if event != nil {
+		submissions, err := submissionStore.ListByRepository(ctx, event.ID, repo.ID)
+		if err != nil {
+			return nil, err
+		}
+		submissionReport = h.buildSubmissionReport(repo, submissions, typeCounts)
+	}
+
+	// Measure code written before the event
+	preEvent, err := detection.MeasurePreEventCode(ctx, h.db, repo, event)
+	if err != nil {
+		return nil, err
+	}
+	var preEventReport *PreEventReport
+	if preEvent != nil {
+		preEventReport = h.buildPreEventReport(repo, preEvent)
+	}
 
 	// Build checks
-	checks := h.buildChecks(repo, commitStats, typeCounts)
+	checks := h.buildChecks(repo, commitStats, typeCounts, forcePushes)
+	if teamReport != nil {
+		checks = append(checks, h.buildTeamCheck(teamReport))
+	}
+	if submissionReport != nil {
+		checks = append(checks, h.buildSubmissionCheck(submissionReport))
+	}
+	if preEventReport != nil {
+		checks = append(checks, h.buildPreEventCheck(preEventReport))
+	}
 
 	// Calculate overall score
 	overallScore := h.calculateOverallScore(checks)
@@ -151,15 +333,25 @@ func (h *Handler) buildScorecard(ctx context.Context, repo *models.Repository) (
 	// Build alert summaries
 	alertSummaries := h.buildAlertSummaries(typeCounts)
 
+	// Build force push reports
+	forcePushReports, droppedCommits, rewrittenDates := h.buildForcePushReports(forcePushes)
+
 	// Build contributor stats
-	contributorStats := h.buildContributorStats(contributors, commitStats.TotalCommits)
+	contributorStats := h.buildContributorStats(contributors, offsetProfiles, commitStats.TotalCommits)
 
-	// Build activity summary
+	// Analyse volume over the event window
+	volume, contributorPatterns := h.analyzeVolume(contributorDays, event)
+
+	// Build activity summary; inactivity stops counting once the event ends
+	now := time.Now()
+	if event != nil {
+		now = event.Clamp(now)
+	}
 	daysSinceActivity := 0
 	var lastActivityAt time.Time
 	if repo.LastActivityAt != nil {
 		lastActivityAt = *repo.LastActivityAt
-		daysSinceActivity = int(time.Since(lastActivityAt).Hours() / 24)
+		daysSinceActivity = max(0, int(now.Sub(lastActivityAt).Hours()/24))
 	}
 
 	forcePushCount := typeCounts[models.AlertForcePush]
@@ -170,32 +362,58 @@ func (h *Handler) buildScorecard(ctx context.Context, repo *models.Repository) (
 		licenseID = *repo.LicenseSPDXID
 	}
 
+	var eventInfo *EventInfo
+	if event != nil {
+		eventInfo = &EventInfo{
+			ID:                 event.ID,
+			Name:               event.Name,
+			Timezone:           event.Timezone,
+			OnSite:             event.OnSite,
+			StartsAt:           event.StartsAt,
+			EndsAt:             event.EndsAt,
+			SubmissionDeadline: event.Deadline(),
+		}
+	}
+
 	return &Scorecard{
+		Event:      eventInfo,
+		Team:       teamReport,
+		Submission: submissionReport,
+		PreEvent:   preEventReport,
 		Repository: RepositoryInfo{
 			Owner:      repo.Owner,
 			Name:       repo.Name,
 			FullName:   repo.FullName,
 			HasLicense: repo.HasLicense,
 			LicenseID:  licenseID,
+			Status:     string(repo.Status),
+			Origin:     originInfo(&repo.Origin),
 		},
-		OverallScore:  overallScore,
-		OverallStatus: overallStatus,
-		Checks:        checks,
-		Alerts:        alertSummaries,
-		Contributors:  contributorStats,
+		OverallScore:        overallScore,
+		OverallStatus:       overallStatus,
+		Checks:              checks,
+		Alerts:              alertSummaries,
+		ForcePushes:         forcePushReports,
+		Contributors:        contributorStats,
+		ContributorPatterns: contributorPatterns,
+		Volume:              volume,
 		ActivitySummary: ActivitySummary{
 			TotalCommits:      commitStats.TotalCommits,
 			LastActivityAt:    lastActivityAt,
-			StreakStatus:      repo.StreakStatus,
+			StreakStatus:      string(repo.StreakStatus),
+			StreakDays:        repo.CurrentStreakDays(),
+			LongestStreakDays: max(repo.LongestStreakDays, repo.CurrentStreakDays()),
 			DaysSinceActivity: daysSinceActivity,
 			ForcePushCount:    forcePushCount,
+			DroppedCommits:    droppedCommits,
+			RewrittenDates:    rewrittenDates,
 			BackdateCount:     backdateCount,
 		},
 		GeneratedAt: time.Now(),
 	}, nil
 }
 
-func (h *Handler) buildChecks(repo *models.Repository, commitStats *models.CommitStats, alertCounts map[models.AlertType]int) []CheckResult {
+func (h *Handler) buildChecks(repo *models.Repository, commitStats *models.CommitStats, alertCounts map[models.AlertType]int, forcePushes []*models.ForcePushSummary) []CheckResult {
 	var checks []CheckResult
 
 	// License check
@@ -219,7 +437,8 @@ func (h *Handler) buildChecks(repo *models.Repository, commitStats *models.Commi
 	})
 
 	// Backdate check
-	backdateCount := alertCounts[models.AlertBackdateSuspicious] + alertCounts[models.AlertBackdateCritical]
+	// Future-dated commits are misdated the other way
+	backdateCount := alertCounts[models.AlertBackdateSuspicious] + alertCounts[models.AlertBackdateCritical] + alertCounts[models.AlertFutureDated]
 	backdateScore := 100
 	backdateStatus := "pass"
 	backdateDesc := "No backdated commits detected"
@@ -252,6 +471,16 @@ func (h *Handler) buildChecks(repo *models.Repository, commitStats *models.Commi
 			forcePushStatus = "warn"
 		}
 		forcePushDesc = pluralize(forcePushCount, "force push", "force pushes") + " detected"
+
+		rewrittenDates := 0
+		for _, fp := range forcePushes {
+			rewrittenDates += fp.DateChangedCount
+		}
+		if rewrittenDates > 0 {
+			forcePushScore = 0
+			forcePushStatus = "fail"
+			forcePushDesc += ", rewriting author dates of " + pluralize(rewrittenDates, "commit", "commits")
+		}
 	}
 	checks = append(checks, CheckResult{
 		Name:        "No Force Pushes",
@@ -260,18 +489,60 @@ func (h *Handler) buildChecks(repo *models.Repository, commitStats *models.Commi
 		Description: forcePushDesc,
 	})
 
+	// Shared history check, one alert per other repository sharing commits
+	if repo.EventID != nil {
+		sharedCheck := CheckResult{
+			Name:        "Original History",
+			Status:      "pass",
+			Score:       100,
+			Description: "No commits shared with other enrolled repositories",
+		}
+		if shared := alertCounts[models.AlertSharedHistory]; shared > 0 {
+			sharedCheck.Status = "fail"
+			sharedCheck.Score = 0
+			sharedCheck.Description = "Commits shared with " + pluralize(shared, "other enrolled repository", "other enrolled repositories")
+		}
+		checks = append(checks, sharedCheck)
+	}
+
+	// Origin check, against forks and templates from outside the event
+	if repo.EventID != nil && repo.Origin.CheckedAt != nil {
+		originCheck := CheckResult{
+			Name:        "Independent Origin",
+			Status:      "pass",
+			Score:       100,
+			Description: "Repository was not forked or generated from outside the event",
+		}
+		if external := alertCounts[models.AlertExternalOrigin]; external > 0 {
+			originCheck.Status = "fail"
+			originCheck.Score = 0
+			originCheck.Description = "Repository was forked or generated from outside the event"
+			if !repo.Origin.IsFork {
+				originCheck.Status = "warn"
+				originCheck.Score = 50
+				originCheck.Description = "Repository was generated from a template outside the event"
+			}
+		}
+		checks = append(checks, originCheck)
+	}
+
 	// Streak check
 	streakScore := 100
 	streakStatus := "pass"
 	streakDesc := "Repository has consistent activity"
-	if repo.StreakStatus == "at_risk" {
+	switch repo.StreakStatus {
+	case models.StreakAtRisk:
 		streakScore = 50
 		streakStatus = "warn"
 		streakDesc = "Repository activity streak is at risk"
-	} else if repo.StreakStatus == "inactive" {
+	case models.StreakInactive:
 		streakScore = 0
 		streakStatus = "fail"
 		streakDesc = "Repository has been inactive"
+	case models.StreakRecovered:
+		streakScore = 75
+		streakStatus = "warn"
+		streakDesc = "Repository activity recovered after a gap"
 	}
 	checks = append(checks, CheckResult{
 		Name:        "Activity Streak",
@@ -335,11 +606,26 @@ func (h *Handler) buildAlertSummaries(typeCounts map[models.AlertType]int) []Ale
 	var summaries []AlertSummary
 
 	severityMap := map[models.AlertType]string{
-		models.AlertBackdateSuspicious: "warning",
-		models.AlertBackdateCritical:   "critical",
-		models.AlertForcePush:          "warning",
-		models.AlertNoLicense:          "info",
-		models.AlertStreakAtRisk:       "warning",
+		models.AlertBackdateSuspicious:      "warning",
+		models.AlertBackdateCritical:        "critical",
+		models.AlertForcePush:               "warning",
+		models.AlertNoLicense:               "info",
+		models.AlertStreakAtRisk:            "warning",
+		models.AlertStreakInactive:          "critical",
+		models.AlertStreakRecovered:         "info",
+		models.AlertUnregisteredContributor: "warning",
+		models.AlertPostDeadlinePush:        "critical",
+		models.AlertPreEventCode:            "warning",
+		models.AlertSharedHistory:           "critical",
+		models.AlertExternalOrigin:          "warning",
+		models.AlertCodeSimilarity:          "critical",
+		models.AlertFutureDated:             "warning",
+		models.AlertRewrittenCommits:        "info",
+		models.AlertBulkDateRewrite:         "warning",
+		models.AlertCustomRule:              "warning",
+		models.AlertCodeDump:                "warning",
+		models.AlertScriptedHistory:         "warning",
+		models.AlertTimezoneAnomaly:         "warning",
 	}
 
 	for alertType, count := range typeCounts {
@@ -355,7 +641,256 @@ func (h *Handler) buildAlertSummaries(typeCounts map[models.AlertType]int) []Ale
 	return summaries
 }
 
-func (h *Handler) buildContributorStats(contributors []*models.Contributor, totalCommits int) []ContributorStats {
+func (h *Handler) buildForcePushReports(forcePushes []*models.ForcePushSummary) ([]ForcePushReport, int, int) {
+	reports := make([]ForcePushReport, 0, len(forcePushes))
+	dropped, rewrittenDates := 0, 0
+
+	for _, fp := range forcePushes {
+		forensics := ""
+		if fp.ForensicsStatus != nil {
+			forensics = string(*fp.ForensicsStatus)
+		}
+
+		reports = append(reports, ForcePushReport{
+			Ref:                fp.Ref,
+			Before:             fp.BeforeSHA,
+			After:              fp.AfterSHA,
+			Pusher:             fp.PusherLogin,
+			PushedAt:           fp.ReceivedAt,
+			Forensics:          forensics,
+			DroppedCommits:     fp.DroppedCount,
+			ReplacementCommits: fp.ReplacementCount,
+			RewrittenCommits:   fp.RewrittenCount,
+			AuthorsChanged:     fp.AuthorChangedCount,
+			AuthorDatesChanged: fp.DateChangedCount,
+		})
+		dropped += fp.DroppedCount
+		rewrittenDates += fp.DateChangedCount
+	}
+
+	return reports, dropped, rewrittenDates
+}
+
+// analyzeVolume runs volume analysis over the repository's daily activity,
+// bounded by the event window when the repository is enrolled in one.
+func (h *Handler) analyzeVolume(days []*models.ContributorDay, event *models.Event) (*analysis.VolumeAnalysis, []analysis.ContributorVolumePattern) {
+	var start, end time.Time
+	if event != nil {
+		loc := event.Location()
+		start, end = event.StartsAt.In(loc), event.EndsAt.In(loc)
+	}
+
+	var order []string
+	totals := make(map[string]*analysis.DailyActivity)
+	byContributor := make(map[string][]analysis.DailyActivity)
+	for _, d := range days {
+		key := d.Date.Format("2006-01-02")
+		total, ok := totals[key]
+		if !ok {
+			total = &analysis.DailyActivity{Date: d.Date}
+			totals[key] = total
+			order = append(order, key)
+		}
+		total.Commits += d.Commits
+		total.Additions += d.Additions
+		total.Deletions += d.Deletions
+
+		byContributor[d.Contributor] = append(byContributor[d.Contributor], analysis.DailyActivity{
+			Date:      d.Date,
+			Commits:   d.Commits,
+			Additions: d.Additions,
+			Deletions: d.Deletions,
+		})
+	}
+
+	activities := make([]analysis.DailyActivity, 0, len(order))
+	for _, key := range order {
+		activities = append(activities, *totals[key])
+	}
+
+	patterns := analysis.AnalyzeContributorPatterns(byContributor, start, end)
+	if patterns == nil {
+		patterns = []analysis.ContributorVolumePattern{}
+	}
+	return analysis.AnalyzeVolume(activities, start, end), patterns
+}
+
+// buildTeamReport computes per-member stats and the contribution distribution
+// across the whole roster, so members who never committed count against it.
+func (h *Handler) buildTeamReport(team *models.Team, stats []*models.MemberStats, contributors []*models.Contributor) *TeamReport {
+	report := &TeamReport{
+		ID:                       team.ID,
+		Name:                     team.Name,
+		Members:                  make([]MemberReport, 0, len(stats)),
+		UnregisteredContributors: []string{},
+	}
+
+	data := make([]analysis.ContributorData, 0, len(stats))
+	for _, s := range stats {
+		report.Members = append(report.Members, MemberReport{
+			Login:     s.Member.GitHubLogin,
+			Commits:   s.Commits,
+			Additions: s.Additions,
+			Deletions: s.Deletions,
+		})
+		data = append(data, analysis.ContributorData{
+			Login:     s.Member.GitHubLogin,
+			Commits:   s.Commits,
+			Additions: s.Additions,
+			Deletions: s.Deletions,
+		})
+	}
+	report.Distribution = analysis.AnalyzeDistribution(data)
+
+	for _, c := range contributors {
+		if c.TeamMemberID != nil {
+			continue
+		}
+		identity := c.Email
+		if c.GitHubLogin != nil {
+			identity = *c.GitHubLogin
+		}
+		report.UnregisteredContributors = append(report.UnregisteredContributors, identity)
+	}
+
+	return report
+}
+
+// buildTeamCheck scores the share of roster members who contributed.
+func (h *Handler) buildTeamCheck(report *TeamReport) CheckResult {
+	active := 0
+	for _, m := range report.Members {
+		if m.Commits > 0 {
+			active++
+		}
+	}
+
+	check := CheckResult{
+		Name:        "Team Participation",
+		Status:      "fail",
+		Description: "Team roster has no members",
+	}
+	if len(report.Members) == 0 {
+		return check
+	}
+
+	check.Score = active * 100 / len(report.Members)
+	switch {
+	case check.Score == 100:
+		check.Status = "pass"
+	case check.Score >= 50:
+		check.Status = "warn"
+	}
+	check.Description = fmt.Sprintf("%d of %s contributed", active, pluralize(len(report.Members), "team member", "team members"))
+	return check
+}
+
+// originInfo reports the repository's upstream, or nil when it has none or
+// its origin has not been checked yet.
+func originInfo(o *models.RepositoryOrigin) *OriginInfo {
+	if o.CheckedAt == nil || (!o.IsFork && o.Template == nil) {
+		return nil
+	}
+	return &OriginInfo{
+		IsFork:     o.IsFork,
+		ForkParent: o.Parent,
+		ForkSource: o.Source,
+		Template:   o.Template,
+	}
+}
+
+func (h *Handler) buildSubmissionReport(repo *models.Repository, submissions []*models.Submission, typeCounts map[models.AlertType]int) *SubmissionReport {
+	report := &SubmissionReport{
+		FrozenAt:           repo.SubmissionFrozenAt,
+		Branches:           make([]SubmittedBranch, 0, len(submissions)),
+		PostDeadlinePushes: typeCounts[models.AlertPostDeadlinePush],
+	}
+	for _, sub := range submissions {
+		report.Branches = append(report.Branches, SubmittedBranch{
+			Ref:      sub.Ref,
+			HeadSHA:  sub.HeadSHA,
+			PushedAt: sub.PushedAt,
+			CutoffAt: sub.CutoffAt,
+		})
+	}
+	return report
+}
+
+// buildSubmissionCheck fails repositories that kept pushing after the
+// submission deadline.
+func (h *Handler) buildSubmissionCheck(report *SubmissionReport) CheckResult {
+	check := CheckResult{
+		Name:        "Submitted Before Deadline",
+		Status:      "pass",
+		Score:       100,
+		Description: "No pushes after the submission deadline",
+	}
+	if report.PostDeadlinePushes > 0 {
+		check.Status = "fail"
+		check.Score = 0
+		check.Description = fmt.Sprintf("%s after the submission deadline", pluralize(report.PostDeadlinePushes, "push", "pushes"))
+	}
+	return check
+}
+
+func (h *Handler) buildPreEventReport(repo *models.Repository, m *detection.PreEventReport) *PreEventReport {
+	report := &PreEventReport{
+		RepositoryCreatedAt: repo.GitHubCreatedAt,
+		CreatedBeforeEvent:  m.CreatedBeforeEvent,
+		PreEventCommits:     m.Stats.PreEventCommits,
+		ExemptCommits:       m.Stats.ExemptCommits,
+		TotalCommits:        m.Stats.TotalCommits,
+		CommitPercent:       math.Round(m.Stats.CommitPercent()*10) / 10,
+		PreEventAdditions:   m.Stats.PreEventAdditions,
+		TotalAdditions:      m.Stats.TotalAdditions,
+		LinePercent:         math.Round(m.Stats.LinePercent()*10) / 10,
+		EarliestAuthorDate:  m.Stats.EarliestAuthorAt,
+	}
+	if m.Template != nil {
+		report.StarterTemplate = &StarterTemplateInfo{
+			Source:   m.Template.Source,
+			BaseSHA:  m.Template.BaseSHA,
+			Approved: m.Template.Approved(),
+		}
+	}
+	return report
+}
+
+// buildPreEventCheck scores the share of history written during the event,
+// failing once pre-event commits or lines reach the critical percentage.
+func (h *Handler) buildPreEventCheck(report *PreEventReport) CheckResult {
+	check := CheckResult{
+		Name:        "Built During Event",
+		Status:      "pass",
+		Score:       100,
+		Description: "All commits were authored during the event",
+	}
+	if report.ExemptCommits > 0 {
+		check.Description = fmt.Sprintf("All other commits were authored during the event; %s from the approved starter template", pluralize(report.ExemptCommits, "commit", "commits"))
+	}
+	if report.PreEventCommits == 0 && !report.CreatedBeforeEvent {
+		return check
+	}
+
+	worst := max(int(report.CommitPercent), int(report.LinePercent))
+	check.Score = 100 - worst
+	check.Status = "warn"
+	if worst >= h.cfg.PreEventCriticalPercent {
+		check.Status = "fail"
+	}
+
+	var parts []string
+	if report.PreEventCommits > 0 {
+		parts = append(parts, fmt.Sprintf("%s (%.0f%%) authored before the event", pluralize(report.PreEventCommits, "commit", "commits"), report.CommitPercent))
+	}
+	if report.CreatedBeforeEvent {
+		parts = append(parts, "repository created before the event")
+	}
+	check.Description = strings.Join(parts, "; ")
+	return check
+}
+
+func (h *Handler) buildContributorStats(contributors []*models.Contributor, offsetProfiles map[string]*models.OffsetProfile, totalCommits int) []ContributorStats {
 	var stats []ContributorStats
 
 	for _, c := range contributors {
@@ -388,14 +923,19 @@ func (h *Handler) buildContributorStats(contributors []*models.Contributor, tota
 			}
 		}
 
-		stats = append(stats, ContributorStats{
+		contributor := ContributorStats{
 			Login:               login,
 			TotalCommits:        c.TotalCommits,
 			Additions:           c.TotalAdditions,
 			Deletions:           c.TotalDeletions,
 			CommitFrequency:     frequency,
 			ContributionPattern: pattern,
-		})
+		}
+		if p := offsetProfiles[strings.ToLower(c.Email)]; p != nil {
+			contributor.TypicalUTCOffset = models.FormatUTCOffset(p.Typical)
+			contributor.UTCOffsets = len(p.Offsets)
+		}
+		stats = append(stats, contributor)
 	}
 
 	return stats
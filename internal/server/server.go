@@ -2,7 +2,9 @@ package server
 
 import (
 	"context"
+	"crypto/subtle"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -11,27 +13,34 @@ import (
 	"github.com/harshpatel5940/gitvigil/internal/auth"
 	"github.com/harshpatel5940/gitvigil/internal/config"
 	"github.com/harshpatel5940/gitvigil/internal/database"
+	"github.com/harshpatel5940/gitvigil/internal/detection"
 	"github.com/harshpatel5940/gitvigil/internal/github"
+	"github.com/harshpatel5940/gitvigil/internal/queue"
+	"github.com/harshpatel5940/gitvigil/internal/scheduler"
 	"github.com/harshpatel5940/gitvigil/internal/scorecard"
 	"github.com/harshpatel5940/gitvigil/internal/webhook"
 	"github.com/rs/zerolog"
 )
 
 type Server struct {
-	cfg    *config.Config
-	db     *database.DB
-	gh     *github.AppClient
-	router *chi.Mux
-	logger zerolog.Logger
+	cfg       *config.Config
+	db        *database.DB
+	gh        *github.AppClient
+	queue     *queue.Queue
+	scheduler *scheduler.Scheduler
+	router    *chi.Mux
+	logger    zerolog.Logger
 }
 
 func New(cfg *config.Config, db *database.DB, gh *github.AppClient, logger zerolog.Logger) *Server {
 	s := &Server{
-		cfg:    cfg,
-		db:     db,
-		gh:     gh,
-		router: chi.NewRouter(),
-		logger: logger,
+		cfg:       cfg,
+		db:        db,
+		gh:        gh,
+		queue:     NewQueue(cfg, db, logger),
+		scheduler: NewScheduler(cfg, db, gh, logger),
+		router:    chi.NewRouter(),
+		logger:    logger,
 	}
 
 	s.setupMiddleware()
@@ -40,6 +49,41 @@ func New(cfg *config.Config, db *database.DB, gh *github.AppClient, logger zerol
 	return s
 }
 
+// NewQueue creates the background job queue from configuration.
+func NewQueue(cfg *config.Config, db *database.DB, logger zerolog.Logger) *queue.Queue {
+	return queue.New(db.Pool, queue.Options{
+		Workers:     cfg.QueueWorkers,
+		MaxAttempts: cfg.QueueMaxAttempts,
+		RetryBase:   time.Duration(cfg.QueueRetryBaseSeconds) * time.Second,
+	}, logger)
+}
+
+// NewScheduler creates the scheduler with the detector's periodic checks.
+func NewScheduler(cfg *config.Config, db *database.DB, gh *github.AppClient, logger zerolog.Logger) *scheduler.Scheduler {
+	detector := detection.NewDetector(cfg, db, gh, logger)
+
+	sched := scheduler.New(db.Pool, logger)
+	engine := detector.Rules()
+	sched.Register("streak_check", time.Duration(cfg.StreakCheckIntervalMinutes)*time.Minute, engine.Task(detection.RuleStreak))
+	sched.Register("roster_check", time.Duration(cfg.RosterCheckIntervalMinutes)*time.Minute, engine.Task(detection.RuleUnregisteredContributor))
+	sched.Register("submission_freeze", time.Duration(cfg.SubmissionCheckIntervalMinutes)*time.Minute, detector.FreezeSubmissions)
+	sched.Register("pre_event_check", time.Duration(cfg.PreEventCheckIntervalMinutes)*time.Minute, engine.Task(detection.RulePreEventCode))
+	sched.Register("shared_history_check", time.Duration(cfg.SharedHistoryCheckIntervalMinutes)*time.Minute, engine.Task(detection.RuleSharedHistory))
+	sched.Register("rewrite_check", time.Duration(cfg.RewriteCheckIntervalMinutes)*time.Minute, engine.Task(detection.RuleRewrittenHistory))
+	sched.Register("scripted_check", time.Duration(cfg.ScriptedCheckIntervalMinutes)*time.Minute, engine.Task(detection.RuleScriptedHistory))
+	sched.Register("timezone_check", time.Duration(cfg.TimezoneCheckIntervalMinutes)*time.Minute, engine.Task(detection.RuleTimezoneAnomaly))
+
+	// License, origin and similarity checks need the GitHub API
+	if gh != nil {
+		sched.Register("license_check", time.Duration(cfg.LicenseCheckIntervalMinutes)*time.Minute, engine.Task(detection.RuleLicense))
+		sched.Register("origin_check", time.Duration(cfg.OriginCheckIntervalMinutes)*time.Minute, engine.Task(detection.RuleExternalOrigin))
+		sched.Register("code_similarity", time.Duration(cfg.SimilarityCheckIntervalMinutes)*time.Minute, engine.Task(detection.RuleCodeSimilarity))
+		sched.Register("code_dump_check", time.Duration(cfg.CodeDumpCheckIntervalMinutes)*time.Minute, engine.Task(detection.RuleCodeDump))
+	}
+
+	return sched
+}
+
 func (s *Server) setupMiddleware() {
 	s.router.Use(middleware.RequestID)
 	s.router.Use(middleware.RealIP)
@@ -66,15 +110,55 @@ func (s *Server) loggingMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+func (s *Server) adminAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.cfg.AdminToken == "" {
+			http.Error(w, "admin endpoints disabled (ADMIN_TOKEN not set)", http.StatusServiceUnavailable)
+			return
+		}
+
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.cfg.AdminToken)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
 func (s *Server) setupRoutes() {
 	s.router.Get("/health", s.handleHealth)
 
 	// Webhook endpoint
-	webhookHandler := webhook.NewHandler(s.cfg, s.db, s.gh, s.logger)
+	webhookHandler := webhook.NewHandler(s.cfg, s.db, s.gh, s.queue, s.logger)
+	webhookHandler.RegisterJobs()
 	s.router.Post("/webhook", webhookHandler.ServeHTTP)
 
+	// The API handler also serves organizer actions on the admin router
+	apiHandler := api.NewHandler(s.db, detection.NewDetector(s.cfg, s.db, s.gh, s.logger).Rules(), s.logger)
+
+	// Admin endpoints
+	s.router.Route("/admin", func(r chi.Router) {
+		r.Use(s.adminAuthMiddleware)
+		r.Get("/deliveries", webhookHandler.HandleListDeliveries)
+		r.Post("/deliveries/replay", webhookHandler.HandleReplayRange)
+		r.Post("/deliveries/{deliveryID}/replay", webhookHandler.HandleReplay)
+		r.Get("/jobs", s.queue.HandleList)
+		r.Post("/jobs/{id}/retry", s.queue.HandleRetry)
+		r.Get("/scheduler/tasks", s.scheduler.HandleListTasks)
+		r.Get("/scheduler/runs", s.scheduler.HandleListRuns)
+		r.Post("/scheduler/tasks/{name}/run", s.scheduler.HandleTrigger)
+		r.Post("/repositories/{id}/starter-template/approve", apiHandler.ApproveStarterTemplate)
+		r.Get("/repositories/{id}/rules", apiHandler.GetRepositoryRules)
+		r.Get("/rules", apiHandler.ListRules)
+		r.Get("/rules/{name}/settings", apiHandler.ListRuleSettings)
+		r.Put("/rules/{name}/settings", apiHandler.PutRuleSetting)
+		r.Delete("/rules/{name}/settings", apiHandler.DeleteRuleSetting)
+	})
+
 	// Scorecard endpoint
-	scorecardHandler := scorecard.NewHandler(s.db, s.logger)
+	scorecardHandler := scorecard.NewHandler(s.cfg, s.db, s.logger)
 	s.router.Get("/scorecard", scorecardHandler.ServeHTTP)
 
 	// Auth endpoint
@@ -82,8 +166,7 @@ func (s *Server) setupRoutes() {
 	s.router.Get("/auth/github/callback", authHandler.HandleCallback)
 
 	// API v1 endpoints
-	apiHandler := api.NewHandler(s.db, s.logger)
-	s.router.Mount("/api/v1", apiHandler.Router())
+	s.router.Mount("/api/v1", apiHandler.Router(s.adminAuthMiddleware))
 }
 
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
@@ -113,6 +196,9 @@ func (s *Server) Start(ctx context.Context) error {
 		IdleTimeout:  60 * time.Second,
 	}
 
+	s.queue.Start()
+	s.scheduler.Start()
+
 	s.logger.Info().Str("port", s.cfg.Port).Msg("starting server")
 
 	errCh := make(chan error, 1)
@@ -127,8 +213,27 @@ func (s *Server) Start(ctx context.Context) error {
 		s.logger.Info().Msg("shutting down server")
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
-		return srv.Shutdown(shutdownCtx)
+		err := srv.Shutdown(shutdownCtx)
+
+		// Stop accepting jobs and let in-flight webhook processing finish
+		drainCtx, drainCancel := context.WithTimeout(context.Background(), time.Duration(s.cfg.QueueDrainTimeoutSeconds)*time.Second)
+		defer drainCancel()
+		if stopErr := s.scheduler.Stop(drainCtx); stopErr != nil {
+			s.logger.Error().Err(stopErr).Msg("failed to stop scheduler")
+		}
+		if drainErr := s.queue.Stop(drainCtx); drainErr != nil {
+			s.logger.Error().Err(drainErr).Msg("failed to drain job queue")
+		}
+		return err
 	case err := <-errCh:
+		drainCtx, drainCancel := context.WithTimeout(context.Background(), time.Duration(s.cfg.QueueDrainTimeoutSeconds)*time.Second)
+		defer drainCancel()
+		if stopErr := s.scheduler.Stop(drainCtx); stopErr != nil {
+			s.logger.Error().Err(stopErr).Msg("failed to stop scheduler")
+		}
+		if drainErr := s.queue.Stop(drainCtx); drainErr != nil {
+			s.logger.Error().Err(drainErr).Msg("failed to drain job queue")
+		}
 		return err
 	}
 }
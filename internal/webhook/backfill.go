@@ -0,0 +1,159 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v68/github"
+	ghclient "github.com/harshpatel5940/gitvigil/internal/github"
+	"github.com/harshpatel5940/gitvigil/internal/models"
+	"github.com/harshpatel5940/gitvigil/internal/queue"
+	"github.com/harshpatel5940/gitvigil/internal/rules"
+	"github.com/jackc/pgx/v5"
+)
+
+// JobBackfillRepository is the queue job kind that imports the commit history
+// a repository had before the app was installed on it.
+const JobBackfillRepository = "repository_backfill"
+
+type backfillJob struct {
+	RepositoryID int64 `json:"repository_id"`
+}
+
+// enqueueBackfill schedules a history import for a newly added repository.
+func (h *Handler) enqueueBackfill(ctx context.Context, repoID int64) {
+	if h.gh == nil {
+		return
+	}
+
+	repoStore := models.NewRepositoryStore(h.db.Pool)
+	if err := repoStore.SetBackfillStatus(ctx, repoID, models.BackfillPending, nil); err != nil {
+		h.logger.Error().Err(err).Int64("repository_id", repoID).Msg("failed to mark backfill as pending")
+	}
+	if _, err := h.queue.Enqueue(ctx, JobBackfillRepository, backfillJob{RepositoryID: repoID}); err != nil {
+		h.logger.Error().Err(err).Int64("repository_id", repoID).Msg("failed to enqueue backfill")
+	}
+}
+
+func (h *Handler) handleBackfillJob(ctx context.Context, job *queue.Job) error {
+	var payload backfillJob
+	if err := job.Decode(&payload); err != nil {
+		return err
+	}
+
+	repoStore := models.NewRepositoryStore(h.db.Pool)
+	repo, err := repoStore.GetByID(ctx, payload.RepositoryID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load repository: %w", err)
+	}
+	if repo.Status != models.LifecycleActive {
+		h.logger.Info().Str("repo", repo.FullName).Str("status", string(repo.Status)).Msg("repository no longer active, skipping backfill")
+		return nil
+	}
+
+	if err := repoStore.SetBackfillStatus(ctx, repo.ID, models.BackfillRunning, nil); err != nil {
+		return fmt.Errorf("failed to mark backfill as running: %w", err)
+	}
+
+	imported, err := h.backfill(ctx, &repo.Repository)
+	if err != nil {
+		if delay, ok := ghclient.RateLimitDelay(err); ok {
+			// Commits stored so far are skipped on the next run
+			if markErr := repoStore.SetBackfillStatus(ctx, repo.ID, models.BackfillPending, nil); markErr != nil {
+				h.logger.Error().Err(markErr).Str("repo", repo.FullName).Msg("failed to mark backfill as pending")
+			}
+			return queue.RetryAfter(delay, err)
+		}
+
+		errMsg := err.Error()
+		if markErr := repoStore.SetBackfillStatus(ctx, repo.ID, models.BackfillFailed, &errMsg); markErr != nil {
+			h.logger.Error().Err(markErr).Str("repo", repo.FullName).Msg("failed to mark backfill as failed")
+		}
+		return err
+	}
+
+	if err := repoStore.SetBackfillStatus(ctx, repo.ID, models.BackfillCompleted, nil); err != nil {
+		return fmt.Errorf("failed to mark backfill as completed: %w", err)
+	}
+
+	h.logger.Info().
+		Str("repo", repo.FullName).
+		Int("commits", imported).
+		Msg("repository backfill completed")
+
+	// A newly installed repository's origin and license are checked right
+	// away rather than waiting for the periodic checks; the engine logs each
+	// failing rule itself
+	_ = h.detector.Rules().CheckRepository(ctx, rules.TriggerInstallation, &repo.Repository)
+
+	return nil
+}
+
+// backfill walks the default branch newest first, storing up to
+// BackfillMaxCommits commits, and returns how many were walked.
+func (h *Handler) backfill(ctx context.Context, repo *models.Repository) (int, error) {
+	client, err := h.gh.GetInstallationClient(repo.InstallationID)
+	if err != nil {
+		return 0, err
+	}
+
+	repoStore := models.NewRepositoryStore(h.db.Pool)
+	limit := h.cfg.BackfillMaxCommits
+
+	walked := 0
+	opts := &github.CommitsListOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for walked < limit {
+		page, resp, err := client.Repositories.ListCommits(ctx, repo.Owner, repo.Name, opts)
+		if err != nil {
+			// An empty repository has no history to import. A 404 is a
+			// failure to retry, not an empty history.
+			if isEmptyRepository(err) {
+				return walked, nil
+			}
+			return walked, err
+		}
+
+		if remaining := limit - walked; len(page) > remaining {
+			page = page[:remaining]
+		}
+		commits := make([]*pushCommit, 0, len(page))
+		for _, c := range page {
+			commit := newPushCommitFromAPI(c)
+			commit.Source = commitSourceBackfill
+			commits = append(commits, commit)
+		}
+
+		// Each page is stored atomically, so a retried run resumes cleanly
+		if _, err := h.ingestCommitsTx(ctx, repo.ID, nil, commits, time.Time{}); err != nil {
+			return walked, fmt.Errorf("failed to store commits: %w", err)
+		}
+		walked += len(commits)
+
+		if err := repoStore.SetBackfillProgress(ctx, repo.ID, walked); err != nil {
+			h.logger.Error().Err(err).Str("repo", repo.FullName).Msg("failed to record backfill progress")
+		}
+
+		if resp == nil || resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return walked, nil
+}
+
+// isEmptyRepository reports whether GitHub rejected a commits request because
+// the repository has no commits yet: a 409 "Git Repository is empty".
+func isEmptyRepository(err error) bool {
+	var errResp *github.ErrorResponse
+	return errors.As(err, &errResp) && errResp.Response != nil &&
+		errResp.Response.StatusCode == http.StatusConflict &&
+		strings.Contains(strings.ToLower(errResp.Message), "empty")
+}
@@ -0,0 +1,79 @@
+package webhook
+
+import (
+	"time"
+
+	"github.com/google/go-github/v68/github"
+)
+
+// Commit sources, stored in commits.source.
+const (
+	commitSourcePush     = "push"
+	commitSourceBackfill = "backfill"
+)
+
+// pushCommit is the commit data the ingestion pipeline needs, independent of
+// whether it came from a push payload or from the commits API.
+type pushCommit struct {
+	SHA         string
+	Message     string
+	AuthorName  string
+	AuthorEmail string
+	AuthorLogin string
+	AuthorDate  time.Time
+	// AuthorUTCOffset is the author's UTC offset in minutes. Only push
+	// payloads carry it; the commits API reports dates in UTC.
+	AuthorUTCOffset *int
+	CommitterName   string
+	CommitterEmail  string
+	CommitterLogin  string
+	CommitterDate   time.Time
+	Source          string
+	// DatesVerified is set when both dates come from the API rather than
+	// the payload's single timestamp.
+	DatesVerified bool
+}
+
+// newPushCommit converts a push payload commit. The payload only carries one
+// timestamp, so it is used for both author and committer date until
+// enrichment fetches the real committer date. The timestamp keeps the
+// author's UTC offset, which the API no longer reports.
+func newPushCommit(c *github.HeadCommit) *pushCommit {
+	commit := &pushCommit{
+		SHA:            c.GetID(),
+		Message:        c.GetMessage(),
+		AuthorName:     c.GetAuthor().GetName(),
+		AuthorEmail:    c.GetAuthor().GetEmail(),
+		AuthorLogin:    c.GetAuthor().GetLogin(),
+		AuthorDate:     c.GetTimestamp().Time,
+		CommitterName:  c.GetCommitter().GetName(),
+		CommitterEmail: c.GetCommitter().GetEmail(),
+		CommitterLogin: c.GetCommitter().GetLogin(),
+		CommitterDate:  c.GetTimestamp().Time,
+		Source:         commitSourcePush,
+	}
+	if ts := c.GetTimestamp(); !ts.IsZero() {
+		_, offset := ts.Zone()
+		offset /= 60
+		commit.AuthorUTCOffset = &offset
+	}
+	return commit
+}
+
+// newPushCommitFromAPI converts a commit returned by the commits or compare API.
+func newPushCommitFromAPI(c *github.RepositoryCommit) *pushCommit {
+	return &pushCommit{
+		SHA:            c.GetSHA(),
+		Message:        c.GetCommit().GetMessage(),
+		AuthorName:     c.GetCommit().GetAuthor().GetName(),
+		AuthorEmail:    c.GetCommit().GetAuthor().GetEmail(),
+		AuthorLogin:    c.GetAuthor().GetLogin(),
+		AuthorDate:     c.GetCommit().GetAuthor().GetDate().Time,
+		CommitterName:  c.GetCommit().GetCommitter().GetName(),
+		CommitterEmail: c.GetCommit().GetCommitter().GetEmail(),
+		CommitterLogin: c.GetCommitter().GetLogin(),
+		CommitterDate:  c.GetCommit().GetCommitter().GetDate().Time,
+		Source:         commitSourcePush,
+		DatesVerified:  true,
+	}
+}
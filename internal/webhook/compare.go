@@ -0,0 +1,218 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/go-github/v68/github"
+	ghclient "github.com/harshpatel5940/gitvigil/internal/github"
+	"github.com/harshpatel5940/gitvigil/internal/models"
+	"github.com/harshpatel5940/gitvigil/internal/queue"
+)
+
+// JobCompareCommits is the queue job kind that recovers commits missing from
+// a truncated push payload.
+const JobCompareCommits = "push_compare"
+
+// zeroSHA is the before SHA GitHub sends when a push creates a new ref.
+const zeroSHA = "0000000000000000000000000000000000000000"
+
+type compareJob struct {
+	RepositoryID int64  `json:"repository_id"`
+	PushEventID  int64  `json:"push_event_id,omitempty"`
+	Ref          string `json:"ref"`
+	Before       string `json:"before"`
+	After        string `json:"after"`
+	// Expected is how many commits the payload listed.
+	Expected   int       `json:"expected"`
+	ReceivedAt time.Time `json:"received_at"`
+	// PushedAt is GitHub's timestamp for the push; jobs queued before it was
+	// recorded fall back to ReceivedAt.
+	PushedAt time.Time `json:"pushed_at,omitempty"`
+}
+
+// payloadCommitLimit is the most commits a push payload is trusted to list
+// completely. Payloads at the limit are recovered through the compare API;
+// commits already stored are skipped, so a complete payload only costs the
+// API call.
+const payloadCommitLimit = 20
+
+// pushSize returns the number of commits listed in a push payload. Webhook
+// payloads carry no size field, unlike Events API push events.
+func pushSize(event *github.PushEvent) int {
+	if size := event.GetSize(); size > 0 {
+		return size
+	}
+	return len(event.Commits)
+}
+
+// isTruncated reports whether GitHub may have left commits out of the push
+// payload: it lists payloadCommitLimit commits or more, or the pushed head
+// is not among them.
+func isTruncated(event *github.PushEvent) bool {
+	if event.GetDeleted() || event.GetAfter() == zeroSHA || len(event.Commits) == 0 {
+		return false
+	}
+	if len(event.Commits) >= payloadCommitLimit {
+		return true
+	}
+	for _, c := range event.Commits {
+		if c.GetID() == event.GetAfter() {
+			return false
+		}
+	}
+	return true
+}
+
+func (h *Handler) enqueueCompare(ctx context.Context, repoID, pushEventID int64, event *github.PushEvent, receiveTime, pushedAt time.Time) {
+	h.logger.Info().
+		Str("repo", event.GetRepo().GetFullName()).
+		Int("payload_commits", len(event.Commits)).
+		Str("after", event.GetAfter()).
+		Msg("push payload may be truncated, fetching the pushed range")
+
+	if h.gh == nil {
+		h.logger.Warn().Msg("cannot recover truncated push without GitHub App client")
+		return
+	}
+
+	job := compareJob{
+		RepositoryID: repoID,
+		PushEventID:  pushEventID,
+		Ref:          event.GetRef(),
+		Before:       event.GetBefore(),
+		After:        event.GetAfter(),
+		Expected:     len(event.Commits),
+		ReceivedAt:   receiveTime,
+		PushedAt:     pushedAt,
+	}
+	if _, err := h.queue.Enqueue(ctx, JobCompareCommits, job); err != nil {
+		h.logger.Error().Err(err).Str("after", job.After).Msg("failed to enqueue push compare")
+	}
+}
+
+func (h *Handler) handleCompareJob(ctx context.Context, job *queue.Job) error {
+	var payload compareJob
+	if err := job.Decode(&payload); err != nil {
+		return err
+	}
+
+	repoStore := models.NewRepositoryStore(h.db.Pool)
+	repo, err := repoStore.GetByID(ctx, payload.RepositoryID)
+	if err != nil {
+		return fmt.Errorf("failed to load repository: %w", err)
+	}
+
+	client, err := h.gh.GetInstallationClient(repo.InstallationID)
+	if err != nil {
+		return err
+	}
+
+	// A push creating a ref has no before commit; its new commits are those
+	// not on the default branch, or the branch's history when it is the
+	// default branch
+	base := payload.Before
+	if base == zeroSHA || base == "" {
+		base = ""
+		if ref := "refs/heads/" + repo.DefaultBranch; repo.DefaultBranch != "" && payload.Ref != ref {
+			base = repo.DefaultBranch
+		}
+	}
+
+	var commits []*github.RepositoryCommit
+	if base == "" {
+		commits, err = listCommitsFrom(ctx, client, repo.Owner, repo.Name, payload.After, h.cfg.BackfillMaxCommits)
+	} else {
+		commits, err = compareCommits(ctx, client, repo.Owner, repo.Name, base, payload.After)
+	}
+	if err != nil {
+		if delay, ok := ghclient.RateLimitDelay(err); ok {
+			return queue.RetryAfter(delay, err)
+		}
+		if ghclient.IsNotFound(err) {
+			h.logger.Warn().
+				Str("repo", repo.FullName).
+				Str("before", payload.Before).
+				Str("after", payload.After).
+				Msg("push range no longer exists on GitHub, skipping")
+			return nil
+		}
+		return err
+	}
+
+	// Commits already ingested from the payload are skipped by ingestCommits
+	pushCommits := make([]*pushCommit, 0, len(commits))
+	for _, c := range commits {
+		pushCommits = append(pushCommits, newPushCommitFromAPI(c))
+	}
+
+	var pushEventID *int64
+	if payload.PushEventID != 0 {
+		pushEventID = &payload.PushEventID
+	}
+	pushedAt := payload.PushedAt
+	if pushedAt.IsZero() {
+		pushedAt = payload.ReceivedAt
+	}
+	inserted, err := h.ingestCommitsTx(ctx, repo.ID, pushEventID, pushCommits, pushedAt)
+	if err != nil {
+		return fmt.Errorf("failed to store recovered commits: %w", err)
+	}
+
+	h.logger.Info().
+		Str("repo", repo.FullName).
+		Str("ref", payload.Ref).
+		Int("fetched", len(commits)).
+		Int("new", len(inserted)).
+		Int("expected", payload.Expected).
+		Msg("recovered truncated push")
+
+	return nil
+}
+
+// compareCommits returns every commit reachable from head but not from base.
+func compareCommits(ctx context.Context, client *github.Client, owner, name, base, head string) ([]*github.RepositoryCommit, error) {
+	var commits []*github.RepositoryCommit
+
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		comparison, resp, err := client.Repositories.CompareCommits(ctx, owner, name, base, head, opts)
+		if err != nil {
+			return nil, err
+		}
+		commits = append(commits, comparison.Commits...)
+
+		if resp == nil || resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return commits, nil
+}
+
+// listCommitsFrom walks history back from sha, stopping after limit commits.
+// It is used for pushes that create a ref, where there is no base to compare.
+func listCommitsFrom(ctx context.Context, client *github.Client, owner, name, sha string, limit int) ([]*github.RepositoryCommit, error) {
+	var commits []*github.RepositoryCommit
+
+	opts := &github.CommitsListOptions{SHA: sha, ListOptions: github.ListOptions{PerPage: 100}}
+	for len(commits) < limit {
+		page, resp, err := client.Repositories.ListCommits(ctx, owner, name, opts)
+		if err != nil {
+			return nil, err
+		}
+		commits = append(commits, page...)
+
+		if resp == nil || resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	if len(commits) > limit {
+		commits = commits[:limit]
+	}
+	return commits, nil
+}
@@ -0,0 +1,174 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/go-github/v68/github"
+	ghclient "github.com/harshpatel5940/gitvigil/internal/github"
+	"github.com/harshpatel5940/gitvigil/internal/models"
+	"github.com/harshpatel5940/gitvigil/internal/queue"
+	"github.com/harshpatel5940/gitvigil/internal/rules"
+	"github.com/jackc/pgx/v5"
+)
+
+// JobEnrichCommit is the queue job kind that fetches stats and files for a commit.
+const JobEnrichCommit = "commit_enrich"
+
+type enrichJob struct {
+	RepositoryID int64  `json:"repository_id"`
+	SHA          string `json:"sha"`
+}
+
+// enqueueEnrichment schedules stats enrichment for a newly stored commit.
+// Push payloads carry no line counts, so they have to come from the API.
+func (h *Handler) enqueueEnrichment(ctx context.Context, repoID int64, sha string) {
+	if h.gh == nil {
+		return
+	}
+	if _, err := h.queue.Enqueue(ctx, JobEnrichCommit, enrichJob{RepositoryID: repoID, SHA: sha}); err != nil {
+		h.logger.Error().Err(err).Str("sha", sha).Msg("failed to enqueue commit enrichment")
+	}
+}
+
+func (h *Handler) handleEnrichJob(ctx context.Context, job *queue.Job) error {
+	var payload enrichJob
+	if err := job.Decode(&payload); err != nil {
+		return err
+	}
+
+	repoStore := models.NewRepositoryStore(h.db.Pool)
+	repo, err := repoStore.GetByID(ctx, payload.RepositoryID)
+	if err != nil {
+		return fmt.Errorf("failed to load repository: %w", err)
+	}
+
+	commitStore := models.NewCommitStore(h.db.Pool)
+	commit, err := commitStore.GetBySHA(ctx, repo.ID, payload.SHA)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Purged since the job was queued
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load commit: %w", err)
+	}
+	if commit.EnrichedAt != nil {
+		return nil
+	}
+
+	client, err := h.gh.GetInstallationClient(repo.InstallationID)
+	if err != nil {
+		return err
+	}
+
+	stats, err := fetchCommitStats(ctx, client, repo.Owner, repo.Name, commit.SHA)
+	if err != nil {
+		if delay, ok := ghclient.RateLimitDelay(err); ok {
+			return queue.RetryAfter(delay, err)
+		}
+		if ghclient.IsNotFound(err) {
+			h.logger.Warn().Str("repo", repo.FullName).Str("sha", commit.SHA).Msg("commit no longer exists on GitHub, skipping enrichment")
+			return nil
+		}
+		return err
+	}
+
+	evaluate := func(tx pgx.Tx) error {
+		return h.evaluateEnrichedCommit(ctx, tx, repo.ID, commit.SHA)
+	}
+	applied, err := commitStore.ApplyEnrichment(ctx, commit, stats.Additions, stats.Deletions, stats.Files, stats.CommitterDate, evaluate)
+	if err != nil {
+		return fmt.Errorf("failed to store commit stats: %w", err)
+	}
+	if !applied {
+		return nil
+	}
+
+	// The engine logs each failing rule itself
+	_ = h.detector.Rules().CheckRepository(ctx, rules.TriggerEnrichment, &repo.Repository)
+
+	h.logger.Debug().
+		Str("repo", repo.FullName).
+		Str("sha", commit.SHA).
+		Int("additions", stats.Additions).
+		Int("deletions", stats.Deletions).
+		Int("files", len(stats.Files)).
+		Msg("commit enriched")
+
+	return nil
+}
+
+// evaluateEnrichedCommit runs the enabled enrichment rules, which read line
+// and file counts, on a freshly enriched commit and stores their alerts in
+// the enrichment transaction. A failure rolls the enrichment back, so the
+// retried job evaluates the commit again.
+func (h *Handler) evaluateEnrichedCommit(ctx context.Context, tx pgx.Tx, repoID int64, sha string) error {
+	engine := h.detector.Rules()
+	if len(engine.Registry().ForTrigger(rules.TriggerEnrichment)) == 0 {
+		return nil
+	}
+
+	record, err := models.NewCommitStore(h.db.Pool).GetRecord(ctx, tx, repoID, sha)
+	if err != nil {
+		return fmt.Errorf("failed to load commit record: %w", err)
+	}
+	settings, err := engine.RepositorySettings(ctx, tx, repoID)
+	if err != nil {
+		return err
+	}
+
+	batch := &pgx.Batch{}
+	queueAlerts(batch, nil, engine.EvaluateCommit(settings, rules.TriggerEnrichment, rules.CommitFromRecord(record)))
+	return tx.SendBatch(ctx, batch).Close()
+}
+
+// commitStats is what enrichment fetches for a commit.
+type commitStats struct {
+	Additions     int
+	Deletions     int
+	Files         []*models.CommitFile
+	CommitterDate time.Time
+}
+
+// fetchCommitStats returns a commit's line counts, committer date and full
+// file list, following pagination for commits that touch more than one page
+// of files.
+func fetchCommitStats(ctx context.Context, client *github.Client, owner, name, sha string) (*commitStats, error) {
+	stats := &commitStats{}
+
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		rc, resp, err := client.Repositories.GetCommit(ctx, owner, name, sha, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		if opts.Page <= 1 {
+			stats.Additions = rc.GetStats().GetAdditions()
+			stats.Deletions = rc.GetStats().GetDeletions()
+			stats.CommitterDate = rc.GetCommit().GetCommitter().GetDate().Time
+		}
+
+		for _, f := range rc.Files {
+			file := &models.CommitFile{
+				Filename:  f.GetFilename(),
+				Status:    f.GetStatus(),
+				Additions: f.GetAdditions(),
+				Deletions: f.GetDeletions(),
+			}
+			if prev := f.GetPreviousFilename(); prev != "" {
+				file.PreviousFilename = &prev
+			}
+			stats.Files = append(stats.Files, file)
+		}
+
+		if resp == nil || resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return stats, nil
+}
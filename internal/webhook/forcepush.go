@@ -0,0 +1,235 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v68/github"
+	ghclient "github.com/harshpatel5940/gitvigil/internal/github"
+	"github.com/harshpatel5940/gitvigil/internal/models"
+	"github.com/harshpatel5940/gitvigil/internal/queue"
+)
+
+// JobForcePushForensics is the queue job kind that works out which commits a
+// forced push rewrote.
+const JobForcePushForensics = "force_push_forensics"
+
+// maxAlertSHAs caps the SHA lists copied into alert metadata; the full sets
+// live in force_push_commits.
+const maxAlertSHAs = 50
+
+// forensicsJob is the payload of a forensics job. AlertID is the push's
+// force_push alert, or 0 when the rule raised none.
+type forensicsJob struct {
+	RepositoryID int64  `json:"repository_id"`
+	PushEventID  int64  `json:"push_event_id"`
+	AlertID      int64  `json:"alert_id"`
+	Ref          string `json:"ref"`
+	Before       string `json:"before"`
+	After        string `json:"after"`
+}
+
+func (h *Handler) enqueueForensics(ctx context.Context, job forensicsJob) {
+	forcePushStore := models.NewForcePushStore(h.db.Pool)
+	if h.gh == nil || job.Before == zeroSHA || job.After == zeroSHA {
+		h.markForensicsUnavailable(ctx, job, "history comparison not possible for this push")
+		return
+	}
+
+	if err := forcePushStore.SetForensicsStatus(ctx, job.PushEventID, models.ForensicsPending); err != nil {
+		h.logger.Error().Err(err).Int64("push_event_id", job.PushEventID).Msg("failed to mark forensics as pending")
+	}
+	if _, err := h.queue.Enqueue(ctx, JobForcePushForensics, job); err != nil {
+		h.logger.Error().Err(err).Int64("push_event_id", job.PushEventID).Msg("failed to enqueue force push forensics")
+	}
+}
+
+func (h *Handler) handleForensicsJob(ctx context.Context, job *queue.Job) error {
+	var payload forensicsJob
+	if err := job.Decode(&payload); err != nil {
+		return err
+	}
+
+	repoStore := models.NewRepositoryStore(h.db.Pool)
+	repo, err := repoStore.GetByID(ctx, payload.RepositoryID)
+	if err != nil {
+		return fmt.Errorf("failed to load repository: %w", err)
+	}
+
+	client, err := h.gh.GetInstallationClient(repo.InstallationID)
+	if err != nil {
+		return err
+	}
+
+	// Comparing in both directions splits the histories at their merge base
+	dropped, err := compareCommits(ctx, client, repo.Owner, repo.Name, payload.After, payload.Before)
+	var replacements []*github.RepositoryCommit
+	if err == nil {
+		replacements, err = compareCommits(ctx, client, repo.Owner, repo.Name, payload.Before, payload.After)
+	}
+	if err != nil {
+		if delay, ok := ghclient.RateLimitDelay(err); ok {
+			return queue.RetryAfter(delay, err)
+		}
+		if ghclient.IsNotFound(err) {
+			// The old head has been garbage collected, nothing left to diff
+			h.markForensicsUnavailable(ctx, payload, "previous head no longer exists on GitHub")
+			return nil
+		}
+		return err
+	}
+
+	commits := matchRewrittenCommits(repo.ID, dropped, replacements)
+
+	forcePushStore := models.NewForcePushStore(h.db.Pool)
+	if err := forcePushStore.SaveCommits(ctx, payload.PushEventID, commits); err != nil {
+		return fmt.Errorf("failed to store force push commits: %w", err)
+	}
+
+	if err := h.updateForcePushAlert(ctx, payload.AlertID, commits); err != nil {
+		return fmt.Errorf("failed to update force push alert: %w", err)
+	}
+
+	h.logger.Info().
+		Str("repo", repo.FullName).
+		Str("ref", payload.Ref).
+		Int("dropped", len(dropped)).
+		Int("replacements", len(replacements)).
+		Msg("force push forensics completed")
+
+	return nil
+}
+
+// matchRewrittenCommits pairs each replacement with a dropped commit carrying
+// the same message, which is what a rebase or amend leaves behind, and flags
+// whether the rewrite changed the author or author date.
+func matchRewrittenCommits(repoID int64, dropped, replacements []*github.RepositoryCommit) []*models.ForcePushCommit {
+	var commits []*models.ForcePushCommit
+
+	unmatched := make(map[string][]*models.ForcePushCommit)
+	for _, c := range dropped {
+		commit := newForcePushCommit(repoID, models.ForcePushDropped, newPushCommitFromAPI(c))
+		key := strings.TrimSpace(commit.Message)
+		unmatched[key] = append(unmatched[key], commit)
+		commits = append(commits, commit)
+	}
+
+	for _, c := range replacements {
+		commit := newForcePushCommit(repoID, models.ForcePushReplacement, newPushCommitFromAPI(c))
+		key := strings.TrimSpace(commit.Message)
+		if candidates := unmatched[key]; len(candidates) > 0 {
+			original := candidates[0]
+			unmatched[key] = candidates[1:]
+
+			commit.ReplacesSHA = &original.SHA
+			commit.AuthorChanged = !strings.EqualFold(commit.AuthorEmail, original.AuthorEmail)
+			commit.AuthorDateChanged = !commit.AuthorDate.Equal(original.AuthorDate)
+		}
+		commits = append(commits, commit)
+	}
+
+	return commits
+}
+
+func newForcePushCommit(repoID int64, kind models.ForcePushCommitKind, c *pushCommit) *models.ForcePushCommit {
+	return &models.ForcePushCommit{
+		RepositoryID:  repoID,
+		Kind:          kind,
+		SHA:           c.SHA,
+		Message:       c.Message,
+		AuthorName:    c.AuthorName,
+		AuthorEmail:   c.AuthorEmail,
+		AuthorDate:    c.AuthorDate,
+		CommitterDate: c.CommitterDate,
+	}
+}
+
+// updateForcePushAlert copies the forensics summary into the alert and
+// escalates it when the rewrite moved author timestamps. Without an alert
+// there is nothing to update.
+func (h *Handler) updateForcePushAlert(ctx context.Context, alertID int64, commits []*models.ForcePushCommit) error {
+	if alertID == 0 {
+		return nil
+	}
+
+	var droppedSHAs []string
+	var dateChanges []map[string]interface{}
+	dropped, replacements, rewritten, authorsChanged, datesChanged := 0, 0, 0, 0, 0
+
+	originals := make(map[string]*models.ForcePushCommit)
+	for _, c := range commits {
+		if c.Kind == models.ForcePushDropped {
+			originals[c.SHA] = c
+		}
+	}
+
+	for _, c := range commits {
+		switch c.Kind {
+		case models.ForcePushDropped:
+			dropped++
+			if len(droppedSHAs) < maxAlertSHAs {
+				droppedSHAs = append(droppedSHAs, c.SHA)
+			}
+		case models.ForcePushReplacement:
+			replacements++
+			if c.ReplacesSHA == nil {
+				continue
+			}
+			rewritten++
+			if c.AuthorChanged {
+				authorsChanged++
+			}
+			if c.AuthorDateChanged {
+				datesChanged++
+				if len(dateChanges) < maxAlertSHAs {
+					dateChanges = append(dateChanges, map[string]interface{}{
+						"sha":             c.SHA,
+						"replaces_sha":    *c.ReplacesSHA,
+						"old_author_date": originals[*c.ReplacesSHA].AuthorDate,
+						"new_author_date": c.AuthorDate,
+					})
+				}
+			}
+		}
+	}
+
+	metadata := map[string]interface{}{
+		"forensics":            models.ForensicsCompleted,
+		"dropped_commits":      dropped,
+		"replacement_commits":  replacements,
+		"rewritten_commits":    rewritten,
+		"authors_changed":      authorsChanged,
+		"author_dates_changed": datesChanged,
+		"dropped_shas":         droppedSHAs,
+		"date_changes":         dateChanges,
+	}
+
+	var severity *models.Severity
+	if datesChanged > 0 {
+		critical := models.SeverityCritical
+		severity = &critical
+	}
+
+	alertStore := models.NewAlertStore(h.db.Pool)
+	return alertStore.MergeMetadata(ctx, alertID, severity, metadata)
+}
+
+func (h *Handler) markForensicsUnavailable(ctx context.Context, job forensicsJob, reason string) {
+	forcePushStore := models.NewForcePushStore(h.db.Pool)
+	if err := forcePushStore.SetForensicsStatus(ctx, job.PushEventID, models.ForensicsUnavailable); err != nil {
+		h.logger.Error().Err(err).Int64("push_event_id", job.PushEventID).Msg("failed to mark forensics as unavailable")
+	}
+	if job.AlertID == 0 {
+		return
+	}
+
+	alertStore := models.NewAlertStore(h.db.Pool)
+	err := alertStore.MergeMetadata(ctx, job.AlertID, nil, map[string]interface{}{
+		"forensics":        models.ForensicsUnavailable,
+		"forensics_reason": reason,
+	})
+	if err != nil {
+		h.logger.Error().Err(err).Int64("alert_id", job.AlertID).Msg("failed to update force push alert")
+	}
+}
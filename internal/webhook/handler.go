@@ -3,34 +3,63 @@ package webhook
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/google/go-github/v68/github"
 	"github.com/harshpatel5940/gitvigil/internal/config"
 	"github.com/harshpatel5940/gitvigil/internal/database"
+	"github.com/harshpatel5940/gitvigil/internal/detection"
 	ghclient "github.com/harshpatel5940/gitvigil/internal/github"
+	"github.com/harshpatel5940/gitvigil/internal/models"
+	"github.com/harshpatel5940/gitvigil/internal/queue"
+	"github.com/harshpatel5940/gitvigil/internal/rules"
+	"github.com/jackc/pgx/v5"
 	"github.com/rs/zerolog"
 )
 
+// Queue job kinds for stored deliveries.
+const (
+	// JobProcessDelivery processes a newly received delivery.
+	JobProcessDelivery = "webhook_delivery"
+	// JobReplayDelivery reprocesses a delivery regardless of its status.
+	JobReplayDelivery = "webhook_replay"
+)
+
 type Handler struct {
-	cfg    *config.Config
-	db     *database.DB
-	gh     *ghclient.AppClient
-	logger zerolog.Logger
+	cfg      *config.Config
+	db       *database.DB
+	gh       *ghclient.AppClient
+	queue    *queue.Queue
+	detector *detection.Detector
+	logger   zerolog.Logger
 }
 
-func NewHandler(cfg *config.Config, db *database.DB, gh *ghclient.AppClient, logger zerolog.Logger) *Handler {
+func NewHandler(cfg *config.Config, db *database.DB, gh *ghclient.AppClient, q *queue.Queue, logger zerolog.Logger) *Handler {
 	return &Handler{
-		cfg:    cfg,
-		db:     db,
-		gh:     gh,
-		logger: logger.With().Str("component", "webhook").Logger(),
+		cfg:      cfg,
+		db:       db,
+		gh:       gh,
+		queue:    q,
+		detector: detection.NewDetector(cfg, db, gh, logger),
+		logger:   logger.With().Str("component", "webhook").Logger(),
 	}
 }
 
+// RegisterJobs registers the webhook's background job handlers on the queue.
+func (h *Handler) RegisterJobs() {
+	h.queue.Register(JobProcessDelivery, h.handleDeliveryJob)
+	h.queue.Register(JobReplayDelivery, h.handleReplayJob)
+	h.queue.Register(JobEnrichCommit, h.handleEnrichJob)
+	h.queue.Register(JobCompareCommits, h.handleCompareJob)
+	h.queue.Register(JobBackfillRepository, h.handleBackfillJob)
+	h.queue.Register(JobForcePushForensics, h.handleForensicsJob)
+}
+
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
@@ -78,32 +107,140 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		Str("delivery_id", deliveryID).
 		Msg("received webhook")
 
-	// Record receive time for backdate detection
-	receiveTime := time.Now()
+	// Deliveries are deduplicated and replayed by ID, so GitHub always sends one
+	if deliveryID == "" {
+		h.logger.Warn().Str("event", eventType).Msg("webhook has no delivery ID, rejecting")
+		http.Error(w, "missing X-GitHub-Delivery header", http.StatusBadRequest)
+		return
+	}
+
+	// Entry for the delivery log, keeping the original receive time for
+	// replays
+	delivery := &models.Delivery{
+		DeliveryID: deliveryID,
+		EventType:  eventType,
+		Payload:    body,
+		ReceivedAt: time.Now(),
+	}
 
-	// Route event
+	// Persist the raw payload; redeliveries are only queued again when the
+	// earlier attempt failed
 	ctx := r.Context()
-	switch eventType {
+	store := models.NewDeliveryStore(h.db.Pool)
+	queued, err := store.Record(ctx, delivery)
+	if err != nil {
+		h.logger.Error().Err(err).Str("delivery_id", deliveryID).Msg("failed to record delivery")
+		http.Error(w, "failed to record delivery", http.StatusInternalServerError)
+		return
+	}
+	if !queued {
+		h.logger.Info().
+			Str("delivery_id", deliveryID).
+			Str("status", string(delivery.Status)).
+			Msg("duplicate delivery, skipping")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// Hand off to the job queue and acknowledge right away
+	if _, err := h.queue.Enqueue(ctx, JobProcessDelivery, deliveryJob{DeliveryID: deliveryID}); err != nil {
+		h.logger.Error().Err(err).Str("delivery_id", deliveryID).Msg("failed to enqueue delivery")
+		// Let a redelivery queue it
+		if markErr := store.MarkFailed(ctx, delivery.ID, "failed to enqueue: "+err.Error()); markErr != nil {
+			h.logger.Error().Err(markErr).Str("delivery_id", deliveryID).Msg("failed to mark delivery as failed")
+		}
+		http.Error(w, "failed to enqueue delivery", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+type deliveryJob struct {
+	DeliveryID string `json:"delivery_id"`
+}
+
+func (h *Handler) handleDeliveryJob(ctx context.Context, job *queue.Job) error {
+	var payload deliveryJob
+	if err := job.Decode(&payload); err != nil {
+		return err
+	}
+
+	store := models.NewDeliveryStore(h.db.Pool)
+	delivery, err := store.GetByDeliveryID(ctx, payload.DeliveryID)
+	if err != nil {
+		return err
+	}
+
+	// A redelivery of a failed delivery queues a second job, which finds it
+	// processing or processed by the first
+	claimed, err := store.Claim(ctx, delivery.ID)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		h.logger.Debug().Str("delivery_id", delivery.DeliveryID).Msg("delivery already handled by another job, skipping")
+		return nil
+	}
+
+	return h.handle(ctx, delivery)
+}
+
+// process runs a recorded delivery through the event handlers regardless
+// of its status and records the outcome.
+func (h *Handler) process(ctx context.Context, delivery *models.Delivery) error {
+	store := models.NewDeliveryStore(h.db.Pool)
+	if err := store.MarkProcessing(ctx, delivery.ID); err != nil {
+		h.logger.Error().Err(err).Str("delivery_id", delivery.DeliveryID).Msg("failed to mark delivery as processing")
+	}
+	return h.handle(ctx, delivery)
+}
+
+// handle dispatches a delivery marked as processing and records the outcome.
+func (h *Handler) handle(ctx context.Context, delivery *models.Delivery) error {
+	store := models.NewDeliveryStore(h.db.Pool)
+	// The outcome is recorded even if a queue shutdown cancelled ctx, so the
+	// delivery does not stay processing
+	statusCtx := context.Background()
+	if err := h.dispatch(ctx, delivery); err != nil {
+		h.logger.Error().
+			Err(err).
+			Str("event", delivery.EventType).
+			Str("delivery_id", delivery.DeliveryID).
+			Msg("failed to process webhook")
+		if markErr := store.MarkFailed(statusCtx, delivery.ID, err.Error()); markErr != nil {
+			h.logger.Error().Err(markErr).Str("delivery_id", delivery.DeliveryID).Msg("failed to mark delivery as failed")
+		}
+		return err
+	}
+
+	if err := store.MarkProcessed(statusCtx, delivery.ID); err != nil {
+		h.logger.Error().Err(err).Str("delivery_id", delivery.DeliveryID).Msg("failed to mark delivery as processed")
+	}
+	return nil
+}
+
+// dispatch routes a delivery to the handler for its event type.
+func (h *Handler) dispatch(ctx context.Context, delivery *models.Delivery) error {
+	switch delivery.EventType {
 	case "push":
-		h.handlePush(ctx, body, receiveTime)
+		return h.handlePush(ctx, delivery.Payload, delivery.DeliveryID, delivery.ReceivedAt)
 	case "installation":
-		h.handleInstallation(ctx, body)
+		return h.handleInstallation(ctx, delivery.Payload)
 	case "installation_repositories":
-		h.handleInstallationRepositories(ctx, body)
+		return h.handleInstallationRepositories(ctx, delivery.Payload)
 	case "ping":
 		h.logger.Info().Msg("received ping event")
 	default:
-		h.logger.Debug().Str("event", eventType).Msg("ignoring unhandled event type")
+		h.logger.Debug().Str("event", delivery.EventType).Msg("ignoring unhandled event type")
 	}
-
-	w.WriteHeader(http.StatusOK)
+	return nil
 }
 
-func (h *Handler) handlePush(ctx context.Context, body []byte, receiveTime time.Time) {
+func (h *Handler) handlePush(ctx context.Context, body []byte, deliveryID string, receiveTime time.Time) error {
 	var event github.PushEvent
 	if err := json.Unmarshal(body, &event); err != nil {
-		h.logger.Error().Err(err).Msg("failed to parse push event")
-		return
+		return fmt.Errorf("failed to parse push event: %w", err)
 	}
 
 	repo := event.GetRepo()
@@ -115,34 +252,77 @@ func (h *Handler) handlePush(ctx context.Context, body []byte, receiveTime time.
 		Str("pusher", event.GetPusher().GetLogin()).
 		Msg("processing push event")
 
+	// The whole push is written atomically so a failure leaves nothing behind
+	// for the retry to trip over
+	tx, err := h.db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// Commits are timed against GitHub's push timestamp rather than the
+	// receive time, which a delayed or redelivered webhook pushes back
+	pushedAt := githubPushTime(&event, receiveTime)
+
 	// Store push event
 	installationID := event.GetInstallation().GetID()
-	if err := h.storePushEvent(ctx, &event, installationID, receiveTime); err != nil {
-		h.logger.Error().Err(err).Msg("failed to store push event")
-		return
+	repoID, pushEventID, isNew, err := h.storePushEvent(ctx, tx, &event, installationID, deliveryID, receiveTime, pushedAt)
+	if err != nil {
+		return fmt.Errorf("failed to store push event: %w", err)
 	}
 
-	// Process commits for backdate detection
+	// Process commits for backdate and future date detection
+	commits := make([]*pushCommit, 0, len(event.Commits))
 	for _, commit := range event.Commits {
-		if err := h.processCommit(ctx, repo, commit, installationID, receiveTime); err != nil {
-			h.logger.Error().
-				Err(err).
-				Str("sha", commit.GetID()).
-				Msg("failed to process commit")
+		commits = append(commits, newPushCommit(commit))
+	}
+	inserted, err := h.ingestCommits(ctx, tx, repoID, &pushEventID, commits, pushedAt)
+	if err != nil {
+		return fmt.Errorf("failed to store commits: %w", err)
+	}
+
+	// Push rules run only once per push event, replays must not duplicate them
+	var forcePushAlertID int64
+	if isNew {
+		forcePushAlertID, err = h.evaluatePush(ctx, tx, repoID, pushEventID, &event, pushedAt)
+		if err != nil {
+			return fmt.Errorf("failed to evaluate push: %w", err)
 		}
 	}
 
-	// Check for force push
-	if event.GetForced() {
-		h.createForcePushAlert(ctx, repo, &event)
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	// Follow-up work is queued only once the push is durable
+	h.enqueueEnrichments(ctx, repoID, inserted)
+	h.checkCommits(ctx, repoID, inserted)
+
+	// Payloads may leave commits out; fetch the pushed range from the API
+	if isTruncated(&event) {
+		h.enqueueCompare(ctx, repoID, pushEventID, &event, receiveTime, pushedAt)
 	}
+
+	// Forensics are stored facts about the push, so they run even where the
+	// force_push rule raises no alert
+	if isNew && event.GetForced() {
+		h.enqueueForensics(ctx, forensicsJob{
+			RepositoryID: repoID,
+			PushEventID:  pushEventID,
+			AlertID:      forcePushAlertID,
+			Ref:          event.GetRef(),
+			Before:       event.GetBefore(),
+			After:        event.GetAfter(),
+		})
+	}
+
+	return nil
 }
 
-func (h *Handler) handleInstallation(ctx context.Context, body []byte) {
+func (h *Handler) handleInstallation(ctx context.Context, body []byte) error {
 	var event github.InstallationEvent
 	if err := json.Unmarshal(body, &event); err != nil {
-		h.logger.Error().Err(err).Msg("failed to parse installation event")
-		return
+		return fmt.Errorf("failed to parse installation event: %w", err)
 	}
 
 	action := event.GetAction()
@@ -158,182 +338,221 @@ func (h *Handler) handleInstallation(ctx context.Context, body []byte) {
 	switch action {
 	case "created":
 		if err := h.storeInstallation(ctx, installation); err != nil {
-			h.logger.Error().Err(err).Msg("failed to store installation")
+			return fmt.Errorf("failed to store installation: %w", err)
 		}
 		// Store repositories
+		var errs []error
 		for _, repo := range event.Repositories {
-			if err := h.storeRepository(ctx, repo, installation.GetID()); err != nil {
+			repoID, err := h.storeRepository(ctx, repo, installation.GetID())
+			if err != nil {
 				h.logger.Error().Err(err).Str("repo", repo.GetFullName()).Msg("failed to store repository")
+				errs = append(errs, err)
+				continue
 			}
+			h.enqueueBackfill(ctx, repoID)
 		}
+		return errors.Join(errs...)
 	case "deleted":
-		// Clean up installation data
-		h.logger.Info().Int64("installation_id", installation.GetID()).Msg("installation deleted")
+		return h.deleteInstallation(ctx, installation.GetID())
+	case "suspend":
+		return h.suspendInstallation(ctx, installation.GetID())
+	case "unsuspend":
+		return h.unsuspendInstallation(ctx, installation.GetID())
 	}
+
+	return nil
 }
 
-func (h *Handler) handleInstallationRepositories(ctx context.Context, body []byte) {
+func (h *Handler) handleInstallationRepositories(ctx context.Context, body []byte) error {
 	var event github.InstallationRepositoriesEvent
 	if err := json.Unmarshal(body, &event); err != nil {
-		h.logger.Error().Err(err).Msg("failed to parse installation_repositories event")
-		return
+		return fmt.Errorf("failed to parse installation_repositories event: %w", err)
 	}
 
 	installationID := event.GetInstallation().GetID()
 
 	// Handle added repositories
+	var errs []error
 	for _, repo := range event.RepositoriesAdded {
-		if err := h.storeRepository(ctx, repo, installationID); err != nil {
+		repoID, err := h.storeRepository(ctx, repo, installationID)
+		if err != nil {
 			h.logger.Error().Err(err).Str("repo", repo.GetFullName()).Msg("failed to store added repository")
+			errs = append(errs, err)
+			continue
 		}
+		h.enqueueBackfill(ctx, repoID)
 	}
 
 	// Handle removed repositories
 	for _, repo := range event.RepositoriesRemoved {
 		h.logger.Info().Str("repo", repo.GetFullName()).Msg("repository removed from installation")
+		if err := h.removeRepository(ctx, repo.GetID()); err != nil {
+			h.logger.Error().Err(err).Str("repo", repo.GetFullName()).Msg("failed to remove repository")
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// githubPushTime returns when GitHub recorded the push, taken from the
+// payload's repository.pushed_at so it is the same for every delivery of the
+// push. Payloads without it fall back to receiveTime.
+func githubPushTime(event *github.PushEvent, receiveTime time.Time) time.Time {
+	pushedAt := event.GetRepo().GetPushedAt()
+	if pushedAt.IsZero() {
+		return receiveTime
 	}
+	return pushedAt.Time
 }
 
-func (h *Handler) storePushEvent(ctx context.Context, event *github.PushEvent, installationID int64, receiveTime time.Time) error {
+// storePushEvent upserts the repository and records the push inside tx. It
+// returns the repository and push event IDs, and whether the push event is
+// new, which is false when a delivery is replayed.
+func (h *Handler) storePushEvent(ctx context.Context, tx pgx.Tx, event *github.PushEvent, installationID int64, deliveryID string, receiveTime, pushedAt time.Time) (int64, int64, bool, error) {
 	repo := event.GetRepo()
 
-	// First ensure repository exists
-	_, err := h.db.Pool.Exec(ctx, `
-		INSERT INTO repositories (github_id, installation_id, owner, name, full_name, last_push_at, last_activity_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $6)
+	// Lock the repository row so the streak transition below sees a stable status
+	previousStreak := models.StreakActive
+	err := tx.QueryRow(ctx, `
+		SELECT streak_status FROM repositories WHERE github_id = $1 FOR UPDATE
+	`, repo.GetID()).Scan(&previousStreak)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return 0, 0, false, err
+	}
+
+	// The payload reports when the repository was created on GitHub, which
+	// pre-event code detection compares against the event start
+	var createdAt *time.Time
+	if repo.CreatedAt != nil && !repo.CreatedAt.IsZero() {
+		createdAt = &repo.CreatedAt.Time
+	}
+
+	// First ensure repository exists. Push activity is only recorded below,
+	// once the push is known to be new
+	owner, name := splitFullName(repo.GetFullName(), repo.GetOwner().GetLogin(), repo.GetName())
+	var repoID int64
+	err = tx.QueryRow(ctx, `
+		INSERT INTO repositories (github_id, installation_id, owner, name, full_name, github_created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
 		ON CONFLICT (github_id) DO UPDATE SET
-			last_push_at = $6,
-			last_activity_at = $6,
-			streak_status = 'active',
+			owner = EXCLUDED.owner,
+			name = EXCLUDED.name,
+			full_name = EXCLUDED.full_name,
+			github_created_at = COALESCE($6, repositories.github_created_at),
 			updated_at = NOW()
-	`, repo.GetID(), installationID, repo.GetOwner().GetLogin(), repo.GetName(), repo.GetFullName(), receiveTime)
+		RETURNING id
+	`, repo.GetID(), installationID, owner, name, repo.GetFullName(), createdAt).Scan(&repoID)
 	if err != nil {
-		return err
+		return 0, 0, false, err
 	}
 
-	// Get repository ID
-	var repoID int64
-	err = h.db.Pool.QueryRow(ctx, `SELECT id FROM repositories WHERE github_id = $1`, repo.GetID()).Scan(&repoID)
-	if err != nil {
-		return err
+	var deliveryIDParam *string
+	if deliveryID != "" {
+		deliveryIDParam = &deliveryID
 	}
 
 	// Store push event
-	_, err = h.db.Pool.Exec(ctx, `
-		INSERT INTO push_events (repository_id, push_id, ref, before_sha, after_sha, forced, pusher_login, commit_count, distinct_count, received_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	var pushEventID int64
+	err = tx.QueryRow(ctx, `
+		INSERT INTO push_events (repository_id, push_id, ref, before_sha, after_sha, forced, pusher_login, commit_count, distinct_count, received_at, delivery_id, github_pushed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (delivery_id) WHERE delivery_id IS NOT NULL DO NOTHING
+		RETURNING id
 	`, repoID, event.GetPushID(), event.GetRef(), event.GetBefore(), event.GetAfter(),
-		event.GetForced(), event.GetPusher().GetLogin(), len(event.Commits), event.GetDistinctSize(), receiveTime)
-
-	return err
-}
+		event.GetForced(), event.GetPusher().GetLogin(), pushSize(event), event.GetDistinctSize(), receiveTime, deliveryIDParam, pushedAt,
+	).Scan(&pushEventID)
+	if err == nil {
+		if err := h.recordPushActivity(ctx, tx, repoID, previousStreak, receiveTime); err != nil {
+			return 0, 0, false, err
+		}
+		return repoID, pushEventID, true, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return 0, 0, false, err
+	}
 
-func (h *Handler) processCommit(ctx context.Context, repo *github.PushEventRepository, commit *github.HeadCommit, installationID int64, receiveTime time.Time) error {
-	// Get repository ID
-	var repoID int64
-	err := h.db.Pool.QueryRow(ctx, `SELECT id FROM repositories WHERE github_id = $1`, repo.GetID()).Scan(&repoID)
+	// Replayed delivery, reuse the existing push event
+	err = tx.QueryRow(ctx, `SELECT id FROM push_events WHERE delivery_id = $1`, deliveryID).Scan(&pushEventID)
 	if err != nil {
-		return err
+		return 0, 0, false, err
 	}
+	return repoID, pushEventID, false, nil
+}
 
-	// Get commit author date
-	authorDate := commit.GetTimestamp().Time
-
-	// Calculate backdate hours
-	backdateHours := int(receiveTime.Sub(authorDate).Hours())
-	isBackdated := backdateHours > h.cfg.BackdateSuspiciousHours
-
-	// Determine conventional commit type
-	isConventional, conventionalType, conventionalScope := parseConventionalCommit(commit.GetMessage())
-
-	// Store commit
-	_, err = h.db.Pool.Exec(ctx, `
-		INSERT INTO commits (repository_id, sha, message, author_email, author_name, author_date, committer_date, pushed_at, additions, deletions, is_conventional, conventional_type, conventional_scope, is_backdated, backdate_hours)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
-		ON CONFLICT (sha) DO NOTHING
-	`, repoID, commit.GetID(), commit.GetMessage(),
-		commit.GetAuthor().GetEmail(), commit.GetAuthor().GetName(),
-		authorDate, commit.GetTimestamp().Time, receiveTime,
-		0, 0, // additions/deletions not available in push event
-		isConventional, conventionalType, conventionalScope,
-		isBackdated, backdateHours)
+// recordPushActivity extends the repository's streak with a new push. A
+// broken streak is not extended here; the recovery transition starts a new
+// one.
+func (h *Handler) recordPushActivity(ctx context.Context, tx pgx.Tx, repoID int64, previousStreak models.StreakStatus, receiveTime time.Time) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE repositories SET
+			last_push_at = $2,
+			last_activity_at = GREATEST(last_activity_at, $2),
+			streak_started_at = COALESCE(streak_started_at, $2),
+			longest_streak_days = CASE
+				WHEN streak_status = 'inactive' THEN longest_streak_days
+				ELSE GREATEST(longest_streak_days,
+					FLOOR(EXTRACT(EPOCH FROM ($2 - COALESCE(streak_started_at, $2))) / 86400)::int + 1)
+			END,
+			updated_at = NOW()
+		WHERE id = $1
+	`, repoID, receiveTime)
 	if err != nil {
 		return err
 	}
 
-	// Create backdate alert if needed
-	if isBackdated {
-		severity := "warning"
-		alertType := "backdate_suspicious"
-		if backdateHours > h.cfg.BackdateCriticalHours {
-			severity = "critical"
-			alertType = "backdate_critical"
-		}
-
-		_, err = h.db.Pool.Exec(ctx, `
-			INSERT INTO alerts (repository_id, commit_sha, alert_type, severity, title, description, metadata)
-			VALUES ($1, $2, $3, $4, $5, $6, $7)
-		`, repoID, commit.GetID(), alertType, severity,
-			"Backdated commit detected",
-			"Commit author date is significantly older than push time",
-			map[string]interface{}{
-				"author_date":    authorDate,
-				"pushed_at":      receiveTime,
-				"backdate_hours": backdateHours,
-			})
-		if err != nil {
-			h.logger.Error().Err(err).Msg("failed to create backdate alert")
+	// Activity after the streak was at risk or broken is a recovery
+	if previousStreak == models.StreakAtRisk || previousStreak == models.StreakInactive {
+		streakStore := models.NewStreakStore(h.db.Pool)
+		if _, err := streakStore.Transition(ctx, tx, repoID, previousStreak, models.StreakRecovered, models.StreakReasonPush, receiveTime); err != nil {
+			return fmt.Errorf("failed to record streak recovery: %w", err)
 		}
 	}
-
-	// Update contributor stats
-	if err := h.updateContributor(ctx, repoID, commit, receiveTime); err != nil {
-		h.logger.Error().Err(err).Msg("failed to update contributor")
-	}
-
 	return nil
 }
 
-func (h *Handler) updateContributor(ctx context.Context, repoID int64, commit *github.HeadCommit, receiveTime time.Time) error {
-	author := commit.GetAuthor()
-
-	_, err := h.db.Pool.Exec(ctx, `
-		INSERT INTO contributors (repository_id, github_login, email, name, total_commits, first_commit_at, last_commit_at)
-		VALUES ($1, $2, $3, $4, 1, $5, $5)
-		ON CONFLICT (repository_id, email) DO UPDATE SET
-			github_login = COALESCE(EXCLUDED.github_login, contributors.github_login),
-			name = COALESCE(EXCLUDED.name, contributors.name),
-			total_commits = contributors.total_commits + 1,
-			last_commit_at = $5,
-			updated_at = NOW()
-	`, repoID, author.GetLogin(), author.GetEmail(), author.GetName(), receiveTime)
-
-	return err
-}
+// evaluatePush runs the enabled push rules on a new push and stores their
+// alerts inside tx. It returns the ID of the force push alert, if any, which
+// the forensics job later fills in with the rewritten commits.
+func (h *Handler) evaluatePush(ctx context.Context, tx pgx.Tx, repoID, pushEventID int64, event *github.PushEvent, pushedAt time.Time) (int64, error) {
+	push := &rules.Push{
+		RepositoryID: repoID,
+		PushEventID:  pushEventID,
+		Ref:          event.GetRef(),
+		Before:       event.GetBefore(),
+		After:        event.GetAfter(),
+		Forced:       event.GetForced(),
+		Pusher:       event.GetPusher().GetLogin(),
+		Commits:      len(event.Commits),
+		PushedAt:     pushedAt,
+	}
+	if err := h.markPostDeadlinePush(ctx, tx, push); err != nil {
+		return 0, fmt.Errorf("failed to check submission deadline: %w", err)
+	}
 
-func (h *Handler) createForcePushAlert(ctx context.Context, repo *github.PushEventRepository, event *github.PushEvent) {
-	var repoID int64
-	err := h.db.Pool.QueryRow(ctx, `SELECT id FROM repositories WHERE github_id = $1`, repo.GetID()).Scan(&repoID)
+	engine := h.detector.Rules()
+	settings, err := engine.RepositorySettings(ctx, tx, repoID)
 	if err != nil {
-		h.logger.Error().Err(err).Msg("failed to get repository ID for force push alert")
-		return
+		return 0, err
 	}
 
-	_, err = h.db.Pool.Exec(ctx, `
-		INSERT INTO alerts (repository_id, alert_type, severity, title, description, metadata)
-		VALUES ($1, $2, $3, $4, $5, $6)
-	`, repoID, "force_push", "warning",
-		"Force push detected",
-		"Repository history was rewritten",
-		map[string]interface{}{
-			"ref":    event.GetRef(),
-			"before": event.GetBefore(),
-			"after":  event.GetAfter(),
-			"pusher": event.GetPusher().GetLogin(),
-		})
-	if err != nil {
-		h.logger.Error().Err(err).Msg("failed to create force push alert")
+	var forcePushAlertID int64
+	for _, alert := range engine.EvaluatePush(settings, push) {
+		err := tx.QueryRow(ctx, `
+			INSERT INTO alerts (repository_id, commit_sha, push_event_id, alert_type, severity, title, description, metadata)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			RETURNING id
+		`, alert.RepositoryID, alert.CommitSHA, alert.PushEventID, alert.AlertType,
+			alert.Severity, alert.Title, alert.Description, alert.Metadata,
+		).Scan(&alert.ID)
+		if err != nil {
+			return 0, err
+		}
+		if alert.AlertType == models.AlertForcePush {
+			forcePushAlertID = alert.ID
+		}
 	}
+	return forcePushAlertID, nil
 }
 
 func (h *Handler) storeInstallation(ctx context.Context, installation *github.Installation) error {
@@ -343,20 +562,45 @@ func (h *Handler) storeInstallation(ctx context.Context, installation *github.In
 		VALUES ($1, $2, $3)
 		ON CONFLICT (installation_id) DO UPDATE SET
 			account_login = $2,
+			status = 'active',
+			suspended_at = NULL,
+			deleted_at = NULL,
 			updated_at = NOW()
 	`, installation.GetID(), account.GetLogin(), account.GetType())
 	return err
 }
 
-func (h *Handler) storeRepository(ctx context.Context, repo *github.Repository, installationID int64) error {
-	_, err := h.db.Pool.Exec(ctx, `
+// storeRepository upserts a repository added to an installation and returns
+// its internal ID.
+func (h *Handler) storeRepository(ctx context.Context, repo *github.Repository, installationID int64) (int64, error) {
+	owner, name := splitFullName(repo.GetFullName(), repo.GetOwner().GetLogin(), repo.GetName())
+	var id int64
+	err := h.db.Pool.QueryRow(ctx, `
 		INSERT INTO repositories (github_id, installation_id, owner, name, full_name)
 		VALUES ($1, $2, $3, $4, $5)
 		ON CONFLICT (github_id) DO UPDATE SET
 			installation_id = $2,
+			owner = EXCLUDED.owner,
+			name = EXCLUDED.name,
+			full_name = EXCLUDED.full_name,
+			status = 'active',
+			suspended_at = NULL,
+			removed_at = NULL,
+			deleted_at = NULL,
 			updated_at = NOW()
-	`, repo.GetID(), installationID, repo.GetOwner().GetLogin(), repo.GetName(), repo.GetFullName())
-	return err
+		RETURNING id
+	`, repo.GetID(), installationID, owner, name, repo.GetFullName()).Scan(&id)
+	return id, err
+}
+
+// splitFullName returns the owner and name of an "owner/name" repository.
+// Installation payloads leave the owner out, so both are taken from the
+// full name, falling back to the payload's fields when it has no slash.
+func splitFullName(fullName, owner, name string) (string, string) {
+	if o, n, ok := strings.Cut(fullName, "/"); ok {
+		return o, n
+	}
+	return owner, name
 }
 
 func parseConventionalCommit(message string) (bool, string, string) {
@@ -0,0 +1,333 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/harshpatel5940/gitvigil/internal/detection"
+	"github.com/harshpatel5940/gitvigil/internal/models"
+	"github.com/harshpatel5940/gitvigil/internal/rules"
+	"github.com/jackc/pgx/v5"
+)
+
+// storedCommit is a commit that ingestCommits inserted, as opposed to one
+// that was already present from an earlier delivery or replay.
+type storedCommit struct {
+	*pushCommit
+	PushedAt time.Time
+	// timing is the commit as the commit rules see it, with its dates
+	// measured against the push.
+	timing        *rules.Commit
+	IsBackdated   bool
+	IsFutureDated bool
+	alerts        []*models.Alert
+}
+
+// contributorDelta aggregates the new commits of one author so each
+// contributor row is written once per batch.
+type contributorDelta struct {
+	login, email, name string
+	commits            int
+	first, last        time.Time
+}
+
+type dailyKey struct {
+	email string
+	date  string
+}
+
+// ingestCommits writes commits inside tx using batched statements. Commits
+// already stored are skipped; new ones get the alerts of the enabled commit
+// rules, linked to pushEventID (when non-nil), and are rolled into
+// contributor totals, linked to the team roster, and daily stats. Commit
+// dates are compared against pushedAt, GitHub's timestamp for the push,
+// except for backfilled commits, which were never pushed while the app was
+// installed and use their committer date instead. The backdate and future
+// date flags follow those rules' thresholds even where their alerts are
+// disabled, so settings never change stored commit facts.
+func (h *Handler) ingestCommits(ctx context.Context, tx pgx.Tx, repoID int64, pushEventID *int64, commits []*pushCommit, pushedAt time.Time) ([]*storedCommit, error) {
+	if len(commits) == 0 {
+		return nil, nil
+	}
+
+	engine := h.detector.Rules()
+	settings, err := engine.RepositorySettings(ctx, tx, repoID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	candidates := make([]*storedCommit, 0, len(commits))
+	batch := &pgx.Batch{}
+	for _, commit := range commits {
+		c := measureCommitTiming(repoID, commit, pushedAt, now)
+		c.alerts = engine.EvaluateCommit(settings, rules.TriggerCommit, c.timing)
+		c.IsBackdated = len(engine.CommitFindings(settings, detection.RuleBackdate, c.timing)) > 0
+		c.IsFutureDated = len(engine.CommitFindings(settings, detection.RuleFutureDated, c.timing)) > 0
+		candidates = append(candidates, c)
+
+		futureMinutes := 0
+		if c.IsFutureDated {
+			futureMinutes = c.timing.FutureMinutes
+		}
+
+		// Determine conventional commit type
+		isConventional, conventionalType, conventionalScope := parseConventionalCommit(commit.Message)
+
+		batch.Queue(`
+			INSERT INTO commits (repository_id, sha, message, author_email, author_name, author_date, committer_date, pushed_at, additions, deletions, is_conventional, conventional_type, conventional_scope, is_backdated, backdate_hours, source, author_login, committer_name, committer_email, committer_login, is_future_dated, future_minutes, committer_date_verified, author_utc_offset)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, NULLIF($17, ''), NULLIF($18, ''), NULLIF($19, ''), NULLIF($20, ''), $21, $22, $23, $24)
+			ON CONFLICT (repository_id, sha) DO NOTHING
+			RETURNING id
+		`, repoID, commit.SHA, commit.Message,
+			commit.AuthorEmail, commit.AuthorName,
+			commit.AuthorDate, commit.CommitterDate, c.PushedAt,
+			0, 0, // additions/deletions not available in push event
+			isConventional, conventionalType, conventionalScope,
+			c.IsBackdated, c.timing.BackdateHours, commit.Source,
+			commit.AuthorLogin, commit.CommitterName, commit.CommitterEmail, commit.CommitterLogin,
+			c.IsFutureDated, futureMinutes, commit.DatesVerified, commit.AuthorUTCOffset)
+	}
+
+	// Commits already stored (redelivery or replay) return no row and are not counted twice
+	var inserted []*storedCommit
+	results := tx.SendBatch(ctx, batch)
+	for _, c := range candidates {
+		var id int64
+		err := results.QueryRow().Scan(&id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			results.Close()
+			return nil, err
+		}
+		inserted = append(inserted, c)
+	}
+	if err := results.Close(); err != nil {
+		return nil, err
+	}
+	if len(inserted) == 0 {
+		return nil, nil
+	}
+
+	alerts := &pgx.Batch{}
+	for _, c := range inserted {
+		queueAlerts(alerts, pushEventID, c.alerts)
+	}
+	if alerts.Len() > 0 {
+		if err := tx.SendBatch(ctx, alerts).Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	contributorIDs, err := h.upsertContributors(ctx, tx, repoID, inserted)
+	if err != nil {
+		return nil, err
+	}
+
+	// Match contributors against the repository's team roster
+	ids := make([]int64, 0, len(contributorIDs))
+	for _, id := range contributorIDs {
+		ids = append(ids, id)
+	}
+	if err := models.NewTeamStore(h.db.Pool).LinkContributors(ctx, tx, ids); err != nil {
+		return nil, err
+	}
+
+	if err := upsertDailyStats(ctx, tx, repoID, contributorIDs, inserted); err != nil {
+		return nil, err
+	}
+
+	return inserted, nil
+}
+
+// measureCommitTiming compares a commit's dates against the push. Backdate
+// hours are how long before the push the commit was authored, and future
+// minutes how far its latest date is ahead of the push. Backfilled commits
+// have no push, so their author date is measured against their committer
+// date and their dates against now.
+func measureCommitTiming(repoID int64, commit *pushCommit, pushedAt, now time.Time) *storedCommit {
+	futureReference := pushedAt
+	if commit.Source == commitSourceBackfill {
+		pushedAt = commit.CommitterDate
+		futureReference = now
+	}
+
+	latest := commit.AuthorDate
+	if commit.CommitterDate.After(latest) {
+		latest = commit.CommitterDate
+	}
+
+	return &storedCommit{
+		pushCommit: commit,
+		PushedAt:   pushedAt,
+		timing: &rules.Commit{
+			RepositoryID:      repoID,
+			SHA:               commit.SHA,
+			Message:           commit.Message,
+			AuthorName:        commit.AuthorName,
+			AuthorEmail:       commit.AuthorEmail,
+			AuthorLogin:       commit.AuthorLogin,
+			AuthorDate:        commit.AuthorDate,
+			CommitterName:     commit.CommitterName,
+			CommitterEmail:    commit.CommitterEmail,
+			CommitterLogin:    commit.CommitterLogin,
+			CommitterDate:     commit.CommitterDate,
+			Source:            commit.Source,
+			DatesVerified:     commit.DatesVerified,
+			PushedAt:          pushedAt,
+			BackdateHours:     int(pushedAt.Sub(commit.AuthorDate).Hours()),
+			CommitterGapHours: int(commit.CommitterDate.Sub(commit.AuthorDate).Hours()),
+			FutureMinutes:     int(latest.Sub(futureReference).Minutes()),
+		},
+	}
+}
+
+// queueAlerts queues the insertion of alerts linked to pushEventID (when
+// non-nil) onto batch.
+func queueAlerts(batch *pgx.Batch, pushEventID *int64, alerts []*models.Alert) {
+	for _, alert := range alerts {
+		if pushEventID != nil {
+			alert.PushEventID = pushEventID
+		}
+		batch.Queue(`
+			INSERT INTO alerts (repository_id, commit_sha, push_event_id, alert_type, severity, title, description, metadata)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		`, alert.RepositoryID, alert.CommitSHA, alert.PushEventID, alert.AlertType,
+			alert.Severity, alert.Title, alert.Description, alert.Metadata)
+	}
+}
+
+// upsertContributors updates contributor stats and returns contributor IDs by email.
+func (h *Handler) upsertContributors(ctx context.Context, tx pgx.Tx, repoID int64, commits []*storedCommit) (map[string]int64, error) {
+	var order []string
+	deltas := make(map[string]*contributorDelta)
+	for _, c := range commits {
+		d, ok := deltas[c.AuthorEmail]
+		if !ok {
+			d = &contributorDelta{email: c.AuthorEmail, first: c.PushedAt, last: c.PushedAt}
+			deltas[c.AuthorEmail] = d
+			order = append(order, c.AuthorEmail)
+		}
+		d.commits++
+		if c.AuthorLogin != "" {
+			d.login = c.AuthorLogin
+		}
+		if c.AuthorName != "" {
+			d.name = c.AuthorName
+		}
+		if c.PushedAt.Before(d.first) {
+			d.first = c.PushedAt
+		}
+		if c.PushedAt.After(d.last) {
+			d.last = c.PushedAt
+		}
+	}
+
+	batch := &pgx.Batch{}
+	for _, email := range order {
+		d := deltas[email]
+		batch.Queue(`
+			INSERT INTO contributors (repository_id, github_login, email, name, total_commits, first_commit_at, last_commit_at)
+			VALUES ($1, NULLIF($2, ''), $3, NULLIF($4, ''), $5, $6, $7)
+			ON CONFLICT (repository_id, email) DO UPDATE SET
+				github_login = COALESCE(EXCLUDED.github_login, contributors.github_login),
+				name = COALESCE(EXCLUDED.name, contributors.name),
+				total_commits = contributors.total_commits + EXCLUDED.total_commits,
+				first_commit_at = LEAST(contributors.first_commit_at, EXCLUDED.first_commit_at),
+				last_commit_at = GREATEST(contributors.last_commit_at, EXCLUDED.last_commit_at),
+				updated_at = NOW()
+			RETURNING id
+		`, repoID, d.login, d.email, d.name, d.commits, d.first, d.last)
+	}
+
+	ids := make(map[string]int64, len(order))
+	results := tx.SendBatch(ctx, batch)
+	for _, email := range order {
+		var id int64
+		if err := results.QueryRow().Scan(&id); err != nil {
+			results.Close()
+			return nil, err
+		}
+		ids[email] = id
+	}
+	return ids, results.Close()
+}
+
+// upsertDailyStats counts the new commits per contributor and author day.
+// Line counts are added later by commit enrichment.
+func upsertDailyStats(ctx context.Context, tx pgx.Tx, repoID int64, contributorIDs map[string]int64, commits []*storedCommit) error {
+	var order []dailyKey
+	counts := make(map[dailyKey]int)
+	for _, c := range commits {
+		key := dailyKey{email: c.AuthorEmail, date: c.AuthorDate.UTC().Format("2006-01-02")}
+		if _, ok := counts[key]; !ok {
+			order = append(order, key)
+		}
+		counts[key]++
+	}
+
+	batch := &pgx.Batch{}
+	for _, key := range order {
+		batch.Queue(`
+			INSERT INTO daily_stats (repository_id, contributor_id, stat_date, commit_count)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (repository_id, contributor_id, stat_date) DO UPDATE SET
+				commit_count = daily_stats.commit_count + EXCLUDED.commit_count
+		`, repoID, contributorIDs[key.email], key.date, counts[key])
+	}
+	return tx.SendBatch(ctx, batch).Close()
+}
+
+// enqueueEnrichments schedules enrichment for commits once their transaction
+// has committed and the contributor rows exist.
+func (h *Handler) enqueueEnrichments(ctx context.Context, repoID int64, commits []*storedCommit) {
+	for _, c := range commits {
+		h.enqueueEnrichment(ctx, repoID, c.SHA)
+	}
+}
+
+// ingestCommitsTx runs ingestCommits in its own transaction and enqueues
+// enrichment for the inserted commits after it commits.
+func (h *Handler) ingestCommitsTx(ctx context.Context, repoID int64, pushEventID *int64, commits []*pushCommit, pushedAt time.Time) ([]*storedCommit, error) {
+	tx, err := h.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	inserted, err := h.ingestCommits(ctx, tx, repoID, pushEventID, commits, pushedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	h.enqueueEnrichments(ctx, repoID, inserted)
+
+	// Backfilled history is left to the periodic checks, rather than
+	// rescanning the repository for every page
+	if pushEventID != nil {
+		h.checkCommits(ctx, repoID, inserted)
+	}
+	return inserted, nil
+}
+
+// checkCommits runs the repository rules consuming pushes after new commits
+// are stored. Failures are only logged; the periodic checks retry them.
+func (h *Handler) checkCommits(ctx context.Context, repoID int64, commits []*storedCommit) {
+	if len(commits) == 0 {
+		return
+	}
+
+	repo, err := models.NewRepositoryStore(h.db.Pool).GetByID(ctx, repoID)
+	if err != nil {
+		h.logger.Error().Err(err).Int64("repository_id", repoID).Msg("failed to load repository for commit checks")
+		return
+	}
+	// The engine logs each failing rule itself
+	_ = h.detector.Rules().CheckRepository(ctx, rules.TriggerPush, &repo.Repository)
+}
@@ -0,0 +1,117 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+
+	"github.com/harshpatel5940/gitvigil/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// deleteInstallation marks an uninstalled installation and all of its
+// repositories as deleted, then applies the configured data policy.
+func (h *Handler) deleteInstallation(ctx context.Context, installationID int64) error {
+	installationStore := models.NewInstallationStore(h.db.Pool)
+	if err := installationStore.SetStatus(ctx, installationID, models.LifecycleDeleted); err != nil {
+		return err
+	}
+
+	repoStore := models.NewRepositoryStore(h.db.Pool)
+	repoIDs, err := repoStore.SetStatusByInstallation(ctx, installationID,
+		[]models.LifecycleStatus{models.LifecycleActive, models.LifecycleSuspended, models.LifecycleRemoved},
+		models.LifecycleDeleted)
+	if err != nil {
+		return err
+	}
+
+	h.logger.Info().
+		Int64("installation_id", installationID).
+		Int("repositories", len(repoIDs)).
+		Msg("installation deleted")
+
+	return h.applyDataPolicy(ctx, repoIDs...)
+}
+
+// suspendInstallation marks an installation and its active repositories as suspended.
+// Data is always kept: a suspended installation can come back.
+func (h *Handler) suspendInstallation(ctx context.Context, installationID int64) error {
+	installationStore := models.NewInstallationStore(h.db.Pool)
+	if err := installationStore.SetStatus(ctx, installationID, models.LifecycleSuspended); err != nil {
+		return err
+	}
+
+	repoStore := models.NewRepositoryStore(h.db.Pool)
+	repoIDs, err := repoStore.SetStatusByInstallation(ctx, installationID,
+		[]models.LifecycleStatus{models.LifecycleActive}, models.LifecycleSuspended)
+	if err != nil {
+		return err
+	}
+
+	h.logger.Info().
+		Int64("installation_id", installationID).
+		Int("repositories", len(repoIDs)).
+		Msg("installation suspended")
+	return nil
+}
+
+// unsuspendInstallation reactivates an installation and the repositories that
+// were suspended with it.
+func (h *Handler) unsuspendInstallation(ctx context.Context, installationID int64) error {
+	installationStore := models.NewInstallationStore(h.db.Pool)
+	if err := installationStore.SetStatus(ctx, installationID, models.LifecycleActive); err != nil {
+		return err
+	}
+
+	repoStore := models.NewRepositoryStore(h.db.Pool)
+	repoIDs, err := repoStore.SetStatusByInstallation(ctx, installationID,
+		[]models.LifecycleStatus{models.LifecycleSuspended}, models.LifecycleActive)
+	if err != nil {
+		return err
+	}
+
+	h.logger.Info().
+		Int64("installation_id", installationID).
+		Int("repositories", len(repoIDs)).
+		Msg("installation unsuspended")
+	return nil
+}
+
+// removeRepository marks a repository that was removed from an installation
+// and applies the configured data policy.
+func (h *Handler) removeRepository(ctx context.Context, githubID int64) error {
+	repoStore := models.NewRepositoryStore(h.db.Pool)
+	if err := repoStore.SetStatus(ctx, githubID, models.LifecycleRemoved); err != nil {
+		return err
+	}
+
+	repo, err := repoStore.GetByGitHubID(ctx, githubID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Never seen this repository, nothing to clean up
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return h.applyDataPolicy(ctx, repo.ID)
+}
+
+// applyDataPolicy purges collected data for the given repositories when the
+// lifecycle policy is "purge". With "archive" the data is left in place.
+func (h *Handler) applyDataPolicy(ctx context.Context, repoIDs ...int64) error {
+	if h.cfg.LifecycleDataPolicy != "purge" {
+		return nil
+	}
+
+	repoStore := models.NewRepositoryStore(h.db.Pool)
+	var errs []error
+	for _, id := range repoIDs {
+		if err := repoStore.PurgeData(ctx, id); err != nil {
+			h.logger.Error().Err(err).Int64("repo_id", id).Msg("failed to purge repository data")
+			errs = append(errs, err)
+			continue
+		}
+		h.logger.Info().Int64("repo_id", id).Msg("purged repository data")
+	}
+	return errors.Join(errs...)
+}
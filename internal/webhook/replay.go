@@ -0,0 +1,196 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/harshpatel5940/gitvigil/internal/models"
+	"github.com/harshpatel5940/gitvigil/internal/queue"
+	"github.com/jackc/pgx/v5"
+)
+
+// ReplayResult summarizes a replay run.
+type ReplayResult struct {
+	Replayed int      `json:"replayed"`
+	Failed   int      `json:"failed"`
+	Errors   []string `json:"errors,omitempty"`
+}
+
+// Replay reprocesses a single stored delivery, regardless of its status.
+// The original receive time is kept so backdate detection is unaffected.
+func (h *Handler) Replay(ctx context.Context, deliveryID string) error {
+	store := models.NewDeliveryStore(h.db.Pool)
+	delivery, err := store.GetByDeliveryID(ctx, deliveryID)
+	if err != nil {
+		return err
+	}
+
+	h.logger.Info().
+		Str("delivery_id", deliveryID).
+		Str("event", delivery.EventType).
+		Msg("replaying delivery")
+
+	return h.process(ctx, delivery)
+}
+
+// ReplayRange reprocesses every delivery received in [from, to), oldest first.
+// Failures are collected and do not stop the run.
+func (h *Handler) ReplayRange(ctx context.Context, from, to time.Time) (*ReplayResult, error) {
+	store := models.NewDeliveryStore(h.db.Pool)
+	deliveries, err := store.ListByTimeRange(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ReplayResult{}
+	for _, delivery := range deliveries {
+		if err := h.process(ctx, delivery); err != nil {
+			result.Failed++
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", delivery.DeliveryID, err))
+			continue
+		}
+		result.Replayed++
+	}
+
+	h.logger.Info().
+		Time("from", from).
+		Time("to", to).
+		Int("replayed", result.Replayed).
+		Int("failed", result.Failed).
+		Msg("replay completed")
+
+	return result, nil
+}
+
+// QueueReplayRange enqueues one replay job per delivery received in
+// [from, to), oldest first, and returns how many were queued.
+func (h *Handler) QueueReplayRange(ctx context.Context, from, to time.Time) (int, error) {
+	store := models.NewDeliveryStore(h.db.Pool)
+	ids, err := store.ListIDsByTimeRange(ctx, from, to)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, id := range ids {
+		if _, err := h.queue.Enqueue(ctx, JobReplayDelivery, deliveryJob{DeliveryID: id}); err != nil {
+			return i, fmt.Errorf("failed to enqueue replay of %s: %w", id, err)
+		}
+	}
+
+	h.logger.Info().
+		Time("from", from).
+		Time("to", to).
+		Int("queued", len(ids)).
+		Msg("replay queued")
+
+	return len(ids), nil
+}
+
+func (h *Handler) handleReplayJob(ctx context.Context, job *queue.Job) error {
+	var payload deliveryJob
+	if err := job.Decode(&payload); err != nil {
+		return err
+	}
+	return h.Replay(ctx, payload.DeliveryID)
+}
+
+// HandleListDeliveries lists recent deliveries, optionally filtered by ?status=.
+func (h *Handler) HandleListDeliveries(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if v, err := strconv.Atoi(l); err == nil && v > 0 && v <= 500 {
+			limit = v
+		}
+	}
+	status := models.DeliveryStatus(r.URL.Query().Get("status"))
+
+	store := models.NewDeliveryStore(h.db.Pool)
+	deliveries, err := store.ListRecent(r.Context(), status, limit)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to list deliveries")
+		http.Error(w, "failed to list deliveries", http.StatusInternalServerError)
+		return
+	}
+
+	type deliveryResponse struct {
+		DeliveryID  string     `json:"delivery_id"`
+		EventType   string     `json:"event_type"`
+		Status      string     `json:"status"`
+		Attempts    int        `json:"attempts"`
+		LastError   *string    `json:"last_error,omitempty"`
+		ReceivedAt  time.Time  `json:"received_at"`
+		ProcessedAt *time.Time `json:"processed_at,omitempty"`
+	}
+
+	response := make([]deliveryResponse, 0, len(deliveries))
+	for _, d := range deliveries {
+		response = append(response, deliveryResponse{
+			DeliveryID:  d.DeliveryID,
+			EventType:   d.EventType,
+			Status:      string(d.Status),
+			Attempts:    d.Attempts,
+			LastError:   d.LastError,
+			ReceivedAt:  d.ReceivedAt,
+			ProcessedAt: d.ProcessedAt,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"deliveries": response})
+}
+
+// HandleReplay replays the delivery named in the URL.
+func (h *Handler) HandleReplay(w http.ResponseWriter, r *http.Request) {
+	deliveryID := chi.URLParam(r, "deliveryID")
+
+	if err := h.Replay(r.Context(), deliveryID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, "delivery not found", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, &ReplayResult{Failed: 1, Errors: []string{err.Error()}})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, &ReplayResult{Replayed: 1})
+}
+
+// HandleReplayRange queues a replay of every delivery between ?from= and
+// ?to= (RFC 3339). Large ranges outlast the request, so the replays run as
+// jobs and the response only counts them.
+func (h *Handler) HandleReplayRange(w http.ResponseWriter, r *http.Request) {
+	from, err := time.Parse(time.RFC3339, r.URL.Query().Get("from"))
+	if err != nil {
+		http.Error(w, "from parameter is required (RFC 3339)", http.StatusBadRequest)
+		return
+	}
+
+	to := time.Now()
+	if toParam := r.URL.Query().Get("to"); toParam != "" {
+		to, err = time.Parse(time.RFC3339, toParam)
+		if err != nil {
+			http.Error(w, "invalid to parameter (RFC 3339)", http.StatusBadRequest)
+			return
+		}
+	}
+
+	queued, err := h.QueueReplayRange(r.Context(), from, to)
+	if err != nil {
+		h.logger.Error().Err(err).Int("queued", queued).Msg("failed to queue replays")
+		http.Error(w, "failed to queue replays", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, map[string]int{"queued": queued})
+}
+
+func writeJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
@@ -0,0 +1,54 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/harshpatel5940/gitvigil/internal/rules"
+	"github.com/jackc/pgx/v5"
+)
+
+// markPostDeadlinePush fills in the deadline of the repository's event on p
+// and, for a push made after the deadline plus the grace period, marks the
+// push event as post-deadline. p.PushedAt is GitHub's push timestamp, so a
+// delivery delayed past the deadline is not flagged. Pushes to repositories
+// outside any event are left alone.
+func (h *Handler) markPostDeadlinePush(ctx context.Context, tx pgx.Tx, p *rules.Push) error {
+	var eventID int64
+	var deadline time.Time
+	err := tx.QueryRow(ctx, `
+		SELECT e.id, COALESCE(e.submission_deadline, e.ends_at)
+		FROM repositories r
+		JOIN events e ON e.id = r.event_id
+		WHERE r.id = $1
+	`, p.RepositoryID).Scan(&eventID, &deadline)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	p.EventID = &eventID
+	p.Deadline = &deadline
+	p.GraceMinutes = h.cfg.SubmissionGraceMinutes
+
+	cutoff := deadline.Add(time.Duration(h.cfg.SubmissionGraceMinutes) * time.Minute)
+	if !p.PushedAt.After(cutoff) {
+		return nil
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE push_events SET post_deadline = TRUE WHERE id = $1`, p.PushEventID); err != nil {
+		return err
+	}
+
+	p.PostDeadline = true
+	p.MinutesLate = int(p.PushedAt.Sub(deadline).Minutes())
+	h.logger.Warn().
+		Int64("repository_id", p.RepositoryID).
+		Str("ref", p.Ref).
+		Int("minutes_late", p.MinutesLate).
+		Msg("push after submission deadline")
+	return nil
+}